@@ -2,15 +2,21 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"os"
+	"path"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/pkg/sftp"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/crypto/ssh"
 
 	"go-term/models"
 	"go-term/services"
@@ -26,27 +32,117 @@ type SSHController struct {
 	connections      map[string]*services.SSHConnection
 	sftpClients      map[string]*sftp.Client
 	terminalSessions map[string]*services.TerminalSession
+	commandStreams   map[string]*commandStream
+
+	// 非终端的交互式命令（可写入标准输入），按命令ID索引
+	interactiveCmds map[string]*interactiveCommand
+
+	// 正在跟踪的远程文件，按跟踪ID索引
+	tails map[string]*remoteTail
+
+	// 正在执行的批量脚本，按批量执行ID索引，用于取消
+	batchRuns map[string]context.CancelFunc
+
+	// 主机密钥校验
+	knownHosts     *services.KnownHostsManager
+	hostKeyPrompts map[string]chan bool // 等待前端确认的未知主机密钥请求
+
+	// 等待前端回答的 keyboard-interactive 认证问题
+	authPrompts map[string]chan []string
+
+	// 连接状态事件防抖
+	connectionEvents *services.ConnectionStateDebouncer
+
+	// 目录列表缓存，用于文件浏览器的本地过滤
+	dirCache *services.DirectoryCache
+
+	// 进行中的文件传输，按传输ID索引，用于取消
+	transfers map[string]*fileTransfer
+
+	// 端口转发（隧道）
+	forwards *services.ForwardManager
+
+	// 按服务器保存的命令历史
+	commandHistory *services.CommandHistory
+
+	// 后台连接监控：各连接的保活状态、缓存的连接状态，以及停止监控的函数
+	keepAlives       map[string]*keepAliveProbe
+	connectionStatus map[string]bool
+	stopMonitor      context.CancelFunc
+
+	// 正在重新连接的服务器，避免重复重连
+	reconnecting map[string]bool
+
+	// 应用层健康检查
+	healthCheckers map[string]context.CancelFunc
+	healthStatus   map[string]services.HealthStatus
 
 	// 配置文件相关
 	configFile         string
 	useEncryption      bool
 	encryptionPassword string
-	needReencrypt      bool // 标记是否需要重新加密保存
+	needReencrypt      bool   // 标记是否需要重新加密保存
+	passwordWarning    string // 主密码强度警告，为空表示密码强度足够或未启用加密
 
 	// 全局用于保护 map 的读写（短时持有）
 	mutex sync.RWMutex
 
+	// 串行化配置文件的写入；修改上面的配置文件字段需同时持有 mutex 和 saveMutex，
+	// 因此只持有 saveMutex 也可以安全读取它们（连接统计的保存不必占用全局锁）。加锁顺序：先 mutex 后 saveMutex
+	saveMutex sync.Mutex
+
 	// per-server lock，用于序列化同一 server 上的高风险操作（创建/关闭 session 等）
 	locksMutex     sync.Mutex
 	perServerLocks map[string]*sync.Mutex
 }
 
+// commandStream 一个正在执行或已结束的流式命令
+type commandStream struct {
+	serverID string
+	buffer   *services.StreamBuffer
+	cancel   context.CancelFunc
+}
+
+// interactiveCommand 一个正在执行的交互式命令，命令结束时移除
+type interactiveCommand struct {
+	serverID string
+	cmd      *services.InteractiveCommand
+}
+
+// remoteTail 一个正在跟踪的远程文件
+type remoteTail struct {
+	serverID string
+	cancel   context.CancelFunc
+}
+
+// fileTransfer 一个正在进行的文件传输
+type fileTransfer struct {
+	serverID string
+	cancel   context.CancelFunc
+}
+
 // NewSSHController 创建新的SSH控制器
 func NewSSHController() *SSHController {
-	return &SSHController{
+	sc := &SSHController{
 		connections:      make(map[string]*services.SSHConnection),
 		sftpClients:      make(map[string]*sftp.Client),
 		terminalSessions: make(map[string]*services.TerminalSession),
+		commandStreams:   make(map[string]*commandStream),
+		interactiveCmds:  make(map[string]*interactiveCommand),
+		tails:            make(map[string]*remoteTail),
+		batchRuns:        make(map[string]context.CancelFunc),
+		knownHosts:       services.NewKnownHostsManager("config/known_hosts"),
+		hostKeyPrompts:   make(map[string]chan bool),
+		authPrompts:      make(map[string]chan []string),
+		dirCache:         services.NewDirectoryCache(),
+		transfers:        make(map[string]*fileTransfer),
+		forwards:         services.NewForwardManager(),
+		commandHistory:   services.NewCommandHistory("config/history.json"),
+		keepAlives:       make(map[string]*keepAliveProbe),
+		connectionStatus: make(map[string]bool),
+		reconnecting:     make(map[string]bool),
+		healthCheckers:   make(map[string]context.CancelFunc),
+		healthStatus:     make(map[string]services.HealthStatus),
 		perServerLocks:   make(map[string]*sync.Mutex),
 		configFile:       "config/servers.dat", // 默认使用加密文件扩展名
 		useEncryption:    true,                 // 默认启用加密
@@ -55,12 +151,54 @@ func NewSSHController() *SSHController {
 		scriptParser:     services.NewScriptParser(),
 		enhancedExecutor: services.NewEnhancedScriptExecutor(),
 	}
+	sc.connectionEvents = services.NewConnectionStateDebouncer(services.DefaultConnectionGracePeriod, sc.emitConnectionState)
+	return sc
+}
+
+// emitConnectionState 推送经过防抖的连接状态事件
+func (sc *SSHController) emitConnectionState(serverID string, connected bool) {
+	if sc.ctx == nil {
+		return
+	}
+	event := "server:dropped"
+	if connected {
+		event = "server:connected"
+	}
+	runtime.EventsEmit(sc.ctx, event, serverID)
+}
+
+// emitEvent 推送事件到前端，未启动（无 ctx）时忽略
+func (sc *SSHController) emitEvent(event string, data interface{}) {
+	if sc.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(sc.ctx, event, data)
+}
+
+// SetConnectionEventGracePeriod 设置连接断开事件的宽限期（毫秒），断开持续超过该时间才通知界面
+func (sc *SSHController) SetConnectionEventGracePeriod(milliseconds int) {
+	sc.connectionEvents.SetGracePeriod(time.Duration(milliseconds) * time.Millisecond)
+}
+
+// GetConnectionEventGracePeriod 获取连接断开事件的宽限期（毫秒）
+func (sc *SSHController) GetConnectionEventGracePeriod() int {
+	return int(sc.connectionEvents.GracePeriod() / time.Millisecond)
 }
 
 // SetEncryptionConfig 设置加密配置
+// useEncryption 为 false 但提供了密码时使用混合模式：配置文件保持明文JSON，只有密码和私钥字段加密保存
 func (sc *SSHController) SetEncryptionConfig(useEncryption bool, password string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.saveMutex.Lock()
+	defer sc.saveMutex.Unlock()
+
 	sc.useEncryption = useEncryption
 	sc.encryptionPassword = password
+	sc.passwordWarning = ""
+	if useEncryption || password != "" {
+		sc.passwordWarning = passwordStrengthWarning(password)
+	}
 
 	// 根据是否使用加密设置配置文件路径
 	if useEncryption {
@@ -70,6 +208,73 @@ func (sc *SSHController) SetEncryptionConfig(useEncryption bool, password string
 	}
 }
 
+// ChangeMasterPassword 修改加密配置的主密码，用旧密码解密 servers.dat 后以新密码重新加密并原子替换
+// 旧密码错误时返回 services.ErrWrongPassword，配置文件保持不变
+func (sc *SSHController) ChangeMasterPassword(oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return fmt.Errorf("新密码不能为空")
+	}
+
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.saveMutex.Lock()
+	defer sc.saveMutex.Unlock()
+
+	if !sc.useEncryption && sc.encryptionPassword == "" {
+		return fmt.Errorf("未启用配置加密")
+	}
+	if oldPassword != sc.encryptionPassword {
+		return services.ErrWrongPassword
+	}
+
+	if !sc.useEncryption {
+		// 混合模式：内存中已是解密后的数据，用新密码重新保存即可；保存失败时恢复旧密码
+		sc.serverManager.SetSecretPassword(newPassword)
+		if err := sc.writeConfig(); err != nil {
+			sc.serverManager.SetSecretPassword(oldPassword)
+			return fmt.Errorf("修改主密码失败: %w", err)
+		}
+		sc.encryptionPassword = newPassword
+		sc.passwordWarning = passwordStrengthWarning(newPassword)
+		return nil
+	}
+
+	if _, err := os.Stat(sc.configFile); os.IsNotExist(err) {
+		// 配置文件尚未创建，直接用新密码保存当前配置；保存成功后才切换到新密码
+		if err := sc.serverManager.SaveToEncryptedFile(sc.configFile, newPassword); err != nil {
+			return fmt.Errorf("修改主密码失败: %w", err)
+		}
+		sc.encryptionPassword = newPassword
+		sc.passwordWarning = passwordStrengthWarning(newPassword)
+		return nil
+	}
+
+	if err := services.ReencryptFile(sc.configFile, oldPassword, newPassword); err != nil {
+		if errors.Is(err, services.ErrWrongPassword) {
+			return err
+		}
+		return fmt.Errorf("修改主密码失败: %w", err)
+	}
+	sc.encryptionPassword = newPassword
+	sc.passwordWarning = passwordStrengthWarning(newPassword)
+	return nil
+}
+
+// GetPasswordStrengthWarning 获取主密码强度警告，为空表示没有问题；弱密码不会阻止使用，仅供界面提示
+func (sc *SSHController) GetPasswordStrengthWarning() string {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	return sc.passwordWarning
+}
+
+// passwordStrengthWarning 返回密码强度警告文本，强度足够时返回空
+func passwordStrengthWarning(password string) string {
+	if err := services.ValidatePasswordStrength(password); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
 // helper: 获取或创建单个 server 的互斥锁
 func (sc *SSHController) getServerLock(serverID string) *sync.Mutex {
 	sc.locksMutex.Lock()
@@ -85,8 +290,14 @@ func (sc *SSHController) getServerLock(serverID string) *sync.Mutex {
 // Startup 初始化控制器
 func (sc *SSHController) Startup(ctx context.Context) {
 	sc.ctx = ctx
+	sc.startConnectionMonitor(ctx)
 	sc.serverManager = services.NewServerManager()
 
+	// 未启用整体加密但设置了主密码时，明文配置中的密码和私钥字段单独加密
+	if !sc.useEncryption && sc.encryptionPassword != "" {
+		sc.serverManager.SetSecretPassword(sc.encryptionPassword)
+	}
+
 	// 加载服务器配置
 	if sc.useEncryption {
 		// 使用新的加载方法，支持从明文自动转换为加密格式
@@ -115,16 +326,39 @@ func (sc *SSHController) Startup(ctx context.Context) {
 	if err := sc.scriptManager.LoadFromFile("config/scripts.json"); err != nil {
 		fmt.Printf("警告: 无法加载脚本配置: %v\n", err)
 	}
+
+	// 加载命令历史
+	if err := sc.commandHistory.LoadFromFile(); err != nil {
+		fmt.Printf("警告: 无法加载命令历史: %v\n", err)
+	}
 }
 
 // saveConfig 保存配置的辅助函数
 func (sc *SSHController) saveConfig() error {
+	sc.saveMutex.Lock()
+	defer sc.saveMutex.Unlock()
+	return sc.writeConfig()
+}
+
+// writeConfig 保存配置，调用方需持有 saveMutex
+func (sc *SSHController) writeConfig() error {
 	if sc.useEncryption {
 		return sc.serverManager.SaveToEncryptedFile(sc.configFile, sc.encryptionPassword)
 	}
 	return sc.serverManager.SaveToFile(sc.configFile)
 }
 
+// saveStats 保存只有连接统计变化的配置，不轮转备份；只占用 saveMutex，不阻塞持有全局锁的其他操作
+func (sc *SSHController) saveStats() error {
+	sc.saveMutex.Lock()
+	defer sc.saveMutex.Unlock()
+
+	if sc.useEncryption {
+		return sc.serverManager.SaveStatsToEncryptedFile(sc.configFile, sc.encryptionPassword)
+	}
+	return sc.serverManager.SaveStatsToFile(sc.configFile)
+}
+
 // GetServerGroups 获取所有服务器分组
 func (sc *SSHController) GetServerGroups() []models.ServerGroup {
 	sc.mutex.RLock()
@@ -134,29 +368,15 @@ func (sc *SSHController) GetServerGroups() []models.ServerGroup {
 }
 
 // GetServerConnectionStatus 获取服务器连接状态
+// 返回后台连接监控缓存的最近状态，不会发起网络请求
 func (sc *SSHController) GetServerConnectionStatus() map[string]bool {
 	sc.mutex.RLock()
 	defer sc.mutex.RUnlock()
 
-	status := make(map[string]bool)
-
-	for serverID, conn := range sc.connections {
-		if conn != nil && conn.Client != nil {
-			// 更可靠的检查方式：使用 SendRequest 而不是创建新 session
-			// SendRequest "keepalive@openssh.com" 是轻量级检查，不会创建新 session
-			_, _, err := conn.Client.SendRequest("keepalive@openssh.com", true, nil)
-			if err == nil {
-				status[serverID] = true
-			} else {
-				// 连接已断开，清理
-				delete(sc.connections, serverID)
-				status[serverID] = false
-			}
-		} else {
-			status[serverID] = false
-		}
+	status := make(map[string]bool, len(sc.connectionStatus))
+	for serverID, connected := range sc.connectionStatus {
+		status[serverID] = connected
 	}
-
 	return status
 }
 
@@ -190,10 +410,20 @@ func (sc *SSHController) DeleteServerGroup(groupID string) error {
 	sc.mutex.Lock()
 	defer sc.mutex.Unlock()
 
+	var serverIDs []string
+	for _, group := range sc.serverManager.GetGroups() {
+		if group.ID == groupID {
+			for _, server := range group.Servers {
+				serverIDs = append(serverIDs, server.ID)
+			}
+		}
+	}
+
 	err := sc.serverManager.DeleteGroup(groupID)
 	if err != nil {
 		return err
 	}
+	sc.removeCommandHistory(serverIDs...)
 
 	// 保存到文件
 	return sc.saveConfig()
@@ -236,11 +466,111 @@ func (sc *SSHController) DeleteServer(groupID, serverID string) error {
 	if err != nil {
 		return err
 	}
+	sc.removeCommandHistory(serverID)
+
+	// 保存到文件
+	return sc.saveConfig()
+}
+
+// removeCommandHistory 删除服务器时一并删除其命令历史
+func (sc *SSHController) removeCommandHistory(serverIDs ...string) {
+	for _, serverID := range serverIDs {
+		if err := sc.commandHistory.Remove(serverID); err != nil {
+			log.Printf("删除服务器 %s 的命令历史失败: %v", serverID, err)
+		}
+	}
+}
+
+// ReorderGroups 按给定的ID顺序排列分组并保存，未列出的分组追加到末尾
+func (sc *SSHController) ReorderGroups(orderedIDs []string) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if err := sc.serverManager.ReorderGroups(orderedIDs); err != nil {
+		return err
+	}
+
+	// 保存到文件
+	return sc.saveConfig()
+}
+
+// ReorderServers 按给定的ID顺序排列分组内的服务器并保存，未列出的服务器追加到末尾
+func (sc *SSHController) ReorderServers(groupID string, orderedIDs []string) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if err := sc.serverManager.ReorderServers(groupID, orderedIDs); err != nil {
+		return err
+	}
 
 	// 保存到文件
 	return sc.saveConfig()
 }
 
+// GetRecentServers 获取最近连接过的服务器，按最近连接时间降序，最多 limit 个
+func (sc *SSHController) GetRecentServers(limit int) []models.Server {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return sc.serverManager.GetRecentServers(limit)
+}
+
+// SearchServers 按名称、主机、用户名、备注和标签搜索服务器（不区分大小写），
+// 查询中的 tag:xxx 表示只保留带有该标签的服务器，例如 "tag:prod web"
+func (sc *SSHController) SearchServers(query string) []models.ServerSearchResult {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return sc.serverManager.SearchServers(query)
+}
+
+// GetServersByTag 获取带有指定标签的服务器（跨所有分组）
+func (sc *SSHController) GetServersByTag(tag string) []models.Server {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return sc.serverManager.GetServersByTag(tag)
+}
+
+// GetAllTags 获取所有服务器使用过的标签
+func (sc *SSHController) GetAllTags() []string {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return sc.serverManager.GetAllTags()
+}
+
+// GenerateKeyPair 生成SSH密钥对，返回 OpenSSH 格式私钥和 authorized_keys 格式公钥
+func (sc *SSHController) GenerateKeyPair(keyType string, comment string) (map[string]string, error) {
+	privatePEM, publicKey, err := services.GenerateKeyPair(keyType, comment)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"privateKey": privatePEM,
+		"publicKey":  publicKey,
+	}, nil
+}
+
+// SaveServerKeyData 将私钥内容保存到服务器配置（随配置文件加密保存）
+func (sc *SSHController) SaveServerKeyData(serverID, privatePEM string) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	server, err := sc.serverManager.GetServerByID(serverID)
+	if err != nil {
+		return err
+	}
+
+	updated := *server
+	updated.KeyData = privatePEM
+	if err := sc.serverManager.UpdateServer(updated.GroupID, updated); err != nil {
+		return err
+	}
+
+	return sc.saveConfig()
+}
+
 // ConnectToServer 连接到服务器
 func (sc *SSHController) ConnectToServer(serverID string) (string, error) {
 	// 先读取服务器配置 & 当前连接状态（短锁）
@@ -252,16 +582,10 @@ func (sc *SSHController) ConnectToServer(serverID string) (string, error) {
 		return "已连接到服务器", nil
 	}
 
-	// 从 serverManager 获取 server 信息（此处使用方法可能会读取内部数据结构；serverManager 本身应保证并发安全）
-	server, err := sc.serverManager.GetServerByID(serverID)
-	if err != nil {
-		return "", fmt.Errorf("无法找到服务器: %v", err)
-	}
-
 	// 创建连接是在无全局锁下进行的耗时 IO
-	connection := &services.SSHConnection{}
-	if err := connection.Connect(server.Host, server.Port, server.Username, server.Password, server.KeyFile); err != nil {
-		return "", fmt.Errorf("连接失败: %v", err)
+	server, connection, err := sc.dialServer(serverID)
+	if err != nil {
+		return "", err
 	}
 
 	// 成功后将连接写入 map（短锁）
@@ -277,9 +601,209 @@ func (sc *SSHController) ConnectToServer(serverID string) (string, error) {
 	sc.connections[serverID] = connection
 	sc.mutex.Unlock()
 
+	sc.recordConnection(serverID)
+	sc.setConnectionStatus(serverID, true)
+	sc.startKeepAlive(serverID, connection, server.KeepAliveIntervalSeconds)
+	sc.startHealthCheck(serverID, server.HealthCheckCommand, server.HealthCheckIntervalSeconds)
+
 	return "连接成功", nil
 }
 
+// dialServer 按服务器配置建立一个新的 SSH 连接，不登记到连接列表，也不更新连接统计或启动后台监控
+func (sc *SSHController) dialServer(serverID string) (*models.Server, *services.SSHConnection, error) {
+	// 从 serverManager 获取 server 信息（此处使用方法可能会读取内部数据结构；serverManager 本身应保证并发安全）
+	server, err := sc.serverManager.GetServerByID(serverID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法找到服务器: %v", err)
+	}
+
+	connection := &services.SSHConnection{
+		KeyData:             server.KeyData,
+		UseAgent:            server.UseAgent,
+		HostKeyCallback:     sc.knownHosts.HostKeyCallback(sc.confirmHostKey(serverID)),
+		KeyboardInteractive: sc.keyboardInteractiveChallenge(serverID, server.Password),
+		CommandWrapper:      server.CommandWrapper,
+		WrapTerminal:        server.WrapTerminal,
+		Env:                 server.Env,
+		DialTimeout:         services.ConnectTimeout(server.ConnectTimeoutSeconds),
+		ProxySOCKS5:         server.ProxySOCKS5,
+		CommandTimeout:      services.CommandTimeout(server.CommandTimeoutSeconds),
+	}
+	if err := connection.Connect(server.Host, server.Port, server.Username, server.Password, server.KeyFile); err != nil {
+		var mismatch *services.HostKeyMismatchError
+		if errors.As(err, &mismatch) && sc.ctx != nil {
+			runtime.EventsEmit(sc.ctx, "host-key-mismatch", map[string]interface{}{
+				"serverID":    serverID,
+				"host":        mismatch.Host,
+				"fingerprint": mismatch.Fingerprint,
+			})
+		}
+		return nil, nil, fmt.Errorf("连接失败: %w", err)
+	}
+
+	return server, connection, nil
+}
+
+// TestConnection 测试服务器配置能否连接，不会保存配置也不会加入连接列表，可用于尚未保存的表单数据
+// 连接成功后执行 echo ok 并立即断开，返回耗时和结果；连接失败不作为错误返回，而是记录在结果中。
+// 主机密钥只校验不记录，测试时接受的未知主机密钥不会写入 known_hosts
+func (sc *SSHController) TestConnection(server models.Server) services.ConnectionTestResult {
+	connection := &services.SSHConnection{
+		KeyData:             server.KeyData,
+		UseAgent:            server.UseAgent,
+		HostKeyCallback:     sc.knownHosts.VerifyHostKeyCallback(sc.confirmHostKey(server.ID)),
+		KeyboardInteractive: sc.keyboardInteractiveChallenge(server.ID, server.Password),
+		CommandWrapper:      server.CommandWrapper,
+		DialTimeout:         services.ConnectTimeout(server.ConnectTimeoutSeconds),
+		ProxySOCKS5:         server.ProxySOCKS5,
+	}
+	return services.ProbeConnection(connection, server.Host, server.Port, server.Username, server.Password, server.KeyFile)
+}
+
+// recordConnection 更新服务器的连接统计并保存，失败只打印警告，不影响连接
+// 统计在 ServerManager 的锁内更新，保存（加密时包含耗时的密钥派生）不持有全局 sc.mutex
+func (sc *SSHController) recordConnection(serverID string) {
+	if err := sc.serverManager.RecordConnection(serverID); err != nil {
+		fmt.Printf("警告: 无法更新连接统计: %v\n", err)
+		return
+	}
+	if err := sc.saveStats(); err != nil {
+		fmt.Printf("警告: 无法保存连接统计: %v\n", err)
+	}
+}
+
+// hostKeyConfirmTimeout 等待用户确认未知主机密钥的最长时间
+const hostKeyConfirmTimeout = 2 * time.Minute
+
+// confirmHostKey 返回未知主机密钥的确认函数：推送 host-key-unknown 事件并等待前端调用 RespondHostKey
+func (sc *SSHController) confirmHostKey(serverID string) services.HostKeyConfirmFunc {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) bool {
+		if sc.ctx == nil {
+			return false
+		}
+
+		requestID := fmt.Sprintf("hostkey_%s_%d", serverID, time.Now().UnixNano())
+		answer := make(chan bool, 1)
+
+		sc.mutex.Lock()
+		sc.hostKeyPrompts[requestID] = answer
+		sc.mutex.Unlock()
+
+		defer func() {
+			sc.mutex.Lock()
+			delete(sc.hostKeyPrompts, requestID)
+			sc.mutex.Unlock()
+		}()
+
+		runtime.EventsEmit(sc.ctx, "host-key-unknown", map[string]interface{}{
+			"requestID":   requestID,
+			"serverID":    serverID,
+			"host":        hostname,
+			"keyType":     key.Type(),
+			"fingerprint": ssh.FingerprintSHA256(key),
+		})
+
+		select {
+		case accepted := <-answer:
+			return accepted
+		case <-time.After(hostKeyConfirmTimeout):
+			return false
+		}
+	}
+}
+
+// RespondHostKey 前端对未知主机密钥的确认结果，accept 为 true 时记录到 known_hosts
+func (sc *SSHController) RespondHostKey(requestID string, accept bool) error {
+	sc.mutex.RLock()
+	answer, exists := sc.hostKeyPrompts[requestID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("主机密钥确认请求不存在或已超时")
+	}
+
+	select {
+	case answer <- accept:
+	default:
+	}
+	return nil
+}
+
+// authPromptTimeout 等待用户回答认证问题的最长时间
+const authPromptTimeout = 2 * time.Minute
+
+// keyboardInteractiveChallenge 返回 keyboard-interactive 认证回调
+// 单个不回显的密码问题且已配置密码时自动回答；其余问题推送 auth-prompt 事件，等待前端调用 RespondAuthPrompt
+func (sc *SSHController) keyboardInteractiveChallenge(serverID, password string) ssh.KeyboardInteractiveChallenge {
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		// 部分服务器会发送没有问题的空轮次
+		if len(questions) == 0 {
+			return []string{}, nil
+		}
+
+		if len(questions) == 1 && !echos[0] && password != "" &&
+			strings.Contains(strings.ToLower(questions[0]), "password") {
+			return []string{password}, nil
+		}
+
+		if sc.ctx == nil {
+			return nil, fmt.Errorf("无法进行交互式认证")
+		}
+
+		requestID := fmt.Sprintf("auth_%s_%d", serverID, time.Now().UnixNano())
+		answer := make(chan []string, 1)
+
+		sc.mutex.Lock()
+		sc.authPrompts[requestID] = answer
+		sc.mutex.Unlock()
+
+		defer func() {
+			sc.mutex.Lock()
+			delete(sc.authPrompts, requestID)
+			sc.mutex.Unlock()
+		}()
+
+		runtime.EventsEmit(sc.ctx, "auth-prompt", map[string]interface{}{
+			"requestID":   requestID,
+			"serverID":    serverID,
+			"name":        name,
+			"instruction": instruction,
+			"questions":   questions,
+			"echos":       echos,
+		})
+
+		select {
+		case answers := <-answer:
+			if answers == nil {
+				return nil, fmt.Errorf("用户取消了认证")
+			}
+			if len(answers) != len(questions) {
+				return nil, fmt.Errorf("认证回答数量不匹配: 需要 %d 个，收到 %d 个", len(questions), len(answers))
+			}
+			return answers, nil
+		case <-time.After(authPromptTimeout):
+			return nil, fmt.Errorf("等待认证回答超时")
+		}
+	}
+}
+
+// RespondAuthPrompt 前端对认证问题的回答，answers 为 nil 表示取消认证
+func (sc *SSHController) RespondAuthPrompt(requestID string, answers []string) error {
+	sc.mutex.RLock()
+	answer, exists := sc.authPrompts[requestID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("认证请求不存在或已超时")
+	}
+
+	select {
+	case answer <- answers:
+	default:
+	}
+	return nil
+}
+
 // ExecuteCommand 在服务器上执行命令
 func (sc *SSHController) ExecuteCommand(serverID, command string) (string, error) {
 	// 优先检查是否存在终端会话（短锁）
@@ -287,6 +811,8 @@ func (sc *SSHController) ExecuteCommand(serverID, command string) (string, error
 	session, hasSession := sc.terminalSessions[serverID]
 	sc.mutex.RUnlock()
 
+	sc.commandHistory.Add(serverID, command)
+
 	if hasSession {
 		// 在发送命令前确保shell状态干净
 		// 发送 Ctrl+U 清除当前可能存在的输入，然后发送用户选择的命令
@@ -314,43 +840,94 @@ func (sc *SSHController) ExecuteCommand(serverID, command string) (string, error
 
 	result, err := conn.ExecuteCommand(command)
 	if err != nil {
-		return "", fmt.Errorf("执行命令失败: %v", err)
+		return "", fmt.Errorf("执行命令失败: %w", err)
 	}
 	return result, nil
 }
 
-// DisconnectFromServer 断开服务器连接 - 修复死锁版本
-func (sc *SSHController) DisconnectFromServer(serverID string) (string, error) {
-	// 使用超时上下文避免死锁
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	// 分步操作，避免锁嵌套
-
-	// 1. 先获取连接信息（只读）
+// ExecuteHostCommand 直接在主机上执行命令，跳过服务器配置的命令包装（用于诊断）
+func (sc *SSHController) ExecuteHostCommand(serverID, command string) (string, error) {
 	sc.mutex.RLock()
-	session, hasSession := sc.terminalSessions[serverID]
-	conn, hasConn := sc.connections[serverID]
-	sftpClient, hasSftp := sc.sftpClients[serverID]
+	conn, exists := sc.connections[serverID]
 	sc.mutex.RUnlock()
 
-	var errMsgs []string
-
-	// 2. 在无锁状态下关闭资源
-	if hasSession && session != nil {
-		if err := sc.closeSessionWithTimeout(ctx, session); err != nil {
-			errMsgs = append(errMsgs, fmt.Sprintf("关闭终端会话失败: %v", err))
-		}
+	if !exists || conn.Client == nil {
+		return "", fmt.Errorf("服务器未连接，请先连接服务器")
 	}
 
-	if hasSftp && sftpClient != nil {
-		if err := sftpClient.Close(); err != nil {
-			log.Printf("关闭SFTP客户端警告: %v", err)
-		}
+	result, err := conn.ExecuteCommandOnHost(command)
+	if err != nil {
+		return result, fmt.Errorf("执行命令失败: %v", err)
 	}
+	return result, nil
+}
+
+// DisconnectFromServer 断开服务器连接 - 修复死锁版本
+func (sc *SSHController) DisconnectFromServer(serverID string) (string, error) {
+	// 分步操作，避免锁嵌套
+
+	// 1. 先获取连接信息（只读）
+	sc.mutex.RLock()
+	session, hasSession := sc.terminalSessions[serverID]
+	conn, hasConn := sc.connections[serverID]
+	sftpClient, hasSftp := sc.sftpClients[serverID]
+	sc.mutex.RUnlock()
+
+	var errMsgs []string
+
+	// 先停止该服务器上的后台任务，让文件传输尽量在连接关闭前清理未完成的文件
+	sc.stopServerActivity(serverID)
 
+	// 2. 在无锁状态下并行关闭资源，总共最多等待 disconnectCloseTimeout，避免其中一个卡住导致整个断开过程挂起
+	ctx, cancel := context.WithTimeout(context.Background(), disconnectCloseTimeout)
+	defer cancel()
+	var sessionErr, sftpErr, connErr error
+	var wg sync.WaitGroup
+	if hasSession && session != nil {
+		// 连接可能先于会话关闭，提前标记，避免把主动断开当作意外断开推送事件
+		session.MarkClosing()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sessionErr = closeWithTimeout(ctx, "会话", session.Close)
+		}()
+	}
+	if hasSftp && sftpClient != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sftpErr = closeWithTimeout(ctx, "SFTP客户端", sftpClient.Close)
+		}()
+	}
 	if hasConn && conn != nil {
-		conn.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			connErr = closeWithTimeout(ctx, "SSH连接", func() error {
+				conn.Close()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+
+	// SSH连接同时关闭，会话和SFTP客户端的通道随之失效，关闭时的其他错误只记录日志，超时才视为失败
+	if sessionErr != nil {
+		if errors.Is(sessionErr, errCloseTimeout) {
+			errMsgs = append(errMsgs, fmt.Sprintf("关闭终端会话失败: %v", sessionErr))
+		} else {
+			log.Printf("关闭终端会话警告: %v", sessionErr)
+		}
+	}
+	if sftpErr != nil {
+		if errors.Is(sftpErr, errCloseTimeout) {
+			errMsgs = append(errMsgs, fmt.Sprintf("关闭SFTP客户端失败: %v", sftpErr))
+		} else {
+			log.Printf("关闭SFTP客户端警告: %v", sftpErr)
+		}
+	}
+	if connErr != nil {
+		errMsgs = append(errMsgs, fmt.Sprintf("关闭SSH连接失败: %v", connErr))
 	}
 
 	// 3. 最后清理数据结构
@@ -364,8 +941,12 @@ func (sc *SSHController) DisconnectFromServer(serverID string) (string, error) {
 	if hasConn {
 		delete(sc.connections, serverID)
 	}
+	delete(sc.connectionStatus, serverID)
 	sc.mutex.Unlock()
 
+	// 主动断开不发出连接状态事件；连接已移除，进行中的保活探测不会再上报状态
+	sc.connectionEvents.Forget(serverID)
+
 	// 清理per-server锁
 	sc.locksMutex.Lock()
 	delete(sc.perServerLocks, serverID)
@@ -378,12 +959,19 @@ func (sc *SSHController) DisconnectFromServer(serverID string) (string, error) {
 	return "服务器连接已安全断开", nil
 }
 
-// closeSessionWithTimeout 带超时的会话关闭
-func (sc *SSHController) closeSessionWithTimeout(ctx context.Context, session *services.TerminalSession) error {
+// disconnectCloseTimeout 断开连接时关闭终端会话、SFTP客户端和SSH连接的总时限（三者并行关闭）
+const disconnectCloseTimeout = 10 * time.Second
+
+// errCloseTimeout 关闭资源超时
+var errCloseTimeout = errors.New("超时")
+
+// closeWithTimeout 在单独的 goroutine 中关闭资源，ctx 结束（超过时限）后不再等待并返回 errCloseTimeout
+// 超时后关闭操作仍在后台继续，结果被丢弃
+func closeWithTimeout(ctx context.Context, name string, closeFn func() error) error {
 	resultChan := make(chan error, 1)
 
 	go func() {
-		resultChan <- session.Close()
+		resultChan <- closeFn()
 	}()
 
 	select {
@@ -393,7 +981,63 @@ func (sc *SSHController) closeSessionWithTimeout(ctx context.Context, session *s
 		}
 		return nil
 	case <-ctx.Done():
-		return fmt.Errorf("关闭会话超时")
+		return fmt.Errorf("关闭%s%w", name, errCloseTimeout)
+	}
+}
+
+// shutdownTimeout 应用退出时关闭所有连接的总时限，超时后不再等待，避免退出卡住
+const shutdownTimeout = 5 * time.Second
+
+// Shutdown 应用退出时调用：保存延迟写入的命令历史，停止后台监控，并行断开所有服务器（终端会话、SFTP客户端和SSH连接），
+// 让服务器端及时释放 shell 和 SFTP 进程。尽力而为，超过 shutdownTimeout 后直接返回
+func (sc *SSHController) Shutdown() {
+	// 保存延迟写入的命令历史
+	if err := sc.commandHistory.Flush(); err != nil {
+		log.Printf("退出时保存命令历史失败: %v", err)
+	}
+
+	sc.mutex.Lock()
+	if sc.stopMonitor != nil {
+		sc.stopMonitor()
+		sc.stopMonitor = nil
+	}
+	serverIDs := make(map[string]struct{})
+	for serverID := range sc.terminalSessions {
+		serverIDs[serverID] = struct{}{}
+	}
+	for serverID := range sc.sftpClients {
+		serverIDs[serverID] = struct{}{}
+	}
+	for serverID := range sc.connections {
+		serverIDs[serverID] = struct{}{}
+	}
+	sc.mutex.Unlock()
+
+	if len(serverIDs) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for serverID := range serverIDs {
+		wg.Add(1)
+		go func(serverID string) {
+			defer wg.Done()
+			if _, err := sc.DisconnectFromServer(serverID); err != nil {
+				log.Printf("退出时断开服务器 %s 失败: %v", serverID, err)
+			}
+		}(serverID)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(shutdownTimeout):
+		log.Printf("退出时断开连接超时（%v），剩余连接将由服务器端超时释放", shutdownTimeout)
 	}
 }
 
@@ -420,33 +1064,34 @@ func (sc *SSHController) isConnectionHealthy(serverID string) bool {
 		return false
 	}
 
-	// 简单的连通性检查
-	_, err := conn.Client.NewSession()
+	// 简单的连通性检查，检查用的会话需要立即关闭
+	session, err := conn.Client.NewSession()
 	if err != nil {
-		// 连接已断开，清理
-		sc.mutex.Lock()
-		delete(sc.connections, serverID)
-		sc.mutex.Unlock()
+		// 连接已断开：在写锁下清理（仅当连接仍是检查时的那个）
+		sc.handleConnectionLost(serverID, conn)
 		return false
 	}
+	session.Close()
 
 	return true
 }
 
-// isSessionActive 检查会话是否真正活跃
-func (sc *SSHController) isSessionActive(session *services.TerminalSession) bool {
-	if session == nil || session.OutputChan == nil {
+// removeTerminalSession 在写锁下从 map 中移除终端会话，会话已被替换时不做处理
+func (sc *SSHController) removeTerminalSession(serverID string, session *services.TerminalSession) bool {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	if current, ok := sc.terminalSessions[serverID]; !ok || current != session {
 		return false
 	}
+	delete(sc.terminalSessions, serverID)
+	return true
+}
 
-	select {
-	case _, ok := <-session.OutputChan:
-		return ok // 如果channel已关闭，返回false
-	default:
-		// channel正常，尝试发送一个简单的心跳命令
-		// 这里可以添加更复杂的健康检查逻辑
-		return true
-	}
+// isSessionActive 检查会话是否真正活跃
+// 只读取会话的结束标记，不会从输出通道中取走数据
+func (sc *SSHController) isSessionActive(session *services.TerminalSession) bool {
+	return session != nil && !session.IsClosed()
 }
 
 // CreateTerminalSession 创建终端会话 - 修复竞态条件
@@ -468,9 +1113,7 @@ func (sc *SSHController) CreateTerminalSession(serverID string) (string, error)
 		}
 
 		// 清理无效会话
-		sc.mutex.Lock()
-		delete(sc.terminalSessions, serverID)
-		sc.mutex.Unlock()
+		sc.removeTerminalSession(serverID, existingSession)
 	}
 
 	// 3. 使用无锁方式创建会话
@@ -501,6 +1144,9 @@ func (sc *SSHController) CreateTerminalSession(serverID string) (string, error)
 
 	// 设置事件推送函数并启动推送协程
 	terminalSession.SetEventEmitter(serverID, func(event string, data ...interface{}) {
+		if sc.ctx == nil {
+			return
+		}
 		runtime.EventsEmit(sc.ctx, event, data...)
 	})
 	terminalSession.StartOutputPusher()
@@ -510,10 +1156,15 @@ func (sc *SSHController) CreateTerminalSession(serverID string) (string, error)
 
 // CreateTerminalSessionWithSize 创建指定尺寸的终端会话
 func (sc *SSHController) CreateTerminalSessionWithSize(serverID string, width, height int) (string, error) {
+	return sc.CreateTerminalSessionWithOptions(serverID, width, height, services.TerminalOptions{})
+}
+
+// CreateTerminalSessionWithOptions 按选项（如终端类型 $TERM）创建指定尺寸的终端会话
+func (sc *SSHController) CreateTerminalSessionWithOptions(serverID string, width, height int, options services.TerminalOptions) (string, error) {
 	// 先短锁读取 connection 和会话存在性
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
-	_, sessionExists := sc.terminalSessions[serverID]
+	existingSession, sessionExists := sc.terminalSessions[serverID]
 	sc.mutex.RUnlock()
 
 	if !exists || conn.Client == nil {
@@ -526,9 +1177,7 @@ func (sc *SSHController) CreateTerminalSessionWithSize(serverID string, width, h
 		if !sc.IsTerminalSessionActive(serverID) {
 			fmt.Println("会话已失效", serverID)
 			// 会话已失效，清理并允许创建新会话
-			sc.mutex.Lock()
-			delete(sc.terminalSessions, serverID)
-			sc.mutex.Unlock()
+			sc.removeTerminalSession(serverID, existingSession)
 		} else {
 			// 会话仍然有效
 			return "终端会话已存在", nil
@@ -541,7 +1190,7 @@ func (sc *SSHController) CreateTerminalSessionWithSize(serverID string, width, h
 	defer serverLock.Unlock()
 
 	// createTerminal 是耗时 IO —— 必须在没有持有全局 sc.mutex 的情况下执行
-	terminalSession, err := conn.CreateTerminalSession(width, height)
+	terminalSession, err := conn.CreateTerminalSessionWithOptions(width, height, options)
 	if err != nil {
 		return "", fmt.Errorf("创建终端会话失败: %v", err)
 	}
@@ -560,13 +1209,42 @@ func (sc *SSHController) CreateTerminalSessionWithSize(serverID string, width, h
 
 	// 设置事件推送函数并启动推送协程
 	terminalSession.SetEventEmitter(serverID, func(event string, data ...interface{}) {
+		if sc.ctx == nil {
+			return
+		}
 		runtime.EventsEmit(sc.ctx, event, data...)
 	})
 	terminalSession.StartOutputPusher()
+	terminalSession.StartIdleWatcher(func() {
+		sc.closeIdleTerminalSession(serverID, terminalSession)
+	})
 
 	return "终端会话创建成功", nil
 }
 
+// closeIdleTerminalSession 关闭空闲超时的终端会话并推送 terminal:idle-timeout 事件
+// 会话已被替换或关闭时不做处理
+func (sc *SSHController) closeIdleTerminalSession(serverID string, session *services.TerminalSession) {
+	sc.mutex.Lock()
+	if current, ok := sc.terminalSessions[serverID]; !ok || current != session {
+		sc.mutex.Unlock()
+		return
+	}
+	delete(sc.terminalSessions, serverID)
+	sc.mutex.Unlock()
+
+	if sc.ctx != nil {
+		runtime.EventsEmit(sc.ctx, "terminal:idle-timeout", map[string]interface{}{
+			"serverID":    serverID,
+			"idleSeconds": int(session.IdleTimeout() / time.Second),
+		})
+	}
+
+	if err := session.Close(); err != nil {
+		log.Printf("关闭空闲终端会话失败: %v", err)
+	}
+}
+
 // CreateSFTPClient 创建SFTP客户端
 func (sc *SSHController) CreateSFTPClient(serverID string) (string, error) {
 	// 读取 connection 副本（短锁）
@@ -655,6 +1333,68 @@ func (sc *SSHController) ClearTerminalOutputBuffer(serverID string) error {
 	return nil
 }
 
+// GetTerminalHistory 获取终端最近的最多 maxBytes 字节原始输出（maxBytes <= 0 时返回全部），用于界面重连后重绘
+func (sc *SSHController) GetTerminalHistory(serverID string, maxBytes int) (string, error) {
+	sc.mutex.RLock()
+	terminalSession, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("终端会话不存在")
+	}
+	return terminalSession.GetHistory(maxBytes), nil
+}
+
+// StartRecording 开始将终端会话录制为 asciinema v2（.cast）文件
+func (sc *SSHController) StartRecording(serverID, filePath string) (string, error) {
+	sc.mutex.RLock()
+	terminalSession, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("终端会话不存在")
+	}
+	if err := terminalSession.StartRecording(filePath); err != nil {
+		return "", fmt.Errorf("开始录制失败: %v", err)
+	}
+	return "开始录制", nil
+}
+
+// StopRecording 停止终端会话录制，返回录制文件路径
+func (sc *SSHController) StopRecording(serverID string) (string, error) {
+	sc.mutex.RLock()
+	terminalSession, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("终端会话不存在")
+	}
+	path, err := terminalSession.StopRecording()
+	if err != nil {
+		return "", fmt.Errorf("停止录制失败: %v", err)
+	}
+	return path, nil
+}
+
+// FindInScrollback 在终端回滚缓冲区中搜索（已去除ANSI序列），返回匹配位置供前端高亮和跳转
+func (sc *SSHController) FindInScrollback(serverID, pattern string, useRegex, ignoreCase bool) ([]services.ScrollbackMatch, error) {
+	sc.mutex.RLock()
+	terminalSession, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("终端会话不存在")
+	}
+
+	return terminalSession.FindInScrollback(pattern, useRegex, ignoreCase)
+}
+
+// Tab补全时等待输出的参数：输出平静多久视为结束，以及每一步的最长等待时间
+const (
+	completionQuietWindow = 80 * time.Millisecond
+	completionMaxWait     = 2 * time.Second
+)
+
 // GetAutoCompleteSuggestions 获取自动补全建议
 func (sc *SSHController) GetAutoCompleteSuggestions(serverID, partialCommand string) ([]string, error) {
 	sc.mutex.RLock()
@@ -665,61 +1405,165 @@ func (sc *SSHController) GetAutoCompleteSuggestions(serverID, partialCommand str
 		return nil, fmt.Errorf("终端会话不存在")
 	}
 
-	// 清空输出缓冲区
+	// 优先通过一次性 exec 会话查询命令/路径候选，速度更快且不影响终端
+	sc.mutex.RLock()
+	conn, hasConn := sc.connections[serverID]
+	sc.mutex.RUnlock()
+	if hasConn && conn.Client != nil {
+		suggestions, err := conn.QueryCompletions(partialCommand)
+		if err != nil {
+			log.Printf("查询补全候选失败，回退到Tab补全: %v", err)
+		} else if len(suggestions) > 0 {
+			return suggestions, nil
+		}
+	}
+
+	// 回退：通过终端Tab补全处理工具特定的补全
+	// 每一步都等待输出平静后再继续，而不是固定等待，慢速链路上也能拿到完整的补全输出。
+	// 补全期间的终端输出暂存而不推送到界面，输入行按固定顺序修改和还原：
+	//   1. Ctrl+U 清空当前输入行
+	//   2. 输入部分命令，发送一到两次Tab
+	//   3. 发送 n 回答可能出现的 "Display all N possibilities?" 询问或退出分页，
+	//      再用 Ctrl+U 清空输入行并重新输入部分命令
+	// 还原后输入行恢复为补全前的部分命令；等待输出平静后恢复推送，暂存的输出一次性补发，
+	// 界面与远程终端保持一致，期间其他程序的输出也不会丢失
+	terminalSession.SetOutputSuppressed(true)
+	defer func() {
+		if err := terminalSession.SendCommandWithoutNewline("n\x15" + partialCommand); err != nil {
+			log.Printf("还原补全输入行失败: %v", err)
+		}
+		terminalSession.WaitForOutputQuiet(completionQuietWindow, completionMaxWait)
+		terminalSession.ClearOutputBuffer()
+		terminalSession.SetOutputSuppressed(false)
+	}()
+
+	if err := terminalSession.SendCommandWithoutNewline("\x15"); err != nil {
+		return nil, fmt.Errorf("发送命令失败: %v", err)
+	}
+	terminalSession.WaitForOutputQuiet(completionQuietWindow, completionMaxWait)
 	terminalSession.ClearOutputBuffer()
 
-	// 发送部分命令（不带换行符）
+	// 发送部分命令（不带换行符），等待回显
 	if err := terminalSession.SendCommandWithoutNewline(partialCommand); err != nil {
 		return nil, fmt.Errorf("发送命令失败: %v", err)
 	}
+	terminalSession.WaitForOutputQuiet(completionQuietWindow, completionMaxWait)
 
-	// 等待一小段时间让shell处理
-	time.Sleep(20 * time.Millisecond)
-
-	// 发送两次Tab字符获取补全选项列表
-	if err := terminalSession.SendCommandWithoutNewline("\t\t"); err != nil {
+	// 发送Tab：唯一匹配时shell直接补全，有多个匹配时通常只响铃
+	if err := terminalSession.SendCommandWithoutNewline("\t"); err != nil {
 		return nil, fmt.Errorf("发送Tab失败: %v", err)
 	}
+	received := terminalSession.WaitForOutputQuiet(completionQuietWindow, completionMaxWait)
 
-	// 等待shell处理补全
-	time.Sleep(150 * time.Millisecond)
+	// 没有补全出内容时再发送一次Tab，让shell列出所有候选
+	if received < 2 {
+		if err := terminalSession.SendCommandWithoutNewline("\t"); err != nil {
+			return nil, fmt.Errorf("发送Tab失败: %v", err)
+		}
+		terminalSession.WaitForOutputQuiet(completionQuietWindow, completionMaxWait)
+	}
 
-	// 获取补全输出
-	output := terminalSession.GetLastOutput()
+	// 获取全部补全输出
+	output := terminalSession.GetBufferedOutput()
 
-	// 如果没有获取到有效的补全输出，尝试单次Tab
-	if strings.TrimSpace(output) == "" || len(strings.TrimSpace(output)) < 2 {
-		// 再次清空缓冲区
-		terminalSession.ClearOutputBuffer()
+	// 解析补全建议
+	suggestions := terminalSession.ParseAutoCompleteSuggestions(partialCommand, output)
+	if len(suggestions) > services.MaxCompletionResults {
+		suggestions = suggestions[:services.MaxCompletionResults]
+	}
 
-		// 重新发送命令
-		if err := terminalSession.SendCommandWithoutNewline(partialCommand); err != nil {
-			return nil, fmt.Errorf("重新发送命令失败: %v", err)
+	return suggestions, nil
+}
+
+// sftpProgressInterval sftp:progress 事件的最小推送间隔
+const sftpProgressInterval = 100 * time.Millisecond
+
+// sftpProgress 创建传输进度回调，按时间节流推送 "sftp:progress" 事件
+// 事件中包含时间戳和平均速率（字节/秒），传输完成时总会推送一次
+func (sc *SSHController) sftpProgress(serverID, transferID, direction, remotePath string) func(transferred, total int64) {
+	start := time.Now()
+	var lastEmit time.Time
+	return func(transferred, total int64) {
+		now := time.Now()
+		if transferred < total && now.Sub(lastEmit) < sftpProgressInterval {
+			return
+		}
+		lastEmit = now
+		if sc.ctx == nil {
+			return
 		}
-		time.Sleep(20 * time.Millisecond)
 
-		// 发送单次Tab
-		if err := terminalSession.SendCommandWithoutNewline("\t"); err != nil {
-			return nil, fmt.Errorf("发送单次Tab失败: %v", err)
+		var percent, bytesPerSecond float64
+		if total > 0 {
+			percent = float64(transferred) / float64(total) * 100
+		}
+		if elapsed := now.Sub(start).Seconds(); elapsed > 0 {
+			bytesPerSecond = float64(transferred) / elapsed
 		}
-		time.Sleep(100 * time.Millisecond)
+		runtime.EventsEmit(sc.ctx, "sftp:progress", map[string]interface{}{
+			"serverID":       serverID,
+			"transferID":     transferID,
+			"direction":      direction,
+			"path":           remotePath,
+			"transferred":    transferred,
+			"total":          total,
+			"percent":        percent,
+			"bytesPerSecond": bytesPerSecond,
+			"timestamp":      now.UnixMilli(),
+		})
+	}
+}
 
-		// 获取新的输出
-		output = terminalSession.GetLastOutput()
+// beginTransfer 登记一个可取消的文件传输，transferID 为空时自动生成
+// parent 取消时传输同样会被取消；返回的 done 必须在传输结束后调用以注销
+func (sc *SSHController) beginTransfer(parent context.Context, serverID, transferID string) (string, context.Context, func()) {
+	if transferID == "" {
+		transferID = fmt.Sprintf("transfer_%s_%d", serverID, time.Now().UnixNano())
 	}
+	ctx, cancel := context.WithCancel(parent)
 
-	// 解析补全建议
-	suggestions := terminalSession.ParseAutoCompleteSuggestions(partialCommand, output)
+	sc.mutex.Lock()
+	sc.transfers[transferID] = &fileTransfer{serverID: serverID, cancel: cancel}
+	sc.mutex.Unlock()
 
-	// 只清空内部缓冲区，不在终端发送任何清理字符
-	// 前端会负责显示管理，避免污染终端状态
-	terminalSession.ClearOutputBuffer()
+	return transferID, ctx, func() {
+		cancel()
+		sc.mutex.Lock()
+		delete(sc.transfers, transferID)
+		sc.mutex.Unlock()
+	}
+}
 
-	return suggestions, nil
+// CancelTransfer 取消正在进行的文件传输，未完成的目标文件会被删除
+func (sc *SSHController) CancelTransfer(serverID, transferID string) (string, error) {
+	sc.mutex.RLock()
+	transfer, exists := sc.transfers[transferID]
+	sc.mutex.RUnlock()
+
+	if !exists || transfer.serverID != serverID {
+		return "", fmt.Errorf("传输不存在或已结束: %s", transferID)
+	}
+	transfer.cancel()
+	return "已取消传输", nil
+}
+
+// cancelServerTransfers 取消服务器上所有进行中的文件传输
+func (sc *SSHController) cancelServerTransfers(serverID string) {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	for _, transfer := range sc.transfers {
+		if transfer.serverID == serverID {
+			transfer.cancel()
+		}
+	}
 }
 
 // UploadFile 上传文件
 func (sc *SSHController) UploadFile(serverID, localPath, remotePath string) (string, error) {
+	return sc.uploadFile(context.Background(), serverID, localPath, remotePath)
+}
+
+func (sc *SSHController) uploadFile(parent context.Context, serverID, localPath, remotePath string) (string, error) {
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
 	sftpClient, sftpExists := sc.sftpClients[serverID]
@@ -733,15 +1577,23 @@ func (sc *SSHController) UploadFile(serverID, localPath, remotePath string) (str
 	}
 
 	// 上传文件（不持锁）
-	if err := conn.UploadFile(sftpClient, localPath, remotePath, nil); err != nil {
+	transferID, ctx, done := sc.beginTransfer(parent, serverID, "")
+	defer done()
+	if err := conn.UploadFile(ctx, sftpClient, localPath, remotePath, sc.sftpProgress(serverID, transferID, "upload", remotePath)); err != nil {
 		return "", fmt.Errorf("上传文件失败: %v", err)
 	}
+	sc.dirCache.InvalidatePath(serverID, remotePath)
 	return "文件上传成功", nil
 }
 
 // UploadFileWithProgress 带进度回调的上传文件
 // wails:export
 func (sc *SSHController) UploadFileWithProgress(serverID, localPath, remotePath string) (string, error) {
+	return sc.UploadFileWithOptions(serverID, localPath, remotePath, services.TransferOptions{})
+}
+
+// UploadFileWithOptions 按传输选项（如换行符转换）上传文件，并推送进度
+func (sc *SSHController) UploadFileWithOptions(serverID, localPath, remotePath string, options services.TransferOptions) (string, error) {
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
 	sftpClient, sftpExists := sc.sftpClients[serverID]
@@ -754,8 +1606,20 @@ func (sc *SSHController) UploadFileWithProgress(serverID, localPath, remotePath
 		return "", fmt.Errorf("SFTP客户端未创建，请先创建SFTP客户端")
 	}
 
+	// 预检目标目录是否可写，避免传输大量数据后才失败
+	if options.Preflight {
+		if err := conn.CheckRemoteWritable(sftpClient, path.Dir(remotePath)); err != nil {
+			return "", fmt.Errorf("上传文件失败: %v", err)
+		}
+	}
+
 	// 带进度回调的上传
-	if err := conn.UploadFile(sftpClient, localPath, remotePath, func(transferred, total int64) {
+	transferID, ctx, done := sc.beginTransfer(context.Background(), serverID, options.TransferID)
+	defer done()
+	progress := sc.sftpProgress(serverID, transferID, "upload", remotePath)
+	if err := conn.UploadFileWithOptions(ctx, sftpClient, localPath, remotePath, options, func(transferred, total int64) {
+		progress(transferred, total)
+
 		// 发送进度事件到前端
 		percent := float64(transferred) / float64(total) * 100
 		runtime.EventsEmit(sc.ctx, "file-upload-progress", map[string]interface{}{
@@ -767,11 +1631,51 @@ func (sc *SSHController) UploadFileWithProgress(serverID, localPath, remotePath
 	}); err != nil {
 		return "", fmt.Errorf("上传文件失败: %v", err)
 	}
+	sc.dirCache.InvalidatePath(serverID, remotePath)
 	return "文件上传成功", nil
 }
 
+// UploadFiles 以默认并发数批量上传多个文件，返回每个文件的结果
+func (sc *SSHController) UploadFiles(serverID string, pairs []services.TransferPair) ([]services.TransferResult, error) {
+	return sc.UploadFilesWithConcurrency(serverID, pairs, services.DefaultTransferConcurrency)
+}
+
+// UploadFilesWithConcurrency 使用指定数量的并发工作协程批量上传多个文件
+// 同一个 SFTP 客户端支持并发请求，单个文件失败不影响其它文件
+func (sc *SSHController) UploadFilesWithConcurrency(serverID string, pairs []services.TransferPair, concurrency int) ([]services.TransferResult, error) {
+	if len(pairs) == 0 {
+		return []services.TransferResult{}, nil
+	}
+	if err := sc.EnsureSFTPClient(serverID); err != nil {
+		return nil, fmt.Errorf("创建SFTP客户端失败: %v", err)
+	}
+
+	results := services.RunTransfers(pairs, concurrency, func(pair services.TransferPair) error {
+		_, err := sc.UploadFile(serverID, pair.LocalPath, pair.RemotePath)
+		return err
+	})
+	return results, nil
+}
+
+// CheckRemoteWritable 检查远程目录是否可写（创建并删除一个临时文件）
+// 不可写时返回 false 和具体原因
+func (sc *SSHController) CheckRemoteWritable(serverID, dirPath string) (bool, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return false, err
+	}
+	if err := conn.CheckRemoteWritable(sftpClient, dirPath); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // DownloadFile 下载文件
 func (sc *SSHController) DownloadFile(serverID, remotePath, localPath string) (string, error) {
+	return sc.downloadFile(context.Background(), serverID, remotePath, localPath)
+}
+
+func (sc *SSHController) downloadFile(parent context.Context, serverID, remotePath, localPath string) (string, error) {
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
 	sftpClient, sftpExists := sc.sftpClients[serverID]
@@ -785,7 +1689,9 @@ func (sc *SSHController) DownloadFile(serverID, remotePath, localPath string) (s
 	}
 
 	// 下载文件（不持锁）
-	if err := conn.DownloadFile(sftpClient, remotePath, localPath, nil); err != nil {
+	transferID, ctx, done := sc.beginTransfer(parent, serverID, "")
+	defer done()
+	if err := conn.DownloadFile(ctx, sftpClient, remotePath, localPath, sc.sftpProgress(serverID, transferID, "download", remotePath)); err != nil {
 		return "", fmt.Errorf("下载文件失败: %v", err)
 	}
 	return "文件下载成功", nil
@@ -794,6 +1700,11 @@ func (sc *SSHController) DownloadFile(serverID, remotePath, localPath string) (s
 // DownloadFileWithProgress 带进度回调的下载文件
 // wails:export
 func (sc *SSHController) DownloadFileWithProgress(serverID, remotePath, localPath string) (string, error) {
+	return sc.DownloadFileWithOptions(serverID, remotePath, localPath, services.TransferOptions{})
+}
+
+// DownloadFileWithOptions 按传输选项（如换行符转换）下载文件，并推送进度
+func (sc *SSHController) DownloadFileWithOptions(serverID, remotePath, localPath string, options services.TransferOptions) (string, error) {
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
 	sftpClient, sftpExists := sc.sftpClients[serverID]
@@ -807,7 +1718,12 @@ func (sc *SSHController) DownloadFileWithProgress(serverID, remotePath, localPat
 	}
 
 	// 带进度回调的下载
-	if err := conn.DownloadFile(sftpClient, remotePath, localPath, func(transferred, total int64) {
+	transferID, ctx, done := sc.beginTransfer(context.Background(), serverID, options.TransferID)
+	defer done()
+	progress := sc.sftpProgress(serverID, transferID, "download", remotePath)
+	if err := conn.DownloadFileWithOptions(ctx, sftpClient, remotePath, localPath, options, func(transferred, total int64) {
+		progress(transferred, total)
+
 		// 发送进度事件到前端
 		percent := float64(transferred) / float64(total) * 100
 		runtime.EventsEmit(sc.ctx, "file-download-progress", map[string]interface{}{
@@ -841,9 +1757,24 @@ func (sc *SSHController) ListDirectory(serverID, path string) ([]services.FileIn
 	if err != nil {
 		return nil, fmt.Errorf("列出目录内容失败: %v", err)
 	}
+	sc.dirCache.Put(serverID, path, files)
 	return files, nil
 }
 
+// FilterDirectory 按名称过滤目录内容，优先使用缓存的目录列表，避免每次输入都访问服务器
+// pattern 包含通配符时按通配符匹配，fuzzy 为 true 时按子序列模糊匹配，否则按子串匹配
+func (sc *SSHController) FilterDirectory(serverID, path, pattern string, fuzzy bool) ([]services.FileInfo, error) {
+	files, ok := sc.dirCache.Get(serverID, path)
+	if !ok {
+		var err error
+		files, err = sc.ListDirectory(serverID, path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return services.FilterFileInfos(files, pattern, fuzzy), nil
+}
+
 // CreateDirectory 创建目录
 func (sc *SSHController) CreateDirectory(serverID, path string) (string, error) {
 	sc.mutex.RLock()
@@ -862,6 +1793,7 @@ func (sc *SSHController) CreateDirectory(serverID, path string) (string, error)
 	if err := conn.CreateDirectory(sftpClient, path); err != nil {
 		return "", fmt.Errorf("创建目录失败: %v", err)
 	}
+	sc.dirCache.InvalidatePath(serverID, path)
 	return "目录创建成功", nil
 }
 
@@ -883,25 +1815,200 @@ func (sc *SSHController) DeleteFile(serverID, path string) (string, error) {
 	if err := conn.DeleteFile(sftpClient, path); err != nil {
 		return "", fmt.Errorf("删除文件失败: %v", err)
 	}
+	sc.dirCache.InvalidatePath(serverID, path)
 	return "文件删除成功", nil
 }
 
-// ExecuteCommandWithoutNewline 执行命令但不添加换行符
-func (sc *SSHController) ExecuteCommandWithoutNewline(serverID, command string) (string, error) {
-	// 优先检查是否存在终端会话（短锁）
+// RenameFile 重命名或移动远程文件/目录
+func (sc *SSHController) RenameFile(serverID, oldPath, newPath string) (string, error) {
 	sc.mutex.RLock()
-	session, hasSession := sc.terminalSessions[serverID]
+	conn, exists := sc.connections[serverID]
+	sftpClient, sftpExists := sc.sftpClients[serverID]
 	sc.mutex.RUnlock()
 
-	if hasSession {
-		// 通过终端会话发送命令（不添加换行符）
-		if err := session.SendCommandWithoutNewline(command); err != nil {
-			return "", fmt.Errorf("发送命令失败: %v", err)
-		}
-		return "命令已发送", nil
+	if !exists || conn.Client == nil {
+		return "", fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+	if !sftpExists {
+		return "", fmt.Errorf("SFTP客户端未创建，请先创建SFTP客户端")
 	}
 
-	return "", fmt.Errorf("终端会话不存在")
+	// 重命名（不持锁）
+	if err := conn.RenameFile(sftpClient, oldPath, newPath); err != nil {
+		return "", fmt.Errorf("重命名文件失败: %v", err)
+	}
+	sc.dirCache.InvalidatePath(serverID, oldPath)
+	sc.dirCache.InvalidatePath(serverID, newPath)
+	return "文件重命名成功", nil
+}
+
+// CopyRemoteFile 在同一服务器上复制文件或目录，不经过本地磁盘
+func (sc *SSHController) CopyRemoteFile(serverID, srcPath, dstPath string) (string, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := conn.CopyRemoteFile(sftpClient, srcPath, dstPath); err != nil {
+		return "", fmt.Errorf("复制文件失败: %v", err)
+	}
+	sc.dirCache.InvalidatePath(serverID, dstPath)
+	return "文件复制成功", nil
+}
+
+// GetDirectorySize 递归统计远程目录的总大小和文件数，统计期间推送 "sftp:dir-size-progress" 事件
+// 可通过 CancelDirectorySize 取消，取消时返回已统计的部分结果
+func (sc *SSHController) GetDirectorySize(serverID, path string) (services.DirectorySize, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return services.DirectorySize{Path: path}, err
+	}
+
+	// 与文件传输共用取消登记，使用固定ID以便按路径取消
+	_, ctx, done := sc.beginTransfer(context.Background(), serverID, dirSizeTaskID(serverID, path))
+	defer done()
+
+	size, err := conn.DirectorySize(ctx, sftpClient, path, func(progress services.DirectorySize) {
+		if sc.ctx != nil {
+			runtime.EventsEmit(sc.ctx, "sftp:dir-size-progress", serverID, progress)
+		}
+	})
+	if err != nil {
+		return size, fmt.Errorf("统计目录大小失败: %v", err)
+	}
+	return size, nil
+}
+
+// CancelDirectorySize 取消正在进行的目录大小统计
+func (sc *SSHController) CancelDirectorySize(serverID, path string) (string, error) {
+	return sc.CancelTransfer(serverID, dirSizeTaskID(serverID, path))
+}
+
+func dirSizeTaskID(serverID, path string) string {
+	return fmt.Sprintf("dirsize_%s_%s", serverID, path)
+}
+
+// ChangeFilePermissions 修改远程文件权限，mode 为八进制字符串，如 "755" 或 "0644"
+func (sc *SSHController) ChangeFilePermissions(serverID, path, mode string) (string, error) {
+	perm, err := strconv.ParseUint(strings.TrimSpace(mode), 8, 32)
+	if err != nil || perm > 07777 {
+		return "", fmt.Errorf("无效的权限值: %s", mode)
+	}
+
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sftpClient, sftpExists := sc.sftpClients[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return "", fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+	if !sftpExists {
+		return "", fmt.Errorf("SFTP客户端未创建，请先创建SFTP客户端")
+	}
+
+	// 修改权限（不持锁），sftp 会直接传递 setuid/setgid/sticky 位
+	if err := conn.Chmod(sftpClient, path, os.FileMode(perm)); err != nil {
+		return "", fmt.Errorf("修改文件权限失败: %v", err)
+	}
+	sc.dirCache.InvalidatePath(serverID, path)
+	return "文件权限修改成功", nil
+}
+
+// ExecuteCommandWithoutNewline 执行命令但不添加换行符
+func (sc *SSHController) ExecuteCommandWithoutNewline(serverID, command string) (string, error) {
+	// 优先检查是否存在终端会话（短锁）
+	sc.mutex.RLock()
+	session, hasSession := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if hasSession {
+		// 通过终端会话发送命令（不添加换行符）
+		if err := session.SendCommandWithoutNewline(command); err != nil {
+			return "", fmt.Errorf("发送命令失败: %v", err)
+		}
+		return "命令已发送", nil
+	}
+
+	return "", fmt.Errorf("终端会话不存在")
+}
+
+// PasteToTerminal 向终端粘贴文本
+// 远程程序开启了括号粘贴模式时用 ESC[200~ … ESC[201~ 包装整段文本，多行内容不会被逐行执行；
+// 否则逐行发送，行间稍作停顿，避免远程来不及处理
+func (sc *SSHController) PasteToTerminal(serverID, text string) error {
+	sc.mutex.RLock()
+	session, hasSession := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !hasSession {
+		return fmt.Errorf("终端会话不存在")
+	}
+
+	if session.BracketedPasteEnabled() {
+		if err := session.SendCommandWithoutNewline(services.BracketedPaste(text)); err != nil {
+			return fmt.Errorf("粘贴失败: %v", err)
+		}
+		return nil
+	}
+
+	for i, line := range services.PasteLines(text) {
+		if i > 0 {
+			time.Sleep(services.PasteLineDelay)
+		}
+		if err := session.SendCommandWithoutNewline(line); err != nil {
+			return fmt.Errorf("粘贴失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// BroadcastCommand 向多个服务器的终端会话同时发送命令（自动添加换行），返回发送失败的服务器及原因
+// 没有终端会话的服务器同样记录在结果中；全部成功时返回空 map
+func (sc *SSHController) BroadcastCommand(serverIDs []string, command string) map[string]string {
+	return sc.broadcastToTerminals(serverIDs, func(session *services.TerminalSession) error {
+		return session.SendCommand(command)
+	})
+}
+
+// BroadcastInput 向多个服务器的终端会话同时发送原始输入（按键级广播，不添加换行），返回值同 BroadcastCommand
+func (sc *SSHController) BroadcastInput(serverIDs []string, data string) map[string]string {
+	return sc.broadcastToTerminals(serverIDs, func(session *services.TerminalSession) error {
+		return session.SendCommandWithoutNewline(data)
+	})
+}
+
+// broadcastToTerminals 并发地对每个服务器的终端会话执行 send，收集失败信息
+func (sc *SSHController) broadcastToTerminals(serverIDs []string, send func(session *services.TerminalSession) error) map[string]string {
+	failures := make(map[string]string)
+	var failuresMutex sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, serverID := range serverIDs {
+		sc.mutex.RLock()
+		session, hasSession := sc.terminalSessions[serverID]
+		sc.mutex.RUnlock()
+
+		if !hasSession {
+			failuresMutex.Lock()
+			failures[serverID] = "终端会话不存在"
+			failuresMutex.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(serverID string, session *services.TerminalSession) {
+			defer wg.Done()
+			if err := send(session); err != nil {
+				failuresMutex.Lock()
+				failures[serverID] = fmt.Sprintf("发送失败: %v", err)
+				failuresMutex.Unlock()
+			}
+		}(serverID, session)
+	}
+
+	wg.Wait()
+	return failures
 }
 
 // InterruptCommand 中断当前正在执行的命令（发送 Ctrl+C）
@@ -970,9 +2077,7 @@ func (sc *SSHController) CloseTerminalSession(serverID string) (string, error) {
 	}
 
 	// 确保清理数据结构（短锁）
-	sc.mutex.Lock()
-	delete(sc.terminalSessions, serverID)
-	sc.mutex.Unlock()
+	sc.removeTerminalSession(serverID, session)
 
 	if errMsg != "" {
 		return "", fmt.Errorf("%s", errMsg)
@@ -980,6 +2085,35 @@ func (sc *SSHController) CloseTerminalSession(serverID string) (string, error) {
 	return "终端会话已关闭", nil
 }
 
+// SetTerminalIdleFlushInterval 设置终端输出的空闲刷新间隔（毫秒），小于等于0时恢复默认值
+// 没有新输出超过该时间时，暂存的输出（如不带换行的提示符）会立即推送到前端
+func (sc *SSHController) SetTerminalIdleFlushInterval(serverID string, milliseconds int) (string, error) {
+	sc.mutex.RLock()
+	session, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("终端会话不存在")
+	}
+
+	session.SetIdleFlushInterval(time.Duration(milliseconds) * time.Millisecond)
+	return "设置成功", nil
+}
+
+// SetTerminalBellEnabled 设置终端会话是否推送 terminal:bell 响铃事件
+func (sc *SSHController) SetTerminalBellEnabled(serverID string, enabled bool) (string, error) {
+	sc.mutex.RLock()
+	session, exists := sc.terminalSessions[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("终端会话不存在")
+	}
+
+	session.SetBellEnabled(enabled)
+	return "设置成功", nil
+}
+
 // ResizeTerminal 调整终端大小
 func (sc *SSHController) ResizeTerminal(serverID string, width, height int) (string, error) {
 	// 读取终端会话（短锁）
@@ -1011,6 +2145,15 @@ func (sc *SSHController) AddBatchScript(script models.BatchScript) error {
 	return sc.scriptManager.AddScript(script)
 }
 
+// ValidateBatchScript 检查批量脚本的执行配置和内容，返回发现的问题（带行号，供编辑器标出），没有问题时返回空列表
+func (sc *SSHController) ValidateBatchScript(script models.BatchScript) []services.ScriptValidationIssue {
+	issues := services.ValidateScript(script)
+	if issues == nil {
+		issues = []services.ScriptValidationIssue{}
+	}
+	return issues
+}
+
 // UpdateBatchScript 更新批量脚本
 func (sc *SSHController) UpdateBatchScript(script models.BatchScript) error {
 	return sc.scriptManager.UpdateScript(script)
@@ -1022,19 +2165,51 @@ func (sc *SSHController) DeleteBatchScript(scriptID string) error {
 }
 
 // ExecuteBatchScript 执行批量脚本
+// 执行开始时固定脚本快照，执行期间对脚本的修改或删除不影响本次执行，
+// 本次执行的所有结果都记录为快照中的脚本名称和版本。
+// 脚本设置了时间预算时，超出预算后中止正在执行的命令，尚未开始的服务器标记为 skipped，并返回已有的部分结果。
+// 执行过程中每台服务器状态变化（pending、running、完成）和每条命令完成时发送 batch:progress 事件，数据为该服务器当前的 ScriptExecution。
+// 执行方式为 sequential 时按服务器列表顺序逐台执行，设置了 StopOnFirstFailure 时某台失败后其余服务器标记为 skipped。
+// 开始时发送 batch:started（batchID、scriptID）事件，执行期间可用 batchID 调用 CancelBatchExecution 取消
 func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.ScriptExecution, error) {
-	// 获取脚本
-	script, err := sc.scriptManager.GetScriptByID(scriptID)
+	// 获取脚本快照
+	script, err := sc.scriptManager.SnapshotScript(scriptID)
 	if err != nil {
 		return nil, fmt.Errorf("获取脚本失败: %v", err)
 	}
 
-	// 获取所有服务器组以解析服务器名称
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if script.MaxTotalDurationSeconds > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, time.Duration(script.MaxTotalDurationSeconds)*time.Second)
+		defer cancelTimeout()
+	}
+
+	// 登记本次执行，用于取消
+	batchID := fmt.Sprintf("batch_%s_%d", scriptID, time.Now().UnixNano())
+	sc.mutex.Lock()
+	sc.batchRuns[batchID] = cancel
+	sc.mutex.Unlock()
+	defer func() {
+		sc.mutex.Lock()
+		delete(sc.batchRuns, batchID)
+		sc.mutex.Unlock()
+	}()
+	sc.emitEvent("batch:started", map[string]interface{}{
+		"batchID":  batchID,
+		"scriptID": scriptID,
+	})
+	executor := &contextExecutor{sc: sc, ctx: ctx}
+	options := services.NewExecutionOptions(script)
+	options.Context = ctx
+
+	// 获取所有服务器组以解析服务器名称和脚本内置变量
 	groups := sc.serverManager.GetGroups()
-	serverMap := make(map[string]string)
+	serverMap := make(map[string]models.Server)
 	for _, group := range groups {
 		for _, server := range group.Servers {
-			serverMap[server.ID] = server.Name
+			serverMap[server.ID] = server
 		}
 	}
 
@@ -1043,32 +2218,81 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 	var wg sync.WaitGroup
 	var resultMutex sync.Mutex
 
-	// 并发控制 - 限制最大并发数为10
-	maxConcurrent := 10
-	semaphore := make(chan struct{}, maxConcurrent)
+	// 并发控制 - 按脚本配置限制最大并发数（默认10）
+	semaphore := make(chan struct{}, services.BatchConcurrency(script))
+	sequential := script.ExecutionMode == "sequential"
+
+	// 保存服务器的执行状态，并通过 batch:progress 事件通知界面
+	setResult := func(execution models.ScriptExecution) {
+		resultMutex.Lock()
+		results[execution.ServerID] = execution
+		resultMutex.Unlock()
+		sc.emitEvent("batch:progress", execution)
+	}
+
+	newExecution := func(sid string) models.ScriptExecution {
+		return models.ScriptExecution{
+			ID:             fmt.Sprintf("exec_%s_%s_%d", scriptID, sid, time.Now().Unix()),
+			BatchID:        batchID,
+			ScriptID:       scriptID,
+			ScriptName:     script.Name,
+			ScriptVersion:  script.UpdatedAt,
+			ServerID:       sid,
+			ServerName:     serverMap[sid].Name,
+			Status:         "pending",
+			StartTime:      time.Now().Format("2006-01-02 15:04:05"),
+			CommandOutputs: make([]models.CommandOutput, 0),
+		}
+	}
 
-	for _, serverID := range script.ServerIDs {
+	for i, serverID := range script.ServerIDs {
 		wg.Add(1)
 		go func(sid string) {
 			defer wg.Done()
 
-			// 获取信号量
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+			execution := newExecution(sid)
 
-			execution := models.ScriptExecution{
-				ID:             fmt.Sprintf("exec_%s_%s_%d", scriptID, sid, time.Now().Unix()),
-				ScriptID:       scriptID,
-				ServerID:       sid,
-				ServerName:     serverMap[sid],
-				Status:         "pending",
-				StartTime:      time.Now().Format("2006-01-02 15:04:05"),
-				CommandOutputs: make([]models.CommandOutput, 0),
+			setResult(execution)
+
+			// 获取信号量，等待期间时间预算耗尽则跳过该服务器，执行被取消则标记为 cancelled
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				execution.Status = "skipped"
+				execution.Error = "跳过：已超出时间预算"
+				if errors.Is(ctx.Err(), context.Canceled) {
+					execution.Status = "cancelled"
+					execution.Error = "执行已取消"
+				}
+				execution.EndTime = time.Now().Format("2006-01-02 15:04:05")
+				setResult(execution)
+				return
 			}
 
-			resultMutex.Lock()
-			results[sid] = execution
-			resultMutex.Unlock()
+			// 替换脚本变量，存在未定义的变量时不执行
+			content, err := sc.scriptParser.SubstituteVariables(script.Content, services.ScriptVariables(script.Variables, serverMap[sid]))
+			if err != nil {
+				execution.Status = "failed"
+				execution.Error = err.Error()
+				execution.EndTime = time.Now().Format("2006-01-02 15:04:05")
+				setResult(execution)
+				return
+			}
+
+			execution.Status = "running"
+			setResult(execution)
+
+			// 每条命令完成时通知界面，附带到目前为止的命令结果
+			serverOptions := options
+			serverOptions.OnCommandDone = func(output models.CommandOutput) {
+				execution.CommandOutputs = append(execution.CommandOutputs, output)
+				progress := execution
+				progress.CommandOutputs = append([]models.CommandOutput(nil), execution.CommandOutputs...)
+				setResult(progress)
+			}
 
 			var commandOutputs []models.CommandOutput
 			var execErr error
@@ -1076,24 +2300,24 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 			// 根据执行类型选择执行方式
 			if script.ExecutionType == "script" {
 				// 脚本模式：将整个脚本内容作为一个整体执行
-				commandOutputs, execErr = sc.enhancedExecutor.ExecuteScriptMode(script.Content, sc, sid)
+				commandOutputs, execErr = sc.enhancedExecutor.ExecuteScriptModeWithOptions(content, executor, sid, serverOptions)
 			} else {
 				// 命令模式：逐条执行每个命令（默认模式）
-				parsedCommands := sc.enhancedExecutor.ParseCommands(script.Content)
+				parsedCommands := sc.enhancedExecutor.ParseCommands(content)
 				if len(parsedCommands) == 0 {
 					execErr = fmt.Errorf("脚本中没有有效的命令")
 				} else {
-					commandOutputs, execErr = sc.enhancedExecutor.ExecuteCommandMode(parsedCommands, sc, sid)
+					commandOutputs, execErr = sc.enhancedExecutor.ExecuteCommandModeWithOptions(parsedCommands, executor, sid, serverOptions)
 				}
 			}
 
 			execution.EndTime = time.Now().Format("2006-01-02 15:04:05")
 			execution.CommandOutputs = commandOutputs
 
-			// 检查是否有失败的命令
+			// 检查是否有失败（含超时）的命令，允许失败（$ne）的命令不计入
 			hasFailedCommand := false
 			for _, cmdOutput := range commandOutputs {
-				if cmdOutput.Status == "failed" {
+				if (cmdOutput.Status == "failed" || cmdOutput.Status == "timeout") && !cmdOutput.ContinueOnError {
 					hasFailedCommand = true
 					break
 				}
@@ -1107,7 +2331,7 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 				execution.Status = "failed"
 				// 显示第一个失败的命令的错误信息
 				for _, cmdOutput := range commandOutputs {
-					if cmdOutput.Status == "failed" {
+					if (cmdOutput.Status == "failed" || cmdOutput.Status == "timeout") && !cmdOutput.ContinueOnError {
 						// 优先使用命令级别的错误信息
 						if cmdOutput.Error != "" {
 							execution.Error = cmdOutput.Error
@@ -1116,6 +2340,10 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 						} else {
 							execution.Error = "命令执行失败，但没有详细的错误信息"
 						}
+						// 标明失败的命令在脚本中的行号
+						if cmdOutput.Line > 0 {
+							execution.Error = fmt.Sprintf("第%d行: %s", cmdOutput.Line, execution.Error)
+						}
 						break
 					}
 				}
@@ -1127,6 +2355,16 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 				execution.Status = "success"
 			}
 
+			// 执行过程中超出时间预算，明确标注中止原因
+			if execution.Status == "failed" && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				execution.Error = fmt.Sprintf("超出时间预算（%d秒），执行已中止: %s", script.MaxTotalDurationSeconds, execution.Error)
+			}
+			// 执行过程中被取消
+			if execution.Status == "failed" && errors.Is(ctx.Err(), context.Canceled) {
+				execution.Status = "cancelled"
+				execution.Error = fmt.Sprintf("执行已取消: %s", execution.Error)
+			}
+
 			// 最终检查：确保失败状态一定有错误信息
 			if execution.Status == "failed" && execution.Error == "" {
 				execution.Error = "执行失败，但未能获取具体的错误信息"
@@ -1147,16 +2385,117 @@ func (sc *SSHController) ExecuteBatchScript(scriptID string) (map[string]models.
 				}
 			}
 
-			resultMutex.Lock()
-			results[sid] = execution
-			resultMutex.Unlock()
+			setResult(execution)
 		}(serverID)
+
+		// 逐台执行：等待当前服务器执行完成后再开始下一台
+		if !sequential {
+			continue
+		}
+		wg.Wait()
+		if script.StopOnFirstFailure && results[serverID].Status == "failed" {
+			failedName := serverMap[serverID].Name
+			if failedName == "" {
+				failedName = serverID
+			}
+			for _, sid := range script.ServerIDs[i+1:] {
+				execution := newExecution(sid)
+				execution.Status = "skipped"
+				execution.Error = fmt.Sprintf("跳过：服务器 %s 执行失败", failedName)
+				execution.EndTime = execution.StartTime
+				setResult(execution)
+			}
+			break
+		}
 	}
 
 	wg.Wait()
 	return results, nil
 }
 
+// CancelBatchExecution 取消正在进行的批量执行：中止正在执行的命令，尚未开始的服务器标记为 cancelled，
+// ExecuteBatchScript 随后返回已得到的部分结果
+func (sc *SSHController) CancelBatchExecution(batchID string) error {
+	sc.mutex.RLock()
+	cancel, exists := sc.batchRuns[batchID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("批量执行不存在或已结束: %s", batchID)
+	}
+
+	cancel()
+	return nil
+}
+
+// CollectAcrossServers 在选中的所有服务器上并发执行同一命令，返回各服务器输出及取值统计
+// 未连接的服务器会临时连接，执行完成后断开，不改变原有的连接状态
+func (sc *SSHController) CollectAcrossServers(selector models.ServerSelector, command string) (*services.FleetCollectResult, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("命令不能为空")
+	}
+
+	servers := sc.serverManager.SelectServers(selector)
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("没有匹配的服务器")
+	}
+
+	outputs := make(map[string]string)
+	errs := make(map[string]string)
+	var wg sync.WaitGroup
+	var resultMutex sync.Mutex
+
+	// 并发控制，与批量脚本执行的默认值保持一致
+	semaphore := make(chan struct{}, services.DefaultBatchConcurrency)
+
+	for _, server := range servers {
+		wg.Add(1)
+		go func(sid string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			output, err := sc.collectFromServer(sid, command)
+
+			resultMutex.Lock()
+			defer resultMutex.Unlock()
+			if err != nil {
+				errs[sid] = err.Error()
+			} else {
+				outputs[sid] = output
+			}
+		}(server.ID)
+	}
+
+	wg.Wait()
+
+	return &services.FleetCollectResult{
+		Outputs: outputs,
+		Errors:  errs,
+		Stats:   services.AggregateOutputs(outputs, errs),
+	}, nil
+}
+
+// collectFromServer 在单个服务器上执行命令；未连接时临时建立一个独立连接，执行后直接关闭
+// 临时连接不登记到连接列表，不更新连接统计、不发送连接事件，也不会影响用户在此期间打开的连接
+func (sc *SSHController) collectFromServer(serverID, command string) (string, error) {
+	sc.mutex.RLock()
+	_, connected := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	if connected {
+		return sc.ExecCommandDirect(serverID, command)
+	}
+
+	_, conn, err := sc.dialServer(serverID)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return execOnConnection(context.Background(), conn, command, 0)
+}
+
 // SendScriptToTerminal 逐行发送脚本命令到终端（用于命令模式）
 // wails:export
 func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string) error {
@@ -1179,8 +2518,8 @@ func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string)
 		return fmt.Errorf("服务器不在脚本的目标服务器列表中")
 	}
 
-	// 只处理命令模式的脚本
-	if script.ExecutionType != "command" {
+	// 只处理命令模式的脚本（没有指定执行类型的旧脚本同样按命令模式执行）
+	if script.ExecutionType != "command" && script.ExecutionType != "" {
 		return fmt.Errorf("仅支持命令模式脚本的终端交互执行")
 	}
 
@@ -1189,6 +2528,12 @@ func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string)
 	if len(parsedCommands) == 0 {
 		return fmt.Errorf("脚本中没有有效的命令")
 	}
+	// 终端中无法获取每条命令的执行结果，不支持条件块
+	for _, parsedCmd := range parsedCommands {
+		if parsedCmd.CommandType == "if" {
+			return fmt.Errorf("终端交互执行不支持条件块（$if），请使用命令模式执行")
+		}
+	}
 
 	// 确保终端会话存在
 	_, err = sc.CreateTerminalSession(serverID)
@@ -1200,9 +2545,9 @@ func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string)
 	for _, parsedCmd := range parsedCommands {
 		// 处理文件上传命令
 		if parsedCmd.CommandType == "upload" {
-			// 解析上传命令参数
-			parts := strings.Fields(parsedCmd.Command)
-			if len(parts) >= 2 {
+			// 解析上传命令参数（包含空格的路径可以用引号括起来）
+			parts, parseErr := services.SplitCommandArgs(parsedCmd.Command)
+			if parseErr == nil && len(parts) >= 2 {
 				localPath := parts[0]
 				remoteDir := parts[1]
 
@@ -1256,9 +2601,9 @@ func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string)
 
 		// 处理文件下载命令
 		if parsedCmd.CommandType == "download" {
-			// 解析下载命令参数
-			parts := strings.Fields(parsedCmd.Command)
-			if len(parts) >= 2 {
+			// 解析下载命令参数（包含空格的路径可以用引号括起来）
+			parts, parseErr := services.SplitCommandArgs(parsedCmd.Command)
+			if parseErr == nil && len(parts) >= 2 {
 				remotePath := parts[0]
 				localPath := parts[1]
 
@@ -1319,6 +2664,16 @@ func (sc *SSHController) SendScriptToTerminal(scriptID string, serverID string)
 			continue
 		}
 
+		// 处理 $cd 指令，终端会话本身保持工作目录，直接发送 cd 命令
+		if parsedCmd.CommandType == "cd" {
+			_, err = sc.ExecuteCommand(serverID, services.ChangeDirCommand(parsedCmd.Command))
+			if err != nil {
+				fmt.Printf("发送命令到终端失败: %v\n", err)
+			}
+			time.Sleep(500 * time.Millisecond)
+			continue
+		}
+
 		// 处理shell类型的命令，发送到终端
 		if parsedCmd.CommandType == "shell" {
 			// 发送命令到终端（带换行符，让命令执行）
@@ -1342,6 +2697,11 @@ func (sc *SSHController) ExecCommand(serverID, command string) (string, error) {
 }
 
 func (sc *SSHController) ExecCommandDirect(serverID, command string) (string, error) {
+	return sc.execCommandDirect(context.Background(), serverID, command, 0)
+}
+
+// execCommandDirect 直接执行命令，timeout 为0时不限制执行时间
+func (sc *SSHController) execCommandDirect(ctx context.Context, serverID, command string, timeout time.Duration) (string, error) {
 	// 直接通过 SSHConnection 执行，不检查终端会话
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
@@ -1350,19 +2710,34 @@ func (sc *SSHController) ExecCommandDirect(serverID, command string) (string, er
 	if !exists || conn.Client == nil {
 		return "", fmt.Errorf("服务器未连接，请先连接服务器")
 	}
+	return execOnConnection(ctx, conn, command, timeout)
+}
 
-	result, err := conn.ExecuteCommand(command)
+// execOnConnection 在指定连接上执行命令，timeout 为0时不限制执行时间
+func execOnConnection(ctx context.Context, conn *services.SSHConnection, command string, timeout time.Duration) (string, error) {
+	result, err := conn.ExecuteCommandTimeout(ctx, command, timeout)
+	var timeoutErr *services.CommandTimeoutError
+	if errors.As(err, &timeoutErr) {
+		// 超时错误原样返回，便于调用方区分
+		return result, err
+	}
 	if err != nil {
 		// 如果有输出结果，说明命令执行了但有错误，返回完整的错误信息
 		if result != "" {
-			return result, fmt.Errorf("执行命令失败: %v\n输出: %s", err, result)
+			return result, fmt.Errorf("执行命令失败: %w\n输出: %s", err, result)
 		}
-		return "", fmt.Errorf("执行命令失败: %v", err)
+		return "", fmt.Errorf("执行命令失败: %w", err)
 	}
 	return result, nil
 }
 
-func (sc *SSHController) ExecCommandsInSharedSession(serverID string, commands []string) ([]string, error) {
+func (sc *SSHController) ExecCommandsInSharedSession(serverID string, commands []string) ([]services.CommandResult, error) {
+	return sc.execCommandsInSharedSession(context.Background(), serverID, commands, nil, 0)
+}
+
+// execCommandsInSharedSession 在同一个session中执行多个命令，timeout 为每条命令的执行时间限制，0表示不限制；
+// stopOnError 为 nil 时某条命令失败后继续执行之后的命令
+func (sc *SSHController) execCommandsInSharedSession(ctx context.Context, serverID string, commands []string, stopOnError []bool, timeout time.Duration) ([]services.CommandResult, error) {
 	// 直接通过 SSHConnection 执行，不检查终端会话
 	sc.mutex.RLock()
 	conn, exists := sc.connections[serverID]
@@ -1372,7 +2747,7 @@ func (sc *SSHController) ExecCommandsInSharedSession(serverID string, commands [
 		return nil, fmt.Errorf("服务器未连接，请先连接服务器")
 	}
 
-	result, err := conn.ExecuteCommandsWithSharedSession(commands)
+	result, err := conn.ExecuteCommandsWithSharedSessionTimeout(ctx, commands, stopOnError, timeout)
 	if err != nil {
 		return result, err
 	}
@@ -1387,6 +2762,48 @@ func (sc *SSHController) ExecDownloadFile(serverID, remotePath, localPath string
 	return sc.DownloadFile(serverID, remotePath, localPath)
 }
 
+// contextExecutor 绑定上下文的命令执行器，上下文取消时中止正在执行的命令和文件传输
+type contextExecutor struct {
+	sc  *SSHController
+	ctx context.Context
+}
+
+func (e *contextExecutor) ExecCommand(serverID, command string) (string, error) {
+	return e.sc.ExecCommand(serverID, command)
+}
+
+func (e *contextExecutor) ExecCommandDirect(serverID, command string) (string, error) {
+	return e.sc.execCommandDirect(e.ctx, serverID, command, 0)
+}
+
+func (e *contextExecutor) ExecCommandDirectWithTimeout(serverID, command string, timeout time.Duration) (string, error) {
+	return e.sc.execCommandDirect(e.ctx, serverID, command, timeout)
+}
+
+func (e *contextExecutor) ExecCommandsInSharedSession(serverID string, commands []string) ([]services.CommandResult, error) {
+	return e.sc.execCommandsInSharedSession(e.ctx, serverID, commands, nil, 0)
+}
+
+func (e *contextExecutor) ExecCommandsInSharedSessionWithTimeout(serverID string, commands []string, stopOnError []bool, timeout time.Duration) ([]services.CommandResult, error) {
+	return e.sc.execCommandsInSharedSession(e.ctx, serverID, commands, stopOnError, timeout)
+}
+
+func (e *contextExecutor) ExecUploadFile(serverID, localPath, remotePath string) (string, error) {
+	return e.sc.uploadFile(e.ctx, serverID, localPath, remotePath)
+}
+
+func (e *contextExecutor) ExecDownloadFile(serverID, remotePath, localPath string) (string, error) {
+	return e.sc.downloadFile(e.ctx, serverID, remotePath, localPath)
+}
+
+func (e *contextExecutor) EnsureSFTPClient(serverID string) error {
+	return e.sc.EnsureSFTPClient(serverID)
+}
+
+func (e *contextExecutor) GlobRemoteFiles(serverID, pattern string) ([]string, error) {
+	return e.sc.GlobRemoteFiles(serverID, pattern)
+}
+
 // HandleFileUploadRequest 处理文件上传请求
 func (sc *SSHController) HandleFileUploadRequest(serverID, localPath, remotePath string) error {
 	// 确保SFTP客户端已创建
@@ -1436,3 +2853,1086 @@ func (sc *SSHController) EnsureSFTPClient(serverID string) error {
 	_, err := sc.CreateSFTPClient(serverID)
 	return err
 }
+
+// ========== 流式命令输出相关方法 ==========
+
+// StartCommandStream 启动流式命令，输出保留在后端，通过 ReadStreamChunk 分页读取
+func (sc *SSHController) StartCommandStream(serverID, command string) (string, error) {
+	return sc.StartCommandStreamWithEvents(serverID, command, false)
+}
+
+// finishedStreamTTL 命令结束后输出流最多保留的时间，超时后即使没有读取完也会被释放
+const finishedStreamTTL = 10 * time.Minute
+
+// StartCommandStreamWithEvents 启动流式命令，emitEvents 为 true 时同时推送实时输出事件：
+// 每段输出推送 command-stream:output（streamID、data、offset），结束时推送 command-stream:done（streamID、error）。
+// 输出同样保留在后端，可以通过 ReadStreamChunk 补读错过的部分；命令结束后输出流最多保留 finishedStreamTTL
+func (sc *SSHController) StartCommandStreamWithEvents(serverID, command string, emitEvents bool) (string, error) {
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return "", fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &commandStream{
+		serverID: serverID,
+		buffer:   services.NewStreamBuffer(),
+		cancel:   cancel,
+	}
+	streamID := fmt.Sprintf("stream_%s_%d", serverID, time.Now().UnixNano())
+
+	sc.mutex.Lock()
+	sc.commandStreams[streamID] = stream
+	sc.mutex.Unlock()
+
+	onChunk := stream.buffer.Append
+	if emitEvents {
+		var offset int64
+		onChunk = func(data []byte) {
+			stream.buffer.Append(data)
+			sc.emitEvent("command-stream:output", map[string]interface{}{
+				"streamID": streamID,
+				"data":     string(data),
+				"offset":   offset,
+			})
+			offset += int64(len(data))
+		}
+	}
+
+	go func() {
+		defer cancel()
+		err := conn.ExecuteCommandStreamContext(ctx, command, onChunk)
+		stream.buffer.Finish(err)
+		time.AfterFunc(finishedStreamTTL, func() {
+			sc.removeCommandStream(streamID, stream)
+		})
+		if emitEvents {
+			errMsg := ""
+			if err != nil {
+				errMsg = err.Error()
+			}
+			sc.emitEvent("command-stream:done", map[string]interface{}{
+				"streamID": streamID,
+				"error":    errMsg,
+			})
+		}
+	}()
+
+	return streamID, nil
+}
+
+// ReadStreamChunk 从指定偏移读取流式命令的输出，maxBytes <= 0 表示读取全部已有数据
+// 返回 Done 为 true（命令已结束且输出已全部读取）后输出流随即释放，之后不能再读取
+func (sc *SSHController) ReadStreamChunk(streamID string, fromOffset int64, maxBytes int) (*services.StreamChunk, error) {
+	sc.mutex.RLock()
+	stream, exists := sc.commandStreams[streamID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("输出流不存在: %s", streamID)
+	}
+
+	chunk := stream.buffer.ReadChunk(fromOffset, maxBytes)
+	if chunk.Done {
+		sc.removeCommandStream(streamID, stream)
+	}
+	return &chunk, nil
+}
+
+// removeCommandStream 移除已结束的输出流；streamID 已对应其他输出流或已被移除时不做任何事
+func (sc *SSHController) removeCommandStream(streamID string, stream *commandStream) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if sc.commandStreams[streamID] == stream {
+		delete(sc.commandStreams, streamID)
+	}
+}
+
+// CloseCommandStream 停止流式命令（如仍在运行）并释放保留的输出
+func (sc *SSHController) CloseCommandStream(streamID string) error {
+	sc.mutex.Lock()
+	stream, exists := sc.commandStreams[streamID]
+	delete(sc.commandStreams, streamID)
+	sc.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("输出流不存在: %s", streamID)
+	}
+
+	stream.cancel()
+	return nil
+}
+
+// stopServerStreams 停止并移除指定服务器上的所有流式命令
+func (sc *SSHController) stopServerStreams(serverID string) {
+	sc.mutex.Lock()
+	var streams []*commandStream
+	for id, stream := range sc.commandStreams {
+		if stream.serverID == serverID {
+			streams = append(streams, stream)
+			delete(sc.commandStreams, id)
+		}
+	}
+	sc.mutex.Unlock()
+
+	for _, stream := range streams {
+		stream.cancel()
+	}
+}
+
+// ========== 交互式命令相关方法 ==========
+
+// StartCommand 在独立会话中启动非终端的交互式命令，返回命令ID
+// 通过 WriteCommandStdin 写入标准输入，输出以 command:output（commandID、data）事件推送，
+// 结束时推送 command:exit（commandID、exitCode、error）并释放命令，之后无法再通过命令ID操作；
+// WaitCommand 可在命令结束前等待其结束
+func (sc *SSHController) StartCommand(serverID, command string) (string, error) {
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return "", fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+
+	sc.commandHistory.Add(serverID, command)
+
+	commandID := fmt.Sprintf("cmd_%s_%d", serverID, time.Now().UnixNano())
+	cmd, err := conn.StartInteractiveCommand(command, func(data []byte) {
+		sc.emitEvent("command:output", map[string]interface{}{
+			"commandID": commandID,
+			"data":      string(data),
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sc.mutex.Lock()
+	sc.interactiveCmds[commandID] = &interactiveCommand{serverID: serverID, cmd: cmd}
+	sc.mutex.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		sc.removeInteractiveCommand(commandID, cmd)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		sc.emitEvent("command:exit", map[string]interface{}{
+			"commandID": commandID,
+			"exitCode":  services.ExitCodeFromError(err),
+			"error":     errMsg,
+		})
+	}()
+
+	return commandID, nil
+}
+
+// getInteractiveCommand 按ID查找交互式命令
+func (sc *SSHController) getInteractiveCommand(commandID string) (*services.InteractiveCommand, error) {
+	sc.mutex.RLock()
+	command, exists := sc.interactiveCmds[commandID]
+	sc.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("命令不存在: %s", commandID)
+	}
+	return command.cmd, nil
+}
+
+// WriteCommandStdin 向交互式命令的标准输入写入数据（需要换行时由调用方附加）
+func (sc *SSHController) WriteCommandStdin(commandID, data string) error {
+	cmd, err := sc.getInteractiveCommand(commandID)
+	if err != nil {
+		return err
+	}
+	return cmd.WriteStdin([]byte(data))
+}
+
+// CloseCommandStdin 关闭交互式命令的标准输入，命令会读到 EOF
+func (sc *SSHController) CloseCommandStdin(commandID string) error {
+	cmd, err := sc.getInteractiveCommand(commandID)
+	if err != nil {
+		return err
+	}
+	return cmd.CloseStdin()
+}
+
+// WaitCommand 等待交互式命令结束，返回退出码（无法获取时为 -1）和执行错误
+func (sc *SSHController) WaitCommand(commandID string) (int, error) {
+	cmd, err := sc.getInteractiveCommand(commandID)
+	if err != nil {
+		return -1, err
+	}
+
+	err = cmd.Wait()
+	sc.removeInteractiveCommand(commandID, cmd)
+	return services.ExitCodeFromError(err), err
+}
+
+// removeInteractiveCommand 移除已结束的交互式命令，ID 已对应其他命令时不做处理
+func (sc *SSHController) removeInteractiveCommand(commandID string, cmd *services.InteractiveCommand) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if command, ok := sc.interactiveCmds[commandID]; ok && command.cmd == cmd {
+		delete(sc.interactiveCmds, commandID)
+	}
+}
+
+// KillCommand 强制结束交互式命令并释放
+func (sc *SSHController) KillCommand(commandID string) error {
+	sc.mutex.Lock()
+	command, exists := sc.interactiveCmds[commandID]
+	delete(sc.interactiveCmds, commandID)
+	sc.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("命令不存在: %s", commandID)
+	}
+
+	command.cmd.Kill()
+	return nil
+}
+
+// stopServerCommands 结束并移除指定服务器上的所有交互式命令
+func (sc *SSHController) stopServerCommands(serverID string) {
+	sc.mutex.Lock()
+	var commands []*interactiveCommand
+	for id, command := range sc.interactiveCmds {
+		if command.serverID == serverID {
+			commands = append(commands, command)
+			delete(sc.interactiveCmds, id)
+		}
+	}
+	sc.mutex.Unlock()
+
+	for _, command := range commands {
+		command.cmd.Kill()
+	}
+}
+
+// ========== 远程文件跟踪相关方法 ==========
+
+// StartTail 通过 SFTP 轮询跟踪远程文件（类似 tail -f，不需要 PTY），返回跟踪ID
+// 先推送文件末尾的一部分，之后新增的内容以 tail:data（tailID、data）事件推送；
+// 文件被截断或轮转时推送 tail:rotated（tailID）并从头读取，结束时推送 tail:stopped（tailID、error）；
+// 文件不存在或是目录时直接返回错误，不会启动跟踪
+func (sc *SSHController) StartTail(serverID, remotePath string) (string, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return "", err
+	}
+	info, err := conn.StatTailFile(sftpClient, remotePath)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	tailID := fmt.Sprintf("tail_%s_%d", serverID, time.Now().UnixNano())
+
+	sc.mutex.Lock()
+	sc.tails[tailID] = &remoteTail{serverID: serverID, cancel: cancel}
+	sc.mutex.Unlock()
+
+	go func() {
+		defer cancel()
+		err := conn.TailRemoteFile(ctx, sftpClient, remotePath, info, services.DefaultTailPollInterval,
+			func(data []byte) {
+				sc.emitEvent("tail:data", map[string]interface{}{
+					"tailID": tailID,
+					"data":   string(data),
+				})
+			},
+			func() {
+				sc.emitEvent("tail:rotated", map[string]interface{}{
+					"tailID": tailID,
+				})
+			})
+
+		sc.mutex.Lock()
+		delete(sc.tails, tailID)
+		sc.mutex.Unlock()
+
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		sc.emitEvent("tail:stopped", map[string]interface{}{
+			"tailID": tailID,
+			"error":  errMsg,
+		})
+	}()
+
+	return tailID, nil
+}
+
+// StopTail 停止跟踪远程文件
+func (sc *SSHController) StopTail(tailID string) error {
+	sc.mutex.Lock()
+	tail, exists := sc.tails[tailID]
+	delete(sc.tails, tailID)
+	sc.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("文件跟踪不存在: %s", tailID)
+	}
+
+	tail.cancel()
+	return nil
+}
+
+// stopServerTails 停止指定服务器上的所有文件跟踪
+func (sc *SSHController) stopServerTails(serverID string) {
+	sc.mutex.Lock()
+	var tails []*remoteTail
+	for id, tail := range sc.tails {
+		if tail.serverID == serverID {
+			tails = append(tails, tail)
+			delete(sc.tails, id)
+		}
+	}
+	sc.mutex.Unlock()
+
+	for _, tail := range tails {
+		tail.cancel()
+	}
+}
+
+// ========== 连接监控与保活相关方法 ==========
+
+// connectionMonitorTick 后台连接监控的检查周期，各连接按自己的保活间隔探测
+const connectionMonitorTick = time.Second
+
+// connectionStatusCheckInterval 禁用保活的连接检查连接状态的间隔
+const connectionStatusCheckInterval = 2 * time.Minute
+
+// keepAliveProbe 后台监控中单个连接的保活状态
+type keepAliveProbe struct {
+	conn      *services.SSHConnection
+	interval  time.Duration
+	nextProbe time.Time
+	probing   bool // 正在探测，避免慢速连接上的探测堆积
+}
+
+// startConnectionMonitor 启动后台连接监控协程，统一按各连接的保活间隔发送保活请求，
+// 缓存连接状态（供 GetServerConnectionStatus 返回）并在状态变化时推送事件
+func (sc *SSHController) startConnectionMonitor(parent context.Context) {
+	ctx, cancel := context.WithCancel(parent)
+
+	sc.mutex.Lock()
+	if sc.stopMonitor != nil {
+		sc.stopMonitor()
+	}
+	sc.stopMonitor = cancel
+	sc.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(connectionMonitorTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				sc.probeDueConnections(now)
+			}
+		}
+	}()
+}
+
+// probeDueConnections 对到达保活时间的连接并发发送保活请求
+func (sc *SSHController) probeDueConnections(now time.Time) {
+	type dueProbe struct {
+		serverID string
+		probe    *keepAliveProbe
+	}
+
+	var due []dueProbe
+	sc.mutex.Lock()
+	for serverID, probe := range sc.keepAlives {
+		if !probe.probing && !now.Before(probe.nextProbe) {
+			probe.probing = true
+			due = append(due, dueProbe{serverID, probe})
+		}
+	}
+	sc.mutex.Unlock()
+
+	for _, d := range due {
+		go func(serverID string, probe *keepAliveProbe) {
+			sc.checkConnection(serverID, probe.conn)
+
+			sc.mutex.Lock()
+			probe.probing = false
+			probe.nextProbe = time.Now().Add(probe.interval)
+			sc.mutex.Unlock()
+		}(d.serverID, d.probe)
+	}
+}
+
+// startKeepAlive 将已连接的服务器加入后台监控，保活失败时按连接断开处理
+// 禁用保活的服务器不发送保活请求保持连接，但仍按 connectionStatusCheckInterval 检查连接状态
+func (sc *SSHController) startKeepAlive(serverID string, conn *services.SSHConnection, intervalSeconds int) {
+	interval := services.KeepAliveInterval(intervalSeconds)
+	if interval <= 0 {
+		interval = connectionStatusCheckInterval
+	}
+
+	sc.mutex.Lock()
+	sc.keepAlives[serverID] = &keepAliveProbe{
+		conn:      conn,
+		interval:  interval,
+		nextProbe: time.Now().Add(interval),
+	}
+	sc.mutex.Unlock()
+}
+
+// stopKeepAlive 将服务器移出后台监控
+func (sc *SSHController) stopKeepAlive(serverID string) {
+	sc.mutex.Lock()
+	delete(sc.keepAlives, serverID)
+	sc.mutex.Unlock()
+}
+
+// setConnectionStatus 更新缓存的连接状态，并通过防抖器推送状态变化事件
+func (sc *SSHController) setConnectionStatus(serverID string, connected bool) {
+	sc.mutex.Lock()
+	sc.connectionStatus[serverID] = connected
+	sc.mutex.Unlock()
+
+	sc.connectionEvents.Report(serverID, connected)
+}
+
+// checkConnection 发送保活请求检查连接是否存活
+// 失败时清理该连接及其终端会话、SFTP客户端；conn 已不是该服务器的当前连接时不更新状态
+func (sc *SSHController) checkConnection(serverID string, conn *services.SSHConnection) bool {
+	if err := conn.SendKeepAlive(services.KeepAliveTimeout); err != nil {
+		log.Printf("服务器 %s 连接检查失败: %v", serverID, err)
+		sc.handleConnectionLost(serverID, conn)
+		return false
+	}
+
+	// 探测期间连接可能已断开或被重新连接替换，只为仍是当前连接的 conn 更新状态
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if current, ok := sc.connections[serverID]; !ok || current != conn {
+		return false
+	}
+	sc.connectionStatus[serverID] = true
+	sc.connectionEvents.Report(serverID, true)
+	return true
+}
+
+// handleConnectionLost 清理已断开的连接；连接已被替换（如重新连接）时不做处理
+func (sc *SSHController) handleConnectionLost(serverID string, conn *services.SSHConnection) {
+	sc.mutex.Lock()
+	if current, ok := sc.connections[serverID]; !ok || current != conn {
+		sc.mutex.Unlock()
+		return
+	}
+	delete(sc.connections, serverID)
+	session := sc.terminalSessions[serverID]
+	delete(sc.terminalSessions, serverID)
+	sftpClient := sc.sftpClients[serverID]
+	delete(sc.sftpClients, serverID)
+	sc.mutex.Unlock()
+
+	sc.stopServerActivity(serverID)
+	sc.setConnectionStatus(serverID, false)
+
+	// 关闭可能阻塞在已断开的连接上，放到后台进行
+	go func() {
+		if session != nil {
+			session.Close()
+		}
+		if sftpClient != nil {
+			sftpClient.Close()
+		}
+		conn.Close()
+	}()
+}
+
+// stopServerActivity 停止服务器上依赖连接的后台任务：文件传输、端口转发、流式命令、
+// 交互式命令、文件跟踪、保活和健康检查，并清除目录缓存。主动断开和连接丢失共用
+func (sc *SSHController) stopServerActivity(serverID string) {
+	sc.cancelServerTransfers(serverID)
+	// 本地监听的转发不会随连接断开而停止，需要主动关闭
+	sc.forwards.StopServer(serverID)
+	sc.stopServerStreams(serverID)
+	sc.stopServerCommands(serverID)
+	sc.stopServerTails(serverID)
+	sc.stopKeepAlive(serverID)
+	sc.stopHealthCheck(serverID)
+	sc.dirCache.InvalidateServer(serverID)
+}
+
+// ========== 重新连接相关方法 ==========
+
+// 重新连接的重试参数
+const (
+	reconnectMaxAttempts = 5
+	reconnectBaseDelay   = time.Second
+	reconnectMaxDelay    = 30 * time.Second
+)
+
+// Reconnect 重新连接服务器，原来有终端会话时按原尺寸和选项重新创建
+func (sc *SSHController) Reconnect(serverID string) (string, error) {
+	return sc.ReconnectWithOptions(serverID, true)
+}
+
+// ReconnectWithOptions 关闭服务器现有的连接、终端会话和SFTP客户端，按指数退避重试连接
+// 每次尝试前推送 server:reconnecting 事件（attempt/maxAttempts），成功后推送 server:reconnected，最终失败推送 server:reconnect-failed；
+// 原有的终端会话会被关闭并推送 terminal:dead 事件，recreateTerminal 为 true 时连接成功后重新创建
+func (sc *SSHController) ReconnectWithOptions(serverID string, recreateTerminal bool) (string, error) {
+	if _, err := sc.serverManager.GetServerByID(serverID); err != nil {
+		return "", fmt.Errorf("无法找到服务器: %v", err)
+	}
+
+	sc.mutex.Lock()
+	if sc.reconnecting[serverID] {
+		sc.mutex.Unlock()
+		return "", fmt.Errorf("服务器正在重新连接")
+	}
+	sc.reconnecting[serverID] = true
+	session := sc.terminalSessions[serverID]
+	sc.mutex.Unlock()
+
+	defer func() {
+		sc.mutex.Lock()
+		delete(sc.reconnecting, serverID)
+		sc.mutex.Unlock()
+	}()
+
+	// 记录原终端会话的尺寸和选项，然后关闭所有旧资源
+	var width, height int
+	var options services.TerminalOptions
+	if session != nil {
+		width, height = session.Size()
+		options = session.Options()
+	}
+	if _, err := sc.DisconnectFromServer(serverID); err != nil {
+		log.Printf("重新连接前关闭服务器 %s 的旧连接: %v", serverID, err)
+	}
+	if session != nil {
+		sc.emitEvent("terminal:dead", map[string]interface{}{"serverID": serverID})
+	}
+
+	var lastErr error
+	delay := reconnectBaseDelay
+	for attempt := 1; attempt <= reconnectMaxAttempts; attempt++ {
+		sc.emitEvent("server:reconnecting", map[string]interface{}{
+			"serverID":    serverID,
+			"attempt":     attempt,
+			"maxAttempts": reconnectMaxAttempts,
+		})
+
+		if _, lastErr = sc.ConnectToServer(serverID); lastErr == nil {
+			break
+		}
+
+		// 主机密钥不匹配等需要用户处理的错误，重试没有意义
+		var mismatch *services.HostKeyMismatchError
+		if errors.As(lastErr, &mismatch) || attempt == reconnectMaxAttempts {
+			break
+		}
+		log.Printf("重新连接服务器 %s 失败（第 %d/%d 次）: %v", serverID, attempt, reconnectMaxAttempts, lastErr)
+		time.Sleep(delay)
+		if delay *= 2; delay > reconnectMaxDelay {
+			delay = reconnectMaxDelay
+		}
+	}
+
+	if lastErr != nil {
+		sc.emitEvent("server:reconnect-failed", map[string]interface{}{
+			"serverID": serverID,
+			"error":    lastErr.Error(),
+		})
+		return "", fmt.Errorf("重新连接失败: %w", lastErr)
+	}
+
+	sc.emitEvent("server:reconnected", map[string]interface{}{"serverID": serverID})
+
+	if session != nil && recreateTerminal {
+		if _, err := sc.CreateTerminalSessionWithOptions(serverID, width, height, options); err != nil {
+			return "重新连接成功，但终端会话重建失败", fmt.Errorf("重建终端会话失败: %v", err)
+		}
+		return "重新连接成功，终端会话已重建", nil
+	}
+	return "重新连接成功", nil
+}
+
+// ========== 健康检查相关方法 ==========
+
+// startHealthCheck 为已连接的服务器启动周期性健康检查，未配置检查命令时不启动
+func (sc *SSHController) startHealthCheck(serverID, command string, intervalSeconds int) {
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sc.mutex.Lock()
+	if existing, ok := sc.healthCheckers[serverID]; ok {
+		existing()
+	}
+	sc.healthCheckers[serverID] = cancel
+	sc.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(services.HealthCheckInterval(intervalSeconds))
+		defer ticker.Stop()
+
+		for {
+			sc.runHealthCheck(ctx, serverID, command)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// runHealthCheck 执行一次健康检查，健康状态变化时推送 server:health-changed 事件
+func (sc *SSHController) runHealthCheck(ctx context.Context, serverID, command string) {
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	// 服务器未连接时不执行
+	if !exists || conn.Client == nil {
+		return
+	}
+
+	status := conn.RunHealthCheck(serverID, command)
+	// 检查期间已停止（如断开连接），丢弃结果
+	if ctx.Err() != nil {
+		return
+	}
+
+	sc.mutex.Lock()
+	previous, hadPrevious := sc.healthStatus[serverID]
+	sc.healthStatus[serverID] = status
+	sc.mutex.Unlock()
+
+	if (!hadPrevious || previous.Healthy != status.Healthy) && sc.ctx != nil {
+		runtime.EventsEmit(sc.ctx, "server:health-changed", status)
+	}
+}
+
+// stopHealthCheck 停止服务器的健康检查并清除结果
+func (sc *SSHController) stopHealthCheck(serverID string) {
+	sc.mutex.Lock()
+	cancel, exists := sc.healthCheckers[serverID]
+	delete(sc.healthCheckers, serverID)
+	delete(sc.healthStatus, serverID)
+	sc.mutex.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// GetServerHealthStatus 获取已连接服务器最近一次的健康检查结果
+func (sc *SSHController) GetServerHealthStatus() map[string]services.HealthStatus {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	result := make(map[string]services.HealthStatus, len(sc.healthStatus))
+	for serverID, status := range sc.healthStatus {
+		result[serverID] = status
+	}
+	return result
+}
+
+// ========== 远程文件内容相关方法 ==========
+
+// getSFTPClient 获取服务器的连接和SFTP客户端，SFTP客户端不存在时自动创建
+func (sc *SSHController) getSFTPClient(serverID string) (*services.SSHConnection, *sftp.Client, error) {
+	if err := sc.EnsureSFTPClient(serverID); err != nil {
+		return nil, nil, err
+	}
+
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sftpClient, sftpExists := sc.sftpClients[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return nil, nil, fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+	if !sftpExists {
+		return nil, nil, fmt.Errorf("SFTP客户端未创建，请先创建SFTP客户端")
+	}
+	return conn, sftpClient, nil
+}
+
+// RemoteFileExists 检查远程路径是否存在（符号链接即使目标不存在也视为存在）
+func (sc *SSHController) RemoteFileExists(serverID, remotePath string) (bool, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return false, err
+	}
+
+	_, exists, err := conn.StatRemote(sftpClient, remotePath)
+	return exists, err
+}
+
+// GlobRemoteFiles 展开远程路径中的通配符，返回匹配的文件（不含目录）
+func (sc *SSHController) GlobRemoteFiles(serverID, pattern string) ([]string, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return nil, err
+	}
+	return conn.GlobRemoteFiles(sftpClient, pattern)
+}
+
+// StatRemoteFile 获取远程路径的信息（类型、大小、权限等），用于上传前决定覆盖还是重命名；路径不存在时返回 nil
+func (sc *SSHController) StatRemoteFile(serverID, remotePath string) (*services.FileInfo, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	info, exists, err := conn.StatRemote(sftpClient, remotePath)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ReadRemoteFile 直接读取远程文件内容用于预览，不保存到本地；maxBytes <= 0 时最多读取1MB
+// 文件超过 maxBytes 时返回错误，truncate 为 true 时只返回开头部分；二进制文件只返回 binary 标记
+func (sc *SSHController) ReadRemoteFile(serverID, remotePath string, maxBytes int64, truncate bool) (*services.RemoteFileContent, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := conn.ReadRemoteFile(sftpClient, remotePath, maxBytes, truncate)
+	if err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// WriteRemoteFile 用 content 创建或覆盖远程文件（用于内置编辑器），已存在的文件保持原有权限
+func (sc *SSHController) WriteRemoteFile(serverID, remotePath, content string) error {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.WriteRemoteFile(sftpClient, remotePath, content); err != nil {
+		return err
+	}
+	sc.dirCache.InvalidatePath(serverID, remotePath)
+	return nil
+}
+
+// DiffRemoteFile 比较远程文件当前内容与将要写入的新内容，返回统一diff格式的差异
+func (sc *SSHController) DiffRemoteFile(serverID, remotePath, newContent string) (string, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return "", err
+	}
+
+	diff, err := conn.DiffRemoteFile(sftpClient, remotePath, newContent)
+	if err != nil {
+		return "", fmt.Errorf("比较文件失败: %v", err)
+	}
+	return diff, nil
+}
+
+// ========== 配置导入导出相关方法 ==========
+
+// ExportAll 将服务器、脚本和 known_hosts 打包为一个加密的配置包
+func (sc *SSHController) ExportAll(path, password string) error {
+	if password == "" {
+		return fmt.Errorf("导出密码不能为空")
+	}
+
+	knownHosts, err := sc.knownHosts.Content()
+	if err != nil {
+		return err
+	}
+
+	sc.mutex.RLock()
+	bundle := &services.ConfigBundle{
+		Groups:     sc.serverManager.GetGroups(),
+		Scripts:    sc.scriptManager.GetScripts(),
+		KnownHosts: knownHosts,
+	}
+	err = services.SaveConfigBundle(bundle, path, password)
+	sc.mutex.RUnlock()
+
+	return err
+}
+
+// ImportAll 从加密配置包恢复配置，各集合按 mergeStrategy（replace/skip/overwrite）处理冲突
+func (sc *SSHController) ImportAll(path, password, mergeStrategy string) (map[string]services.ImportSummary, error) {
+	strategy, err := services.ValidateMergeStrategy(mergeStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle, err := services.LoadConfigBundle(path, password)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]services.ImportSummary)
+
+	sc.mutex.Lock()
+	result["servers"] = sc.serverManager.MergeGroups(bundle.Groups, strategy)
+	err = sc.saveConfig()
+	sc.mutex.Unlock()
+	if err != nil {
+		return result, fmt.Errorf("保存服务器配置失败: %v", err)
+	}
+
+	scriptSummary, err := sc.scriptManager.ImportScripts(bundle.Scripts, strategy)
+	result["scripts"] = scriptSummary
+	if err != nil {
+		return result, fmt.Errorf("保存脚本配置失败: %v", err)
+	}
+
+	hostsSummary, err := sc.knownHosts.MergeContent(bundle.KnownHosts, strategy)
+	result["knownHosts"] = hostsSummary
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// ExportServers 将服务器分组导出为明文JSON，includeSecrets 为 false 时不导出密码、密钥文件和私钥
+func (sc *SSHController) ExportServers(path string, includeSecrets bool) error {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	if err := sc.serverManager.ExportToFile(path, includeSecrets); err != nil {
+		return fmt.Errorf("导出服务器配置失败: %v", err)
+	}
+	return nil
+}
+
+// ListConfigBackups 列出服务器配置文件的滚动备份（每次保存前自动创建），按从新到旧排列
+func (sc *SSHController) ListConfigBackups() []services.BackupInfo {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return services.ListBackups(sc.configFile)
+}
+
+// RestoreConfigBackup 用第 index 个备份（0为最近）恢复服务器配置
+// 备份会先按当前的加密设置加载校验，无法加载（如备份使用了修改前的主密码）时配置保持不变；
+// 恢复前的配置同样会被备份，可以再次恢复
+func (sc *SSHController) RestoreConfigBackup(index int) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	backupPath := services.BackupPath(sc.configFile, index)
+	if _, err := os.Stat(backupPath); err != nil {
+		return fmt.Errorf("备份不存在: %s", backupPath)
+	}
+
+	restored := services.NewServerManager()
+	if !sc.useEncryption && sc.encryptionPassword != "" {
+		restored.SetSecretPassword(sc.encryptionPassword)
+	}
+	needReencrypt := false
+	if sc.useEncryption {
+		var err error
+		if needReencrypt, err = restored.LoadFromFileWithFallback(backupPath, sc.encryptionPassword); err != nil {
+			return fmt.Errorf("无法加载备份: %w", err)
+		}
+	} else if err := restored.LoadFromFile(backupPath); err != nil {
+		return fmt.Errorf("无法加载备份: %w", err)
+	}
+
+	if err := services.RestoreBackup(sc.configFile, index); err != nil {
+		return err
+	}
+	sc.serverManager.MergeGroups(restored.GetGroups(), services.MergeReplace)
+
+	// 明文备份在启用加密时需要重新以加密格式保存
+	if needReencrypt {
+		if err := sc.saveConfig(); err != nil {
+			return fmt.Errorf("保存加密配置失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// ImportServers 从 ExportServers 导出的文件导入服务器分组
+// merge 为 false 时整体替换现有配置；为 true 时按分组和服务器ID合并，ID冲突的服务器保持不变并在结果的 conflicts 中列出
+func (sc *SSHController) ImportServers(path string, merge bool) (services.ImportSummary, error) {
+	groups, err := services.LoadServerGroupsFromFile(path)
+	if err != nil {
+		return services.ImportSummary{}, err
+	}
+
+	strategy := services.MergeReplace
+	if merge {
+		strategy = services.MergeSkip
+	}
+
+	sc.mutex.Lock()
+	summary := sc.serverManager.MergeGroups(groups, strategy)
+	err = sc.saveConfig()
+	sc.mutex.Unlock()
+	if err != nil {
+		return summary, fmt.Errorf("保存服务器配置失败: %v", err)
+	}
+	return summary, nil
+}
+
+// ========== 端口转发相关方法 ==========
+
+// forwardDialTimeout 远程端口转发连接本地目标的超时时间
+const forwardDialTimeout = 10 * time.Second
+
+// StartLocalForward 启动本地端口转发（ssh -L）：在本地监听 localAddr，接受的连接经SSH连接转发到远程的 remoteAddr
+// localAddr 的端口为0时自动分配，实际地址见返回信息；单个连接的错误推送 forward:error 事件，不会中断转发
+func (sc *SSHController) StartLocalForward(serverID, localAddr, remoteAddr string) (services.ForwardInfo, error) {
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return services.ForwardInfo{}, fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return services.ForwardInfo{}, fmt.Errorf("本地监听 %s 失败: %v", localAddr, err)
+	}
+
+	forward := sc.startForward(serverID, services.ForwardTypeLocal, listener.Addr().String(), remoteAddr, listener,
+		func(net.Conn) (net.Conn, error) {
+			return conn.Client.Dial("tcp", remoteAddr)
+		})
+	return forward.Info(), nil
+}
+
+// StartRemoteForward 启动远程端口转发（ssh -R）：在服务器上监听 remoteAddr，接受的连接转发到本地的 localAddr
+// 服务器的 sshd 需要允许端口转发（AllowTcpForwarding），监听非回环地址还需要 GatewayPorts
+func (sc *SSHController) StartRemoteForward(serverID, remoteAddr, localAddr string) (services.ForwardInfo, error) {
+	sc.mutex.RLock()
+	conn, exists := sc.connections[serverID]
+	sc.mutex.RUnlock()
+
+	if !exists || conn.Client == nil {
+		return services.ForwardInfo{}, fmt.Errorf("服务器未连接，请先连接服务器")
+	}
+
+	listener, err := conn.Client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return services.ForwardInfo{}, fmt.Errorf("远程监听 %s 失败: %v", remoteAddr, err)
+	}
+
+	forward := sc.startForward(serverID, services.ForwardTypeRemote, localAddr, listener.Addr().String(), listener,
+		func(net.Conn) (net.Conn, error) {
+			return net.DialTimeout("tcp", localAddr, forwardDialTimeout)
+		})
+	return forward.Info(), nil
+}
+
+// startForward 登记端口转发并在后台开始接受连接，错误通过 forward:error 事件推送
+func (sc *SSHController) startForward(serverID, forwardType, localAddr, remoteAddr string, listener net.Listener, dial func(net.Conn) (net.Conn, error)) *services.PortForward {
+	forwardID := fmt.Sprintf("forward_%s_%d", serverID, time.Now().UnixNano())
+	forward := services.NewPortForward(forwardID, serverID, forwardType, localAddr, remoteAddr, listener)
+	sc.forwards.Add(forward)
+
+	go func() {
+		forward.Serve(dial, func(err error) {
+			log.Printf("端口转发 %s 错误: %v", forwardID, err)
+			sc.emitEvent("forward:error", map[string]interface{}{
+				"forwardID": forwardID,
+				"serverID":  serverID,
+				"error":     err.Error(),
+			})
+		})
+		// Serve 返回说明监听已停止（被关闭或出错），不再保留在活动转发列表中
+		sc.forwards.Remove(forward)
+	}()
+	return forward
+}
+
+// GetActiveForwards 获取所有活动的端口转发及其连接数、流量统计
+func (sc *SSHController) GetActiveForwards() []services.ForwardInfo {
+	return sc.forwards.List()
+}
+
+// StopForward 停止指定的端口转发，不影响服务器连接
+func (sc *SSHController) StopForward(forwardID string) (string, error) {
+	if err := sc.forwards.Stop(forwardID); err != nil {
+		return "", fmt.Errorf("停止端口转发失败: %v", err)
+	}
+	return "端口转发已停止", nil
+}
+
+// ========== 命令历史相关方法 ==========
+
+// GetCommandHistory 获取服务器的命令历史（由旧到新）
+func (sc *SSHController) GetCommandHistory(serverID string) []string {
+	return sc.commandHistory.Get(serverID)
+}
+
+// ImportRemoteHistory 从远程的 ~/.bash_history 和 ~/.zsh_history 导入命令历史
+// 只导入最近的 MaxImportedHistory 条命令，可能包含敏感信息的命令会被跳过；返回实际导入的命令
+func (sc *SSHController) ImportRemoteHistory(serverID string) ([]string, error) {
+	conn, sftpClient, err := sc.getSFTPClient(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	// SFTP 会话的初始目录即用户主目录
+	home, err := sftpClient.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("获取远程主目录失败: %v", err)
+	}
+
+	var commands []string
+	found := false
+	for _, name := range []string{".bash_history", ".zsh_history"} {
+		data, exists, err := conn.ReadRemoteFileLimited(sftpClient, path.Join(home, name), services.MaxRemoteHistorySize)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %v", name, err)
+		}
+		if exists {
+			found = true
+			commands = append(commands, services.ParseShellHistory(data)...)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("远程主目录下没有找到 shell 历史文件")
+	}
+
+	// 去重（保留最后一次出现的位置）并过滤敏感命令
+	seen := make(map[string]bool)
+	var imported []string
+	for i := len(commands) - 1; i >= 0 && len(imported) < services.MaxImportedHistory; i-- {
+		command := commands[i]
+		if seen[command] || services.IsSensitiveCommand(command) {
+			continue
+		}
+		seen[command] = true
+		imported = append(imported, command)
+	}
+	// 恢复为由旧到新的顺序
+	for i, j := 0, len(imported)-1; i < j; i, j = i+1, j-1 {
+		imported[i], imported[j] = imported[j], imported[i]
+	}
+
+	if _, err := sc.commandHistory.Merge(serverID, imported); err != nil {
+		return imported, fmt.Errorf("保存命令历史失败: %v", err)
+	}
+	return imported, nil
+}
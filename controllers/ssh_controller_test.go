@@ -0,0 +1,708 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-term/internal/sshtest"
+	"go-term/models"
+	"go-term/services"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestController 创建使用临时配置目录的控制器，不启动后台监控
+func newTestController(t *testing.T) *SSHController {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	sc := NewSSHController()
+	sc.serverManager = services.NewServerManager()
+	sc.useEncryption = false
+	return sc
+}
+
+// addTestServers 在控制器中添加一个分组及其中的服务器
+func addTestServers(t *testing.T, sc *SSHController, serverIDs ...string) {
+	t.Helper()
+	sc.serverManager.AddGroup(models.ServerGroup{ID: "group", Name: "group"})
+	for _, id := range serverIDs {
+		if err := sc.serverManager.AddServer("group", models.Server{ID: id, Name: "server-" + id}); err != nil {
+			t.Fatalf("添加服务器失败: %v", err)
+		}
+	}
+}
+
+// connectTestServer 把到测试服务器的连接登记为服务器 serverID 的连接
+func connectTestServer(t *testing.T, sc *SSHController, srv *sshtest.Server, serverID string) {
+	t.Helper()
+	conn := &services.SSHConnection{Client: srv.Dial(t)}
+	sc.mutex.Lock()
+	sc.connections[serverID] = conn
+	sc.mutex.Unlock()
+}
+
+func TestExecuteBatchScriptUsesPinnedSnapshot(t *testing.T) {
+	serverIDs := []string{"s1", "s2"}
+	started := make(chan struct{}, len(serverIDs))
+	release := make(chan struct{})
+	srv := sshtest.NewServer(t, func(command string, stdin io.Reader, stdout io.Writer) int {
+		started <- struct{}{}
+		<-release
+		fmt.Fprint(stdout, "done")
+		return 0
+	})
+
+	sc := newTestController(t)
+	addTestServers(t, sc, serverIDs...)
+	for _, id := range serverIDs {
+		connectTestServer(t, sc, srv, id)
+	}
+
+	script := models.BatchScript{
+		ID:            "deploy",
+		Name:          "deploy v1",
+		Content:       "echo v1",
+		ExecutionType: "script",
+		ServerIDs:     serverIDs,
+	}
+	if err := sc.scriptManager.AddScript(script); err != nil {
+		t.Fatalf("添加脚本失败: %v", err)
+	}
+
+	type batchResult struct {
+		results map[string]models.ScriptExecution
+		err     error
+	}
+	done := make(chan batchResult, 1)
+	go func() {
+		results, err := sc.ExecuteBatchScript(script.ID)
+		done <- batchResult{results, err}
+	}()
+
+	// 所有服务器都开始执行后，并发修改并最终删除脚本
+	for range serverIDs {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("等待脚本开始执行超时")
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			updated := script
+			updated.Name = fmt.Sprintf("deploy v%d", i+2)
+			updated.Content = "echo changed"
+			updated.ServerIDs = []string{"s3"}
+			if err := sc.scriptManager.UpdateScript(updated); err != nil {
+				t.Errorf("更新脚本失败: %v", err)
+			}
+			sc.scriptManager.GetScripts()
+		}(i)
+	}
+	wg.Wait()
+	if err := sc.scriptManager.DeleteScript(script.ID); err != nil {
+		t.Fatalf("删除脚本失败: %v", err)
+	}
+	close(release)
+
+	var result batchResult
+	select {
+	case result = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待批量执行结束超时")
+	}
+	if result.err != nil {
+		t.Fatalf("执行脚本失败: %v", result.err)
+	}
+	if len(result.results) != len(serverIDs) {
+		t.Fatalf("得到 %d 个服务器的结果, 期望 %d", len(result.results), len(serverIDs))
+	}
+	for _, id := range serverIDs {
+		execution, ok := result.results[id]
+		if !ok {
+			t.Fatalf("缺少服务器 %s 的结果", id)
+		}
+		if execution.Status != "success" {
+			t.Fatalf("服务器 %s 的执行状态为 %s: %s", id, execution.Status, execution.Error)
+		}
+		if execution.ScriptID != script.ID || execution.ScriptName != "deploy v1" {
+			t.Fatalf("服务器 %s 的结果没有记录为快照中的脚本: %s/%s", id, execution.ScriptID, execution.ScriptName)
+		}
+	}
+}
+
+func TestHandleConnectionLostStopsServerActivity(t *testing.T) {
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1", "s2")
+	connectTestServer(t, sc, srv, "s1")
+	connectTestServer(t, sc, srv, "s2")
+
+	// 为两台服务器各登记一个流式命令、文件跟踪和健康检查
+	contexts := make(map[string][]context.Context)
+	register := func(serverID string) {
+		newCtx := func() context.CancelFunc {
+			ctx, cancel := context.WithCancel(context.Background())
+			contexts[serverID] = append(contexts[serverID], ctx)
+			return cancel
+		}
+		sc.mutex.Lock()
+		sc.commandStreams["stream-"+serverID] = &commandStream{serverID: serverID, cancel: newCtx()}
+		sc.tails["tail-"+serverID] = &remoteTail{serverID: serverID, cancel: newCtx()}
+		sc.healthCheckers[serverID] = newCtx()
+		sc.mutex.Unlock()
+	}
+	register("s1")
+	register("s2")
+
+	sc.mutex.RLock()
+	conn := sc.connections["s1"]
+	sc.mutex.RUnlock()
+	sc.handleConnectionLost("s1", conn)
+
+	for _, ctx := range contexts["s1"] {
+		if ctx.Err() == nil {
+			t.Fatal("连接丢失后服务器上的后台任务没有停止")
+		}
+	}
+	for _, ctx := range contexts["s2"] {
+		if ctx.Err() != nil {
+			t.Fatal("其他服务器的后台任务被停止")
+		}
+	}
+
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	if _, ok := sc.commandStreams["stream-s1"]; ok {
+		t.Fatal("连接丢失后流式命令没有移除")
+	}
+	if _, ok := sc.tails["tail-s1"]; ok {
+		t.Fatal("连接丢失后文件跟踪没有移除")
+	}
+	if _, ok := sc.healthCheckers["s1"]; ok {
+		t.Fatal("连接丢失后健康检查没有移除")
+	}
+	if _, ok := sc.connections["s1"]; ok {
+		t.Fatal("连接丢失后连接没有移除")
+	}
+	if len(sc.commandStreams) != 1 || len(sc.tails) != 1 || len(sc.healthCheckers) != 1 {
+		t.Fatal("其他服务器的后台任务被移除")
+	}
+}
+
+func TestCheckConnectionIgnoresReplacedConnection(t *testing.T) {
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+
+	connectTestServer(t, sc, srv, "s1")
+	sc.mutex.RLock()
+	stale := sc.connections["s1"]
+	sc.mutex.RUnlock()
+
+	// 探测期间服务器被重新连接，旧连接的探测结果不能更新状态
+	connectTestServer(t, sc, srv, "s1")
+	if sc.checkConnection("s1", stale) {
+		t.Fatal("已被替换的连接探测成功后仍然返回 true")
+	}
+	sc.mutex.RLock()
+	_, hasStatus := sc.connectionStatus["s1"]
+	sc.mutex.RUnlock()
+	if hasStatus {
+		t.Fatal("已被替换的连接更新了连接状态")
+	}
+
+	// 断开后完成的探测同样不更新状态
+	sc.mutex.RLock()
+	current := sc.connections["s1"]
+	sc.mutex.RUnlock()
+	if _, err := sc.DisconnectFromServer("s1"); err != nil {
+		t.Fatalf("断开连接失败: %v", err)
+	}
+	sc.checkConnection("s1", current)
+	sc.mutex.RLock()
+	_, hasStatus = sc.connectionStatus["s1"]
+	sc.mutex.RUnlock()
+	if hasStatus {
+		t.Fatal("断开后完成的探测更新了连接状态")
+	}
+}
+
+func TestDisabledKeepAliveStillChecksConnection(t *testing.T) {
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	sc.mutex.RLock()
+	conn := sc.connections["s1"]
+	sc.mutex.RUnlock()
+	sc.startKeepAlive("s1", conn, -1)
+
+	sc.mutex.RLock()
+	probe, ok := sc.keepAlives["s1"]
+	sc.mutex.RUnlock()
+	if !ok || probe.interval != connectionStatusCheckInterval {
+		t.Fatalf("禁用保活的服务器没有加入连接检查: %+v", probe)
+	}
+
+	// 连接断开后，到达检查时间的探测发现并清理该连接
+	conn.Client.Close()
+	sc.probeDueConnections(time.Now().Add(connectionStatusCheckInterval))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sc.mutex.RLock()
+		_, connected := sc.connections["s1"]
+		status, hasStatus := sc.connectionStatus["s1"]
+		sc.mutex.RUnlock()
+		if !connected && hasStatus && !status {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("禁用保活的服务器断开后没有被检测到")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnectionStatusConcurrentWithRemoval(t *testing.T) {
+	// 在 -race 下运行：读取连接状态、检查连接健康的同时不断建立和移除连接
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	serverIDs := []string{"s1", "s2", "s3", "s4"}
+	addTestServers(t, sc, serverIDs...)
+
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				sc.GetServerConnectionStatus()
+				for _, id := range serverIDs {
+					sc.isConnectionHealthy(id)
+					sc.mutex.RLock()
+					conn := sc.connections[id]
+					sc.mutex.RUnlock()
+					if conn != nil {
+						sc.checkConnection(id, conn)
+					}
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for _, id := range serverIDs {
+		writers.Add(1)
+		go func(id string) {
+			defer writers.Done()
+			for i := 0; i < 10; i++ {
+				connectTestServer(t, sc, srv, id)
+				sc.setConnectionStatus(id, true)
+
+				sc.mutex.RLock()
+				conn := sc.connections[id]
+				sc.mutex.RUnlock()
+				if i%2 == 0 {
+					sc.handleConnectionLost(id, conn)
+					conn.Close()
+				} else if _, err := sc.DisconnectFromServer(id); err != nil {
+					t.Errorf("断开连接失败: %v", err)
+				}
+			}
+		}(id)
+	}
+	writers.Wait()
+	close(stop)
+	readers.Wait()
+
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	if len(sc.connections) != 0 {
+		t.Fatalf("移除后仍有 %d 个连接", len(sc.connections))
+	}
+	for id, connected := range sc.connectionStatus {
+		if connected {
+			t.Fatalf("服务器 %s 移除后状态仍为已连接", id)
+		}
+	}
+}
+
+func TestInteractiveCommandReleasedOnExit(t *testing.T) {
+	srv := sshtest.NewServer(t, func(command string, stdin io.Reader, stdout io.Writer) int {
+		io.Copy(stdout, stdin)
+		return 3
+	})
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	commandID, err := sc.StartCommand("s1", "cat")
+	if err != nil {
+		t.Fatalf("启动命令失败: %v", err)
+	}
+	if err := sc.WriteCommandStdin(commandID, "hello\n"); err != nil {
+		t.Fatalf("写入标准输入失败: %v", err)
+	}
+	if err := sc.CloseCommandStdin(commandID); err != nil {
+		t.Fatalf("关闭标准输入失败: %v", err)
+	}
+
+	// 命令结束后即使没有调用 WaitCommand 也会被释放
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sc.mutex.RLock()
+		remaining := len(sc.interactiveCmds)
+		sc.mutex.RUnlock()
+		if remaining == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("命令结束后没有释放")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := sc.WriteCommandStdin(commandID, "again\n"); err == nil {
+		t.Fatal("命令释放后仍可写入标准输入")
+	}
+}
+
+func TestWaitCommandReturnsExitCode(t *testing.T) {
+	srv := sshtest.NewServer(t, func(command string, stdin io.Reader, stdout io.Writer) int {
+		io.Copy(stdout, stdin)
+		return 3
+	})
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	commandID, err := sc.StartCommand("s1", "cat")
+	if err != nil {
+		t.Fatalf("启动命令失败: %v", err)
+	}
+
+	// 命令结束前开始等待
+	exitCode := make(chan int, 1)
+	go func() {
+		code, _ := sc.WaitCommand(commandID)
+		exitCode <- code
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err := sc.CloseCommandStdin(commandID); err != nil {
+		t.Fatalf("关闭标准输入失败: %v", err)
+	}
+	if code := <-exitCode; code != 3 {
+		t.Fatalf("退出码为 %d, 期望 3", code)
+	}
+
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	if len(sc.interactiveCmds) != 0 {
+		t.Fatal("WaitCommand 返回后命令没有释放")
+	}
+}
+
+func TestStartTailReportsStatErrors(t *testing.T) {
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	_, sftpClient, err := sc.getSFTPClient("s1")
+	if err != nil {
+		t.Fatalf("创建SFTP客户端失败: %v", err)
+	}
+	if err := sftpClient.Mkdir("/logs"); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	file, err := sftpClient.Create("/logs/app.log")
+	if err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	file.Write([]byte("line\n"))
+	file.Close()
+
+	for _, path := range []string{"/logs/missing.log", "/logs"} {
+		if _, err := sc.StartTail("s1", path); err == nil {
+			t.Fatalf("跟踪 %s 应直接返回错误", path)
+		}
+	}
+	sc.mutex.RLock()
+	remaining := len(sc.tails)
+	sc.mutex.RUnlock()
+	if remaining != 0 {
+		t.Fatal("启动失败的跟踪不应登记")
+	}
+
+	tailID, err := sc.StartTail("s1", "/logs/app.log")
+	if err != nil {
+		t.Fatalf("跟踪文件失败: %v", err)
+	}
+	if err := sc.StopTail(tailID); err != nil {
+		t.Fatalf("停止跟踪失败: %v", err)
+	}
+}
+
+func TestDisconnectClosesAllResources(t *testing.T) {
+	srv := sshtest.NewServer(t, nil)
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	if _, err := sc.CreateTerminalSession("s1"); err != nil {
+		t.Fatalf("创建终端会话失败: %v", err)
+	}
+	if _, err := sc.CreateSFTPClient("s1"); err != nil {
+		t.Fatalf("创建SFTP客户端失败: %v", err)
+	}
+
+	// 会话、SFTP客户端和连接并行关闭，连接关闭不会让其他资源报错
+	if _, err := sc.DisconnectFromServer("s1"); err != nil {
+		t.Fatalf("断开连接失败: %v", err)
+	}
+
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+	if len(sc.terminalSessions) != 0 || len(sc.sftpClients) != 0 || len(sc.connections) != 0 {
+		t.Fatal("断开连接后仍有残留的资源")
+	}
+}
+
+func TestCloseWithTimeoutSharesDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	block := make(chan struct{})
+	defer close(block)
+	closers := []func() error{
+		func() error { <-block; return nil },
+		func() error { <-block; return nil },
+		func() error { return nil },
+	}
+
+	start := time.Now()
+	errs := make([]error, len(closers))
+	var wg sync.WaitGroup
+	for i, closeFn := range closers {
+		wg.Add(1)
+		go func(i int, closeFn func() error) {
+			defer wg.Done()
+			errs[i] = closeWithTimeout(ctx, fmt.Sprintf("资源%d", i), closeFn)
+		}(i, closeFn)
+	}
+	wg.Wait()
+
+	// 多个卡住的资源共用同一个时限，总耗时不会累加
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("关闭耗时 %v，超过了共同的时限", elapsed)
+	}
+	for i := 0; i < 2; i++ {
+		if !errors.Is(errs[i], errCloseTimeout) {
+			t.Fatalf("卡住的资源%d 返回 %v, 期望超时", i, errs[i])
+		}
+	}
+	if errs[2] != nil {
+		t.Fatalf("正常关闭的资源返回 %v", errs[2])
+	}
+}
+
+func TestFinishedStreamReleasedAfterDoneRead(t *testing.T) {
+	srv := sshtest.NewServer(t, func(command string, stdin io.Reader, stdout io.Writer) int {
+		fmt.Fprint(stdout, "output")
+		return 0
+	})
+	sc := newTestController(t)
+	addTestServers(t, sc, "s1")
+	connectTestServer(t, sc, srv, "s1")
+
+	streamID, err := sc.StartCommandStream("s1", "echo output")
+	if err != nil {
+		t.Fatalf("启动流式命令失败: %v", err)
+	}
+
+	var data string
+	offset := int64(0)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		chunk, err := sc.ReadStreamChunk(streamID, offset, 0)
+		if err != nil {
+			t.Fatalf("读取输出失败: %v", err)
+		}
+		data += chunk.Data
+		offset = chunk.NextOffset
+		if chunk.Done {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("流式命令没有结束")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if data != "output" {
+		t.Fatalf("输出 = %q", data)
+	}
+
+	// 读取到结束标记后输出流被释放
+	if _, err := sc.ReadStreamChunk(streamID, offset, 0); err == nil {
+		t.Fatal("读取到结束标记后输出流应被释放")
+	}
+}
+
+func TestRecordConnectionDoesNotHoldGlobalLock(t *testing.T) {
+	sc := newTestController(t)
+	sc.SetEncryptionConfig(true, "correct horse battery staple")
+	addTestServers(t, sc, "a")
+
+	// 持有全局锁时连接统计仍能更新并保存（加密保存的密钥派生不应占用全局锁）
+	sc.mutex.Lock()
+	done := make(chan struct{})
+	go func() {
+		sc.recordConnection("a")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		sc.mutex.Unlock()
+		t.Fatal("recordConnection 不应等待全局锁")
+	}
+	sc.mutex.Unlock()
+
+	server, err := sc.serverManager.GetServerByID("a")
+	if err != nil {
+		t.Fatalf("获取服务器失败: %v", err)
+	}
+	if server.ConnectCount != 1 {
+		t.Fatalf("ConnectCount = %d, 期望 1", server.ConnectCount)
+	}
+
+	loaded := services.NewServerManager()
+	if err := loaded.LoadFromEncryptedFile(sc.configFile, "correct horse battery staple"); err != nil {
+		t.Fatalf("加载保存的配置失败: %v", err)
+	}
+	if server, err := loaded.GetServerByID("a"); err != nil || server.ConnectCount != 1 {
+		t.Fatalf("保存的连接统计 = %+v, %v, 期望 ConnectCount 为 1", server, err)
+	}
+}
+
+// addTestServerFor 添加一个指向测试服务器的服务器配置，并把测试服务器的主机密钥记入 known_hosts
+func addTestServerFor(t *testing.T, sc *SSHController, srv *sshtest.Server, serverID string) {
+	t.Helper()
+	host, portText, err := net.SplitHostPort(srv.Addr())
+	if err != nil {
+		t.Fatalf("解析测试服务器地址失败: %v", err)
+	}
+	port, _ := strconv.Atoi(portText)
+
+	client, err := ssh.Dial("tcp", srv.Addr(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: sc.knownHosts.AddHostKey,
+	})
+	if err != nil {
+		t.Fatalf("记录测试服务器主机密钥失败: %v", err)
+	}
+	client.Close()
+
+	sc.serverManager.AddGroup(models.ServerGroup{ID: "group", Name: "group"})
+	if err := sc.serverManager.AddServer("group", models.Server{ID: serverID, Name: "server-" + serverID, Host: host, Port: port, Username: "test"}); err != nil {
+		t.Fatalf("添加服务器失败: %v", err)
+	}
+}
+
+func TestCollectFromServerUsesStandaloneConnection(t *testing.T) {
+	sc := newTestController(t)
+	srv := sshtest.NewServer(t, func(command string, stdin io.Reader, stdout io.Writer) int {
+		fmt.Fprint(stdout, "collected")
+		return 0
+	})
+	addTestServerFor(t, sc, srv, "a")
+
+	output, err := sc.collectFromServer("a", "uptime")
+	if err != nil {
+		t.Fatalf("收集失败: %v", err)
+	}
+	if output != "collected" {
+		t.Fatalf("输出 = %q, 期望 collected", output)
+	}
+
+	// 临时连接不应登记到连接列表，也不应更新连接统计或连接状态
+	sc.mutex.RLock()
+	_, registered := sc.connections["a"]
+	_, monitored := sc.keepAlives["a"]
+	sc.mutex.RUnlock()
+	if registered || monitored {
+		t.Fatalf("临时连接不应登记到控制器: connections=%v keepAlives=%v", registered, monitored)
+	}
+	server, err := sc.serverManager.GetServerByID("a")
+	if err != nil {
+		t.Fatalf("获取服务器失败: %v", err)
+	}
+	if server.ConnectCount != 0 {
+		t.Fatalf("临时连接不应计入连接统计: ConnectCount = %d", server.ConnectCount)
+	}
+}
+
+// failingListener Accept 总是返回非关闭错误的监听器
+type failingListener struct{ net.Listener }
+
+func (failingListener) Accept() (net.Conn, error) { return nil, errors.New("too many open files") }
+func (failingListener) Close() error              { return nil }
+
+func TestForwardRemovedWhenListenerFails(t *testing.T) {
+	sc := newTestController(t)
+	sc.startForward("a", services.ForwardTypeLocal, "127.0.0.1:0", "127.0.0.1:1", failingListener{}, func(net.Conn) (net.Conn, error) {
+		return nil, errors.New("不应建立目标连接")
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(sc.GetActiveForwards()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("监听出错的转发应从活动列表移除: %+v", sc.GetActiveForwards())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChangeMasterPasswordKeepsOldPasswordWhenFirstSaveFails(t *testing.T) {
+	sc := newTestController(t)
+	sc.SetEncryptionConfig(true, "old password 123")
+	wantWarning := sc.GetPasswordStrengthWarning()
+
+	// 配置目录是指向不存在位置的符号链接：配置文件不存在，但也无法创建
+	if err := os.Symlink("missing", "broken"); err != nil {
+		t.Skipf("无法创建符号链接: %v", err)
+	}
+	sc.configFile = "broken/servers.dat"
+
+	if err := sc.ChangeMasterPassword("old password 123", "weak"); err == nil {
+		t.Fatal("保存失败时修改主密码应返回错误")
+	}
+	if sc.encryptionPassword != "old password 123" {
+		t.Fatalf("保存失败后主密码 = %q, 期望保持旧密码", sc.encryptionPassword)
+	}
+	if got := sc.GetPasswordStrengthWarning(); got != wantWarning {
+		t.Fatalf("保存失败后密码强度警告 = %q, 期望 %q", got, wantWarning)
+	}
+}
@@ -10,18 +10,44 @@ export function AddServer(arg1:string,arg2:models.Server):Promise<void>;
 
 export function AddServerGroup(arg1:models.ServerGroup):Promise<void>;
 
+export function BroadcastCommand(arg1:Array<string>,arg2:string):Promise<Record<string, string>>;
+
+export function BroadcastInput(arg1:Array<string>,arg2:string):Promise<Record<string, string>>;
+
+export function CancelBatchExecution(arg1:string):Promise<void>;
+
+export function CancelDirectorySize(arg1:string,arg2:string):Promise<string>;
+
+export function CancelTransfer(arg1:string,arg2:string):Promise<string>;
+
+export function ChangeFilePermissions(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ChangeMasterPassword(arg1:string,arg2:string):Promise<void>;
+
+export function CheckRemoteWritable(arg1:string,arg2:string):Promise<boolean>;
+
 export function ClearTerminalOutputBuffer(arg1:string):Promise<void>;
 
+export function CloseCommandStdin(arg1:string):Promise<void>;
+
+export function CloseCommandStream(arg1:string):Promise<void>;
+
 export function CloseTerminalSession(arg1:string):Promise<string>;
 
+export function CollectAcrossServers(arg1:models.ServerSelector,arg2:string):Promise<services.FleetCollectResult>;
+
 export function ConnectToServer(arg1:string):Promise<string>;
 
+export function CopyRemoteFile(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function CreateDirectory(arg1:string,arg2:string):Promise<string>;
 
 export function CreateSFTPClient(arg1:string):Promise<string>;
 
 export function CreateTerminalSession(arg1:string):Promise<string>;
 
+export function CreateTerminalSessionWithOptions(arg1:string,arg2:number,arg3:number,arg4:services.TerminalOptions):Promise<string>;
+
 export function CreateTerminalSessionWithSize(arg1:string,arg2:number,arg3:number):Promise<string>;
 
 export function DeleteBatchScript(arg1:string):Promise<void>;
@@ -32,10 +58,14 @@ export function DeleteServer(arg1:string,arg2:string):Promise<void>;
 
 export function DeleteServerGroup(arg1:string):Promise<void>;
 
+export function DiffRemoteFile(arg1:string,arg2:string,arg3:string):Promise<string>;
+
 export function DisconnectFromServer(arg1:string):Promise<string>;
 
 export function DownloadFile(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function DownloadFileWithOptions(arg1:string,arg2:string,arg3:string,arg4:services.TransferOptions):Promise<string>;
+
 export function DownloadFileWithProgress(arg1:string,arg2:string,arg3:string):Promise<string>;
 
 export function EnsureSFTPClient(arg1:string):Promise<void>;
@@ -44,7 +74,7 @@ export function ExecCommand(arg1:string,arg2:string):Promise<string>;
 
 export function ExecCommandDirect(arg1:string,arg2:string):Promise<string>;
 
-export function ExecCommandsInSharedSession(arg1:string,arg2:Array<string>):Promise<Array<string>>;
+export function ExecCommandsInSharedSession(arg1:string,arg2:Array<string>):Promise<Array<services.CommandResult>>;
 
 export function ExecDownloadFile(arg1:string,arg2:string,arg3:string):Promise<string>;
 
@@ -56,36 +86,140 @@ export function ExecuteCommand(arg1:string,arg2:string):Promise<string>;
 
 export function ExecuteCommandWithoutNewline(arg1:string,arg2:string):Promise<string>;
 
+export function ExecuteHostCommand(arg1:string,arg2:string):Promise<string>;
+
+export function ExportAll(arg1:string,arg2:string):Promise<void>;
+
+export function ExportServers(arg1:string,arg2:boolean):Promise<void>;
+
+export function FilterDirectory(arg1:string,arg2:string,arg3:string,arg4:boolean):Promise<Array<services.FileInfo>>;
+
+export function FindInScrollback(arg1:string,arg2:string,arg3:boolean,arg4:boolean):Promise<Array<services.ScrollbackMatch>>;
+
+export function GenerateKeyPair(arg1:string,arg2:string):Promise<Record<string, string>>;
+
+export function GetActiveForwards():Promise<Array<services.ForwardInfo>>;
+
+export function GetAllTags():Promise<Array<string>>;
+
 export function GetAutoCompleteSuggestions(arg1:string,arg2:string):Promise<Array<string>>;
 
 export function GetBatchScripts():Promise<Array<models.BatchScript>>;
 
+export function GetCommandHistory(arg1:string):Promise<Array<string>>;
+
+export function GetConnectionEventGracePeriod():Promise<number>;
+
+export function GetDirectorySize(arg1:string,arg2:string):Promise<services.DirectorySize>;
+
+export function GetPasswordStrengthWarning():Promise<string>;
+
+export function GetRecentServers(arg1:number):Promise<Array<models.Server>>;
+
 export function GetServerConnectionStatus():Promise<Record<string, boolean>>;
 
 export function GetServerGroups():Promise<Array<models.ServerGroup>>;
 
+export function GetServerHealthStatus():Promise<Record<string, services.HealthStatus>>;
+
+export function GetServersByTag(arg1:string):Promise<Array<models.Server>>;
+
+export function GetTerminalHistory(arg1:string,arg2:number):Promise<string>;
+
 export function GetTerminalLastOutput(arg1:string):Promise<string>;
 
+export function GlobRemoteFiles(arg1:string,arg2:string):Promise<Array<string>>;
+
 export function HandleFileDownloadRequest(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function HandleFileUploadRequest(arg1:string,arg2:string,arg3:string):Promise<void>;
 
+export function ImportAll(arg1:string,arg2:string,arg3:string):Promise<Record<string, services.ImportSummary>>;
+
+export function ImportRemoteHistory(arg1:string):Promise<Array<string>>;
+
+export function ImportServers(arg1:string,arg2:boolean):Promise<services.ImportSummary>;
+
 export function InterruptCommand(arg1:string):Promise<string>;
 
 export function IsTerminalSessionActive(arg1:string):Promise<boolean>;
 
+export function KillCommand(arg1:string):Promise<void>;
+
+export function ListConfigBackups():Promise<Array<services.BackupInfo>>;
+
 export function ListDirectory(arg1:string,arg2:string):Promise<Array<services.FileInfo>>;
 
+export function PasteToTerminal(arg1:string,arg2:string):Promise<void>;
+
+export function ReadRemoteFile(arg1:string,arg2:string,arg3:number,arg4:boolean):Promise<services.RemoteFileContent>;
+
+export function ReadStreamChunk(arg1:string,arg2:number,arg3:number):Promise<services.StreamChunk>;
+
 export function ReadTerminalOutput(arg1:string):Promise<string>;
 
+export function Reconnect(arg1:string):Promise<string>;
+
+export function ReconnectWithOptions(arg1:string,arg2:boolean):Promise<string>;
+
+export function RemoteFileExists(arg1:string,arg2:string):Promise<boolean>;
+
+export function RenameFile(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function ReorderGroups(arg1:Array<string>):Promise<void>;
+
+export function ReorderServers(arg1:string,arg2:Array<string>):Promise<void>;
+
 export function ResizeTerminal(arg1:string,arg2:number,arg3:number):Promise<string>;
 
+export function RespondAuthPrompt(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function RespondHostKey(arg1:string,arg2:boolean):Promise<void>;
+
+export function RestoreConfigBackup(arg1:number):Promise<void>;
+
+export function SaveServerKeyData(arg1:string,arg2:string):Promise<void>;
+
+export function SearchServers(arg1:string):Promise<Array<models.ServerSearchResult>>;
+
 export function SendScriptToTerminal(arg1:string,arg2:string):Promise<void>;
 
+export function SetConnectionEventGracePeriod(arg1:number):Promise<void>;
+
 export function SetEncryptionConfig(arg1:boolean,arg2:string):Promise<void>;
 
+export function SetTerminalBellEnabled(arg1:string,arg2:boolean):Promise<string>;
+
+export function SetTerminalIdleFlushInterval(arg1:string,arg2:number):Promise<string>;
+
+export function Shutdown():Promise<void>;
+
+export function StartCommand(arg1:string,arg2:string):Promise<string>;
+
+export function StartCommandStream(arg1:string,arg2:string):Promise<string>;
+
+export function StartCommandStreamWithEvents(arg1:string,arg2:string,arg3:boolean):Promise<string>;
+
+export function StartLocalForward(arg1:string,arg2:string,arg3:string):Promise<services.ForwardInfo>;
+
+export function StartRecording(arg1:string,arg2:string):Promise<string>;
+
+export function StartRemoteForward(arg1:string,arg2:string,arg3:string):Promise<services.ForwardInfo>;
+
+export function StartTail(arg1:string,arg2:string):Promise<string>;
+
 export function Startup(arg1:context.Context):Promise<void>;
 
+export function StatRemoteFile(arg1:string,arg2:string):Promise<services.FileInfo>;
+
+export function StopForward(arg1:string):Promise<string>;
+
+export function StopRecording(arg1:string):Promise<string>;
+
+export function StopTail(arg1:string):Promise<void>;
+
+export function TestConnection(arg1:models.Server):Promise<services.ConnectionTestResult>;
+
 export function UpdateBatchScript(arg1:models.BatchScript):Promise<void>;
 
 export function UpdateServer(arg1:string,arg2:models.Server):Promise<void>;
@@ -94,4 +228,18 @@ export function UpdateServerGroup(arg1:models.ServerGroup):Promise<void>;
 
 export function UploadFile(arg1:string,arg2:string,arg3:string):Promise<string>;
 
+export function UploadFileWithOptions(arg1:string,arg2:string,arg3:string,arg4:services.TransferOptions):Promise<string>;
+
 export function UploadFileWithProgress(arg1:string,arg2:string,arg3:string):Promise<string>;
+
+export function UploadFiles(arg1:string,arg2:Array<services.TransferPair>):Promise<Array<services.TransferResult>>;
+
+export function UploadFilesWithConcurrency(arg1:string,arg2:Array<services.TransferPair>,arg3:number):Promise<Array<services.TransferResult>>;
+
+export function ValidateBatchScript(arg1:models.BatchScript):Promise<Array<services.ScriptValidationIssue>>;
+
+export function WaitCommand(arg1:string):Promise<number>;
+
+export function WriteCommandStdin(arg1:string,arg2:string):Promise<void>;
+
+export function WriteRemoteFile(arg1:string,arg2:string,arg3:string):Promise<void>;
@@ -14,18 +14,66 @@ export function AddServerGroup(arg1) {
   return window['go']['controllers']['SSHController']['AddServerGroup'](arg1);
 }
 
+export function BroadcastCommand(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['BroadcastCommand'](arg1, arg2);
+}
+
+export function BroadcastInput(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['BroadcastInput'](arg1, arg2);
+}
+
+export function CancelBatchExecution(arg1) {
+  return window['go']['controllers']['SSHController']['CancelBatchExecution'](arg1);
+}
+
+export function CancelDirectorySize(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['CancelDirectorySize'](arg1, arg2);
+}
+
+export function CancelTransfer(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['CancelTransfer'](arg1, arg2);
+}
+
+export function ChangeFilePermissions(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['ChangeFilePermissions'](arg1, arg2, arg3);
+}
+
+export function ChangeMasterPassword(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ChangeMasterPassword'](arg1, arg2);
+}
+
+export function CheckRemoteWritable(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['CheckRemoteWritable'](arg1, arg2);
+}
+
 export function ClearTerminalOutputBuffer(arg1) {
   return window['go']['controllers']['SSHController']['ClearTerminalOutputBuffer'](arg1);
 }
 
+export function CloseCommandStdin(arg1) {
+  return window['go']['controllers']['SSHController']['CloseCommandStdin'](arg1);
+}
+
+export function CloseCommandStream(arg1) {
+  return window['go']['controllers']['SSHController']['CloseCommandStream'](arg1);
+}
+
 export function CloseTerminalSession(arg1) {
   return window['go']['controllers']['SSHController']['CloseTerminalSession'](arg1);
 }
 
+export function CollectAcrossServers(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['CollectAcrossServers'](arg1, arg2);
+}
+
 export function ConnectToServer(arg1) {
   return window['go']['controllers']['SSHController']['ConnectToServer'](arg1);
 }
 
+export function CopyRemoteFile(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['CopyRemoteFile'](arg1, arg2, arg3);
+}
+
 export function CreateDirectory(arg1, arg2) {
   return window['go']['controllers']['SSHController']['CreateDirectory'](arg1, arg2);
 }
@@ -38,6 +86,10 @@ export function CreateTerminalSession(arg1) {
   return window['go']['controllers']['SSHController']['CreateTerminalSession'](arg1);
 }
 
+export function CreateTerminalSessionWithOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['CreateTerminalSessionWithOptions'](arg1, arg2, arg3, arg4);
+}
+
 export function CreateTerminalSessionWithSize(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['CreateTerminalSessionWithSize'](arg1, arg2, arg3);
 }
@@ -58,6 +110,10 @@ export function DeleteServerGroup(arg1) {
   return window['go']['controllers']['SSHController']['DeleteServerGroup'](arg1);
 }
 
+export function DiffRemoteFile(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['DiffRemoteFile'](arg1, arg2, arg3);
+}
+
 export function DisconnectFromServer(arg1) {
   return window['go']['controllers']['SSHController']['DisconnectFromServer'](arg1);
 }
@@ -66,6 +122,10 @@ export function DownloadFile(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['DownloadFile'](arg1, arg2, arg3);
 }
 
+export function DownloadFileWithOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['DownloadFileWithOptions'](arg1, arg2, arg3, arg4);
+}
+
 export function DownloadFileWithProgress(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['DownloadFileWithProgress'](arg1, arg2, arg3);
 }
@@ -106,6 +166,38 @@ export function ExecuteCommandWithoutNewline(arg1, arg2) {
   return window['go']['controllers']['SSHController']['ExecuteCommandWithoutNewline'](arg1, arg2);
 }
 
+export function ExecuteHostCommand(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ExecuteHostCommand'](arg1, arg2);
+}
+
+export function ExportAll(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ExportAll'](arg1, arg2);
+}
+
+export function ExportServers(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ExportServers'](arg1, arg2);
+}
+
+export function FilterDirectory(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['FilterDirectory'](arg1, arg2, arg3, arg4);
+}
+
+export function FindInScrollback(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['FindInScrollback'](arg1, arg2, arg3, arg4);
+}
+
+export function GenerateKeyPair(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['GenerateKeyPair'](arg1, arg2);
+}
+
+export function GetActiveForwards() {
+  return window['go']['controllers']['SSHController']['GetActiveForwards']();
+}
+
+export function GetAllTags() {
+  return window['go']['controllers']['SSHController']['GetAllTags']();
+}
+
 export function GetAutoCompleteSuggestions(arg1, arg2) {
   return window['go']['controllers']['SSHController']['GetAutoCompleteSuggestions'](arg1, arg2);
 }
@@ -114,6 +206,26 @@ export function GetBatchScripts() {
   return window['go']['controllers']['SSHController']['GetBatchScripts']();
 }
 
+export function GetCommandHistory(arg1) {
+  return window['go']['controllers']['SSHController']['GetCommandHistory'](arg1);
+}
+
+export function GetConnectionEventGracePeriod() {
+  return window['go']['controllers']['SSHController']['GetConnectionEventGracePeriod']();
+}
+
+export function GetDirectorySize(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['GetDirectorySize'](arg1, arg2);
+}
+
+export function GetPasswordStrengthWarning() {
+  return window['go']['controllers']['SSHController']['GetPasswordStrengthWarning']();
+}
+
+export function GetRecentServers(arg1) {
+  return window['go']['controllers']['SSHController']['GetRecentServers'](arg1);
+}
+
 export function GetServerConnectionStatus() {
   return window['go']['controllers']['SSHController']['GetServerConnectionStatus']();
 }
@@ -122,10 +234,26 @@ export function GetServerGroups() {
   return window['go']['controllers']['SSHController']['GetServerGroups']();
 }
 
+export function GetServerHealthStatus() {
+  return window['go']['controllers']['SSHController']['GetServerHealthStatus']();
+}
+
+export function GetServersByTag(arg1) {
+  return window['go']['controllers']['SSHController']['GetServersByTag'](arg1);
+}
+
+export function GetTerminalHistory(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['GetTerminalHistory'](arg1, arg2);
+}
+
 export function GetTerminalLastOutput(arg1) {
   return window['go']['controllers']['SSHController']['GetTerminalLastOutput'](arg1);
 }
 
+export function GlobRemoteFiles(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['GlobRemoteFiles'](arg1, arg2);
+}
+
 export function HandleFileDownloadRequest(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['HandleFileDownloadRequest'](arg1, arg2, arg3);
 }
@@ -134,6 +262,18 @@ export function HandleFileUploadRequest(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['HandleFileUploadRequest'](arg1, arg2, arg3);
 }
 
+export function ImportAll(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['ImportAll'](arg1, arg2, arg3);
+}
+
+export function ImportRemoteHistory(arg1) {
+  return window['go']['controllers']['SSHController']['ImportRemoteHistory'](arg1);
+}
+
+export function ImportServers(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ImportServers'](arg1, arg2);
+}
+
 export function InterruptCommand(arg1) {
   return window['go']['controllers']['SSHController']['InterruptCommand'](arg1);
 }
@@ -142,30 +282,158 @@ export function IsTerminalSessionActive(arg1) {
   return window['go']['controllers']['SSHController']['IsTerminalSessionActive'](arg1);
 }
 
+export function KillCommand(arg1) {
+  return window['go']['controllers']['SSHController']['KillCommand'](arg1);
+}
+
+export function ListConfigBackups() {
+  return window['go']['controllers']['SSHController']['ListConfigBackups']();
+}
+
 export function ListDirectory(arg1, arg2) {
   return window['go']['controllers']['SSHController']['ListDirectory'](arg1, arg2);
 }
 
+export function PasteToTerminal(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['PasteToTerminal'](arg1, arg2);
+}
+
+export function ReadRemoteFile(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['ReadRemoteFile'](arg1, arg2, arg3, arg4);
+}
+
+export function ReadStreamChunk(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['ReadStreamChunk'](arg1, arg2, arg3);
+}
+
 export function ReadTerminalOutput(arg1) {
   return window['go']['controllers']['SSHController']['ReadTerminalOutput'](arg1);
 }
 
+export function Reconnect(arg1) {
+  return window['go']['controllers']['SSHController']['Reconnect'](arg1);
+}
+
+export function ReconnectWithOptions(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ReconnectWithOptions'](arg1, arg2);
+}
+
+export function RemoteFileExists(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['RemoteFileExists'](arg1, arg2);
+}
+
+export function RenameFile(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['RenameFile'](arg1, arg2, arg3);
+}
+
+export function ReorderGroups(arg1) {
+  return window['go']['controllers']['SSHController']['ReorderGroups'](arg1);
+}
+
+export function ReorderServers(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['ReorderServers'](arg1, arg2);
+}
+
 export function ResizeTerminal(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['ResizeTerminal'](arg1, arg2, arg3);
 }
 
+export function RespondAuthPrompt(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['RespondAuthPrompt'](arg1, arg2);
+}
+
+export function RespondHostKey(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['RespondHostKey'](arg1, arg2);
+}
+
+export function RestoreConfigBackup(arg1) {
+  return window['go']['controllers']['SSHController']['RestoreConfigBackup'](arg1);
+}
+
+export function SaveServerKeyData(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['SaveServerKeyData'](arg1, arg2);
+}
+
+export function SearchServers(arg1) {
+  return window['go']['controllers']['SSHController']['SearchServers'](arg1);
+}
+
 export function SendScriptToTerminal(arg1, arg2) {
   return window['go']['controllers']['SSHController']['SendScriptToTerminal'](arg1, arg2);
 }
 
+export function SetConnectionEventGracePeriod(arg1) {
+  return window['go']['controllers']['SSHController']['SetConnectionEventGracePeriod'](arg1);
+}
+
 export function SetEncryptionConfig(arg1, arg2) {
   return window['go']['controllers']['SSHController']['SetEncryptionConfig'](arg1, arg2);
 }
 
+export function SetTerminalBellEnabled(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['SetTerminalBellEnabled'](arg1, arg2);
+}
+
+export function SetTerminalIdleFlushInterval(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['SetTerminalIdleFlushInterval'](arg1, arg2);
+}
+
+export function Shutdown() {
+  return window['go']['controllers']['SSHController']['Shutdown']();
+}
+
+export function StartCommand(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['StartCommand'](arg1, arg2);
+}
+
+export function StartCommandStream(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['StartCommandStream'](arg1, arg2);
+}
+
+export function StartCommandStreamWithEvents(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['StartCommandStreamWithEvents'](arg1, arg2, arg3);
+}
+
+export function StartLocalForward(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['StartLocalForward'](arg1, arg2, arg3);
+}
+
+export function StartRecording(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['StartRecording'](arg1, arg2);
+}
+
+export function StartRemoteForward(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['StartRemoteForward'](arg1, arg2, arg3);
+}
+
+export function StartTail(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['StartTail'](arg1, arg2);
+}
+
 export function Startup(arg1) {
   return window['go']['controllers']['SSHController']['Startup'](arg1);
 }
 
+export function StatRemoteFile(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['StatRemoteFile'](arg1, arg2);
+}
+
+export function StopForward(arg1) {
+  return window['go']['controllers']['SSHController']['StopForward'](arg1);
+}
+
+export function StopRecording(arg1) {
+  return window['go']['controllers']['SSHController']['StopRecording'](arg1);
+}
+
+export function StopTail(arg1) {
+  return window['go']['controllers']['SSHController']['StopTail'](arg1);
+}
+
+export function TestConnection(arg1) {
+  return window['go']['controllers']['SSHController']['TestConnection'](arg1);
+}
+
 export function UpdateBatchScript(arg1) {
   return window['go']['controllers']['SSHController']['UpdateBatchScript'](arg1);
 }
@@ -182,6 +450,34 @@ export function UploadFile(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['UploadFile'](arg1, arg2, arg3);
 }
 
+export function UploadFileWithOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['controllers']['SSHController']['UploadFileWithOptions'](arg1, arg2, arg3, arg4);
+}
+
 export function UploadFileWithProgress(arg1, arg2, arg3) {
   return window['go']['controllers']['SSHController']['UploadFileWithProgress'](arg1, arg2, arg3);
 }
+
+export function UploadFiles(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['UploadFiles'](arg1, arg2);
+}
+
+export function UploadFilesWithConcurrency(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['UploadFilesWithConcurrency'](arg1, arg2, arg3);
+}
+
+export function ValidateBatchScript(arg1) {
+  return window['go']['controllers']['SSHController']['ValidateBatchScript'](arg1);
+}
+
+export function WaitCommand(arg1) {
+  return window['go']['controllers']['SSHController']['WaitCommand'](arg1);
+}
+
+export function WriteCommandStdin(arg1, arg2) {
+  return window['go']['controllers']['SSHController']['WriteCommandStdin'](arg1, arg2);
+}
+
+export function WriteRemoteFile(arg1, arg2, arg3) {
+  return window['go']['controllers']['SSHController']['WriteRemoteFile'](arg1, arg2, arg3);
+}
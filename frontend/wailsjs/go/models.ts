@@ -26,6 +26,14 @@ export namespace models {
 	    content: string;
 	    serverIds: string[];
 	    executionType: string;
+	    maxTotalDurationSeconds?: number;
+	    commandTimeoutSeconds?: number;
+	    retryCount?: number;
+	    retryDelaySeconds?: number;
+	    executionMode?: string;
+	    stopOnFirstFailure?: boolean;
+	    maxConcurrency?: number;
+	    variables?: Record<string, string>;
 	    createdAt: string;
 	    updatedAt: string;
 	
@@ -41,10 +49,34 @@ export namespace models {
 	        this.content = source["content"];
 	        this.serverIds = source["serverIds"];
 	        this.executionType = source["executionType"];
+	        this.maxTotalDurationSeconds = source["maxTotalDurationSeconds"];
+	        this.commandTimeoutSeconds = source["commandTimeoutSeconds"];
+	        this.retryCount = source["retryCount"];
+	        this.retryDelaySeconds = source["retryDelaySeconds"];
+	        this.executionMode = source["executionMode"];
+	        this.stopOnFirstFailure = source["stopOnFirstFailure"];
+	        this.maxConcurrency = source["maxConcurrency"];
+	        this.variables = source["variables"];
 	        this.createdAt = source["createdAt"];
 	        this.updatedAt = source["updatedAt"];
 	    }
 	}
+	export class SOCKS5Proxy {
+	    address: string;
+	    username?: string;
+	    password?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SOCKS5Proxy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.address = source["address"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	    }
+	}
 	export class Server {
 	    id: string;
 	    name: string;
@@ -53,8 +85,22 @@ export namespace models {
 	    username: string;
 	    password: string;
 	    keyFile: string;
+	    keyData?: string;
+	    useAgent?: boolean;
 	    groupId: string;
 	    note: string;
+	    tags?: string[];
+	    lastConnected?: string;
+	    connectCount?: number;
+	    commandWrapper?: string;
+	    wrapTerminal?: boolean;
+	    healthCheckCommand?: string;
+	    healthCheckIntervalSeconds?: number;
+	    keepAliveIntervalSeconds?: number;
+	    proxySocks5?: SOCKS5Proxy;
+	    connectTimeoutSeconds?: number;
+	    commandTimeoutSeconds?: number;
+	    env?: Record<string, string>;
 	
 	    static createFrom(source: any = {}) {
 	        return new Server(source);
@@ -69,9 +115,41 @@ export namespace models {
 	        this.username = source["username"];
 	        this.password = source["password"];
 	        this.keyFile = source["keyFile"];
+	        this.keyData = source["keyData"];
+	        this.useAgent = source["useAgent"];
 	        this.groupId = source["groupId"];
 	        this.note = source["note"];
+	        this.tags = source["tags"];
+	        this.lastConnected = source["lastConnected"];
+	        this.connectCount = source["connectCount"];
+	        this.commandWrapper = source["commandWrapper"];
+	        this.wrapTerminal = source["wrapTerminal"];
+	        this.healthCheckCommand = source["healthCheckCommand"];
+	        this.healthCheckIntervalSeconds = source["healthCheckIntervalSeconds"];
+	        this.keepAliveIntervalSeconds = source["keepAliveIntervalSeconds"];
+	        this.proxySocks5 = this.convertValues(source["proxySocks5"], SOCKS5Proxy);
+	        this.connectTimeoutSeconds = source["connectTimeoutSeconds"];
+	        this.commandTimeoutSeconds = source["commandTimeoutSeconds"];
+	        this.env = source["env"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ServerGroup {
 	    id: string;
@@ -107,17 +185,198 @@ export namespace models {
 		    return a;
 		}
 	}
+	export class ServerSearchResult {
+	    server: Server;
+	    groupId: string;
+	    groupName: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerSearchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.server = this.convertValues(source["server"], Server);
+	        this.groupId = source["groupId"];
+	        this.groupName = source["groupName"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ServerSelector {
+	    groupIds: string[];
+	    serverIds: string[];
+	    tags: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerSelector(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.groupIds = source["groupIds"];
+	        this.serverIds = source["serverIds"];
+	        this.tags = source["tags"];
+	    }
+	}
 
 }
 
 export namespace services {
 	
+	export class ValueCount {
+	    value: string;
+	    count: number;
+	    serverIds: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ValueCount(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.count = source["count"];
+	        this.serverIds = source["serverIds"];
+	    }
+	}
+	export class AggregateStats {
+	    total: number;
+	    succeeded: number;
+	    failed: number;
+	    values: ValueCount[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AggregateStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.total = source["total"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.values = this.convertValues(source["values"], ValueCount);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BackupInfo {
+	    index: number;
+	    path: string;
+	    size: number;
+	    modTime: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.modTime = source["modTime"];
+	    }
+	}
+	export class CommandResult {
+	    output: string;
+	    exitCode: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.output = source["output"];
+	        this.exitCode = source["exitCode"];
+	    }
+	}
+	export class ConnectionTestResult {
+	    success: boolean;
+	    latencyMs: number;
+	    connectMs: number;
+	    commandMs: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ConnectionTestResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.latencyMs = source["latencyMs"];
+	        this.connectMs = source["connectMs"];
+	        this.commandMs = source["commandMs"];
+	        this.error = source["error"];
+	    }
+	}
+	export class DirectorySize {
+	    path: string;
+	    totalBytes: number;
+	    fileCount: number;
+	    dirCount: number;
+	    skipped: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DirectorySize(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.totalBytes = source["totalBytes"];
+	        this.fileCount = source["fileCount"];
+	        this.dirCount = source["dirCount"];
+	        this.skipped = source["skipped"];
+	    }
+	}
 	export class FileInfo {
 	    name: string;
 	    path: string;
 	    size: number;
 	    mtime: number;
 	    type: string;
+	    perm: string;
+	    mode: string;
+	    uid: number;
+	    gid: number;
+	    isSymlink: boolean;
+	    linkTarget?: string;
+	    linkBroken?: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new FileInfo(source);
@@ -130,6 +389,247 @@ export namespace services {
 	        this.size = source["size"];
 	        this.mtime = source["mtime"];
 	        this.type = source["type"];
+	        this.perm = source["perm"];
+	        this.mode = source["mode"];
+	        this.uid = source["uid"];
+	        this.gid = source["gid"];
+	        this.isSymlink = source["isSymlink"];
+	        this.linkTarget = source["linkTarget"];
+	        this.linkBroken = source["linkBroken"];
+	    }
+	}
+	export class FleetCollectResult {
+	    outputs: Record<string, string>;
+	    errors: Record<string, string>;
+	    stats: AggregateStats;
+	
+	    static createFrom(source: any = {}) {
+	        return new FleetCollectResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outputs = source["outputs"];
+	        this.errors = source["errors"];
+	        this.stats = this.convertValues(source["stats"], AggregateStats);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ForwardInfo {
+	    id: string;
+	    serverId: string;
+	    type: string;
+	    localAddr: string;
+	    remoteAddr: string;
+	    connections: number;
+	    active: number;
+	    bytesSent: number;
+	    bytesReceived: number;
+	    startedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ForwardInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.serverId = source["serverId"];
+	        this.type = source["type"];
+	        this.localAddr = source["localAddr"];
+	        this.remoteAddr = source["remoteAddr"];
+	        this.connections = source["connections"];
+	        this.active = source["active"];
+	        this.bytesSent = source["bytesSent"];
+	        this.bytesReceived = source["bytesReceived"];
+	        this.startedAt = source["startedAt"];
+	    }
+	}
+	export class ImportSummary {
+	    added: number;
+	    updated: number;
+	    skipped: number;
+	    conflicts: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.added = source["added"];
+	        this.updated = source["updated"];
+	        this.skipped = source["skipped"];
+	        this.conflicts = source["conflicts"];
+	    }
+	}
+	export class RemoteFileContent {
+	    content: string;
+	    size: number;
+	    truncated: boolean;
+	    binary: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RemoteFileContent(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.content = source["content"];
+	        this.size = source["size"];
+	        this.truncated = source["truncated"];
+	        this.binary = source["binary"];
+	    }
+	}
+	export class ScriptValidationIssue {
+	    line: number;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScriptValidationIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.line = source["line"];
+	        this.message = source["message"];
+	    }
+	}
+	export class ScrollbackMatch {
+	    offset: number;
+	    length: number;
+	    line: number;
+	    column: number;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScrollbackMatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.offset = source["offset"];
+	        this.length = source["length"];
+	        this.line = source["line"];
+	        this.column = source["column"];
+	        this.text = source["text"];
+	    }
+	}
+	export class StreamChunk {
+	    data: string;
+	    offset: number;
+	    nextOffset: number;
+	    totalBytes: number;
+	    done: boolean;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StreamChunk(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.data = source["data"];
+	        this.offset = source["offset"];
+	        this.nextOffset = source["nextOffset"];
+	        this.totalBytes = source["totalBytes"];
+	        this.done = source["done"];
+	        this.error = source["error"];
+	    }
+	}
+	export class TerminalOptions {
+	    termType: string;
+	    modes: Record<string, number>;
+	    env: Record<string, string>;
+	    scrollbackBytes: number;
+	    outputBufferSize: number;
+	    backpressure: boolean;
+	    idleTimeoutSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TerminalOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.termType = source["termType"];
+	        this.modes = source["modes"];
+	        this.env = source["env"];
+	        this.scrollbackBytes = source["scrollbackBytes"];
+	        this.outputBufferSize = source["outputBufferSize"];
+	        this.backpressure = source["backpressure"];
+	        this.idleTimeoutSeconds = source["idleTimeoutSeconds"];
+	    }
+	}
+	export class TransferOptions {
+	    lineEnding: string;
+	    normalizeForUnix: boolean;
+	    transferId: string;
+	    preflight: boolean;
+	    verifyChecksum: boolean;
+	    rateLimitBytesPerSec: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransferOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.lineEnding = source["lineEnding"];
+	        this.normalizeForUnix = source["normalizeForUnix"];
+	        this.transferId = source["transferId"];
+	        this.preflight = source["preflight"];
+	        this.verifyChecksum = source["verifyChecksum"];
+	        this.rateLimitBytesPerSec = source["rateLimitBytesPerSec"];
+	    }
+	}
+	export class TransferPair {
+	    localPath: string;
+	    remotePath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransferPair(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.localPath = source["localPath"];
+	        this.remotePath = source["remotePath"];
+	    }
+	}
+	export class TransferResult {
+	    localPath: string;
+	    remotePath: string;
+	    success: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransferResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.localPath = source["localPath"];
+	        this.remotePath = source["remotePath"];
+	        this.success = source["success"];
+	        this.error = source["error"];
 	    }
 	}
 
@@ -0,0 +1,198 @@
+// Package sshtest 提供测试用的进程内 SSH 服务器
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// ExecHandler 处理 exec 请求，返回命令的退出码
+type ExecHandler func(command string, stdin io.Reader, stdout io.Writer) int
+
+// Server 测试用的进程内 SSH 服务器：不校验客户端身份，
+// shell 请求把输入原样回显，exec 请求交给 exec 处理（为空时直接以0退出），
+// sftp 子系统使用所有连接共享的内存文件系统
+type Server struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	exec     ExecHandler
+	files    sftp.Handlers
+
+	mutex sync.Mutex
+	conns []net.Conn
+	wg    sync.WaitGroup
+}
+
+// NewServer 启动测试服务器，测试结束时自动关闭
+func NewServer(t testing.TB, exec ExecHandler) *Server {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成主机密钥失败: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("创建主机密钥失败: %v", err)
+	}
+	config := &ssh.ServerConfig{NoClientAuth: true}
+	config.AddHostKey(signer)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+
+	srv := &Server{listener: listener, config: config, exec: exec, files: sftp.InMemHandler()}
+	srv.wg.Add(1)
+	go srv.acceptLoop()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// Addr 返回服务器监听地址
+func (srv *Server) Addr() string {
+	return srv.listener.Addr().String()
+}
+
+// Close 关闭监听和所有连接，并等待处理协程退出
+func (srv *Server) Close() {
+	srv.listener.Close()
+	srv.mutex.Lock()
+	for _, conn := range srv.conns {
+		conn.Close()
+	}
+	srv.mutex.Unlock()
+	srv.wg.Wait()
+}
+
+// Dial 建立到测试服务器的 SSH 连接，测试结束时自动断开
+func (srv *Server) Dial(t testing.TB) *ssh.Client {
+	t.Helper()
+
+	client, err := ssh.Dial("tcp", srv.Addr(), &ssh.ClientConfig{
+		User:            "test",
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err != nil {
+		t.Fatalf("连接测试服务器失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func (srv *Server) acceptLoop() {
+	defer srv.wg.Done()
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		srv.mutex.Lock()
+		srv.conns = append(srv.conns, conn)
+		srv.mutex.Unlock()
+
+		srv.wg.Add(1)
+		go srv.serveConn(conn)
+	}
+}
+
+func (srv *Server) serveConn(netConn net.Conn) {
+	defer srv.wg.Done()
+	defer netConn.Close()
+
+	_, chans, reqs, err := ssh.NewServerConn(netConn, srv.config)
+	if err != nil {
+		return
+	}
+
+	// 全局请求（如 keepalive@openssh.com）一律成功
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+		for req := range reqs {
+			if req.WantReply {
+				req.Reply(true, nil)
+			}
+		}
+	}()
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		srv.wg.Add(1)
+		go srv.serveSession(channel, requests)
+	}
+}
+
+func (srv *Server) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer srv.wg.Done()
+	defer channel.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "shell":
+			req.Reply(true, nil)
+			srv.wg.Add(1)
+			go func() {
+				defer srv.wg.Done()
+				io.Copy(channel, channel)
+				channel.CloseWrite()
+			}()
+		case "exec":
+			command := ""
+			if len(req.Payload) >= 4 {
+				n := binary.BigEndian.Uint32(req.Payload)
+				if int(n) <= len(req.Payload)-4 {
+					command = string(req.Payload[4 : 4+n])
+				}
+			}
+			req.Reply(true, nil)
+			srv.wg.Add(1)
+			go func() {
+				defer srv.wg.Done()
+				status := 0
+				if srv.exec != nil {
+					status = srv.exec(command, channel, channel)
+				}
+				exitStatus := make([]byte, 4)
+				binary.BigEndian.PutUint32(exitStatus, uint32(status))
+				channel.SendRequest("exit-status", false, exitStatus)
+				channel.Close()
+			}()
+		case "subsystem":
+			if len(req.Payload) < 4 || string(req.Payload[4:]) != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			srv.wg.Add(1)
+			go func() {
+				defer srv.wg.Done()
+				server := sftp.NewRequestServer(channel, srv.files)
+				server.Serve()
+				server.Close()
+				channel.Close()
+			}()
+		default:
+			// pty-req、env、window-change 等请求直接接受
+			if req.WantReply {
+				req.Reply(true, nil)
+			}
+		}
+	}
+}
@@ -36,6 +36,9 @@ func main() {
 			app.startup(ctx)
 			sshController.Startup(ctx)
 		},
+		OnShutdown: func(ctx context.Context) {
+			sshController.Shutdown()
+		},
 		Bind: []interface{}{
 			app,
 			sshController,
@@ -16,8 +16,43 @@ type Server struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	KeyFile  string `json:"keyFile"` // SSH密钥文件路径
+	KeyData  string `json:"keyData,omitempty"` // SSH私钥内容（PEM），未设置密钥文件时使用，随配置文件加密保存
+	UseAgent bool   `json:"useAgent,omitempty"` // 是否优先尝试ssh-agent中的密钥
 	GroupID  string `json:"groupId"`
 	Note     string `json:"note"`   // 备注信息
+	Tags     []string `json:"tags,omitempty"` // 标签，如 "prod"、"db"，用于跨分组筛选
+
+	// 连接统计，由连接成功时自动更新，UpdateServer 不会修改
+	LastConnected string `json:"lastConnected,omitempty"` // 最近一次连接成功的时间
+	ConnectCount  int    `json:"connectCount,omitempty"`  // 连接成功的次数
+
+	// 命令包装模板，例如 "sudo -u deploy bash -c {cmd}"，{cmd} 会被替换为转义后的命令
+	CommandWrapper string `json:"commandWrapper,omitempty"`
+	WrapTerminal   bool   `json:"wrapTerminal,omitempty"` // 终端会话是否也直接进入包装环境
+
+	// 应用层健康检查命令，退出码为0表示健康；为空时不检查
+	HealthCheckCommand         string `json:"healthCheckCommand,omitempty"`
+	HealthCheckIntervalSeconds int    `json:"healthCheckIntervalSeconds,omitempty"` // 检查间隔（秒），默认60
+
+	// 连接保活间隔（秒），定期发送 keepalive@openssh.com 防止空闲连接被防火墙或 sshd 断开；0 使用默认值30，负数禁用（仍每2分钟检查一次连接状态）
+	KeepAliveIntervalSeconds int `json:"keepAliveIntervalSeconds,omitempty"`
+
+	// 通过本地 SOCKS5 代理建立连接（与 ProxyJump 不同，代理本身不是SSH服务器）；为空时直连
+	ProxySOCKS5 *SOCKS5Proxy `json:"proxySocks5,omitempty"`
+
+	ConnectTimeoutSeconds int `json:"connectTimeoutSeconds,omitempty"` // 建立连接的超时时间（秒），0 使用默认值30
+	CommandTimeoutSeconds int `json:"commandTimeoutSeconds,omitempty"` // ExecuteCommand 单条命令的超时时间（秒），0 表示不限制
+
+	// 终端会话的环境变量（如 LANG=en_US.UTF-8），创建终端时通过 env 请求发送
+	// 注意：服务端 sshd 的 AcceptEnv 需允许这些变量，否则会回退为在启动shell时设置
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// SOCKS5Proxy SOCKS5 代理配置
+type SOCKS5Proxy struct {
+	Address  string `json:"address"`            // 代理地址，如 127.0.0.1:1080
+	Username string `json:"username,omitempty"` // 代理认证用户名，为空时不认证
+	Password string `json:"password,omitempty"` // 代理认证密码，随配置文件加密保存
 }
 
 // BatchScript 批量脚本
@@ -28,6 +63,15 @@ type BatchScript struct {
 	Content     string   `json:"content"`     // 脚本内容
 	ServerIDs   []string `json:"serverIds"`   // 目标服务器ID列表
 	ExecutionType string `json:"executionType"` // 执行类型: "script"(脚本模式), "command"(命令模式)
+	MaxTotalDurationSeconds int `json:"maxTotalDurationSeconds,omitempty"` // 整次批量执行的时间预算（秒），0表示不限制
+	CommandTimeoutSeconds int `json:"commandTimeoutSeconds,omitempty"` // 单条命令的超时时间（秒），0表示不限制；脚本模式下作用于整个脚本
+	RetryCount        int `json:"retryCount,omitempty"`        // 命令失败（非0退出码或连接错误）后的重试次数，0表示不重试
+	RetryDelaySeconds int `json:"retryDelaySeconds,omitempty"` // 首次重试前等待的秒数，之后每次翻倍
+	ExecutionMode      string `json:"executionMode,omitempty"`      // 服务器执行方式: "parallel"(并发，默认), "sequential"(按列表顺序逐台执行)
+	StopOnFirstFailure bool   `json:"stopOnFirstFailure,omitempty"` // 逐台执行时，某台服务器失败后不再执行之后的服务器（标记为 skipped）
+	MaxConcurrency int `json:"maxConcurrency,omitempty"` // 并发执行时同时执行的最大服务器数量，0表示默认值（10），超过上限时按上限执行
+	// 脚本变量，脚本中的 ${NAME} 执行前会被替换；另有内置变量 SERVER_ID、SERVER_NAME、SERVER_HOST、SERVER_PORT、SERVER_USER
+	Variables map[string]string `json:"variables,omitempty"`
 	CreatedAt   string   `json:"createdAt"`   // 创建时间
 	UpdatedAt   string   `json:"updatedAt"`   // 更新时间
 }
@@ -35,10 +79,13 @@ type BatchScript struct {
 // ScriptExecution 脚本执行记录
 type ScriptExecution struct {
 	ID         string `json:"id"`
+	BatchID    string `json:"batchId,omitempty"` // 所属的批量执行ID，用于取消执行
 	ScriptID   string `json:"scriptId"`   // 脚本ID
+	ScriptName    string `json:"scriptName"`    // 执行时的脚本名称（快照）
+	ScriptVersion string `json:"scriptVersion"` // 执行时脚本的更新时间，用于标识所用的脚本版本
 	ServerID   string `json:"serverId"`   // 服务器ID
 	ServerName string `json:"serverName"` // 服务器名称
-	Status     string `json:"status"`     // 执行状态: pending, running, success, failed
+	Status     string `json:"status"`     // 执行状态: pending, running, success, failed, skipped, cancelled
 	Output     string `json:"output"`     // 执行输出
 	Error      string `json:"error"`      // 错误信息
 	StartTime  string `json:"startTime"`  // 开始时间
@@ -51,7 +98,27 @@ type CommandOutput struct {
 	Command   string `json:"command"`   // 命令内容
 	Output    string `json:"output"`    // 命令输出
 	Error     string `json:"error"`     // 命令错误
-	Status    string `json:"status"`    // 执行状态: success, failed
+	Status    string `json:"status"`    // 执行状态: success, failed, timeout, skipped
 	StartTime string `json:"startTime"` // 开始时间
 	EndTime   string `json:"endTime"`   // 结束时间
-}
\ No newline at end of file
+	ElapsedMs int64  `json:"elapsedMs,omitempty"` // 执行耗时（毫秒），超时的命令为已运行的时间
+	ExitCode  int    `json:"exitCode"` // 退出码，无法获取（未执行、超时、非退出码类错误）时为 -1
+	Attempts      int      `json:"attempts,omitempty"`      // 执行次数（含重试）
+	AttemptErrors []string `json:"attemptErrors,omitempty"` // 重试前每次失败的错误信息
+	ContinueOnError bool `json:"continueOnError,omitempty"` // 命令带 $ne 前缀，失败后继续执行且不影响整体结果
+	Line int `json:"line,omitempty"` // 命令在脚本中的起始行号（从1开始），脚本模式整体执行时为0
+}
+
+// ServerSearchResult 服务器搜索结果，附带所在分组
+type ServerSearchResult struct {
+	Server    Server `json:"server"`
+	GroupID   string `json:"groupId"`
+	GroupName string `json:"groupName"`
+}
+
+// ServerSelector 服务器选择条件，各条件之间为并集；全部为空时选择所有服务器
+type ServerSelector struct {
+	GroupIDs  []string `json:"groupIds"`  // 按分组选择
+	ServerIDs []string `json:"serverIds"` // 按服务器ID选择
+	Tags      []string `json:"tags"`      // 按标签选择（不区分大小写），带有其中任一标签的服务器
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeTempData 向临时文件写入数据，测试中替换它以模拟写入中途失败
+var writeTempData = func(f *os.File, data []byte) (int, error) {
+	return f.Write(data)
+}
+
+// writeFileAtomic 先写入同目录下的临时文件再重命名替换目标文件，避免写入中途崩溃导致文件损坏
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("无法创建目录: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("无法创建临时文件: %v", err)
+	}
+	tmpName := tmp.Name()
+	success := false
+	defer func() {
+		if !success {
+			_ = os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := writeTempData(tmp, data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法写入临时文件: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法写入临时文件: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("无法写入临时文件: %v", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("无法设置文件权限: %v", err)
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		return fmt.Errorf("无法替换文件: %v", err)
+	}
+
+	success = true
+	return nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileAtomicPartialWriteKeepsOriginal(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "servers.dat")
+	original := []byte("original-config")
+	if err := os.WriteFile(filename, original, 0600); err != nil {
+		t.Fatalf("无法写入初始文件: %v", err)
+	}
+
+	// 模拟写入一半时失败（如磁盘已满或进程被中断）
+	saved := writeTempData
+	writeTempData = func(f *os.File, data []byte) (int, error) {
+		n, _ := f.Write(data[:len(data)/2])
+		return n, errors.New("模拟写入中断")
+	}
+	defer func() { writeTempData = saved }()
+
+	if err := writeFileAtomic(filename, []byte("new-config-that-is-much-longer"), 0600); err == nil {
+		t.Fatal("写入中途失败时应返回错误")
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("无法读取配置文件: %v", err)
+	}
+	if string(got) != string(original) {
+		t.Fatalf("写入失败后原文件被修改: %q", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("无法读取目录: %v", err)
+	}
+	if len(entries) != 1 {
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("写入失败后残留临时文件: %v", names)
+	}
+}
+
+func TestWriteFileAtomicReplacesContentAndMode(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "scripts.json")
+	if err := os.WriteFile(filename, []byte("old"), 0644); err != nil {
+		t.Fatalf("无法写入初始文件: %v", err)
+	}
+
+	if err := writeFileAtomic(filename, []byte("new"), 0600); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("无法读取配置文件: %v", err)
+	}
+	if string(got) != "new" {
+		t.Fatalf("文件内容 = %q, 期望 %q", got, "new")
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("无法获取文件信息: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("文件权限 = %v, 期望 0600", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import "sync"
+
+// DefaultTransferConcurrency 批量传输的默认并发数
+const DefaultTransferConcurrency = 4
+
+// TransferPair 一个本地文件与远程文件的对应关系
+type TransferPair struct {
+	LocalPath  string `json:"localPath"`
+	RemotePath string `json:"remotePath"`
+}
+
+// TransferResult 批量传输中单个文件的结果
+type TransferResult struct {
+	LocalPath  string `json:"localPath"`
+	RemotePath string `json:"remotePath"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// RunTransfers 使用固定数量的工作协程执行 transfer，结果顺序与 pairs 一致
+// concurrency <= 0 时使用 DefaultTransferConcurrency
+func RunTransfers(pairs []TransferPair, concurrency int, transfer func(pair TransferPair) error) []TransferResult {
+	if concurrency <= 0 {
+		concurrency = DefaultTransferConcurrency
+	}
+	if concurrency > len(pairs) {
+		concurrency = len(pairs)
+	}
+
+	results := make([]TransferResult, len(pairs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				pair := pairs[i]
+				result := TransferResult{LocalPath: pair.LocalPath, RemotePath: pair.RemotePath, Success: true}
+				if err := transfer(pair); err != nil {
+					result.Success = false
+					result.Error = err.Error()
+				}
+				results[i] = result
+			}
+		}()
+	}
+	for i := range pairs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
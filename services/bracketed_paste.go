@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// 括号粘贴模式（bracketed paste）相关的控制序列
+const (
+	bracketedPasteOn    = "\x1b[?2004h" // 远程程序（shell、编辑器）开启括号粘贴
+	bracketedPasteOff   = "\x1b[?2004l" // 远程程序关闭括号粘贴
+	bracketedPasteStart = "\x1b[200~"   // 粘贴内容开始
+	bracketedPasteEnd   = "\x1b[201~"   // 粘贴内容结束
+)
+
+// PasteLineDelay 远程不支持括号粘贴时逐行发送的间隔
+const PasteLineDelay = 20 * time.Millisecond
+
+// pasteModeTracker 从终端输出中跟踪远程程序是否开启了括号粘贴模式，控制序列可能跨数据块
+type pasteModeTracker struct {
+	tail []byte // 上一块末尾可能是不完整控制序列的部分
+}
+
+// Update 检查一块输出，返回其中最后一次出现的开启/关闭状态；changed 为 false 表示没有出现相关序列
+func (t *pasteModeTracker) Update(data []byte) (enabled bool, changed bool) {
+	buf := data
+	if len(t.tail) > 0 {
+		buf = append(append([]byte(nil), t.tail...), data...)
+	}
+
+	on := bytes.LastIndex(buf, []byte(bracketedPasteOn))
+	off := bytes.LastIndex(buf, []byte(bracketedPasteOff))
+
+	// 两个序列长度相同，保留末尾不足一个序列长度的部分用于和下一块拼接
+	keep := len(bracketedPasteOn) - 1
+	if len(buf) < keep {
+		keep = len(buf)
+	}
+	t.tail = append(t.tail[:0], buf[len(buf)-keep:]...)
+
+	if on < 0 && off < 0 {
+		return false, false
+	}
+	return on > off, true
+}
+
+// BracketedPaste 将文本包装为括号粘贴序列，远程程序会把其中的换行当作普通字符而不是回车执行
+// 文本中的结束序列会被移除，避免粘贴内容提前结束粘贴模式后被当作命令执行
+func BracketedPaste(text string) string {
+	text = strings.ReplaceAll(text, bracketedPasteEnd, "")
+	return bracketedPasteStart + normalizePasteNewlines(text) + bracketedPasteEnd
+}
+
+// PasteLines 将文本按行拆分用于逐行发送，每行以回车结尾（最后一行没有换行时保持原样）
+func PasteLines(text string) []string {
+	text = normalizePasteNewlines(text)
+	var lines []string
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\r')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// normalizePasteNewlines 与终端粘贴行为一致，把 CRLF 和 LF 统一转换为回车
+func normalizePasteNewlines(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\r")
+	return strings.ReplaceAll(text, "\n", "\r")
+}
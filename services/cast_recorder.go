@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// castFlushInterval 录制文件的定期刷新间隔
+	castFlushInterval = time.Second
+	// castEventBuffer 待写入事件的缓冲数量，写入跟不上时丢弃事件而不是阻塞终端输出
+	castEventBuffer = 1024
+)
+
+// castHeader asciinema v2 文件头
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+type castEvent struct {
+	at   time.Duration
+	kind string // "o" 输出，"r" 终端尺寸变化
+	data []byte
+}
+
+// CastRecorder 将终端输出录制为 asciinema v2 格式（.cast）文件
+// 事件由后台协程异步写入，不会阻塞终端输出
+type CastRecorder struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	start  time.Time
+
+	events    chan castEvent
+	done      chan struct{}
+	exited    chan struct{}
+	closeOnce sync.Once
+	dropped   int64
+	err       error // 仅由 run 协程写入，Close 在其退出后读取
+}
+
+// NewCastRecorder 创建录制文件并写入文件头
+func NewCastRecorder(path string, width, height int, termType string) (*CastRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建录制文件失败: %v", err)
+	}
+
+	rec := &CastRecorder{
+		path:   path,
+		file:   file,
+		writer: bufio.NewWriter(file),
+		start:  time.Now(),
+		events: make(chan castEvent, castEventBuffer),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+
+	header, _ := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: rec.start.Unix(),
+		Env:       map[string]string{"TERM": termType},
+	})
+	if _, err := rec.writer.Write(append(header, '\n')); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("写入录制文件失败: %v", err)
+	}
+
+	go rec.run()
+	return rec, nil
+}
+
+// Path 返回录制文件路径
+func (rec *CastRecorder) Path() string {
+	return rec.path
+}
+
+// Output 记录一段终端输出
+func (rec *CastRecorder) Output(data []byte) {
+	rec.enqueue(castEvent{at: time.Since(rec.start), kind: "o", data: append([]byte(nil), data...)})
+}
+
+// Resize 记录终端尺寸变化
+func (rec *CastRecorder) Resize(width, height int) {
+	rec.enqueue(castEvent{at: time.Since(rec.start), kind: "r", data: []byte(fmt.Sprintf("%dx%d", width, height))})
+}
+
+func (rec *CastRecorder) enqueue(event castEvent) {
+	select {
+	case <-rec.done:
+		return
+	default:
+	}
+	select {
+	case rec.events <- event:
+	default:
+		atomic.AddInt64(&rec.dropped, 1)
+	}
+}
+
+// run 后台写入事件并定期刷新文件
+func (rec *CastRecorder) run() {
+	defer close(rec.exited)
+
+	ticker := time.NewTicker(castFlushInterval)
+	defer ticker.Stop()
+
+	// 输出事件中不完整的 UTF-8 字符暂存到下一个事件，避免 JSON 编码时变成替换字符
+	var pending []byte
+	writeLine := func(at time.Duration, kind string, data []byte) {
+		line, _ := json.Marshal([]interface{}{at.Seconds(), kind, string(data)})
+		if _, err := rec.writer.Write(append(line, '\n')); err != nil && rec.err == nil {
+			rec.err = err
+		}
+	}
+	write := func(event castEvent) {
+		data := event.data
+		if event.kind == "o" {
+			data = append(pending, data...)
+			n := completeUTF8Prefix(data)
+			pending = append([]byte(nil), data[n:]...)
+			data = data[:n]
+			if len(data) == 0 {
+				return
+			}
+		}
+		writeLine(event.at, event.kind, data)
+	}
+
+	for {
+		select {
+		case event := <-rec.events:
+			write(event)
+		case <-ticker.C:
+			if err := rec.writer.Flush(); err != nil && rec.err == nil {
+				rec.err = err
+			}
+		case <-rec.done:
+			// 写入剩余的事件
+			for {
+				select {
+				case event := <-rec.events:
+					write(event)
+				default:
+					if len(pending) > 0 {
+						writeLine(time.Since(rec.start), "o", pending)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// Dropped 返回因写入跟不上而丢弃的事件数
+func (rec *CastRecorder) Dropped() int64 {
+	return atomic.LoadInt64(&rec.dropped)
+}
+
+// Close 停止录制，写入剩余事件并关闭文件
+func (rec *CastRecorder) Close() error {
+	var err error
+	rec.closeOnce.Do(func() {
+		close(rec.done)
+		<-rec.exited
+
+		err = rec.err
+		if flushErr := rec.writer.Flush(); err == nil {
+			err = flushErr
+		}
+		if closeErr := rec.file.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
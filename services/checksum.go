@@ -0,0 +1,56 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LocalFileSHA256 计算本地文件的 SHA-256（十六进制小写）
+func LocalFileSHA256(localPath string) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("无法打开本地文件: %v", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("读取本地文件失败: %v", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// RemoteFileSHA256 在远程执行 sha256sum（不可用时回退到 shasum -a 256）计算文件的 SHA-256
+// 直接在主机上执行，不应用命令包装，与 SFTP 使用相同的登录用户
+func (s *SSHConnection) RemoteFileSHA256(remotePath string) (string, error) {
+	quoted := ShellQuote(remotePath)
+	output, err := s.ExecuteCommandOnHost(fmt.Sprintf("sha256sum -- %s 2>/dev/null || shasum -a 256 -- %s", quoted, quoted))
+	if err != nil {
+		return "", fmt.Errorf("远程计算校验和失败: %v", err)
+	}
+	fields := strings.Fields(output)
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("无法解析远程校验和输出: %q", strings.TrimSpace(output))
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// VerifyTransferChecksum 比较本地文件与远程文件的 SHA-256，不一致时返回错误
+func (s *SSHConnection) VerifyTransferChecksum(localPath, remotePath string) error {
+	localSum, err := LocalFileSHA256(localPath)
+	if err != nil {
+		return err
+	}
+	remoteSum, err := s.RemoteFileSHA256(remotePath)
+	if err != nil {
+		return err
+	}
+	if localSum != remoteSum {
+		return fmt.Errorf("校验和不一致，文件可能已损坏: 本地 %s，远程 %s", localSum, remoteSum)
+	}
+	return nil
+}
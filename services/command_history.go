@@ -0,0 +1,281 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxHistoryPerServer 每台服务器保留的最大历史命令数
+	MaxHistoryPerServer = 1000
+	// MaxImportedHistory 单次从远程 shell 历史导入的最大命令数（取最近的命令）
+	MaxImportedHistory = 500
+	// MaxRemoteHistorySize 读取远程历史文件的大小上限
+	MaxRemoteHistorySize = 8 * 1024 * 1024
+	// historySaveDelay 记录命令后延迟保存的时间，期间的多次记录合并为一次写入
+	historySaveDelay = 2 * time.Second
+)
+
+// SensitivePatterns 可能包含密码、令牌等敏感信息的命令模式，匹配的命令不会记录到历史中
+var SensitivePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)(passw(or)?d|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key)\s*[=:]`),
+	regexp.MustCompile(`(?i)--(password|passwd|secret|token|api-key)[= ]\S+`),
+	regexp.MustCompile(`(?i)\bsshpass\s+-p\s*\S+`),
+	regexp.MustCompile(`(?i)\bmysql(dump|admin)?\b.*\s-p\S+`),
+	regexp.MustCompile(`(?i)authorization:\s*(bearer|basic)\s+\S+`),
+	regexp.MustCompile(`(?i)\b(curl|wget)\b.*://[^/\s:@]+:[^/\s@]+@`),
+}
+
+// IsSensitiveCommand 判断命令是否可能包含敏感信息
+func IsSensitiveCommand(command string) bool {
+	for _, pattern := range SensitivePatterns {
+		if pattern.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseShellHistory 解析 bash/zsh 历史文件内容，返回按时间顺序排列的命令
+// 支持 zsh 扩展格式 ": <时间戳>:<耗时>;命令"、zsh 的多行命令续行以及 bash 的 "#时间戳" 行
+func ParseShellHistory(content []byte) []string {
+	text := strings.ReplaceAll(string(unmetafyZsh(content)), "\r\n", "\n")
+	lines := strings.Split(text, "\n")
+
+	var commands []string
+	var current strings.Builder
+	continuing := false
+	for _, line := range lines {
+		if !continuing {
+			// bash HISTTIMEFORMAT 写入的时间戳行
+			if strings.HasPrefix(line, "#") && isDigits(line[1:]) {
+				continue
+			}
+			// zsh 扩展格式
+			if strings.HasPrefix(line, ": ") {
+				if idx := strings.Index(line, ";"); idx > 0 {
+					line = line[idx+1:]
+				}
+			}
+		}
+
+		// zsh 多行命令以反斜杠结尾续行
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\"))
+			current.WriteString("\n")
+			continuing = true
+			continue
+		}
+		current.WriteString(line)
+		continuing = false
+
+		if command := strings.TrimSpace(current.String()); command != "" {
+			commands = append(commands, command)
+		}
+		current.Reset()
+	}
+	return commands
+}
+
+// unmetafyZsh 还原 zsh 历史文件中的元字符编码（0x83 之后的字节与 0x20 异或）
+func unmetafyZsh(data []byte) []byte {
+	const meta = 0x83
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == meta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^0x20)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// CommandHistory 按服务器保存的命令历史，用于命令面板和自动补全
+// 执行命令时记录的历史延迟写入文件，退出前需调用 Flush 保存
+type CommandHistory struct {
+	mutex      sync.RWMutex
+	entries    map[string][]string
+	configFile string
+
+	dirty     bool        // 有尚未写入文件的修改
+	saveTimer *time.Timer // 等待中的延迟保存
+}
+
+// NewCommandHistory 创建命令历史存储
+func NewCommandHistory(configFile string) *CommandHistory {
+	return &CommandHistory{
+		entries:    make(map[string][]string),
+		configFile: configFile,
+	}
+}
+
+// LoadFromFile 从文件加载命令历史，文件不存在时为空
+func (h *CommandHistory) LoadFromFile() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data, err := os.ReadFile(h.configFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取命令历史失败: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &h.entries); err != nil {
+			return fmt.Errorf("解析命令历史失败: %v", err)
+		}
+	}
+	if h.entries == nil {
+		h.entries = make(map[string][]string)
+	}
+	return nil
+}
+
+// saveToFile 保存命令历史（调用方持有锁）
+func (h *CommandHistory) saveToFile() error {
+	if err := os.MkdirAll(filepath.Dir(h.configFile), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	data, err := json.MarshalIndent(h.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化命令历史失败: %v", err)
+	}
+	if err := os.WriteFile(h.configFile, data, 0600); err != nil {
+		return fmt.Errorf("写入命令历史失败: %v", err)
+	}
+	h.dirty = false
+	return nil
+}
+
+// scheduleSave 在 historySaveDelay 后保存，期间的修改合并为一次写入（调用方持有锁）
+func (h *CommandHistory) scheduleSave() {
+	h.dirty = true
+	if h.saveTimer != nil {
+		return
+	}
+	h.saveTimer = time.AfterFunc(historySaveDelay, func() {
+		if err := h.Flush(); err != nil {
+			log.Printf("保存命令历史失败: %v", err)
+		}
+	})
+}
+
+// Flush 立即保存尚未写入文件的修改
+func (h *CommandHistory) Flush() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.saveTimer != nil {
+		h.saveTimer.Stop()
+		h.saveTimer = nil
+	}
+	if !h.dirty {
+		return nil
+	}
+	return h.saveToFile()
+}
+
+// Get 获取服务器的命令历史（由旧到新）
+func (h *CommandHistory) Get(serverID string) []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return append([]string(nil), h.entries[serverID]...)
+}
+
+// Add 记录一条命令，敏感命令会被忽略；在执行命令的路径上调用，写入文件延迟进行
+func (h *CommandHistory) Add(serverID, command string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, changed := h.merge(serverID, []string{command}); changed {
+		h.scheduleSave()
+	}
+}
+
+// Merge 合并多条命令到服务器的历史中并立即保存，重复的命令移动到最新位置，返回新增的命令数
+// 敏感命令会被跳过，历史超过上限时丢弃最旧的命令
+func (h *CommandHistory) Merge(serverID string, commands []string) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	added, changed := h.merge(serverID, commands)
+	if !changed {
+		return 0, nil
+	}
+	return added, h.saveToFile()
+}
+
+// merge 合并命令到内存中的历史，返回新增的命令数和历史是否有变化（调用方持有锁）
+func (h *CommandHistory) merge(serverID string, commands []string) (int, bool) {
+	history := h.entries[serverID]
+	index := make(map[string]int, len(history))
+	for i, command := range history {
+		index[command] = i
+	}
+
+	added := 0
+	changed := false
+	for _, command := range commands {
+		command = strings.TrimSpace(command)
+		if command == "" || IsSensitiveCommand(command) {
+			continue
+		}
+		if i, exists := index[command]; exists {
+			if i == len(history)-1 {
+				continue
+			}
+			// 已存在的命令移动到末尾
+			history = append(history[:i], history[i+1:]...)
+			for j := i; j < len(history); j++ {
+				index[history[j]] = j
+			}
+		} else {
+			added++
+		}
+		index[command] = len(history)
+		history = append(history, command)
+		changed = true
+	}
+	if !changed {
+		return 0, false
+	}
+
+	if len(history) > MaxHistoryPerServer {
+		history = append([]string(nil), history[len(history)-MaxHistoryPerServer:]...)
+	}
+	h.entries[serverID] = history
+	return added, true
+}
+
+// Remove 删除服务器的所有命令历史
+func (h *CommandHistory) Remove(serverID string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, exists := h.entries[serverID]; !exists {
+		return nil
+	}
+	delete(h.entries, serverID)
+	return h.saveToFile()
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCommandHistoryAddDefersSave(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "history.json")
+	history := NewCommandHistory(file)
+
+	history.Add("s1", "ls -l")
+	history.Add("s1", "uptime")
+	history.Add("s1", "ls -l")
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Fatalf("记录命令时不应立即写入文件: %v", err)
+	}
+
+	if err := history.Flush(); err != nil {
+		t.Fatalf("保存命令历史失败: %v", err)
+	}
+	loaded := NewCommandHistory(file)
+	if err := loaded.LoadFromFile(); err != nil {
+		t.Fatalf("加载命令历史失败: %v", err)
+	}
+	if got, want := loaded.Get("s1"), []string{"uptime", "ls -l"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("命令历史为 %q, 期望 %q", got, want)
+	}
+
+	if err := history.Remove("s1"); err != nil {
+		t.Fatalf("删除命令历史失败: %v", err)
+	}
+	loaded = NewCommandHistory(file)
+	if err := loaded.LoadFromFile(); err != nil {
+		t.Fatalf("加载命令历史失败: %v", err)
+	}
+	if got := loaded.Get("s1"); len(got) != 0 {
+		t.Fatalf("删除后仍有命令历史: %q", got)
+	}
+}
+
+func TestCommandHistorySkipsSensitiveCommands(t *testing.T) {
+	history := NewCommandHistory(filepath.Join(t.TempDir(), "history.json"))
+	history.Add("s1", "mysql -uroot -psecret")
+	history.Add("s1", "export API_KEY=abc")
+	if got := history.Get("s1"); len(got) != 0 {
+		t.Fatalf("敏感命令被记录: %q", got)
+	}
+	if err := history.Flush(); err != nil {
+		t.Fatalf("没有修改时保存失败: %v", err)
+	}
+}
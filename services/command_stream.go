@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// maxStreamRetainedBytes 单个输出流在内存中保留的最大字节数，超出后丢弃最早的数据
+const maxStreamRetainedBytes = 64 * 1024 * 1024
+
+// ExecuteCommandStream 执行远程命令并以流的方式增量回调输出（stdout 和 stderr 合并）
+func (s *SSHConnection) ExecuteCommandStream(command string, onChunk func([]byte)) error {
+	return s.ExecuteCommandStreamContext(context.Background(), command, onChunk)
+}
+
+// ExecuteCommandStreamContext 与 ExecuteCommandStream 相同，ctx 取消时关闭会话并返回
+func (s *SSHConnection) ExecuteCommandStreamContext(ctx context.Context, command string, onChunk func([]byte)) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+
+	session, err := s.newSessionContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("无法获取标准输出: %v", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("无法获取错误输出: %v", err)
+	}
+
+	if err := session.Start(WrapCommand(s.CommandWrapper, command)); err != nil {
+		return fmt.Errorf("启动命令失败: %v", err)
+	}
+
+	// 上下文取消时关闭会话，使读取和等待立即返回
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGKILL)
+			_ = session.Close()
+		case <-stop:
+		}
+	}()
+
+	pumpOutput(onChunk, stdout, stderr)
+
+	err = session.Wait()
+	if ctx.Err() != nil {
+		return fmt.Errorf("命令已取消: %v", ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("执行命令失败: %v", err)
+	}
+	return nil
+}
+
+// pumpOutput 并发读取多个输出直到全部结束，读到的数据交给 onChunk
+// 多个读协程共享回调，使用互斥锁保证回调串行执行
+func pumpOutput(onChunk func([]byte), readers ...io.Reader) {
+	var callbackMutex sync.Mutex
+	var wg sync.WaitGroup
+	pump := func(r io.Reader) {
+		defer wg.Done()
+		buf := make([]byte, 32*1024)
+		for {
+			n, readErr := r.Read(buf)
+			if n > 0 && onChunk != nil {
+				data := make([]byte, n)
+				copy(data, buf[:n])
+				callbackMutex.Lock()
+				onChunk(data)
+				callbackMutex.Unlock()
+			}
+			if readErr != nil {
+				return
+			}
+		}
+	}
+	wg.Add(len(readers))
+	for _, r := range readers {
+		go pump(r)
+	}
+	wg.Wait()
+}
+
+// StreamChunk 从输出流缓冲区读取的一段数据
+type StreamChunk struct {
+	Data       string `json:"data"`       // 数据内容
+	Offset     int64  `json:"offset"`     // 本段数据的起始偏移
+	NextOffset int64  `json:"nextOffset"` // 下一次读取应使用的偏移
+	TotalBytes int64  `json:"totalBytes"` // 到目前为止产生的总字节数
+	Done       bool   `json:"done"`       // 命令已结束且数据已全部读取
+	Error      string `json:"error"`      // 命令结束时的错误信息
+}
+
+// streamChunkSize 输出流缓冲区中每个数据块的目标大小，连续的小块输出合并到同一个数据块中
+const streamChunkSize = 32 * 1024
+
+// streamBlock 输出流缓冲区中的一个数据块
+type streamBlock struct {
+	offset int64 // 数据块起始位置的绝对偏移
+	data   []byte
+}
+
+// StreamBuffer 保留命令输出并支持按偏移分页读取
+// 偏移是相对于整个输出流的绝对位置；输出按数据块保存，超出保留上限后整块丢弃最早的数据，
+// 丢弃时不需要移动其余数据
+type StreamBuffer struct {
+	mutex      sync.Mutex
+	blocks     []streamBlock
+	baseOffset int64 // 最早保留的数据的绝对偏移
+	total      int64 // 到目前为止产生的总字节数
+	done       bool
+	err        string
+}
+
+// NewStreamBuffer 创建输出流缓冲区
+func NewStreamBuffer() *StreamBuffer {
+	return &StreamBuffer{}
+}
+
+// Append 追加输出数据
+func (sb *StreamBuffer) Append(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	if n := len(sb.blocks); n > 0 && len(sb.blocks[n-1].data)+len(data) <= streamChunkSize {
+		sb.blocks[n-1].data = append(sb.blocks[n-1].data, data...)
+	} else {
+		block := make([]byte, len(data), max(len(data), streamChunkSize))
+		copy(block, data)
+		sb.blocks = append(sb.blocks, streamBlock{offset: sb.total, data: block})
+	}
+	sb.total += int64(len(data))
+
+	for len(sb.blocks) > 1 && sb.total-sb.blocks[0].offset > maxStreamRetainedBytes {
+		sb.blocks[0] = streamBlock{}
+		sb.blocks = sb.blocks[1:]
+	}
+	if len(sb.blocks) > 0 {
+		sb.baseOffset = sb.blocks[0].offset
+	}
+}
+
+// Finish 标记输出流结束
+func (sb *StreamBuffer) Finish(err error) {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	sb.done = true
+	if err != nil {
+		sb.err = err.Error()
+	}
+}
+
+// ReadChunk 从 fromOffset 开始读取最多 maxBytes 字节
+// 如果 fromOffset 对应的数据已被丢弃，则从最早保留的位置开始读取
+func (sb *StreamBuffer) ReadChunk(fromOffset int64, maxBytes int) StreamChunk {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	if fromOffset < sb.baseOffset {
+		fromOffset = sb.baseOffset
+	}
+	if fromOffset > sb.total {
+		fromOffset = sb.total
+	}
+	end := sb.total
+	if maxBytes > 0 && fromOffset+int64(maxBytes) < end {
+		end = fromOffset + int64(maxBytes)
+	}
+
+	// 找到包含 fromOffset 的数据块，依次拼接到 end
+	var data strings.Builder
+	data.Grow(int(end - fromOffset))
+	i := sort.Search(len(sb.blocks), func(i int) bool {
+		return sb.blocks[i].offset+int64(len(sb.blocks[i].data)) > fromOffset
+	})
+	for ; i < len(sb.blocks) && sb.blocks[i].offset < end; i++ {
+		block := sb.blocks[i]
+		from := max(fromOffset-block.offset, 0)
+		to := min(end-block.offset, int64(len(block.data)))
+		data.Write(block.data[from:to])
+	}
+
+	return StreamChunk{
+		Data:       data.String(),
+		Offset:     fromOffset,
+		NextOffset: end,
+		TotalBytes: sb.total,
+		Done:       sb.done && end == sb.total,
+		Error:      sb.err,
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestStreamBufferReadAcrossBlocks(t *testing.T) {
+	sb := NewStreamBuffer()
+	var all bytes.Buffer
+	// 大小不一的输出，既有合并到同一数据块的小块，也有跨越多个数据块的大块
+	for i, size := range []int{10, 20, streamChunkSize, 5, 3 * streamChunkSize, 1} {
+		chunk := bytes.Repeat([]byte{byte('a' + i)}, size)
+		sb.Append(chunk)
+		all.Write(chunk)
+	}
+
+	var got bytes.Buffer
+	offset := int64(0)
+	for {
+		chunk := sb.ReadChunk(offset, 7000)
+		if chunk.Offset != offset {
+			t.Fatalf("读取偏移 = %d, 期望 %d", chunk.Offset, offset)
+		}
+		got.WriteString(chunk.Data)
+		offset = chunk.NextOffset
+		if chunk.Data == "" {
+			break
+		}
+	}
+	if !bytes.Equal(got.Bytes(), all.Bytes()) {
+		t.Fatalf("分页读取的数据与写入的不一致: 读取 %d 字节, 写入 %d 字节", got.Len(), all.Len())
+	}
+
+	sb.Finish(errors.New("exit 1"))
+	last := sb.ReadChunk(offset, 0)
+	if !last.Done || last.Error != "exit 1" || last.TotalBytes != int64(all.Len()) {
+		t.Fatalf("结束后的读取结果 = %+v", last)
+	}
+}
+
+func TestStreamBufferDropsOldestBlocks(t *testing.T) {
+	sb := NewStreamBuffer()
+	chunk := make([]byte, streamChunkSize)
+	written := int64(0)
+	for written < 2*maxStreamRetainedBytes {
+		chunk[0] = byte(written / streamChunkSize)
+		sb.Append(chunk)
+		written += int64(len(chunk))
+	}
+
+	sb.mutex.Lock()
+	retained := sb.total - sb.baseOffset
+	blocks := len(sb.blocks)
+	sb.mutex.Unlock()
+	if retained > maxStreamRetainedBytes || retained < maxStreamRetainedBytes-streamChunkSize {
+		t.Fatalf("保留 %d 字节, 期望不超过 %d", retained, maxStreamRetainedBytes)
+	}
+	if blocks != int(retained/streamChunkSize) {
+		t.Fatalf("数据块数量 = %d", blocks)
+	}
+
+	// 已丢弃的偏移从最早保留的位置开始读取
+	got := sb.ReadChunk(0, 1)
+	if got.Offset != written-retained || got.TotalBytes != written {
+		t.Fatalf("读取已丢弃的偏移 = %+v", got)
+	}
+	if want := byte((written - retained) / streamChunkSize); got.Data[0] != want {
+		t.Fatalf("最早保留的数据 = %d, 期望 %d", got.Data[0], want)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// CommandTimeoutError 命令执行超时
+type CommandTimeoutError struct {
+	Index   int           // 超时命令在本次执行的命令列表中的序号
+	Command string        // 超时的命令
+	Timeout time.Duration // 超时限制
+	Elapsed time.Duration // 命令已运行的时间
+}
+
+func (e *CommandTimeoutError) Error() string {
+	return fmt.Sprintf("命令执行超时（限制 %v，已运行 %.1f秒）: %s", e.Timeout, e.Elapsed.Seconds(), e.Command)
+}
+
+// CommandTimeout 根据配置的秒数返回单条命令的超时时间，0或负数表示不限制
+func CommandTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// sessionKillWait 超时终止会话后等待其退出的最长时间
+const sessionKillWait = 2 * time.Second
+
+// ExecuteCommandTimeout 执行单条命令，超过 timeout 时发送 SIGKILL 并关闭会话，返回已捕获的部分输出和 *CommandTimeoutError
+// timeout 为0时不限制；ctx 取消时与 ExecuteCommandContext 相同
+func (s *SSHConnection) ExecuteCommandTimeout(ctx context.Context, command string, timeout time.Duration) (string, error) {
+	if timeout <= 0 {
+		return s.ExecuteCommandContext(ctx, command)
+	}
+
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	output, err := s.ExecuteCommandContext(cmdCtx, command)
+	// 只有本命令自己的超时才算超时，外层 ctx 取消仍按中止处理
+	if err != nil && ctx.Err() == nil && errors.Is(cmdCtx.Err(), context.DeadlineExceeded) {
+		return output, &CommandTimeoutError{
+			Command: command,
+			Timeout: timeout,
+			Elapsed: time.Since(start),
+		}
+	}
+	return output, err
+}
+
+// ExecuteCommandsWithSharedSessionTimeout 在同一个会话中执行多个命令，每条命令限时执行
+// 通过流式读取输出中的分隔符判断每条命令何时结束；某条命令超时时终止整个会话，
+// 返回已有的输出和 *CommandTimeoutError，之后的命令不会执行；其他失败返回 *SharedSessionError。timeout 为0时不限制
+// stopOnError 的含义与 ExecuteCommandsWithSharedSessionContext 相同
+func (s *SSHConnection) ExecuteCommandsWithSharedSessionTimeout(ctx context.Context, commands []string, stopOnError []bool, timeout time.Duration) ([]CommandResult, error) {
+	if timeout <= 0 {
+		return s.ExecuteCommandsWithSharedSessionContext(ctx, commands, stopOnError)
+	}
+	if s.Client == nil {
+		return nil, fmt.Errorf("SSH连接未建立")
+	}
+
+	session, err := s.Client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("无法创建会话: %v", err)
+	}
+	defer session.Close()
+
+	script, separator := s.sharedSessionScript(commands, stopOnError)
+
+	output := &notifyingBuffer{notify: make(chan struct{}, 1)}
+	session.Stdout = output
+	session.Stderr = output
+
+	if err := session.Start(script); err != nil {
+		return nil, fmt.Errorf("执行命令失败: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	// 终止会话并等待退出，返回分割后的输出
+	kill := func() []CommandResult {
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		select {
+		case <-done:
+		case <-time.After(sessionKillWait):
+		}
+		return splitSharedSessionOutput(output.String(), separator, len(commands))
+	}
+
+	current := 0 // 正在执行的命令序号
+	scanned := 0 // 已查找过分隔符的输出位置
+	started := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// advance 根据新输出中的分隔符推进当前命令，推进时重新计时
+	advance := func() {
+		finished, next := output.countSeparators(scanned, separator)
+		scanned = next
+		if finished > 0 {
+			current += finished
+			started = time.Now()
+			stopTimer(timer)
+			timer.Reset(timeout)
+		}
+	}
+
+	for {
+		select {
+		case err := <-done:
+			results := splitSharedSessionOutput(output.String(), separator, len(commands))
+			if failure := sharedSessionFailure(commands, results); failure != nil {
+				return results, failure
+			}
+			if err != nil {
+				advance()
+				return results, &SharedSessionError{Completed: current, Err: fmt.Errorf("执行命令失败: %w", err)}
+			}
+			return results, nil
+		case <-output.notify:
+			advance()
+		case <-timer.C:
+			// 超时前可能刚好输出了分隔符，再确认一次
+			advance()
+			if time.Since(started) < timeout || current >= len(commands) {
+				continue
+			}
+			results := kill()
+			return results, &CommandTimeoutError{
+				Index:   current,
+				Command: commands[current],
+				Timeout: timeout,
+				Elapsed: time.Since(started),
+			}
+		case <-ctx.Done():
+			results := kill()
+			advance()
+			return results, &SharedSessionError{Completed: current, Err: fmt.Errorf("执行已中止: %w", ctx.Err())}
+		}
+	}
+}
+
+// notifyingBuffer 并发安全的输出缓冲，每次写入后发出通知（容量为1，不阻塞写入方）
+type notifyingBuffer struct {
+	mutex  sync.Mutex
+	buf    bytes.Buffer
+	notify chan struct{}
+}
+
+func (b *notifyingBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	b.buf.Write(p)
+	b.mutex.Unlock()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+func (b *notifyingBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.String()
+}
+
+// countSeparators 从 offset 开始统计分隔符出现的次数，返回次数和下次查找的起始位置
+func (b *notifyingBuffer) countSeparators(offset int, separator string) (int, int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	data := b.buf.Bytes()
+	sep := []byte(separator)
+	count := 0
+	for {
+		idx := bytes.Index(data[offset:], sep)
+		if idx < 0 {
+			break
+		}
+		count++
+		offset += idx + len(sep)
+	}
+
+	// 分隔符可能被拆分在两次写入之间，保留末尾不足一个分隔符长度的部分
+	if keep := len(data) - len(sep) + 1; keep > offset {
+		offset = keep
+	}
+	return count, offset
+}
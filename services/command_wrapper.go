@@ -0,0 +1,31 @@
+package services
+
+import "strings"
+
+// CommandPlaceholder 命令包装模板中的占位符
+const CommandPlaceholder = "{cmd}"
+
+// ShellQuote 使用单引号对字符串进行shell转义，使其作为一个完整参数传递
+func ShellQuote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// WrapCommand 使用包装模板包装命令
+// 模板中的 {cmd} 会被替换为转义后的命令，例如 "sudo -u deploy bash -c {cmd}"；
+// 模板中没有占位符时，转义后的命令追加到模板末尾。模板为空时原样返回命令。
+// 注意：命令被转义为单个参数，因此类似 docker exec 的包装应写为 "docker exec app sh -c {cmd}"
+func WrapCommand(wrapper, command string) string {
+	wrapper = strings.TrimSpace(wrapper)
+	if wrapper == "" {
+		return command
+	}
+
+	quoted := ShellQuote(command)
+	if strings.Contains(wrapper, CommandPlaceholder) {
+		return strings.ReplaceAll(wrapper, CommandPlaceholder, quoted)
+	}
+	return wrapper + " " + quoted
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"strings"
+	"sync"
+)
+
+// MaxCompletionResults 自动补全返回的最大候选数量
+const MaxCompletionResults = 50
+
+// completionQuery 一个补全候选来源
+type completionQuery struct {
+	command string // 远程执行的查询命令
+	prefix  string // 拼接在候选前面的命令前缀
+}
+
+// QueryCompletions 通过一次性 exec 会话查询补全候选
+// 单个单词时使用 compgen -c 查询命令；绝对路径或 ~ 路径参数时使用 ls -d 查询路径。
+// 两类查询并行执行，结果去重并截断到 MaxCompletionResults。
+// 相对路径依赖终端当前目录，这里无法得知，返回 nil 由调用方回退到 Tab 补全。
+func (s *SSHConnection) QueryCompletions(partialCommand string) ([]string, error) {
+	if strings.TrimSpace(partialCommand) == "" {
+		return nil, nil
+	}
+
+	var queries []completionQuery
+
+	// 以空格结尾表示开始补全一个新的参数
+	fields := strings.Fields(partialCommand)
+	endsWithSpace := strings.HasSuffix(partialCommand, " ")
+	if len(fields) == 1 && !endsWithSpace {
+		word := fields[0]
+		queries = append(queries, completionQuery{command: compgenCommand(word)})
+		if isAbsoluteCompletionPath(word) {
+			queries = append(queries, completionQuery{command: lsCompletionCommand(word)})
+		}
+	} else if !endsWithSpace {
+		lastArg := fields[len(fields)-1]
+		if isAbsoluteCompletionPath(lastArg) {
+			prefix := strings.Join(fields[:len(fields)-1], " ") + " "
+			queries = append(queries, completionQuery{command: lsCompletionCommand(lastArg), prefix: prefix})
+		}
+	}
+
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	results := make([][]string, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, q := range queries {
+		wg.Add(1)
+		go func(i int, q completionQuery) {
+			defer wg.Done()
+			output, err := s.completionExec(q.command)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			for _, line := range strings.Split(output, "\n") {
+				line = strings.TrimSpace(line)
+				if line != "" {
+					results[i] = append(results[i], q.prefix+line)
+				}
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	var suggestions []string
+	for _, r := range results {
+		suggestions = append(suggestions, r...)
+	}
+	suggestions = removeDuplicates(suggestions)
+	if len(suggestions) > MaxCompletionResults {
+		suggestions = suggestions[:MaxCompletionResults]
+	}
+
+	// 所有查询都失败时才返回错误
+	if len(suggestions) == 0 {
+		for _, err := range errs {
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// completionExec 执行补全查询，终端在包装环境中运行时查询也在包装环境中执行
+func (s *SSHConnection) completionExec(command string) (string, error) {
+	if s.WrapTerminal {
+		return s.ExecuteCommand(command)
+	}
+	return s.ExecuteCommandOnHost(command)
+}
+
+// isAbsoluteCompletionPath 判断参数是否是不依赖当前目录的路径
+func isAbsoluteCompletionPath(arg string) bool {
+	return strings.HasPrefix(arg, "/") || arg == "~" || strings.HasPrefix(arg, "~/")
+}
+
+// compgenCommand 构造命令名补全查询（compgen 是 bash 内建命令，显式通过 bash 执行）
+func compgenCommand(word string) string {
+	script := "compgen -c -- " + ShellQuote(word) + " | sort -u | head -n 200"
+	return "bash -c " + ShellQuote(script) + " 2>/dev/null || true"
+}
+
+// lsCompletionCommand 构造路径补全查询，目录会带上结尾的 /
+func lsCompletionCommand(arg string) string {
+	pattern := ShellQuote(arg)
+	// ~ 需要在引号外展开
+	if arg == "~" {
+		pattern = `"$HOME"`
+	} else if strings.HasPrefix(arg, "~/") {
+		pattern = `"$HOME"/` + ShellQuote(strings.TrimPrefix(arg, "~/"))
+	}
+	command := "ls -dp -- " + pattern + "* 2>/dev/null | head -n 200 || true"
+	if strings.HasPrefix(arg, "~") {
+		// 输出中的 $HOME 还原为用户输入的 ~
+		command = "(" + command + `) | sed "s#^$HOME#~#"`
+	}
+	return command
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+)
+
+// ConfigBackupCount 每个配置文件保留的滚动备份数量
+const ConfigBackupCount = 5
+
+// BackupInfo 配置文件的一个备份
+type BackupInfo struct {
+	Index   int    `json:"index"`   // 0 为最近一次保存前的备份，越大越旧
+	Path    string `json:"path"`    // 备份文件路径
+	Size    int64  `json:"size"`    // 文件大小（字节）
+	ModTime string `json:"modTime"` // 备份时间
+}
+
+// BackupPath 返回配置文件第 index 个备份的路径：最近的备份为 filename.bak，更早的为 filename.bak.1、filename.bak.2 ...
+func BackupPath(filename string, index int) string {
+	if index == 0 {
+		return filename + ".bak"
+	}
+	return fmt.Sprintf("%s.bak.%d", filename, index)
+}
+
+// writeConfigFile 保存配置文件：先把现有文件轮转到备份中，再原子写入新内容
+// 备份失败只打印警告，不影响保存
+func writeConfigFile(filename string, data []byte, perm os.FileMode) error {
+	if err := rotateBackups(filename); err != nil {
+		log.Printf("警告: 无法备份配置文件 %s: %v", filename, err)
+	}
+	return writeFileAtomic(filename, data, perm)
+}
+
+// writeConfigFileNoBackup 原子写入配置文件但不轮转备份，用于连接统计这类频繁且无需回退的保存，
+// 避免每次连接都把有价值的备份挤出轮转
+func writeConfigFileNoBackup(filename string, data []byte, perm os.FileMode) error {
+	return writeFileAtomic(filename, data, perm)
+}
+
+// rotateBackups 将备份依次后移（最旧的被丢弃），并把当前文件复制为最近的备份；
+// 文件不存在或内容与最近的备份相同时不做任何事，避免重复保存把旧备份挤出
+func rotateBackups(filename string) error {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if latest, err := os.ReadFile(BackupPath(filename, 0)); err == nil && bytes.Equal(latest, data) {
+		return nil
+	}
+
+	for i := ConfigBackupCount - 1; i > 0; i-- {
+		if err := os.Rename(BackupPath(filename, i-1), BackupPath(filename, i)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(BackupPath(filename, 0), data, info.Mode().Perm())
+}
+
+// ListBackups 列出配置文件现有的备份，按从新到旧排列
+func ListBackups(filename string) []BackupInfo {
+	var backups []BackupInfo
+	for i := 0; i < ConfigBackupCount; i++ {
+		path := BackupPath(filename, i)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, BackupInfo{
+			Index:   i,
+			Path:    path,
+			Size:    info.Size(),
+			ModTime: info.ModTime().Format("2006-01-02 15:04:05"),
+		})
+	}
+	return backups
+}
+
+// RestoreBackup 用第 index 个备份覆盖配置文件
+// 覆盖前当前文件同样会被轮转到备份中，因此恢复操作本身可以撤销
+func RestoreBackup(filename string, index int) error {
+	if index < 0 || index >= ConfigBackupCount {
+		return fmt.Errorf("备份序号超出范围: %d", index)
+	}
+
+	path := BackupPath(filename, index)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("备份不存在: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("无法读取备份: %v", err)
+	}
+
+	if err := writeConfigFile(filename, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("恢复备份失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeEncryptedTestFile 用指定密码加密内容并写入文件
+func writeEncryptedTestFile(t *testing.T, path, password, content string) {
+	t.Helper()
+	encrypted, err := newTestEncryptedConfigManager(t, password, KDFScrypt).encrypt([]byte(content))
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(encrypted), 0600); err != nil {
+		t.Fatalf("无法写入文件: %v", err)
+	}
+}
+
+func TestReencryptFileReencryptsBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "servers.dat")
+	writeEncryptedTestFile(t, filename, "old", "current")
+	writeEncryptedTestFile(t, BackupPath(filename, 0), "old", "backup-0")
+	writeEncryptedTestFile(t, BackupPath(filename, 1), "older", "backup-1")
+	if err := os.WriteFile(BackupPath(filename, 2), []byte(`{"groups":[]}`), 0600); err != nil {
+		t.Fatalf("无法写入明文备份: %v", err)
+	}
+
+	if err := ReencryptFile(filename, "old", "new"); err != nil {
+		t.Fatalf("重新加密失败: %v", err)
+	}
+
+	newManager := NewEncryptedConfigManager("new")
+	for path, want := range map[string]string{
+		filename:                "current",
+		BackupPath(filename, 0): "backup-0",
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("无法读取 %s: %v", path, err)
+		}
+		plaintext, err := newManager.decrypt(string(data))
+		if err != nil {
+			t.Fatalf("新密码无法解密 %s: %v", path, err)
+		}
+		if string(plaintext) != want {
+			t.Fatalf("%s 内容 = %q, 期望 %q", path, plaintext, want)
+		}
+	}
+
+	// 使用其他密码的备份改密后无法恢复，应被删除
+	if _, err := os.Stat(BackupPath(filename, 1)); !os.IsNotExist(err) {
+		t.Fatalf("无法用旧密码解密的备份应被删除: %v", err)
+	}
+	// 明文备份不受密码影响
+	if data, err := os.ReadFile(BackupPath(filename, 2)); err != nil || string(data) != `{"groups":[]}` {
+		t.Fatalf("明文备份不应被修改: %q, %v", data, err)
+	}
+	// 改密本身不应轮转出新的旧密码备份
+	if _, err := os.Stat(BackupPath(filename, 3)); !os.IsNotExist(err) {
+		t.Fatalf("修改密码不应产生新的备份: %v", err)
+	}
+}
+
+func TestReencryptFileWrongPasswordKeepsBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "servers.dat")
+	writeEncryptedTestFile(t, filename, "old", "current")
+	writeEncryptedTestFile(t, BackupPath(filename, 0), "old", "backup-0")
+	before, err := os.ReadFile(BackupPath(filename, 0))
+	if err != nil {
+		t.Fatalf("无法读取备份: %v", err)
+	}
+
+	if err := ReencryptFile(filename, "wrong", "new"); err != ErrWrongPassword {
+		t.Fatalf("错误 = %v, 期望 ErrWrongPassword", err)
+	}
+
+	after, err := os.ReadFile(BackupPath(filename, 0))
+	if err != nil {
+		t.Fatalf("无法读取备份: %v", err)
+	}
+	if string(after) != string(before) {
+		t.Fatal("旧密码错误时备份不应被修改")
+	}
+}
+
+func TestWriteConfigFileSkipsRotationForUnchangedContent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "servers.json")
+	for _, content := range []string{"v1", "v2", "v2", "v2"} {
+		if err := writeConfigFile(filename, []byte(content), 0600); err != nil {
+			t.Fatalf("保存失败: %v", err)
+		}
+	}
+
+	// 只有 v1 -> v2 一次实际变化，重复保存 v2 不应继续轮转
+	if data, err := os.ReadFile(BackupPath(filename, 0)); err != nil || string(data) != "v2" {
+		t.Fatalf("最近的备份 = %q, %v, 期望 v2", data, err)
+	}
+	if data, err := os.ReadFile(BackupPath(filename, 1)); err != nil || string(data) != "v1" {
+		t.Fatalf("次新的备份 = %q, %v, 期望 v1", data, err)
+	}
+	if _, err := os.Stat(BackupPath(filename, 2)); !os.IsNotExist(err) {
+		t.Fatalf("内容未变化的保存不应产生更多备份: %v", err)
+	}
+}
+
+func TestSaveStatsDoesNotRotateBackups(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "servers.json")
+	encrypted := filepath.Join(dir, "servers.dat")
+
+	sm := NewServerManager()
+	if err := sm.SaveToFile(plain); err != nil {
+		t.Fatalf("保存明文配置失败: %v", err)
+	}
+	if err := sm.SaveToEncryptedFile(encrypted, "pw"); err != nil {
+		t.Fatalf("保存加密配置失败: %v", err)
+	}
+
+	for i := 0; i < ConfigBackupCount+1; i++ {
+		if err := sm.SaveStatsToFile(plain); err != nil {
+			t.Fatalf("保存明文连接统计失败: %v", err)
+		}
+		if err := sm.SaveStatsToEncryptedFile(encrypted, "pw"); err != nil {
+			t.Fatalf("保存加密连接统计失败: %v", err)
+		}
+	}
+
+	for _, filename := range []string{plain, encrypted} {
+		if backups := ListBackups(filename); len(backups) != 0 {
+			t.Fatalf("%s 保存连接统计不应轮转备份: %+v", filename, backups)
+		}
+	}
+}
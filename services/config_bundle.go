@@ -0,0 +1,276 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-term/models"
+)
+
+// ConfigBundleVersion 当前配置包格式版本，新增字段时保持向后兼容，不兼容的变更需提升版本
+const ConfigBundleVersion = 1
+
+// 导入时的合并策略
+const (
+	MergeReplace   = "replace"   // 用导入内容整体替换
+	MergeSkip      = "skip"      // ID冲突时保留现有内容
+	MergeOverwrite = "overwrite" // ID冲突时使用导入内容覆盖
+)
+
+// ConfigBundle 完整配置包（服务器、脚本、known_hosts）
+type ConfigBundle struct {
+	Version    int                  `json:"version"`
+	ExportedAt string               `json:"exportedAt"`
+	Groups     []models.ServerGroup `json:"groups"`
+	Scripts    []models.BatchScript `json:"scripts"`
+	KnownHosts string               `json:"knownHosts,omitempty"`
+}
+
+// ImportSummary 单个集合的导入结果
+type ImportSummary struct {
+	Added     int      `json:"added"`
+	Updated   int      `json:"updated"`
+	Skipped   int      `json:"skipped"`
+	Conflicts []string `json:"conflicts"` // 发生ID冲突的条目
+}
+
+// ValidateMergeStrategy 校验合并策略，空值默认为 skip
+func ValidateMergeStrategy(strategy string) (string, error) {
+	switch strategy {
+	case "":
+		return MergeSkip, nil
+	case MergeReplace, MergeSkip, MergeOverwrite:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("不支持的合并策略: %s", strategy)
+	}
+}
+
+// SaveConfigBundle 使用密码加密保存配置包
+func SaveConfigBundle(bundle *ConfigBundle, filename, password string) error {
+	bundle.Version = ConfigBundleVersion
+	bundle.ExportedAt = time.Now().Format("2006-01-02 15:04:05")
+
+	data, err := json.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("无法序列化配置包: %v", err)
+	}
+
+	encryptedData, err := NewEncryptedConfigManager(password).encrypt(data)
+	if err != nil {
+		return fmt.Errorf("加密配置包失败: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("无法创建目录: %v", err)
+	}
+	if err := os.WriteFile(filename, []byte(encryptedData), 0600); err != nil {
+		return fmt.Errorf("无法写入配置包: %v", err)
+	}
+	return nil
+}
+
+// LoadConfigBundle 读取并解密配置包
+func LoadConfigBundle(filename, password string) (*ConfigBundle, error) {
+	encryptedData, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取配置包: %v", err)
+	}
+
+	plaintext, err := NewEncryptedConfigManager(password).decrypt(string(encryptedData))
+	if err != nil {
+		return nil, fmt.Errorf("解密配置包失败: %w", err)
+	}
+
+	var bundle ConfigBundle
+	if err := json.Unmarshal(plaintext, &bundle); err != nil {
+		return nil, fmt.Errorf("无法解析配置包: %v", err)
+	}
+	if bundle.Version > ConfigBundleVersion {
+		return nil, fmt.Errorf("配置包版本 %d 高于当前支持的版本 %d，请升级程序", bundle.Version, ConfigBundleVersion)
+	}
+	return &bundle, nil
+}
+
+// MergeGroups 按合并策略导入服务器分组，分组和服务器都按ID匹配
+func (sm *ServerManager) MergeGroups(groups []models.ServerGroup, strategy string) ImportSummary {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	summary := ImportSummary{Conflicts: []string{}}
+
+	if strategy == MergeReplace {
+		sm.Groups = make([]models.ServerGroup, 0, len(groups))
+		for _, group := range groups {
+			sm.Groups = append(sm.Groups, cloneGroup(group))
+			summary.Added += len(group.Servers)
+		}
+		return summary
+	}
+
+	for _, group := range groups {
+		groupIndex := -1
+		for i := range sm.Groups {
+			if sm.Groups[i].ID == group.ID {
+				groupIndex = i
+				break
+			}
+		}
+		if groupIndex == -1 {
+			sm.Groups = append(sm.Groups, models.ServerGroup{ID: group.ID, Name: group.Name, Servers: []models.Server{}})
+			groupIndex = len(sm.Groups) - 1
+		} else if strategy == MergeOverwrite {
+			sm.Groups[groupIndex].Name = group.Name
+		}
+
+		for _, server := range group.Servers {
+			server = cloneServer(server)
+			server.GroupID = group.ID
+			gi, si := sm.findServer(server.ID)
+			if gi == -1 {
+				sm.Groups[groupIndex].Servers = append(sm.Groups[groupIndex].Servers, server)
+				summary.Added++
+				continue
+			}
+
+			summary.Conflicts = append(summary.Conflicts, server.ID)
+			if strategy != MergeOverwrite {
+				summary.Skipped++
+				continue
+			}
+
+			if gi == groupIndex {
+				sm.Groups[gi].Servers[si] = server
+			} else {
+				// 服务器移动到导入内容中的分组
+				sm.Groups[gi].Servers = append(sm.Groups[gi].Servers[:si], sm.Groups[gi].Servers[si+1:]...)
+				sm.Groups[groupIndex].Servers = append(sm.Groups[groupIndex].Servers, server)
+			}
+			summary.Updated++
+		}
+	}
+	return summary
+}
+
+// findServer 查找服务器所在的分组和位置，未找到时返回 -1, -1；调用方需持有锁
+func (sm *ServerManager) findServer(serverID string) (int, int) {
+	for i := range sm.Groups {
+		for j := range sm.Groups[i].Servers {
+			if sm.Groups[i].Servers[j].ID == serverID {
+				return i, j
+			}
+		}
+	}
+	return -1, -1
+}
+
+// ImportScripts 按合并策略导入脚本并保存
+func (sm *ScriptManager) ImportScripts(scripts []models.BatchScript, strategy string) (ImportSummary, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	summary := ImportSummary{Conflicts: []string{}}
+
+	if strategy == MergeReplace {
+		sm.scripts = make([]models.BatchScript, 0, len(scripts))
+		for _, script := range scripts {
+			sm.scripts = append(sm.scripts, cloneScript(script))
+		}
+		summary.Added = len(scripts)
+		return summary, sm.saveToFile()
+	}
+
+	for _, script := range scripts {
+		index := -1
+		for i := range sm.scripts {
+			if sm.scripts[i].ID == script.ID {
+				index = i
+				break
+			}
+		}
+		if index == -1 {
+			sm.scripts = append(sm.scripts, cloneScript(script))
+			summary.Added++
+			continue
+		}
+
+		summary.Conflicts = append(summary.Conflicts, script.ID)
+		if strategy == MergeOverwrite {
+			sm.scripts[index] = cloneScript(script)
+			summary.Updated++
+		} else {
+			summary.Skipped++
+		}
+	}
+	return summary, sm.saveToFile()
+}
+
+// Content 读取 known_hosts 文件内容，文件不存在时返回空
+func (k *KnownHostsManager) Content() (string, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	data, err := os.ReadFile(k.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("无法读取known_hosts文件: %v", err)
+	}
+	return string(data), nil
+}
+
+// MergeContent 将 known_hosts 内容中尚不存在的行追加到文件，replace 策略时整体替换
+func (k *KnownHostsManager) MergeContent(content, strategy string) (ImportSummary, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	summary := ImportSummary{Conflicts: []string{}}
+	if err := k.ensureFile(); err != nil {
+		return summary, err
+	}
+
+	existing := ""
+	if strategy != MergeReplace {
+		data, err := os.ReadFile(k.path)
+		if err != nil {
+			return summary, fmt.Errorf("无法读取known_hosts文件: %v", err)
+		}
+		existing = string(data)
+	}
+
+	known := make(map[string]bool)
+	var lines []string
+	for _, line := range strings.Split(existing, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			known[line] = true
+			lines = append(lines, line)
+		}
+	}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if known[line] {
+			summary.Skipped++
+			continue
+		}
+		known[line] = true
+		lines = append(lines, line)
+		summary.Added++
+	}
+
+	data := strings.Join(lines, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	if err := os.WriteFile(k.path, []byte(data), 0600); err != nil {
+		return summary, fmt.Errorf("无法写入known_hosts文件: %v", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultConnectionGracePeriod 连接断开后等待恢复的默认宽限期
+const DefaultConnectionGracePeriod = 3 * time.Second
+
+// ConnectionStateDebouncer 连接状态事件防抖
+// 连接断开后只有持续超过宽限期才发出 dropped 事件；在宽限期内恢复则两个事件都不发出，
+// 避免网络抖动导致界面状态闪烁
+type ConnectionStateDebouncer struct {
+	mutex       sync.Mutex
+	gracePeriod time.Duration
+	states      map[string]*connectionState
+	emit        func(serverID string, connected bool)
+}
+
+// connectionState 单个服务器的连接状态
+type connectionState struct {
+	connected     bool        // 当前实际状态
+	emitted       bool        // 是否已发出过事件
+	lastConnected bool        // 最近一次发出的状态
+	dropTimer     *time.Timer // 等待宽限期结束的定时器
+}
+
+// NewConnectionStateDebouncer 创建连接状态防抖器，emit 在状态确认变化时被调用
+func NewConnectionStateDebouncer(gracePeriod time.Duration, emit func(serverID string, connected bool)) *ConnectionStateDebouncer {
+	return &ConnectionStateDebouncer{
+		gracePeriod: gracePeriod,
+		states:      make(map[string]*connectionState),
+		emit:        emit,
+	}
+}
+
+// SetGracePeriod 设置宽限期，对之后的断开生效
+func (d *ConnectionStateDebouncer) SetGracePeriod(gracePeriod time.Duration) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if gracePeriod < 0 {
+		gracePeriod = 0
+	}
+	d.gracePeriod = gracePeriod
+}
+
+// GracePeriod 获取当前宽限期
+func (d *ConnectionStateDebouncer) GracePeriod() time.Duration {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.gracePeriod
+}
+
+// Report 报告服务器的实际连接状态
+func (d *ConnectionStateDebouncer) Report(serverID string, connected bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	state, ok := d.states[serverID]
+	if !ok {
+		state = &connectionState{connected: !connected}
+		d.states[serverID] = state
+	}
+	if state.connected == connected {
+		return
+	}
+	state.connected = connected
+
+	if connected {
+		// 宽限期内恢复：取消待发出的 dropped 事件，也不发出 connected 事件
+		if state.dropTimer != nil {
+			state.dropTimer.Stop()
+			state.dropTimer = nil
+			return
+		}
+		if !state.emitted || !state.lastConnected {
+			d.emitLocked(serverID, state, true)
+		}
+		return
+	}
+
+	// 从未发出过已连接状态时无需报告断开
+	if !state.emitted || !state.lastConnected {
+		return
+	}
+	state.dropTimer = time.AfterFunc(d.gracePeriod, func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		if d.states[serverID] != state || state.connected || state.dropTimer == nil {
+			return
+		}
+		state.dropTimer = nil
+		d.emitLocked(serverID, state, false)
+	})
+}
+
+// Forget 清除服务器的状态（如用户主动断开），不发出事件
+func (d *ConnectionStateDebouncer) Forget(serverID string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if state, ok := d.states[serverID]; ok && state.dropTimer != nil {
+		state.dropTimer.Stop()
+	}
+	delete(d.states, serverID)
+}
+
+// emitLocked 记录并发出状态事件，调用方需持有锁
+func (d *ConnectionStateDebouncer) emitLocked(serverID string, state *connectionState, connected bool) {
+	state.emitted = true
+	state.lastConnected = connected
+	if d.emit != nil {
+		go d.emit(serverID, connected)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// ConnectionTestTimeout 测试连接时执行测试命令的超时时间，以及未配置连接超时时建立连接的超时时间
+const ConnectionTestTimeout = 10 * time.Second
+
+// connectionTestCommand 测试连接时执行的命令及其期望输出
+const connectionTestCommand = "echo ok"
+
+// ConnectionTestResult 连接测试结果
+type ConnectionTestResult struct {
+	Success   bool   `json:"success"`
+	LatencyMs int64  `json:"latencyMs"` // 从开始连接到测试命令返回的总耗时（毫秒）
+	ConnectMs int64  `json:"connectMs"` // 建立连接和认证的耗时（毫秒）
+	CommandMs int64  `json:"commandMs"` // 执行测试命令的耗时（毫秒），约等于一次往返延迟
+	Error     string `json:"error,omitempty"`
+}
+
+// ProbeConnection 建立连接、执行 echo ok 后立即断开，用于测试服务器配置是否可用
+// conn 应为新创建的连接，测试结束后会被关闭
+func ProbeConnection(conn *SSHConnection, host string, port int, username, password, keyFile string) ConnectionTestResult {
+	result := ConnectionTestResult{}
+	if conn.DialTimeout <= 0 {
+		conn.DialTimeout = ConnectionTestTimeout
+	}
+
+	start := time.Now()
+	if err := conn.Connect(host, port, username, password, keyFile); err != nil {
+		result.LatencyMs = time.Since(start).Milliseconds()
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close()
+	result.ConnectMs = time.Since(start).Milliseconds()
+
+	commandStart := time.Now()
+	output, err := conn.ExecuteCommandTimeout(context.Background(), connectionTestCommand, ConnectionTestTimeout)
+	result.CommandMs = time.Since(commandStart).Milliseconds()
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = "连接成功但执行测试命令失败: " + err.Error()
+		return result
+	}
+	if !strings.Contains(output, "ok") {
+		result.Error = "测试命令输出异常: " + strings.TrimSpace(output)
+		return result
+	}
+
+	result.Success = true
+	return result
+}
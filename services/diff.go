@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines 统一diff格式中每个变更块前后的上下文行数
+const diffContextLines = 3
+
+// maxDiffEditCost 逐行比较时搜索的最大编辑距离，差异超出时该部分按整体替换输出，
+// 使差异很大的大文件也能在有限时间内完成；内存占用只与行数成线性关系
+const maxDiffEditCost = 4096
+
+// diffOp 一行差异：' ' 未变化，'-' 删除，'+' 新增
+type diffOp struct {
+	kind byte
+	text string
+}
+
+// UnifiedDiff 计算两段文本的统一diff格式差异，内容相同时返回空字符串
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var changes []int
+	for i, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+
+	// 将相距不超过两倍上下文的变更合并为一个块
+	for start := 0; start < len(changes); {
+		end := start
+		for end+1 < len(changes) && changes[end+1]-changes[end] <= 2*diffContextLines {
+			end++
+		}
+
+		from := changes[start] - diffContextLines
+		if from < 0 {
+			from = 0
+		}
+		to := changes[end] + diffContextLines + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+		writeHunk(&sb, ops, from, to)
+
+		start = end + 1
+	}
+
+	return sb.String()
+}
+
+// writeHunk 输出 ops[from:to] 对应的一个差异块
+func writeHunk(sb *strings.Builder, ops []diffOp, from, to int) {
+	oldBefore, newBefore := 0, 0
+	for _, op := range ops[:from] {
+		if op.kind != '+' {
+			oldBefore++
+		}
+		if op.kind != '-' {
+			newBefore++
+		}
+	}
+
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(oldBefore, oldCount), hunkRange(newBefore, newCount))
+	for _, op := range ops[from:to] {
+		sb.WriteByte(op.kind)
+		sb.WriteString(op.text)
+		if !strings.HasSuffix(op.text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange 格式化块的行范围，数量为1时省略
+func hunkRange(before, count int) string {
+	start := before + 1
+	if count == 0 {
+		start = before
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines 按行分割文本，每行保留结尾的换行符
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines 计算逐行差异（Myers 算法，线性空间）
+func diffLines(a, b []string) []diffOp {
+	// 行内容映射为整数，比较时不再逐字节比较字符串
+	ids := make(map[string]int)
+	intern := func(lines []string) []int {
+		result := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			result[i] = id
+		}
+		return result
+	}
+
+	d := &lineDiff{a: a, b: b, x: intern(a), y: intern(b)}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+// lineDiff 保存一次逐行比较的输入和结果
+type lineDiff struct {
+	a, b []string // 原始行
+	x, y []int    // 行对应的整数编号
+	ops  []diffOp
+}
+
+// compare 比较 a[aLo:aHi] 和 b[bLo:bHi]：去掉相同的首尾部分后，在中间蛇形处一分为二递归比较
+func (d *lineDiff) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.x[aLo] == d.y[bLo] {
+		d.ops = append(d.ops, diffOp{' ', d.a[aLo]})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.x[aHi-1-suffix] == d.y[bHi-1-suffix] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		d.add('+', d.b[bLo:bHi])
+	case bLo == bHi:
+		d.add('-', d.a[aLo:aHi])
+	default:
+		if xMid, yMid, ok := d.middleSnake(aLo, aHi, bLo, bHi); ok {
+			d.compare(aLo, xMid, bLo, yMid)
+			d.compare(xMid, aHi, yMid, bHi)
+		} else {
+			// 差异过大时按整体替换处理
+			d.add('-', d.a[aLo:aHi])
+			d.add('+', d.b[bLo:bHi])
+		}
+	}
+
+	d.add(' ', d.a[aHi:aHi+suffix])
+}
+
+// add 按顺序输出一组同类型的行
+func (d *lineDiff) add(kind byte, lines []string) {
+	for _, line := range lines {
+		d.ops = append(d.ops, diffOp{kind, line})
+	}
+}
+
+// middleSnake 从两端同时搜索最短编辑路径，返回两条路径相遇处的分割点
+// 编辑距离超过 maxDiffEditCost 时放弃搜索，返回 ok 为 false
+func (d *lineDiff) middleSnake(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[k] / backward[k] 为对角线 k 上正向 / 反向路径到达的最远 x（反向从末尾算起），-1 表示未到达
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// 总长度为奇数时正向路径先与反向路径重叠
+	front := delta%2 != 0
+	// 路径越过边界的对角线不再搜索
+	kfStart, kfEnd, kbStart, kbEnd := 0, 0, 0, 0
+
+	for step := 0; step < maxD && step <= maxDiffEditCost; step++ {
+		for k := -step + kfStart; k <= step-kfEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[aLo+x] == d.y[bLo+y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				kfEnd += 2
+			case y > m:
+				kfStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+
+		for k := -step + kbStart; k <= step-kbEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[aHi-1-x] == d.y[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				kbEnd += 2
+			case y > m:
+				kbStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					fy := fx - (j - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
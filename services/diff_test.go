@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lcsLength 动态规划计算最长公共子序列长度，用于验证差异是最短的
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				cur[j] = prev[j+1] + 1
+			case prev[j] >= cur[j+1]:
+				cur[j] = prev[j]
+			default:
+				cur[j] = cur[j+1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[0]
+}
+
+// checkDiffOps 检查差异能还原两段文本，且未变化的行数等于最长公共子序列长度
+func checkDiffOps(t *testing.T, a, b []string, minimal bool) {
+	t.Helper()
+
+	ops := diffLines(a, b)
+	var oldLines, newLines []string
+	same := 0
+	for _, op := range ops {
+		switch op.kind {
+		case ' ':
+			oldLines = append(oldLines, op.text)
+			newLines = append(newLines, op.text)
+			same++
+		case '-':
+			oldLines = append(oldLines, op.text)
+		case '+':
+			newLines = append(newLines, op.text)
+		}
+	}
+	if strings.Join(oldLines, "") != strings.Join(a, "") || strings.Join(newLines, "") != strings.Join(b, "") {
+		t.Fatalf("差异无法还原原始文本\na=%q\nb=%q\nops=%v", a, b, ops)
+	}
+	if minimal {
+		if want := lcsLength(a, b); same != want {
+			t.Fatalf("差异中未变化的行数为 %d, 期望 %d\na=%q\nb=%q", same, want, a, b)
+		}
+	}
+}
+
+func TestDiffLinesIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%c\n", 'a'+rng.Intn(4))
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		checkDiffOps(t, randomLines(), randomLines(), true)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	newText := "a\nb\nc\nd\nE\nf\ng\nh\ni\nj\nk"
+	want := "--- a/f\n+++ b/f\n@@ -2,9 +2,10 @@\n b\n c\n d\n-e\n+E\n f\n g\n h\n i\n j\n+k\n\\ No newline at end of file\n"
+	if got := UnifiedDiff("a/f", "b/f", oldText, newText); got != want {
+		t.Fatalf("UnifiedDiff =\n%s\n期望\n%s", got, want)
+	}
+	if got := UnifiedDiff("a/f", "b/f", oldText, oldText); got != "" {
+		t.Fatalf("相同内容的差异应为空: %q", got)
+	}
+	if got := UnifiedDiff("/dev/null", "b/f", "", "x\n"); got != "--- /dev/null\n+++ b/f\n@@ -0,0 +1 @@\n+x\n" {
+		t.Fatalf("新文件的差异为 %q", got)
+	}
+}
+
+func TestDiffLinesLargeInput(t *testing.T) {
+	// 两个完全不同的大文件：超出编辑距离限制后按整体替换输出，不能耗尽内存或长时间运行
+	const lines = 100000
+	a := make([]string, lines)
+	b := make([]string, lines)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d\n", i)
+		b[i] = fmt.Sprintf("new %d\n", i)
+	}
+	start := time.Now()
+	checkDiffOps(t, a, b, false)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("比较完全不同的大文件耗时 %v", elapsed)
+	}
+
+	// 只有少量修改的大文件仍然得到精确的差异
+	c := append([]string(nil), a...)
+	for i := 0; i < lines; i += 1000 {
+		c[i] = fmt.Sprintf("changed %d\n", i)
+	}
+	ops := diffLines(a, c)
+	changed := 0
+	for _, op := range ops {
+		if op.kind == '+' {
+			changed++
+		}
+	}
+	if changed != lines/1000 {
+		t.Fatalf("新增行数为 %d, 期望 %d", changed, lines/1000)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// directoryCacheTTL 目录列表缓存的有效期
+const directoryCacheTTL = 30 * time.Second
+
+// DirectoryCache 按服务器和路径缓存目录列表，用于本地过滤等不需要重新访问服务器的场景
+type DirectoryCache struct {
+	mutex   sync.Mutex
+	entries map[string]directoryCacheEntry
+}
+
+type directoryCacheEntry struct {
+	files    []FileInfo
+	cachedAt time.Time
+}
+
+// NewDirectoryCache 创建目录列表缓存
+func NewDirectoryCache() *DirectoryCache {
+	return &DirectoryCache{entries: make(map[string]directoryCacheEntry)}
+}
+
+func directoryCacheKey(serverID, dir string) string {
+	return serverID + "\x00" + path.Clean(dir)
+}
+
+// Get 获取未过期的目录列表副本
+func (c *DirectoryCache) Get(serverID, dir string) ([]FileInfo, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[directoryCacheKey(serverID, dir)]
+	if !ok || time.Since(entry.cachedAt) > directoryCacheTTL {
+		return nil, false
+	}
+	return append([]FileInfo(nil), entry.files...), true
+}
+
+// Put 缓存目录列表
+func (c *DirectoryCache) Put(serverID, dir string, files []FileInfo) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.entries[directoryCacheKey(serverID, dir)] = directoryCacheEntry{
+		files:    append([]FileInfo(nil), files...),
+		cachedAt: time.Now(),
+	}
+}
+
+// InvalidatePath 使路径本身及其所在目录的缓存失效（文件增删改后调用）
+func (c *DirectoryCache) InvalidatePath(serverID, p string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.entries, directoryCacheKey(serverID, p))
+	delete(c.entries, directoryCacheKey(serverID, path.Dir(path.Clean(p))))
+}
+
+// InvalidateServer 清除服务器的所有缓存
+func (c *DirectoryCache) InvalidateServer(serverID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	prefix := serverID + "\x00"
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// FilterFileInfos 按名称过滤文件列表（忽略大小写）
+// 包含 * ? [ 时按通配符匹配；fuzzy 为 true 时按子序列模糊匹配；否则按子串匹配
+func FilterFileInfos(files []FileInfo, pattern string, fuzzy bool) []FileInfo {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return files
+	}
+	isGlob := strings.ContainsAny(pattern, "*?[")
+
+	result := make([]FileInfo, 0)
+	for _, file := range files {
+		name := strings.ToLower(file.Name)
+		var matched bool
+		switch {
+		case isGlob:
+			matched, _ = path.Match(pattern, name)
+		case fuzzy:
+			matched = isSubsequence(pattern, name)
+		default:
+			matched = strings.Contains(name, pattern)
+		}
+		if matched {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// isSubsequence 判断 pattern 是否按顺序出现在 s 中（不要求连续）
+func isSubsequence(pattern, s string) bool {
+	pr := []rune(pattern)
+	i := 0
+	for _, r := range s {
+		if i < len(pr) && r == pr[i] {
+			i++
+		}
+	}
+	return i == len(pr)
+}
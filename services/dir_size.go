@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// dirSizeProgressInterval 目录大小统计的进度回调间隔
+const dirSizeProgressInterval = 200 * time.Millisecond
+
+// DirectorySize 目录大小统计结果
+type DirectorySize struct {
+	Path       string `json:"path"`
+	TotalBytes int64  `json:"totalBytes"`
+	FileCount  int64  `json:"fileCount"`
+	DirCount   int64  `json:"dirCount"`
+	Skipped    int64  `json:"skipped"` // 因权限等原因无法读取的目录数
+}
+
+// DirectorySize 递归统计目录下所有文件的大小，不跟随符号链接
+// 无法读取的子目录计入 Skipped 并继续；ctx 取消时返回已统计的部分结果和错误
+func (s *SSHConnection) DirectorySize(ctx context.Context, sftpClient *sftp.Client, root string, onProgress func(DirectorySize)) (DirectorySize, error) {
+	result := DirectorySize{Path: root}
+	if s.Client == nil {
+		return result, fmt.Errorf("SSH连接未建立")
+	}
+
+	if _, err := sftpClient.ReadDir(root); err != nil {
+		return result, fmt.Errorf("读取目录失败: %v", err)
+	}
+
+	lastProgress := time.Now()
+	stack := []string{root}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("统计已取消: %w", err)
+		}
+
+		dir := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		entries, err := sftpClient.ReadDir(dir)
+		if err != nil {
+			result.Skipped++
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				result.DirCount++
+				stack = append(stack, path.Join(dir, entry.Name()))
+			} else {
+				result.FileCount++
+				result.TotalBytes += entry.Size()
+			}
+		}
+
+		if onProgress != nil && time.Since(lastProgress) >= dirSizeProgressInterval {
+			lastProgress = time.Now()
+			onProgress(result)
+		}
+	}
+	return result, nil
+}
@@ -3,108 +3,253 @@ package services
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"go-term/models"
-
-	"golang.org/x/crypto/scrypt"
 )
 
 // EncryptedConfigManager 加密配置管理器
 type EncryptedConfigManager struct {
 	password []byte
+	kdf      kdfSpec // 加密时使用的密钥派生算法和参数，解密时使用文件中记录的算法和参数
 }
 
-// NewEncryptedConfigManager 创建新的加密配置管理器
+// 加密数据格式：
+// 旧格式：salt(16) | nonce | ciphertext
+// 版本1：magic(4) | version(1) | kdf(1) | kdf参数(12) | salt(16) | nonce | ciphertext
+// 版本2：在版本1的盐值之后增加密码校验值(16)，用于在解密前区分密码错误和文件损坏
+// 文件头作为GCM附加数据参与认证
+const (
+	encryptedMagic       = "GTEC"
+	encryptedVersion     = 2
+	kdfParamsSize        = 12
+	encryptedSaltSize    = 16
+	passwordCheckSize    = 16
+	encryptedHeaderSize1 = len(encryptedMagic) + 2 + kdfParamsSize + encryptedSaltSize
+	encryptedHeaderSize  = encryptedHeaderSize1 + passwordCheckSize
+)
+
+// passwordCheckLabel 计算密码校验值时使用的固定消息
+const passwordCheckLabel = "go-term password check"
+
+// 解密失败的原因，可通过 errors.Is 判断
+var (
+	// ErrWrongPassword 密码错误，无法解密配置文件
+	ErrWrongPassword = errors.New("密码错误，无法解密配置文件")
+	// ErrCorruptedData 加密数据已损坏或被篡改
+	ErrCorruptedData = errors.New("加密数据已损坏")
+	// ErrNotEncrypted 数据不是加密格式
+	ErrNotEncrypted = errors.New("数据不是加密格式")
+)
+
+// errWrongPasswordOrCorrupted 旧格式没有密码校验值，解密失败时无法区分原因
+var errWrongPasswordOrCorrupted = fmt.Errorf("%w或文件已损坏", ErrWrongPassword)
+
+// NewEncryptedConfigManager 创建新的加密配置管理器，加密时使用 DefaultKDF
 func NewEncryptedConfigManager(password string) *EncryptedConfigManager {
-	return &EncryptedConfigManager{
+	ecm := &EncryptedConfigManager{
 		password: []byte(password),
+		kdf: kdfSpec{
+			id:     kdfIDArgon2id,
+			scrypt: DefaultScryptParams,
+			argon2: DefaultArgon2Params,
+		},
 	}
+	if id, err := kdfID(DefaultKDF); err == nil {
+		ecm.kdf.id = id
+	}
+	return ecm
 }
 
-// deriveKey 使用scrypt从密码派生密钥
-func (ecm *EncryptedConfigManager) deriveKey(salt []byte) ([]byte, error) {
-	return scrypt.Key(ecm.password, salt, 32768, 8, 1, 32)
+// SetKDF 设置加密时使用的密钥派生算法（scrypt 或 argon2id）
+func (ecm *EncryptedConfigManager) SetKDF(name string) error {
+	id, err := kdfID(name)
+	if err != nil {
+		return err
+	}
+	ecm.kdf.id = id
+	return nil
 }
 
-// encrypt 加密数据
+// SetScryptParams 设置加密时使用的scrypt参数，并选用scrypt算法
+func (ecm *EncryptedConfigManager) SetScryptParams(params ScryptParams) error {
+	if err := params.validate(); err != nil {
+		return err
+	}
+	ecm.kdf.id = kdfIDScrypt
+	ecm.kdf.scrypt = params
+	return nil
+}
+
+// SetArgon2Params 设置加密时使用的Argon2id参数，并选用Argon2id算法
+func (ecm *EncryptedConfigManager) SetArgon2Params(params Argon2Params) error {
+	if err := params.validate(); err != nil {
+		return err
+	}
+	ecm.kdf.id = kdfIDArgon2id
+	ecm.kdf.argon2 = params
+	return nil
+}
+
+// encrypt 加密数据，输出带版本文件头的格式
 func (ecm *EncryptedConfigManager) encrypt(plaintext []byte) (string, error) {
-	// 生成随机盐值
-	salt := make([]byte, 16)
-	if _, err := rand.Read(salt); err != nil {
+	header, key, err := ecm.newHeader()
+	if err != nil {
 		return "", err
 	}
+	return seal(header, key, plaintext)
+}
+
+// newHeader 生成文件头（magic、版本、KDF及其参数、随机盐值、密码校验值）并返回派生的密钥
+func (ecm *EncryptedConfigManager) newHeader() ([]byte, []byte, error) {
+	spec := ecm.kdf
+	if err := spec.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]byte, encryptedHeaderSize)
+	copy(header, encryptedMagic)
+	header[4] = encryptedVersion
+	header[5] = spec.id
+	spec.encodeParams(header[6 : 6+kdfParamsSize])
+	salt := header[encryptedHeaderSize1-encryptedSaltSize : encryptedHeaderSize1]
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
 
 	// 派生密钥
-	key, err := ecm.deriveKey(salt)
+	key, err := spec.deriveKey(ecm.password, salt)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
+	copy(header[encryptedHeaderSize1:], passwordCheck(key))
+	return header, key, nil
+}
 
-	// 创建AES加密器
-	block, err := aes.NewCipher(key)
+// seal 使用随机IV加密数据，输出 base64(header | nonce | ciphertext)
+func seal(header, key, plaintext []byte) (string, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
 		return "", err
 	}
 
 	// 生成随机IV
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", err
-	}
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	// 加密数据
-	ciphertext := gcm.Seal(nonce, nonce, plaintext, nil)
-
-	// 将盐值、加密数据组合并进行base64编码
-	result := make([]byte, 16+len(ciphertext))
-	copy(result[:16], salt)
-	copy(result[16:], ciphertext)
+	// 加密数据，文件头作为附加数据防止参数被篡改
+	result := make([]byte, 0, len(header)+len(nonce)+len(plaintext)+gcm.Overhead())
+	result = append(result, header...)
+	result = append(result, nonce...)
+	result = gcm.Seal(result, nonce, plaintext, header)
 
 	return base64.StdEncoding.EncodeToString(result), nil
 }
 
-// decrypt 解密数据
+// decrypt 解密数据，根据文件头选择密钥派生参数，没有文件头时按旧格式解密
+// 失败原因可通过 errors.Is 判断：ErrNotEncrypted、ErrWrongPassword、ErrCorruptedData
 func (ecm *EncryptedConfigManager) decrypt(encryptedData string) ([]byte, error) {
 	// base64解码
-	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encryptedData))
+	if err != nil || len(data) == 0 {
+		return nil, ErrNotEncrypted
+	}
+
+	header, spec, ok, err := parseEncryptedHeader(data)
 	if err != nil {
 		return nil, err
 	}
+	if !ok {
+		// 旧格式：salt(16) | nonce | ciphertext
+		if len(data) < encryptedSaltSize {
+			return nil, ErrNotEncrypted
+		}
+		legacy := kdfSpec{id: kdfIDScrypt, scrypt: legacyScryptParams}
+		key, err := legacy.deriveKey(ecm.password, data[:encryptedSaltSize])
+		if err != nil {
+			return nil, err
+		}
+		plaintext, err := open(key, data[encryptedSaltSize:], nil)
+		if err != nil {
+			return nil, errWrongPasswordOrCorrupted
+		}
+		return plaintext, nil
+	}
 
-	if len(data) < 16 {
-		return nil, fmt.Errorf("无效的加密数据")
+	salt := header[encryptedHeaderSize1-encryptedSaltSize : encryptedHeaderSize1]
+	key, err := spec.deriveKey(ecm.password, salt)
+	if err != nil {
+		return nil, err
 	}
 
-	// 提取盐值和加密数据
-	salt := data[:16]
-	ciphertext := data[16:]
+	// 版本2先比较密码校验值，密码正确但解密失败说明文件已损坏
+	if len(header) == encryptedHeaderSize {
+		if !hmac.Equal(header[encryptedHeaderSize1:], passwordCheck(key)) {
+			return nil, ErrWrongPassword
+		}
+		plaintext, err := open(key, data[len(header):], header)
+		if err != nil {
+			return nil, ErrCorruptedData
+		}
+		return plaintext, nil
+	}
 
-	// 派生密钥
-	key, err := ecm.deriveKey(salt)
+	plaintext, err := open(key, data[len(header):], header)
 	if err != nil {
-		return nil, err
+		return nil, errWrongPasswordOrCorrupted
 	}
+	return plaintext, nil
+}
 
-	// 创建AES解密器
-	block, err := aes.NewCipher(key)
+// parseEncryptedHeader 解析文件头，ok 为 false 表示数据是没有文件头的旧格式
+func parseEncryptedHeader(data []byte) (header []byte, spec kdfSpec, ok bool, err error) {
+	if len(data) < encryptedHeaderSize1 || string(data[:len(encryptedMagic)]) != encryptedMagic {
+		return nil, spec, false, nil
+	}
+
+	headerSize := 0
+	switch version := data[4]; version {
+	case 1:
+		headerSize = encryptedHeaderSize1
+	case 2:
+		headerSize = encryptedHeaderSize
+	default:
+		return nil, spec, false, fmt.Errorf("不支持的加密格式版本: %d", version)
+	}
+	if len(data) < headerSize {
+		return nil, spec, false, ErrCorruptedData
+	}
+
+	spec, err = decodeKDFSpec(data[5], data[6:6+kdfParamsSize])
 	if err != nil {
-		return nil, err
+		return nil, spec, false, fmt.Errorf("%w: %v", ErrCorruptedData, err)
 	}
+	return data[:headerSize], spec, true, nil
+}
 
-	// 创建GCM
-	gcm, err := cipher.NewGCM(block)
+// passwordCheck 根据派生密钥计算密码校验值
+func passwordCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(passwordCheckLabel))
+	return mac.Sum(nil)[:passwordCheckSize]
+}
+
+// open 解密 nonce | ciphertext
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
 	if err != nil {
 		return nil, err
 	}
@@ -116,12 +261,16 @@ func (ecm *EncryptedConfigManager) decrypt(encryptedData string) ([]byte, error)
 	}
 
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+// newGCM 创建AES-GCM加密器
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
 	}
-
-	return plaintext, nil
+	return cipher.NewGCM(block)
 }
 
 // SaveEncryptedConfig 保存加密的配置文件
@@ -138,15 +287,8 @@ func (ecm *EncryptedConfigManager) SaveEncryptedConfig(config *models.ServerGrou
 		return fmt.Errorf("加密配置失败: %v", err)
 	}
 
-	// 确保目录存在
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("无法创建目录: %v", err)
-	}
-
-	// 写入加密数据到文件
-	err = os.WriteFile(filename, []byte(encryptedData), 0600)
-	if err != nil {
+	// 写入加密数据到文件（先备份现有文件，再写临时文件并重命名，写入中途崩溃不会损坏原配置）
+	if err := writeConfigFile(filename, []byte(encryptedData), 0600); err != nil {
 		return fmt.Errorf("无法写入加密配置文件: %v", err)
 	}
 
@@ -179,6 +321,11 @@ func (ecm *EncryptedConfigManager) LoadEncryptedConfig(filename string) (*models
 
 // SaveEncryptedServerManager 保存加密的服务器管理器配置
 func (ecm *EncryptedConfigManager) SaveEncryptedServerManager(sm *ServerManager, filename string) error {
+	return ecm.saveEncryptedServerManager(sm, filename, true)
+}
+
+// saveEncryptedServerManager 加密保存服务器管理器，backup 为 false 时不轮转备份
+func (ecm *EncryptedConfigManager) saveEncryptedServerManager(sm *ServerManager, filename string, backup bool) error {
 	// 序列化配置
 	data, err := json.MarshalIndent(sm, "", "  ")
 	if err != nil {
@@ -191,15 +338,12 @@ func (ecm *EncryptedConfigManager) SaveEncryptedServerManager(sm *ServerManager,
 		return fmt.Errorf("加密服务器管理器失败: %v", err)
 	}
 
-	// 确保目录存在
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("无法创建目录: %v", err)
+	// 写入加密数据到文件（先备份现有文件，再写临时文件并重命名，写入中途崩溃不会损坏原配置）
+	write := writeConfigFile
+	if !backup {
+		write = writeConfigFileNoBackup
 	}
-
-	// 写入加密数据到文件
-	err = ioutil.WriteFile(filename, []byte(encryptedData), 0600)
-	if err != nil {
+	if err := write(filename, []byte(encryptedData), 0600); err != nil {
 		return fmt.Errorf("无法写入加密服务器管理器文件: %v", err)
 	}
 
@@ -217,7 +361,7 @@ func (ecm *EncryptedConfigManager) LoadEncryptedServerManager(filename string) (
 	// 解密数据
 	plaintext, err := ecm.decrypt(string(encryptedData))
 	if err != nil {
-		return nil, fmt.Errorf("解密服务器管理器失败: %v", err)
+		return nil, fmt.Errorf("解密服务器管理器失败: %w", err)
 	}
 
 	// 反序列化配置
@@ -229,3 +373,65 @@ func (ecm *EncryptedConfigManager) LoadEncryptedServerManager(filename string) (
 
 	return &sm, nil
 }
+
+// ReencryptFile 用旧密码解密配置文件并用新密码重新加密，通过临时文件原子替换原文件，已有的备份同样改用新密码
+// 旧密码错误时返回 ErrWrongPassword，原文件保持不变
+func ReencryptFile(filename, oldPassword, newPassword string) error {
+	encryptedData, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("无法读取加密配置文件: %v", err)
+	}
+
+	plaintext, err := NewEncryptedConfigManager(oldPassword).decrypt(string(encryptedData))
+	if err != nil {
+		if errors.Is(err, ErrWrongPassword) {
+			return ErrWrongPassword
+		}
+		return fmt.Errorf("解密配置失败: %w", err)
+	}
+
+	reencrypted, err := NewEncryptedConfigManager(newPassword).encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("重新加密配置失败: %v", err)
+	}
+
+	// 修改密码不产生新的备份：轮转出的备份仍使用旧密码，之后将无法恢复
+	if err := writeFileAtomic(filename, []byte(reencrypted), 0600); err != nil {
+		return err
+	}
+	reencryptBackups(filename, oldPassword, newPassword)
+	return nil
+}
+
+// reencryptBackups 用新密码重新加密配置文件的滚动备份
+// 明文备份不受密码影响，保持不变；无法用旧密码解密的备份在改密后再也无法恢复，直接删除
+func reencryptBackups(filename, oldPassword, newPassword string) {
+	oldManager := NewEncryptedConfigManager(oldPassword)
+	newManager := NewEncryptedConfigManager(newPassword)
+	for i := 0; i < ConfigBackupCount; i++ {
+		path := BackupPath(filename, i)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		plaintext, err := oldManager.decrypt(string(data))
+		if errors.Is(err, ErrNotEncrypted) {
+			continue
+		}
+		if err == nil {
+			var reencrypted string
+			if reencrypted, err = newManager.encrypt(plaintext); err == nil {
+				err = writeFileAtomic(path, []byte(reencrypted), 0600)
+			}
+			if err == nil {
+				continue
+			}
+		}
+
+		log.Printf("警告: 无法用新密码重新加密备份 %s，已删除: %v", path, err)
+		if err := os.Remove(path); err != nil {
+			log.Printf("警告: 无法删除备份 %s: %v", path, err)
+		}
+	}
+}
@@ -1,13 +1,14 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
-	"syscall"
 	"time"
 
 	"go-term/models"
@@ -26,40 +27,72 @@ func NewEnhancedScriptExecutor() *EnhancedScriptExecutor {
 	}
 }
 
-// ParseCommands 解析普通命令（支持文件操作指令和本地命令）
+// ParseCommands 解析普通命令（支持文件操作指令和本地命令），与 ParseCommandsWithSpecialHandling 相同
 func (ese *EnhancedScriptExecutor) ParseCommands(scriptContent string) []ParsedCommand {
-	rawCommands := ese.scriptParser.ParseCommands(scriptContent)
-	var parsedCommands []ParsedCommand
+	return ese.ParseCommandsWithSpecialHandling(scriptContent)
+}
 
-	for _, cmd := range rawCommands {
-		trimmedCmd := strings.TrimSpace(cmd)
-		parsedCmd := ParsedCommand{}
-
-		// 检查是否是本地命令（以 ! 开头）
-		if ese.scriptParser.IsLocalCommand(trimmedCmd) {
-			parsedCmd.CommandType = "local"
-			parsedCmd.Command = ese.scriptParser.StripLocalCommandPrefix(trimmedCmd)
-		} else if strings.HasPrefix(trimmedCmd, "$upload ") {
-			parsedCmd.CommandType = "upload"
-			parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$upload"))
-		} else if strings.HasPrefix(trimmedCmd, "$download ") {
-			parsedCmd.CommandType = "download"
-			parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$download"))
-		} else {
-			parsedCmd.CommandType = "shell"
-			parsedCmd.Command = cmd
-		}
+// continueOnErrorPrefix 命令前缀，标记命令失败后继续执行之后的命令
+// 只识别写在行首的标记：命令末尾的 $ne 可能是 shell 变量（如 "echo $ne"），按原样交给 shell
+const continueOnErrorPrefix = "$ne "
+
+// ParseCommandsWithSpecialHandling 解析命令并处理特殊前缀：! 本地命令、$upload/$download 文件操作、$cd 切换远程工作目录，
+// 条件块 $if/$else/$endif（语法见 ValidateConditionals），以及 $ne 失败后继续执行，
+// $ne 必须写在命令开头，可以与其他前缀组合使用，如 "$ne !del a.txt"、"$ne $upload a.txt /tmp"
+func (ese *EnhancedScriptExecutor) ParseCommandsWithSpecialHandling(scriptContent string) []ParsedCommand {
+	var parsedCommands []ParsedCommand
 
+	for _, source := range ese.scriptParser.parseSourceCommands(scriptContent) {
+		parsedCmd := ese.parseCommand(source.Text)
+		parsedCmd.Line = source.Line
 		parsedCommands = append(parsedCommands, parsedCmd)
 	}
 
 	return parsedCommands
 }
 
+// parseCommand 解析一条命令的特殊前缀，确定命令类型
+func (ese *EnhancedScriptExecutor) parseCommand(cmd string) ParsedCommand {
+	trimmedCmd := strings.TrimSpace(cmd)
+	parsedCmd := ParsedCommand{}
+
+	// 检查是否标记了失败后继续执行（以 $ne 开头）
+	if strings.HasPrefix(trimmedCmd, continueOnErrorPrefix) {
+		parsedCmd.ContinueOnError = true
+		trimmedCmd = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, continueOnErrorPrefix))
+		cmd = trimmedCmd
+	}
+
+	// 检查是否是本地命令（以 ! 开头）
+	if ese.scriptParser.IsLocalCommand(trimmedCmd) {
+		parsedCmd.CommandType = "local"
+		parsedCmd.Command = ese.scriptParser.StripLocalCommandPrefix(trimmedCmd)
+	} else if strings.HasPrefix(trimmedCmd, "$upload ") {
+		parsedCmd.CommandType = "upload"
+		parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$upload"))
+	} else if strings.HasPrefix(trimmedCmd, "$download ") {
+		parsedCmd.CommandType = "download"
+		parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$download"))
+	} else if strings.HasPrefix(trimmedCmd, "$cd ") {
+		parsedCmd.CommandType = "cd"
+		parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$cd"))
+	} else if directive, arg, ok := parseConditionalDirective(trimmedCmd); ok {
+		parsedCmd.CommandType = directive
+		parsedCmd.Command = arg
+	} else {
+		parsedCmd.CommandType = "shell"
+		parsedCmd.Command = cmd
+	}
+
+	return parsedCmd
+}
+
 // ParsedCommand 解析后的命令
 type ParsedCommand struct {
-	Command     string // 命令内容
-	CommandType string // 命令类型: shell, upload, download
+	Command         string // 命令内容
+	CommandType     string // 命令类型: shell, local, upload, download, cd，以及条件块指令 if, else, endif
+	ContinueOnError bool   // 失败后继续执行之后的命令（$ne 前缀）
+	Line            int    // 命令在脚本中的起始行号（从1开始，跳过的注释、空行和续行都计算在内），0 表示未知
 }
 
 // ExecuteScriptMode 脚本模式执行 - 将整个脚本内容作为一个整体执行
@@ -67,6 +100,16 @@ func (ese *EnhancedScriptExecutor) ExecuteScriptMode(
 	scriptContent string,
 	executor CommandExecutor,
 	serverID string,
+) ([]models.CommandOutput, error) {
+	return ese.ExecuteScriptModeWithOptions(scriptContent, executor, serverID, ExecutionOptions{})
+}
+
+// ExecuteScriptModeWithOptions 按执行选项以脚本模式执行，超时时间和重试作用于整个脚本
+func (ese *EnhancedScriptExecutor) ExecuteScriptModeWithOptions(
+	scriptContent string,
+	executor CommandExecutor,
+	serverID string,
+	options ExecutionOptions,
 ) ([]models.CommandOutput, error) {
 	now := time.Now().Format("2006-01-02 15:04:05")
 
@@ -76,7 +119,7 @@ func (ese *EnhancedScriptExecutor) ExecuteScriptMode(
 	// 如果有特殊操作（文件操作或本地命令），使用命令模式执行所有命令（保持原始顺序）
 	if len(mixedCommands) > 0 {
 		// 使用命令模式执行，这样可以按原始顺序执行所有命令
-		return ese.ExecuteCommandMode(mixedCommands, executor, serverID)
+		return ese.ExecuteCommandModeWithOptions(mixedCommands, executor, serverID, options)
 	}
 
 	// 没有文件操作时，正常执行脚本
@@ -85,26 +128,34 @@ func (ese *EnhancedScriptExecutor) ExecuteScriptMode(
 		Command:   "[完整脚本执行]",
 		Status:    "running",
 		StartTime: now,
+		ExitCode:  -1,
 	}
 
 	// 执行处理后的脚本内容（使用直接执行，不通过终端会话）
-	output, err := executor.ExecCommandDirect(serverID, processedScript)
+	start := time.Now()
+	output, err := ese.runWithRetry(options, &cmdOutput, func() (string, error) {
+		return executor.ExecCommandDirectWithTimeout(serverID, processedScript, options.CommandTimeout)
+	})
 	cmdOutput.EndTime = time.Now().Format("2006-01-02 15:04:05")
+	cmdOutput.ElapsedMs = time.Since(start).Milliseconds()
 	cmdOutput.Output = output
+	cmdOutput.ExitCode = ExitCodeFromError(err)
 
-	if err != nil {
-		cmdOutput.Status = "failed"
-		// 清理错误信息，避免重复包装
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "执行命令失败:") {
-			parts := strings.SplitN(errorMsg, ":", 3)
-			if len(parts) >= 3 {
-				errorMsg = strings.TrimSpace(parts[2])
-			}
+	var timeoutErr *CommandTimeoutError
+	if errors.As(err, &timeoutErr) {
+		cmdOutput.Status = "timeout"
+		cmdOutput.Error = fmt.Sprintf("脚本执行超时（限制 %v，已运行 %.1f秒）", timeoutErr.Timeout, timeoutErr.Elapsed.Seconds())
+		if output == "" {
+			cmdOutput.Output = cmdOutput.Error
+		} else {
+			cmdOutput.Output = fmt.Sprintf("%s\n错误信息: %s", output, cmdOutput.Error)
 		}
+	} else if err != nil {
+		cmdOutput.Status = "failed"
+		errorMsg := failureReason(err, cmdOutput.ExitCode)
 
-		// 尝试从错误信息中提取行号
-		lineInfo := ese.extractLineInfoFromError(errorMsg, scriptContent)
+		// 尝试从输出和错误信息中提取行号（shell 的报错写在输出中）
+		lineInfo := ese.extractLineInfoFromError(output+"\n"+errorMsg, scriptContent)
 		if lineInfo != "" {
 			cmdOutput.Error = fmt.Sprintf("脚本执行失败 (第%s行): %s", lineInfo, errorMsg)
 		} else {
@@ -126,10 +177,26 @@ func (ese *EnhancedScriptExecutor) ExecuteScriptMode(
 		}
 	}
 
+	options.commandDone(cmdOutput)
 	commandOutputs = append(commandOutputs, cmdOutput)
 	return commandOutputs, nil
 }
 
+// failureReason 返回命令失败的原因：以非0退出码结束时给出退出码，
+// 否则给出最内层的错误信息，避免层层包装的错误前缀重复出现
+func failureReason(err error, exitCode int) string {
+	if exitCode > 0 {
+		return fmt.Sprintf("命令退出码 %d", exitCode)
+	}
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err.Error()
+		}
+		err = inner
+	}
+}
+
 // extractLineInfoFromError 从错误信息中提取行号信息
 func (ese *EnhancedScriptExecutor) extractLineInfoFromError(errorMsg, scriptContent string) string {
 	// 常见的错误行号模式匹配
@@ -172,40 +239,36 @@ func (ese *EnhancedScriptExecutor) extractLineInfoFromError(errorMsg, scriptCont
 
 // preprocessScriptForFileOperations 预处理脚本，提取文件操作命令和本地命令
 func (ese *EnhancedScriptExecutor) preprocessScriptForFileOperations(scriptContent string) (string, []ParsedCommand) {
-	// 解析所有命令
-	commands := ese.scriptParser.ParseCommands(scriptContent)
+	// 按原样拆分脚本：本地命令和文件操作命令单独成段，其余内容保持原样（注释、空行、here-document 等不受影响）
+	segments := ese.scriptParser.ParseRaw(scriptContent, func(line string) bool {
+		switch ese.parseCommand(line).CommandType {
+		case "local", "upload", "download":
+			return true
+		}
+		return false
+	})
 	var fileOperations []ParsedCommand
 	var hasSpecialOperations bool
 
 	// 分类命令并按原始顺序创建混合命令列表
 	var mixedCommands []ParsedCommand
 
-	for _, cmd := range commands {
-		trimmedCmd := strings.TrimSpace(cmd)
-		parsedCmd := ParsedCommand{}
-
-		if ese.scriptParser.IsLocalCommand(trimmedCmd) {
-			parsedCmd.CommandType = "local"
-			parsedCmd.Command = ese.scriptParser.StripLocalCommandPrefix(trimmedCmd)
-			mixedCommands = append(mixedCommands, parsedCmd)
-			hasSpecialOperations = true
-		} else if strings.HasPrefix(trimmedCmd, "$upload ") {
-			parsedCmd.CommandType = "upload"
-			parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$upload"))
-			fileOperations = append(fileOperations, parsedCmd)
-			mixedCommands = append(mixedCommands, parsedCmd)
-			hasSpecialOperations = true
-		} else if strings.HasPrefix(trimmedCmd, "$download ") {
-			parsedCmd.CommandType = "download"
-			parsedCmd.Command = strings.TrimSpace(strings.TrimPrefix(trimmedCmd, "$download"))
-			fileOperations = append(fileOperations, parsedCmd)
+	for _, segment := range segments {
+		if segment.Directive {
+			parsedCmd := ese.parseCommand(segment.Text)
+			parsedCmd.Line = segment.Line
+			if parsedCmd.CommandType != "local" {
+				fileOperations = append(fileOperations, parsedCmd)
+			}
 			mixedCommands = append(mixedCommands, parsedCmd)
 			hasSpecialOperations = true
 		} else {
-			// 普通shell命令
-			parsedCmd.CommandType = "shell"
-			parsedCmd.Command = cmd
-			mixedCommands = append(mixedCommands, parsedCmd)
+			// 普通shell片段，作为一条命令原样执行
+			mixedCommands = append(mixedCommands, ParsedCommand{
+				Command:     segment.Text,
+				CommandType: "shell",
+				Line:        segment.Line,
+			})
 		}
 	}
 
@@ -231,9 +294,13 @@ func (ese *EnhancedScriptExecutor) preprocessScriptForFileOperations(scriptConte
 }
 
 // handleUploadCommand 处理文件上传命令
+// 本地路径可以包含通配符（如 /local/logs/*.gz），逐个上传匹配的文件并保留文件名
 func (ese *EnhancedScriptExecutor) handleUploadCommand(executor CommandExecutor, serverID, command string) (string, error) {
-	// 解析命令参数: 本地文件路径 远程保存目录
-	parts := strings.Fields(command)
+	// 解析命令参数: 本地文件路径 远程保存目录（包含空格的路径可以用引号括起来）
+	parts, err := SplitCommandArgs(command)
+	if err != nil {
+		return "", fmt.Errorf("上传命令格式错误: %v", err)
+	}
 	if len(parts) < 2 {
 		return "", fmt.Errorf("上传命令格式错误: $upload 本地文件路径 远程保存目录")
 	}
@@ -241,40 +308,51 @@ func (ese *EnhancedScriptExecutor) handleUploadCommand(executor CommandExecutor,
 	localPath := parts[0]
 	remoteDir := parts[1]
 
-	// 构造远程文件路径
-	// 从本地文件路径中提取文件名
-	localFileName := localPath
-	if idx := strings.LastIndex(localPath, "/"); idx != -1 {
-		localFileName = localPath[idx+1:]
-	} else if idx := strings.LastIndex(localPath, "\\"); idx != -1 {
-		localFileName = localPath[idx+1:]
-	}
-
-	remotePath := remoteDir
-	if !strings.HasSuffix(remoteDir, "/") {
-		remotePath += "/"
+	localPaths := []string{localPath}
+	if hasGlobMeta(localPath) {
+		matches, err := globLocalFiles(localPath)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("没有与 %s 匹配的本地文件", localPath)
+		}
+		localPaths = matches
 	}
-	remotePath += localFileName
 
 	// 确保SFTP客户端已创建
-	err := executor.EnsureSFTPClient(serverID)
+	err = executor.EnsureSFTPClient(serverID)
 	if err != nil {
 		return "", fmt.Errorf("创建SFTP客户端失败: %v", err)
 	}
 
-	// 执行上传操作
-	_, err = executor.ExecUploadFile(serverID, localPath, remotePath)
-	if err != nil {
-		return "", fmt.Errorf("文件上传失败: %v", err)
+	var results []string
+	for _, localFile := range localPaths {
+		// 构造远程文件路径：远程目录 + 本地文件名
+		remotePath := remoteDir
+		if !strings.HasSuffix(remoteDir, "/") {
+			remotePath += "/"
+		}
+		remotePath += localFileName(localFile)
+
+		// 执行上传操作
+		if _, err := executor.ExecUploadFile(serverID, localFile, remotePath); err != nil {
+			return strings.Join(results, "\n"), fmt.Errorf("文件上传失败（%s）: %w", localFile, err)
+		}
+		results = append(results, fmt.Sprintf("文件上传成功: %s -> %s", localFile, remotePath))
 	}
 
-	return fmt.Sprintf("文件上传成功: %s -> %s", localPath, remotePath), nil
+	return strings.Join(results, "\n"), nil
 }
 
 // handleDownloadCommand 处理文件下载命令
+// 远程路径可以包含通配符（如 /var/log/*.gz），此时本地路径为保存目录（不存在时创建），逐个下载匹配的文件并保留文件名
 func (ese *EnhancedScriptExecutor) handleDownloadCommand(executor CommandExecutor, serverID, command string) (string, error) {
-	// 解析命令参数: 远程文件路径 本地保存路径
-	parts := strings.Fields(command)
+	// 解析命令参数: 远程文件路径 本地保存路径（包含空格的路径可以用引号括起来）
+	parts, err := SplitCommandArgs(command)
+	if err != nil {
+		return "", fmt.Errorf("下载命令格式错误: %v", err)
+	}
 	if len(parts) < 2 {
 		return "", fmt.Errorf("下载命令格式错误: $download 远程文件路径 本地保存路径")
 	}
@@ -283,18 +361,70 @@ func (ese *EnhancedScriptExecutor) handleDownloadCommand(executor CommandExecuto
 	localPath := parts[1]
 
 	// 确保SFTP客户端已创建
-	err := executor.EnsureSFTPClient(serverID)
+	err = executor.EnsureSFTPClient(serverID)
 	if err != nil {
 		return "", fmt.Errorf("创建SFTP客户端失败: %v", err)
 	}
 
-	// 执行下载操作
-	_, err = executor.ExecDownloadFile(serverID, remotePath, localPath)
+	if !hasGlobMeta(remotePath) {
+		// 执行下载操作
+		_, err = executor.ExecDownloadFile(serverID, remotePath, localPath)
+		if err != nil {
+			return "", fmt.Errorf("文件下载失败: %w", err)
+		}
+		return fmt.Sprintf("文件下载成功: %s -> %s", remotePath, localPath), nil
+	}
+
+	matches, err := executor.GlobRemoteFiles(serverID, remotePath)
 	if err != nil {
-		return "", fmt.Errorf("文件下载失败: %v", err)
+		return "", err
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("没有与 %s 匹配的远程文件", remotePath)
+	}
+	if err := os.MkdirAll(localPath, 0755); err != nil {
+		return "", fmt.Errorf("无法创建本地目录: %v", err)
+	}
+
+	var results []string
+	for _, remoteFile := range matches {
+		localFile := filepath.Join(localPath, path.Base(remoteFile))
+		if _, err := executor.ExecDownloadFile(serverID, remoteFile, localFile); err != nil {
+			return strings.Join(results, "\n"), fmt.Errorf("文件下载失败（%s）: %w", remoteFile, err)
+		}
+		results = append(results, fmt.Sprintf("文件下载成功: %s -> %s", remoteFile, localFile))
 	}
 
-	return fmt.Sprintf("文件下载成功: %s -> %s", remotePath, localPath), nil
+	return strings.Join(results, "\n"), nil
+}
+
+// hasGlobMeta 检查路径是否包含通配符
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// globLocalFiles 展开本地路径中的通配符，返回匹配的文件（不含目录）
+func globLocalFiles(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("本地文件匹配模式无效: %v", err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && !info.IsDir() {
+			files = append(files, match)
+		}
+	}
+	return files, nil
+}
+
+// localFileName 从本地文件路径中提取文件名，同时支持 / 和 \ 分隔符
+func localFileName(localPath string) string {
+	if idx := strings.LastIndexAny(localPath, "/\\"); idx != -1 {
+		return localPath[idx+1:]
+	}
+	return localPath
 }
 
 // ExecuteCommandMode 命令模式执行 - 逐条执行每个命令
@@ -302,16 +432,54 @@ func (ese *EnhancedScriptExecutor) ExecuteCommandMode(
 	commands []ParsedCommand,
 	executor CommandExecutor,
 	serverID string,
+) ([]models.CommandOutput, error) {
+	return ese.ExecuteCommandModeWithOptions(commands, executor, serverID, ExecutionOptions{})
+}
+
+// ExecuteCommandModeWithOptions 按执行选项以命令模式执行
+// 超时时间限制每条shell命令，超时的命令标记为 timeout；失败的命令按选项重试，
+// 最终失败或超时后，之后的shell命令不再执行，标记为 skipped；标记了 ContinueOnError 的命令失败后继续执行
+// 条件块（$if/$else/$endif）和 $cd 的目录在执行前检查，格式错误时不执行任何命令
+func (ese *EnhancedScriptExecutor) ExecuteCommandModeWithOptions(
+	commands []ParsedCommand,
+	executor CommandExecutor,
+	serverID string,
+	options ExecutionOptions,
+) ([]models.CommandOutput, error) {
+	if err := ValidateConditionals(commands); err != nil {
+		return nil, err
+	}
+	if err := ValidateRemoteWorkDirs(commands); err != nil {
+		return nil, err
+	}
+	state := &commandModeState{lastSuccess: true}
+	return ese.executeCommandBlocks(commands, executor, serverID, options, state)
+}
+
+// executeCommandSegment 执行一段不含条件块的命令：先按顺序执行本地命令和文件操作命令，再在共享session中执行shell命令
+// 执行后把最后一条命令是否成功记录到 state 中；continueLast 为 true 时（该段之后是条件块）最后一条命令失败后不中止执行，
+// 但仍按命令本身是否带 $ne 计入整体结果
+func (ese *EnhancedScriptExecutor) executeCommandSegment(
+	commands []ParsedCommand,
+	executor CommandExecutor,
+	serverID string,
+	options ExecutionOptions,
+	state *commandModeState,
+	continueLast bool,
 ) ([]models.CommandOutput, error) {
 	var commandOutputs []models.CommandOutput
 	now := time.Now().Format("2006-01-02 15:04:05")
+	last := len(commands) - 1
 
 	// 按原始顺序执行所有命令（包括本地命令、文件操作命令和shell命令）
-	for _, parsedCmd := range commands {
+	for i, parsedCmd := range commands {
 		cmdOutput := models.CommandOutput{
-			Command:   parsedCmd.Command,
-			Status:    "running",
-			StartTime: now,
+			Command:         displayCommand(parsedCmd),
+			Status:          "running",
+			StartTime:       now,
+			ExitCode:        -1,
+			ContinueOnError: parsedCmd.ContinueOnError,
+			Line:            parsedCmd.Line,
 		}
 
 		var err error
@@ -320,31 +488,29 @@ func (ese *EnhancedScriptExecutor) ExecuteCommandMode(
 		switch parsedCmd.CommandType {
 		case "local":
 			// 本地命令 - 在本地执行，不发送到服务器
-			output, err = ese.HandleLocalCommand(parsedCmd.Command)
-			cmdOutput.Command = "!" + parsedCmd.Command // 显示时保留前缀
+			output, err = ese.runWithRetry(options, &cmdOutput, func() (string, error) {
+				return ese.HandleLocalCommand(parsedCmd.Command)
+			})
 		case "upload":
-			output, err = ese.handleUploadCommand(executor, serverID, parsedCmd.Command)
-			cmdOutput.Command = "$upload " + parsedCmd.Command // 显示时保留前缀
+			output, err = ese.runWithRetry(options, &cmdOutput, func() (string, error) {
+				return ese.handleUploadCommand(executor, serverID, parsedCmd.Command)
+			})
 		case "download":
-			output, err = ese.handleDownloadCommand(executor, serverID, parsedCmd.Command)
-			cmdOutput.Command = "$download " + parsedCmd.Command // 显示时保留前缀
-		case "shell":
-			// shell命令暂不执行，后续批量处理
+			output, err = ese.runWithRetry(options, &cmdOutput, func() (string, error) {
+				return ese.handleDownloadCommand(executor, serverID, parsedCmd.Command)
+			})
+		case "shell", "cd":
+			// shell命令和 $cd 指令暂不执行，后续批量处理
 			continue
 		}
 
 		cmdOutput.EndTime = time.Now().Format("2006-01-02 15:04:05")
 		cmdOutput.Output = output
+		cmdOutput.ExitCode = ExitCodeFromError(err)
 
 		if err != nil {
 			cmdOutput.Status = "failed"
-			errorMsg := err.Error()
-			if strings.Contains(errorMsg, "执行命令失败:") {
-				parts := strings.SplitN(errorMsg, ":", 3)
-				if len(parts) >= 3 {
-					errorMsg = strings.TrimSpace(parts[2])
-				}
-			}
+			errorMsg := failureReason(err, cmdOutput.ExitCode)
 			switch parsedCmd.CommandType {
 			case "local":
 				cmdOutput.Error = fmt.Sprintf("本地命令执行失败: %s", errorMsg)
@@ -356,66 +522,313 @@ func (ese *EnhancedScriptExecutor) ExecuteCommandMode(
 			if output == "" {
 				cmdOutput.Output = cmdOutput.Error
 			}
-			commandOutputs = append(commandOutputs, cmdOutput)
-			return commandOutputs, fmt.Errorf("命令执行失败")
 		} else {
 			cmdOutput.Status = "success"
-			commandOutputs = append(commandOutputs, cmdOutput)
+		}
+		options.commandDone(cmdOutput)
+		commandOutputs = append(commandOutputs, cmdOutput)
+		if i == last {
+			state.lastSuccess = err == nil
+		}
+		if err != nil && !parsedCmd.ContinueOnError && !(continueLast && i == last) {
+			return commandOutputs, fmt.Errorf("命令执行失败")
 		}
 	}
 
-	// 收集所有shell命令，在一个共享的session中执行
-	var shellCommands []string
-	var shellCommandIndices []int // 记录shell命令在原始commands数组中的索引
-	for i, parsedCmd := range commands {
-		if parsedCmd.CommandType == "shell" {
-			shellCommands = append(shellCommands, parsedCmd.Command)
-			shellCommandIndices = append(shellCommandIndices, i)
+	// 收集所有shell命令（含 $cd 指令），在一个共享的session中执行
+	var shellCommands []ParsedCommand
+	for _, parsedCmd := range commands {
+		if parsedCmd.CommandType == "shell" || parsedCmd.CommandType == "cd" {
+			shellCommands = append(shellCommands, parsedCmd)
 		}
 	}
 
 	// 在一个共享的session中执行所有shell命令
 	if len(shellCommands) > 0 {
-		outputs, err := executor.ExecCommandsInSharedSession(serverID, shellCommands)
+		var scripts []string
+		scripts, state.workDir = remoteCommandScripts(state.workDir, shellCommands)
+		lastIsShell := commands[last].CommandType == "shell" || commands[last].CommandType == "cd"
+		shellOutputs, err := ese.executeSharedShellCommands(executor, serverID, shellCommands, scripts, options, now, continueLast && lastIsShell)
+		commandOutputs = append(commandOutputs, shellOutputs...)
 		if err != nil {
-			// 失败时，为所有shell命令添加失败记录
-			for i, cmd := range shellCommands {
-				cmdOutput := models.CommandOutput{
-					Command:   cmd,
-					Status:    "failed",
-					StartTime: now,
-					EndTime:   time.Now().Format("2006-01-02 15:04:05"),
-				}
-				cmdOutput.Error = err.Error()
-				if i < len(outputs) {
-					cmdOutput.Output = outputs[i]
-				} else {
-					cmdOutput.Output = cmdOutput.Error
-				}
-				commandOutputs = append(commandOutputs, cmdOutput)
-			}
 			return commandOutputs, err
 		}
+		if lastIsShell {
+			state.lastSuccess = shellOutputs[len(shellOutputs)-1].Status == "success"
+		}
+	}
 
-		// 成功时，为每个shell命令添加成功记录
-		// outputs 数组已经包含了每个命令的输出
-		for i, cmd := range shellCommands {
-			cmdOutput := models.CommandOutput{
-				Command:   cmd,
-				Status:    "success",
-				StartTime: now,
-				EndTime:   time.Now().Format("2006-01-02 15:04:05"),
+	return commandOutputs, nil
+}
+
+// executeSharedShellCommands 在共享session中执行shell命令
+// scripts 为每条命令实际执行的内容（见 remoteCommandScripts）。标记了 ContinueOnError 的命令以非0退出码结束时记为失败，
+// 同一个session继续执行下一条命令（不重试）；其他命令失败时session随即结束，失败前已完成的命令记为成功，失败的命令按选项重试：
+// 从失败的命令开始在新的session中继续执行，因此重试时之前命令设置的工作目录（$cd 指令除外）和环境变量不再生效；
+// 标记了 ContinueOnError 的命令超时或session中断时同样从下一条命令开始在新的session中继续执行；
+// continueLast 为 true 时最后一条命令失败后同样不中止执行（用于条件块之前的命令），执行结果中的 ContinueOnError 保持命令本身的标记
+func (ese *EnhancedScriptExecutor) executeSharedShellCommands(
+	executor CommandExecutor,
+	serverID string,
+	commands []ParsedCommand,
+	scripts []string,
+	options ExecutionOptions,
+	startTime string,
+	continueLast bool,
+) ([]models.CommandOutput, error) {
+	results := make([]models.CommandOutput, len(commands))
+	stopOnError := make([]bool, len(commands))
+	for i, cmd := range commands {
+		results[i] = models.CommandOutput{
+			Command:         displayCommand(cmd),
+			Status:          "running",
+			StartTime:       startTime,
+			ExitCode:        -1,
+			ContinueOnError: cmd.ContinueOnError,
+			Line:            cmd.Line,
+		}
+		stopOnError[i] = !cmd.ContinueOnError && !(continueLast && i == len(commands)-1)
+	}
+
+	// failureMessage 命令失败时显示的错误信息
+	failureMessage := func(index int, err error) string {
+		if commands[index].CommandType == "cd" {
+			return fmt.Sprintf("远程目录不存在或无法进入: %s", commands[index].Command)
+		}
+		return err.Error()
+	}
+
+	offset := 0 // 尚未执行的第一条命令
+	for attempt := 1; ; attempt++ {
+		outputs, err := executor.ExecCommandsInSharedSessionWithTimeout(serverID, scripts[offset:], stopOnError[offset:], options.CommandTimeout)
+		end := time.Now().Format("2006-01-02 15:04:05")
+
+		// 依次记录本次执行中已结束（得到退出码）的命令，直到第一条需要停止的失败命令或没有执行完成的命令
+		failed := offset
+		var failure error
+		for ; failed < len(commands); failed++ {
+			i := failed - offset
+			if i >= len(outputs) || outputs[i].ExitCode < 0 {
+				failure = err
+				break
 			}
-			if i < len(outputs) {
-				cmdOutput.Output = outputs[i]
+			if outputs[i].ExitCode > 0 && stopOnError[failed] {
+				failure = &CommandExitError{Command: scripts[failed], ExitCode: outputs[i].ExitCode}
+				break
+			}
+
+			result := &results[failed]
+			result.Attempts++
+			result.EndTime = end
+			result.ExitCode = outputs[i].ExitCode
+			result.Output = outputs[i].Output
+			if result.ExitCode == 0 {
+				result.Status = "success"
 			} else {
-				cmdOutput.Output = "命令执行完成，无输出"
+				// 允许失败的命令，同一个session继续执行下一条命令
+				result.Status = "failed"
+				result.Error = failureMessage(failed, &CommandExitError{Command: scripts[failed], ExitCode: result.ExitCode})
+				if result.Output == "" {
+					result.Output = result.Error
+				}
 			}
-			commandOutputs = append(commandOutputs, cmdOutput)
+			options.commandDone(*result)
+		}
+
+		if failed >= len(commands) {
+			return results, nil
 		}
+		if failure == nil {
+			failure = fmt.Errorf("命令没有执行完成")
+		}
+
+		// 失败的命令
+		result := &results[failed]
+		result.Attempts++
+		result.EndTime = end
+		output := ""
+		if i := failed - offset; i < len(outputs) {
+			output = outputs[i].Output
+			result.ExitCode = outputs[i].ExitCode
+		}
+
+		// 等待重试期间执行被取消时，按最终失败处理
+		if attempt <= options.RetryCount && IsRetryableError(failure, output) && options.waitRetry(attempt) == nil {
+			result.AttemptErrors = append(result.AttemptErrors, failure.Error())
+			offset = failed
+			continue
+		}
+
+		result.Output = output
+		skipReason := "前面的命令执行失败，未执行"
+		var timeoutErr *CommandTimeoutError
+		if errors.As(failure, &timeoutErr) {
+			result.Status = "timeout"
+			result.ElapsedMs = timeoutErr.Elapsed.Milliseconds()
+			result.Error = fmt.Sprintf("命令执行超时（限制 %v，已运行 %.1f秒）", timeoutErr.Timeout, timeoutErr.Elapsed.Seconds())
+			skipReason = "前面的命令执行超时，未执行"
+		} else {
+			result.Status = "failed"
+			result.Error = failureMessage(failed, failure)
+		}
+		if result.Output == "" {
+			result.Output = result.Error
+		}
+		options.commandDone(*result)
+
+		// 允许失败的命令，从下一条命令开始继续执行
+		if !stopOnError[failed] {
+			if failed+1 >= len(commands) {
+				return results, nil
+			}
+			offset = failed + 1
+			attempt = 0
+			continue
+		}
+
+		// 之后的命令没有执行
+		for i := failed + 1; i < len(commands); i++ {
+			results[i].Status = "skipped"
+			results[i].Error = skipReason
+			results[i].Output = skipReason
+			results[i].EndTime = end
+			options.commandDone(results[i])
+		}
+		return results, failure
 	}
+}
 
-	return commandOutputs, nil
+// remoteCommandScripts 生成shell命令实际执行的内容：$cd 指令切换到指定目录，目录不存在时该指令失败，之后的命令不再执行；
+// 之后的shell命令都加上 cd <目录> && 前缀，保证重试或失败后在新的session中继续执行时仍在该目录中
+// workDir 为之前的 $cd 指定的目录，返回每条命令的执行内容和之后的目录
+func remoteCommandScripts(workDir string, commands []ParsedCommand) ([]string, string) {
+	scripts := make([]string, len(commands))
+	for i, cmd := range commands {
+		if cmd.CommandType == "cd" {
+			workDir = resolveRemoteWorkDir(workDir, cmd.Command)
+			scripts[i] = ChangeDirCommand(workDir)
+			continue
+		}
+		if workDir == "" {
+			scripts[i] = cmd.Command
+		} else {
+			scripts[i] = ChangeDirCommand(workDir) + " && " + cmd.Command
+		}
+	}
+	return scripts, workDir
+}
+
+// displayCommand 返回命令在执行结果中显示的内容，保留解析时移除的前缀
+func displayCommand(cmd ParsedCommand) string {
+	display := cmd.Command
+	switch cmd.CommandType {
+	case "local":
+		display = "!" + cmd.Command
+	case "upload":
+		display = "$upload " + cmd.Command
+	case "download":
+		display = "$download " + cmd.Command
+	case "cd":
+		display = "$cd " + cmd.Command
+	}
+	if cmd.ContinueOnError {
+		display = continueOnErrorPrefix + display
+	}
+	return display
+}
+
+// resolveRemoteWorkDir 计算 $cd 之后的远程工作目录，相对路径基于之前 $cd 指定的目录
+// 之前没有 $cd 时不允许相对路径（见 checkRemoteWorkDirs），否则共享session中已进入的目录会被重复拼接
+func resolveRemoteWorkDir(current, dir string) string {
+	if current == "" || isAbsoluteRemoteDir(dir) {
+		return path.Clean(dir)
+	}
+	return path.Join(current, dir)
+}
+
+// isAbsoluteRemoteDir 检查 $cd 的目录是否是绝对路径（以 / 或 ~ 开头）
+func isAbsoluteRemoteDir(dir string) bool {
+	return strings.HasPrefix(dir, "/") || strings.HasPrefix(dir, "~")
+}
+
+// ValidateRemoteWorkDirs 检查 $cd 指令的目录，执行前调用：第一个 $cd 必须使用绝对路径（以 / 或 ~ 开头），
+// 之后的 $cd 可以使用相对于之前目录的路径。条件块中的 $cd 只有在两个分支中都指定了目录时才作为之后的基准目录
+func ValidateRemoteWorkDirs(commands []ParsedCommand) error {
+	index, err := checkRemoteWorkDirs(commands)
+	if err == nil {
+		return nil
+	}
+	if line := commands[index].Line; line > 0 {
+		return fmt.Errorf("第%d行: %v", line, err)
+	}
+	return fmt.Errorf("第%d条命令: %v", index+1, err)
+}
+
+// checkRemoteWorkDirs 检查 $cd 指令的目录，返回出错的命令序号（从0开始）；命令必须已通过条件块检查
+func checkRemoteWorkDirs(commands []ParsedCommand) (int, error) {
+	type branch struct {
+		before   bool // 进入条件块前是否已有基准目录
+		thenBase bool // $if 分支结束时是否已有基准目录
+		hasElse  bool
+	}
+	var open []branch
+	hasBase := false
+	for i, cmd := range commands {
+		switch cmd.CommandType {
+		case "cd":
+			if !hasBase && !isAbsoluteRemoteDir(cmd.Command) {
+				return i, fmt.Errorf("第一个 $cd 需要使用绝对路径（以 / 或 ~ 开头）: %s", cmd.Command)
+			}
+			hasBase = true
+		case "if":
+			open = append(open, branch{before: hasBase})
+		case "else":
+			if n := len(open); n > 0 {
+				open[n-1].thenBase = hasBase
+				open[n-1].hasElse = true
+				hasBase = open[n-1].before
+			}
+		case "endif":
+			if n := len(open); n > 0 {
+				b := open[n-1]
+				open = open[:n-1]
+				if b.hasElse {
+					hasBase = b.thenBase && hasBase
+				} else {
+					hasBase = b.before
+				}
+			}
+		}
+	}
+	return -1, nil
+}
+
+// ChangeDirCommand 生成切换到远程目录的 cd 命令，目录经过转义，以 ~ 开头时展开为用户主目录
+func ChangeDirCommand(dir string) string {
+	if dir == "~" {
+		return `cd "$HOME"`
+	}
+	if strings.HasPrefix(dir, "~/") {
+		return `cd "$HOME"/` + ShellQuote(strings.TrimPrefix(dir, "~/"))
+	}
+	return "cd " + ShellQuote(dir)
+}
+
+// runWithRetry 执行一条命令，失败且可重试时按退避间隔重试，每次失败的错误记录在 cmdOutput 中；
+// 执行选项的上下文取消后不再重试
+func (ese *EnhancedScriptExecutor) runWithRetry(options ExecutionOptions, cmdOutput *models.CommandOutput, run func() (string, error)) (string, error) {
+	for attempt := 1; ; attempt++ {
+		output, err := run()
+		cmdOutput.Attempts = attempt
+		if err == nil || attempt > options.RetryCount || !IsRetryableError(err, output) {
+			return output, err
+		}
+		// 等待重试期间执行被取消时，以本次的错误结束
+		if options.waitRetry(attempt) != nil {
+			return output, err
+		}
+		cmdOutput.AttemptErrors = append(cmdOutput.AttemptErrors, err.Error())
+	}
 }
 
 // HandleLocalCommand 处理本地命令
@@ -463,11 +876,11 @@ func (ese *EnhancedScriptExecutor) HandleLocalCommand(command string) (string, e
 	}
 
 	// 设置隐藏窗口属性，避免执行命令时弹出终端窗口
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	hideWindow(cmd)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return string(output), fmt.Errorf("执行本地命令失败: %v", err)
+		return string(output), fmt.Errorf("执行本地命令失败: %w", err)
 	}
 	return string(output), nil
 }
@@ -486,7 +899,12 @@ type CommandExecutor interface {
 	ExecCommand(serverID, command string) (string, error)
 	ExecUploadFile(serverID, localPath, remotePath string) (string, error)
 	ExecDownloadFile(serverID, remotePath, localPath string) (string, error)
-	EnsureSFTPClient(serverID string) error                                           // 确保SFTP客户端已创建
-	ExecCommandDirect(serverID, command string) (string, error)                       // 直接执行命令（不通过终端会话）
-	ExecCommandsInSharedSession(serverID string, commands []string) ([]string, error) // 在同一个session中执行多个命令
+	EnsureSFTPClient(serverID string) error                                                  // 确保SFTP客户端已创建
+	GlobRemoteFiles(serverID, pattern string) ([]string, error)                              // 展开远程路径中的通配符，返回匹配的文件
+	ExecCommandDirect(serverID, command string) (string, error)                              // 直接执行命令（不通过终端会话）
+	ExecCommandsInSharedSession(serverID string, commands []string) ([]CommandResult, error) // 在同一个session中执行多个命令，返回每个命令的输出和退出码
+	// 与上面两个方法相同，但限制执行时间（共享session中为每条命令的时间），超时返回 *CommandTimeoutError；timeout 为0时不限制
+	// 共享session中 stopOnError 与 commands 一一对应，为 true 的命令失败后不再执行之后的命令，其余命令失败后继续执行
+	ExecCommandDirectWithTimeout(serverID, command string, timeout time.Duration) (string, error)
+	ExecCommandsInSharedSessionWithTimeout(serverID string, commands []string, stopOnError []bool, timeout time.Duration) ([]CommandResult, error)
 }
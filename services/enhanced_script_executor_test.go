@@ -0,0 +1,67 @@
+package services
+
+import "testing"
+
+func TestParseCommandContinueOnError(t *testing.T) {
+	tests := []struct {
+		line            string
+		wantType        string
+		wantCommand     string
+		continueOnError bool
+	}{
+		{"$ne invalid_command", "shell", "invalid_command", true},
+		{"$ne !del a.txt", "local", "del a.txt", true},
+		{"$ne $upload a.txt /tmp", "upload", "a.txt /tmp", true},
+		// 命令末尾的 $ne 是 shell 变量，不是标记
+		{"echo $ne", "shell", "echo $ne", false},
+		{"rm -rf $dir/$ne", "shell", "rm -rf $dir/$ne", false},
+		{"$upload a.txt $ne", "upload", "a.txt $ne", false},
+		{"echo ok", "shell", "echo ok", false},
+	}
+
+	ese := NewEnhancedScriptExecutor()
+	for _, tt := range tests {
+		got := ese.parseCommand(tt.line)
+		if got.CommandType != tt.wantType || got.Command != tt.wantCommand || got.ContinueOnError != tt.continueOnError {
+			t.Errorf("parseCommand(%q) = {%s %q %v}, 期望 {%s %q %v}", tt.line,
+				got.CommandType, got.Command, got.ContinueOnError, tt.wantType, tt.wantCommand, tt.continueOnError)
+		}
+	}
+}
+
+func TestCheckRemoteWorkDirs(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int // 出错的命令序号，-1 表示没有问题
+	}{
+		{"absolute then relative", "$cd /srv\n$cd app\nls", -1},
+		{"home then relative", "$cd ~\n$cd app", -1},
+		{"relative first", "ls\n$cd app", 1},
+		{"base set in both branches", "true\n$if success\n$cd /a\n$else\n$cd /b\n$endif\n$cd sub", -1},
+		{"base set in one branch only", "true\n$if success\n$cd /a\n$endif\n$cd sub", 4},
+		{"base set in then branch only", "true\n$if success\n$cd /a\n$else\nls\n$endif\n$cd sub", 6},
+		{"relative inside branch after base", "$cd /srv\ntrue\n$if failure\n$cd app\n$endif", -1},
+	}
+
+	ese := NewEnhancedScriptExecutor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands := ese.ParseCommandsWithSpecialHandling(tt.script)
+			index, err := checkRemoteWorkDirs(commands)
+			if index != tt.want || (err != nil) != (tt.want >= 0) {
+				t.Fatalf("checkRemoteWorkDirs = %d, %v, 期望 %d", index, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandModeRejectsRelativeFirstCd(t *testing.T) {
+	ese := NewEnhancedScriptExecutor()
+	commands := ese.ParseCommandsWithSpecialHandling("echo start\n$cd app\nls")
+	// 检查失败时不会执行任何命令，执行器不会被调用
+	outputs, err := ese.ExecuteCommandMode(commands, nil, "s1")
+	if err == nil || len(outputs) != 0 {
+		t.Fatalf("第一个 $cd 使用相对路径时应拒绝执行: %v, %v", outputs, err)
+	}
+}
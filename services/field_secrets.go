@@ -0,0 +1,151 @@
+package services
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go-term/models"
+)
+
+// encryptedFieldPrefix 明文配置中已加密字段的前缀，其后为与加密配置文件相同格式的密文
+const encryptedFieldPrefix = "enc:"
+
+// secretCipher 明文配置文件中敏感字段（密码、私钥、代理密码）的字段级加密
+// 所有字段共用一个文件头和密钥，每个字段使用独立的随机IV，避免每个字段、每次保存都重新派生密钥
+type secretCipher struct {
+	ecm    *EncryptedConfigManager
+	header []byte
+	key    []byte
+	keys   map[string][]byte // 解密时按文件头缓存派生的密钥
+	mutex  sync.Mutex
+}
+
+// newSecretCipher 创建字段加密器
+func newSecretCipher(password string) *secretCipher {
+	return &secretCipher{
+		ecm:  NewEncryptedConfigManager(password),
+		keys: make(map[string][]byte),
+	}
+}
+
+// encryptField 加密字段值，空值和已加密的值保持不变
+func (c *secretCipher) encryptField(value string) (string, error) {
+	if value == "" || IsEncryptedField(value) {
+		return value, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.key == nil {
+		header, key, err := c.ecm.newHeader()
+		if err != nil {
+			return "", err
+		}
+		c.header, c.key = header, key
+	}
+
+	sealed, err := seal(c.header, c.key, []byte(value))
+	if err != nil {
+		return "", err
+	}
+	return encryptedFieldPrefix + sealed, nil
+}
+
+// decryptField 解密字段值，没有加密前缀的值原样返回
+func (c *secretCipher) decryptField(value string) (string, error) {
+	if !IsEncryptedField(value) {
+		return value, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedFieldPrefix))
+	if err != nil {
+		return "", ErrCorruptedData
+	}
+	header, spec, ok, err := parseEncryptedHeader(data)
+	if err != nil {
+		return "", err
+	}
+	if !ok || len(header) != encryptedHeaderSize {
+		return "", ErrCorruptedData
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	key, cached := c.keys[string(header)]
+	if !cached {
+		salt := header[encryptedHeaderSize1-encryptedSaltSize : encryptedHeaderSize1]
+		key, err = spec.deriveKey(c.ecm.password, salt)
+		if err != nil {
+			return "", err
+		}
+		if !hmac.Equal(header[encryptedHeaderSize1:], passwordCheck(key)) {
+			return "", ErrWrongPassword
+		}
+		c.keys[string(header)] = key
+	}
+
+	plaintext, err := open(key, data[len(header):], header)
+	if err != nil {
+		return "", ErrCorruptedData
+	}
+	return string(plaintext), nil
+}
+
+// IsEncryptedField 判断字段值是否为字段级加密的密文
+func IsEncryptedField(value string) bool {
+	return strings.HasPrefix(value, encryptedFieldPrefix)
+}
+
+// encryptServerSecrets 返回敏感字段已加密的分组副本
+func encryptServerSecrets(groups []models.ServerGroup, c *secretCipher) ([]models.ServerGroup, error) {
+	result := cloneGroups(groups)
+	for i := range result {
+		for j := range result[i].Servers {
+			server := &result[i].Servers[j]
+			var err error
+			if server.Password, err = c.encryptField(server.Password); err != nil {
+				return nil, fmt.Errorf("加密服务器 %s 的密码失败: %v", server.Name, err)
+			}
+			if server.KeyData, err = c.encryptField(server.KeyData); err != nil {
+				return nil, fmt.Errorf("加密服务器 %s 的私钥失败: %v", server.Name, err)
+			}
+			if server.ProxySOCKS5 != nil {
+				if server.ProxySOCKS5.Password, err = c.encryptField(server.ProxySOCKS5.Password); err != nil {
+					return nil, fmt.Errorf("加密服务器 %s 的代理密码失败: %v", server.Name, err)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// decryptServerSecrets 就地解密分组中字段级加密的敏感字段
+// 存在加密字段但 c 为 nil（未设置密码）时返回 ErrWrongPassword
+func decryptServerSecrets(groups []models.ServerGroup, c *secretCipher) error {
+	for i := range groups {
+		for j := range groups[i].Servers {
+			server := &groups[i].Servers[j]
+			fields := []*string{&server.Password, &server.KeyData}
+			if server.ProxySOCKS5 != nil {
+				fields = append(fields, &server.ProxySOCKS5.Password)
+			}
+			for _, field := range fields {
+				if !IsEncryptedField(*field) {
+					continue
+				}
+				if c == nil {
+					return fmt.Errorf("服务器 %s 的认证信息已加密，需要主密码: %w", server.Name, ErrWrongPassword)
+				}
+				value, err := c.decryptField(*field)
+				if err != nil {
+					return fmt.Errorf("解密服务器 %s 的认证信息失败: %w", server.Name, err)
+				}
+				*field = value
+			}
+		}
+	}
+	return nil
+}
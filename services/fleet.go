@@ -0,0 +1,106 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"go-term/models"
+)
+
+// ValueCount 聚合结果中的一个取值
+type ValueCount struct {
+	Value     string   `json:"value"`     // 输出值（去除首尾空白）
+	Count     int      `json:"count"`     // 出现次数
+	ServerIDs []string `json:"serverIds"` // 输出该值的服务器
+}
+
+// AggregateStats 跨服务器执行结果的统计
+type AggregateStats struct {
+	Total     int          `json:"total"`     // 目标服务器数量
+	Succeeded int          `json:"succeeded"` // 执行成功数量
+	Failed    int          `json:"failed"`    // 执行失败数量
+	Values    []ValueCount `json:"values"`    // 不同取值的直方图，按出现次数降序
+}
+
+// FleetCollectResult 跨服务器执行命令的结果
+type FleetCollectResult struct {
+	Outputs map[string]string `json:"outputs"` // 服务器ID -> 输出
+	Errors  map[string]string `json:"errors"`  // 服务器ID -> 错误信息
+	Stats   AggregateStats    `json:"stats"`
+}
+
+// SelectServers 根据选择条件返回匹配的服务器（按配置顺序，去重）
+func (sm *ServerManager) SelectServers(selector models.ServerSelector) []models.Server {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	selectAll := len(selector.GroupIDs) == 0 && len(selector.ServerIDs) == 0 && len(selector.Tags) == 0
+
+	groupSet := make(map[string]bool)
+	for _, id := range selector.GroupIDs {
+		groupSet[id] = true
+	}
+	serverSet := make(map[string]bool)
+	for _, id := range selector.ServerIDs {
+		serverSet[id] = true
+	}
+
+	var result []models.Server
+	seen := make(map[string]bool)
+	for _, group := range sm.Groups {
+		for _, server := range group.Servers {
+			if seen[server.ID] {
+				continue
+			}
+			if selectAll || groupSet[group.ID] || serverSet[server.ID] || hasAnyTag(server, selector.Tags) {
+				seen[server.ID] = true
+				result = append(result, cloneServer(server))
+			}
+		}
+	}
+	return result
+}
+
+// hasAnyTag 判断服务器是否带有 tags 中的任一标签（不区分大小写）
+func hasAnyTag(server models.Server, tags []string) bool {
+	for _, tag := range tags {
+		if hasTag(server, strings.TrimSpace(tag)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateOutputs 统计各服务器输出的不同取值
+func AggregateOutputs(outputs map[string]string, errs map[string]string) AggregateStats {
+	stats := AggregateStats{
+		Succeeded: len(outputs),
+		Failed:    len(errs),
+		Total:     len(outputs) + len(errs),
+	}
+
+	index := make(map[string]int)
+	for serverID, output := range outputs {
+		value := strings.TrimSpace(output)
+		i, ok := index[value]
+		if !ok {
+			i = len(stats.Values)
+			index[value] = i
+			stats.Values = append(stats.Values, ValueCount{Value: value})
+		}
+		stats.Values[i].Count++
+		stats.Values[i].ServerIDs = append(stats.Values[i].ServerIDs, serverID)
+	}
+
+	for i := range stats.Values {
+		sort.Strings(stats.Values[i].ServerIDs)
+	}
+	sort.Slice(stats.Values, func(i, j int) bool {
+		if stats.Values[i].Count != stats.Values[j].Count {
+			return stats.Values[i].Count > stats.Values[j].Count
+		}
+		return stats.Values[i].Value < stats.Values[j].Value
+	})
+
+	return stats
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 健康检查间隔的默认值和下限
+const (
+	DefaultHealthCheckInterval = 60 * time.Second
+	MinHealthCheckInterval     = 5 * time.Second
+)
+
+// HealthStatus 服务器应用层健康检查结果
+type HealthStatus struct {
+	ServerID  string `json:"serverId"`
+	Healthy   bool   `json:"healthy"`   // 健康检查命令退出码为0
+	ExitCode  int    `json:"exitCode"`  // 退出码，无法获取时为 -1
+	Output    string `json:"output"`    // 命令输出（截断）
+	Error     string `json:"error"`     // 执行错误
+	CheckedAt string `json:"checkedAt"` // 检查时间
+}
+
+// maxHealthOutputLen 健康检查结果中保留的最大输出长度
+const maxHealthOutputLen = 1024
+
+// ExitCodeFromError 从命令执行错误中提取退出码：成功为0，非退出码类错误为 -1
+func ExitCodeFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus()
+	}
+	var commandExit *CommandExitError
+	if errors.As(err, &commandExit) {
+		return commandExit.ExitCode
+	}
+	var execExit *exec.ExitError
+	if errors.As(err, &execExit) {
+		return execExit.ExitCode()
+	}
+	return -1
+}
+
+// HealthCheckInterval 根据配置的秒数返回检查间隔
+func HealthCheckInterval(seconds int) time.Duration {
+	if seconds <= 0 {
+		return DefaultHealthCheckInterval
+	}
+	interval := time.Duration(seconds) * time.Second
+	if interval < MinHealthCheckInterval {
+		return MinHealthCheckInterval
+	}
+	return interval
+}
+
+// RunHealthCheck 执行健康检查命令（应用命令包装）
+func (s *SSHConnection) RunHealthCheck(serverID, command string) HealthStatus {
+	output, err := s.ExecuteCommand(command)
+
+	status := HealthStatus{
+		ServerID:  serverID,
+		ExitCode:  ExitCodeFromError(err),
+		Output:    strings.TrimSpace(output),
+		CheckedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if len(status.Output) > maxHealthOutputLen {
+		status.Output = status.Output[:maxHealthOutputLen]
+	}
+	status.Healthy = status.ExitCode == 0
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
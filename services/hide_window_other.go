@@ -0,0 +1,8 @@
+//go:build !windows
+
+package services
+
+import "os/exec"
+
+// hideWindow 非 Windows 平台执行命令不会弹出窗口，无需处理
+func hideWindow(cmd *exec.Cmd) {}
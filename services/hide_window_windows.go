@@ -0,0 +1,13 @@
+//go:build windows
+
+package services
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow 设置隐藏窗口属性，避免执行本地命令时弹出终端窗口
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
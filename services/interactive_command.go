@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// InteractiveCommand 在独立会话中运行的非终端交互式命令（不分配 PTY），
+// 可以向标准输入写入数据（如回答 apt 的确认或 mysql -p 的密码提示）并等待命令结束
+type InteractiveCommand struct {
+	session *ssh.Session
+	stdin   io.WriteCloser
+
+	stdinMutex sync.Mutex
+	done       chan struct{}
+	err        error
+}
+
+// StartInteractiveCommand 启动交互式命令，stdout 和 stderr 合并后通过 onOutput 增量回调
+// 交互式命令通常在等待输入，因此不受 CommandTimeout 限制，需要由调用方 Wait 或 Kill
+func (s *SSHConnection) StartInteractiveCommand(command string, onOutput func([]byte)) (*InteractiveCommand, error) {
+	if s.Client == nil {
+		return nil, fmt.Errorf("SSH连接未建立")
+	}
+
+	session, err := s.newSessionContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("无法获取标准输入: %v", err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("无法获取标准输出: %v", err)
+	}
+	stderr, err := session.StderrPipe()
+	if err != nil {
+		session.Close()
+		return nil, fmt.Errorf("无法获取错误输出: %v", err)
+	}
+
+	if err := session.Start(WrapCommand(s.CommandWrapper, command)); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("启动命令失败: %v", err)
+	}
+
+	ic := &InteractiveCommand{
+		session: session,
+		stdin:   stdin,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		pumpOutput(onOutput, stdout, stderr)
+		if err := session.Wait(); err != nil {
+			ic.err = fmt.Errorf("执行命令失败: %w", err)
+		}
+		session.Close()
+		close(ic.done)
+	}()
+
+	return ic, nil
+}
+
+// WriteStdin 向命令的标准输入写入数据，命令已结束时返回错误
+func (ic *InteractiveCommand) WriteStdin(data []byte) error {
+	ic.stdinMutex.Lock()
+	defer ic.stdinMutex.Unlock()
+
+	select {
+	case <-ic.done:
+		return fmt.Errorf("命令已结束")
+	default:
+	}
+
+	if _, err := ic.stdin.Write(data); err != nil {
+		return fmt.Errorf("写入标准输入失败: %v", err)
+	}
+	return nil
+}
+
+// CloseStdin 关闭标准输入，向命令发送 EOF
+func (ic *InteractiveCommand) CloseStdin() error {
+	ic.stdinMutex.Lock()
+	defer ic.stdinMutex.Unlock()
+
+	if err := ic.stdin.Close(); err != nil {
+		return fmt.Errorf("关闭标准输入失败: %v", err)
+	}
+	return nil
+}
+
+// Done 返回命令结束时关闭的通道
+func (ic *InteractiveCommand) Done() <-chan struct{} {
+	return ic.done
+}
+
+// Wait 等待命令结束，返回执行错误（非0退出码时可通过 ExitCodeFromError 获取退出码）
+func (ic *InteractiveCommand) Wait() error {
+	<-ic.done
+	return ic.err
+}
+
+// Kill 强制结束命令
+func (ic *InteractiveCommand) Kill() {
+	select {
+	case <-ic.done:
+		return
+	default:
+	}
+	_ = ic.session.Signal(ssh.SIGKILL)
+	_ = ic.session.Close()
+}
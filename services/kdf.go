@@ -0,0 +1,171 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// 支持的密钥派生算法
+const (
+	KDFScrypt   = "scrypt"
+	KDFArgon2id = "argon2id"
+)
+
+// DefaultKDF 新加密文件默认使用的密钥派生算法，解密时始终使用文件头中记录的算法
+var DefaultKDF = KDFArgon2id
+
+// 文件头中的密钥派生算法标识
+const (
+	kdfIDScrypt   = 1
+	kdfIDArgon2id = 2
+)
+
+// derivedKeySize 派生密钥长度（AES-256）
+const derivedKeySize = 32
+
+// ScryptParams scrypt 密钥派生参数
+type ScryptParams struct {
+	N int `json:"n"` // CPU/内存开销，必须是大于1的2的幂
+	R int `json:"r"` // 块大小
+	P int `json:"p"` // 并行度
+}
+
+// DefaultScryptParams 新加密文件默认使用的scrypt参数，提高参数不影响已有文件的解密
+var DefaultScryptParams = ScryptParams{N: 32768, R: 8, P: 1}
+
+// legacyScryptParams 没有文件头的旧格式使用的固定参数
+var legacyScryptParams = ScryptParams{N: 32768, R: 8, P: 1}
+
+// scrypt 参数上限，防止被篡改的文件头导致解密时占用过多内存
+const (
+	maxScryptN = 1 << 22
+	maxScryptR = 32
+	maxScryptP = 16
+)
+
+// validate 校验scrypt参数
+func (p ScryptParams) validate() error {
+	if p.N <= 1 || p.N&(p.N-1) != 0 || p.N > maxScryptN {
+		return fmt.Errorf("无效的scrypt参数N: %d（需为2的幂且不超过%d）", p.N, maxScryptN)
+	}
+	if p.R <= 0 || p.R > maxScryptR {
+		return fmt.Errorf("无效的scrypt参数r: %d", p.R)
+	}
+	if p.P <= 0 || p.P > maxScryptP {
+		return fmt.Errorf("无效的scrypt参数p: %d", p.P)
+	}
+	return nil
+}
+
+// Argon2Params Argon2id 密钥派生参数
+type Argon2Params struct {
+	Time    uint32 `json:"time"`    // 迭代次数
+	Memory  uint32 `json:"memory"`  // 内存开销（KiB）
+	Threads uint8  `json:"threads"` // 并行度
+}
+
+// DefaultArgon2Params 新加密文件默认使用的Argon2id参数（RFC 9106 推荐的低内存配置）
+var DefaultArgon2Params = Argon2Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// Argon2id 参数上限，防止被篡改的文件头导致解密时占用过多资源
+const (
+	maxArgon2Time   = 64
+	maxArgon2Memory = 1024 * 1024 // 1GiB
+)
+
+// validate 校验Argon2id参数
+func (p Argon2Params) validate() error {
+	if p.Time == 0 || p.Time > maxArgon2Time {
+		return fmt.Errorf("无效的Argon2参数time: %d", p.Time)
+	}
+	if p.Threads == 0 {
+		return fmt.Errorf("无效的Argon2参数threads: %d", p.Threads)
+	}
+	// Argon2 要求内存至少为 8*threads KiB
+	if p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2Memory {
+		return fmt.Errorf("无效的Argon2参数memory: %d KiB", p.Memory)
+	}
+	return nil
+}
+
+// kdfSpec 一次加密使用的密钥派生算法及参数
+type kdfSpec struct {
+	id     byte
+	scrypt ScryptParams
+	argon2 Argon2Params
+}
+
+// deriveKey 从密码派生密钥
+func (k kdfSpec) deriveKey(password, salt []byte) ([]byte, error) {
+	switch k.id {
+	case kdfIDScrypt:
+		return scrypt.Key(password, salt, k.scrypt.N, k.scrypt.R, k.scrypt.P, derivedKeySize)
+	case kdfIDArgon2id:
+		return argon2.IDKey(password, salt, k.argon2.Time, k.argon2.Memory, k.argon2.Threads, derivedKeySize), nil
+	default:
+		return nil, fmt.Errorf("不支持的密钥派生算法: %d", k.id)
+	}
+}
+
+// validate 校验算法参数
+func (k kdfSpec) validate() error {
+	switch k.id {
+	case kdfIDScrypt:
+		return k.scrypt.validate()
+	case kdfIDArgon2id:
+		return k.argon2.validate()
+	default:
+		return fmt.Errorf("不支持的密钥派生算法: %d", k.id)
+	}
+}
+
+// encodeParams 将参数写入文件头的参数区（3个大端 uint32）
+func (k kdfSpec) encodeParams(dst []byte) {
+	switch k.id {
+	case kdfIDScrypt:
+		binary.BigEndian.PutUint32(dst[0:4], uint32(k.scrypt.N))
+		binary.BigEndian.PutUint32(dst[4:8], uint32(k.scrypt.R))
+		binary.BigEndian.PutUint32(dst[8:12], uint32(k.scrypt.P))
+	case kdfIDArgon2id:
+		binary.BigEndian.PutUint32(dst[0:4], k.argon2.Time)
+		binary.BigEndian.PutUint32(dst[4:8], k.argon2.Memory)
+		binary.BigEndian.PutUint32(dst[8:12], uint32(k.argon2.Threads))
+	}
+}
+
+// decodeKDFSpec 从文件头的算法标识和参数区解析并校验密钥派生参数
+func decodeKDFSpec(id byte, params []byte) (kdfSpec, error) {
+	a := binary.BigEndian.Uint32(params[0:4])
+	b := binary.BigEndian.Uint32(params[4:8])
+	c := binary.BigEndian.Uint32(params[8:12])
+
+	spec := kdfSpec{id: id}
+	switch id {
+	case kdfIDScrypt:
+		if a > maxScryptN || b > maxScryptR || c > maxScryptP {
+			return spec, fmt.Errorf("无效的scrypt参数")
+		}
+		spec.scrypt = ScryptParams{N: int(a), R: int(b), P: int(c)}
+	case kdfIDArgon2id:
+		if c > 255 {
+			return spec, fmt.Errorf("无效的Argon2参数threads: %d", c)
+		}
+		spec.argon2 = Argon2Params{Time: a, Memory: b, Threads: uint8(c)}
+	}
+	return spec, spec.validate()
+}
+
+// kdfID 将算法名称转换为文件头中的标识
+func kdfID(name string) (byte, error) {
+	switch name {
+	case KDFScrypt:
+		return kdfIDScrypt, nil
+	case KDFArgon2id:
+		return kdfIDArgon2id, nil
+	default:
+		return 0, fmt.Errorf("不支持的密钥派生算法: %s", name)
+	}
+}
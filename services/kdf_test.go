@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// 测试用的低开销参数，避免测试占用过多时间和内存
+var (
+	testScryptParams = ScryptParams{N: 1024, R: 8, P: 1}
+	testArgon2Params = Argon2Params{Time: 1, Memory: 64, Threads: 1}
+)
+
+// newTestEncryptedConfigManager 创建使用指定算法和低开销参数的加密管理器
+func newTestEncryptedConfigManager(t *testing.T, password, kdf string) *EncryptedConfigManager {
+	t.Helper()
+	ecm := NewEncryptedConfigManager(password)
+	var err error
+	switch kdf {
+	case KDFScrypt:
+		err = ecm.SetScryptParams(testScryptParams)
+	case KDFArgon2id:
+		err = ecm.SetArgon2Params(testArgon2Params)
+	}
+	if err != nil {
+		t.Fatalf("设置密钥派生参数失败: %v", err)
+	}
+	return ecm
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	plaintext := []byte(`{"groups":[{"id":"g1","servers":[{"password":"secret"}]}]}`)
+	for _, kdf := range []string{KDFScrypt, KDFArgon2id} {
+		t.Run(kdf, func(t *testing.T) {
+			encrypted, err := newTestEncryptedConfigManager(t, "pass", kdf).encrypt(plaintext)
+			if err != nil {
+				t.Fatalf("加密失败: %v", err)
+			}
+
+			// 解密时使用文件头中记录的算法，与解密方自己的设置无关
+			for _, other := range []string{KDFScrypt, KDFArgon2id} {
+				got, err := newTestEncryptedConfigManager(t, "pass", other).decrypt(encrypted)
+				if err != nil {
+					t.Fatalf("加密设置为 %s 的管理器解密失败: %v", other, err)
+				}
+				if string(got) != string(plaintext) {
+					t.Fatalf("解密结果为 %q", got)
+				}
+			}
+
+			if _, err := NewEncryptedConfigManager("wrong").decrypt(encrypted); !errors.Is(err, ErrWrongPassword) {
+				t.Fatalf("密码错误时错误为 %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptUsesSelectedKDF(t *testing.T) {
+	tests := []struct {
+		kdf    string
+		id     byte
+		params [3]uint32
+	}{
+		{KDFScrypt, kdfIDScrypt, [3]uint32{1024, 8, 1}},
+		{KDFArgon2id, kdfIDArgon2id, [3]uint32{1, 64, 1}},
+	}
+	for _, tt := range tests {
+		encrypted, err := newTestEncryptedConfigManager(t, "pass", tt.kdf).encrypt([]byte("data"))
+		if err != nil {
+			t.Fatalf("加密失败: %v", err)
+		}
+		data, _ := base64.StdEncoding.DecodeString(encrypted)
+		if data[5] != tt.id {
+			t.Fatalf("%s 加密的文件头算法标识为 %d, 期望 %d", tt.kdf, data[5], tt.id)
+		}
+		for i, want := range tt.params {
+			if got := binary.BigEndian.Uint32(data[6+4*i:]); got != want {
+				t.Fatalf("%s 加密的文件头第 %d 个参数为 %d, 期望 %d", tt.kdf, i+1, got, want)
+			}
+		}
+	}
+}
+
+func TestDecryptRejectsSwappedKDF(t *testing.T) {
+	// 把 scrypt 文件头改为 Argon2id（参数对两种算法都合法），不能被当作另一种算法解出数据
+	encrypted, err := newTestEncryptedConfigManager(t, "pass", KDFScrypt).encrypt([]byte("data"))
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	data, _ := base64.StdEncoding.DecodeString(encrypted)
+
+	swapped := append([]byte(nil), data...)
+	swapped[5] = kdfIDArgon2id
+	binary.BigEndian.PutUint32(swapped[6:], 1)
+	binary.BigEndian.PutUint32(swapped[10:], 64)
+	binary.BigEndian.PutUint32(swapped[14:], 1)
+	if _, err := NewEncryptedConfigManager("pass").decrypt(base64.StdEncoding.EncodeToString(swapped)); err == nil {
+		t.Fatal("更换密钥派生算法后仍然解密成功")
+	}
+
+	// 版本1没有密码校验值，文件头同样作为附加数据参与认证
+	v1 := append([]byte(nil), swapped[:encryptedHeaderSize1]...)
+	v1[4] = 1
+	v1 = append(v1, data[encryptedHeaderSize:]...)
+	if _, err := NewEncryptedConfigManager("pass").decrypt(base64.StdEncoding.EncodeToString(v1)); err == nil {
+		t.Fatal("版本1文件头被篡改后仍然解密成功")
+	}
+
+	// 未知算法或超出上限的参数视为损坏
+	unknown := append([]byte(nil), data...)
+	unknown[5] = 9
+	if _, err := NewEncryptedConfigManager("pass").decrypt(base64.StdEncoding.EncodeToString(unknown)); !errors.Is(err, ErrCorruptedData) {
+		t.Fatalf("未知算法时错误为 %v", err)
+	}
+	huge := append([]byte(nil), data...)
+	binary.BigEndian.PutUint32(huge[6:], 1<<30)
+	if _, err := NewEncryptedConfigManager("pass").decrypt(base64.StdEncoding.EncodeToString(huge)); !errors.Is(err, ErrCorruptedData) {
+		t.Fatalf("参数超出上限时错误为 %v", err)
+	}
+}
+
+func TestDecryptLegacyFormat(t *testing.T) {
+	// 旧格式：salt(16) | nonce | ciphertext，使用固定的scrypt参数，没有附加数据
+	salt := []byte("0123456789abcdef")
+	key, err := kdfSpec{id: kdfIDScrypt, scrypt: legacyScryptParams}.deriveKey([]byte("pass"), salt)
+	if err != nil {
+		t.Fatalf("派生密钥失败: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		t.Fatalf("创建加密器失败: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	data := append(append(append([]byte(nil), salt...), nonce...), gcm.Seal(nil, nonce, []byte("legacy"), nil)...)
+	sealed := base64.StdEncoding.EncodeToString(data)
+
+	got, err := NewEncryptedConfigManager("pass").decrypt(sealed)
+	if err != nil || string(got) != "legacy" {
+		t.Fatalf("解密旧格式得到 %q, %v", got, err)
+	}
+	if _, err := NewEncryptedConfigManager("wrong").decrypt(sealed); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("旧格式密码错误时错误为 %v", err)
+	}
+}
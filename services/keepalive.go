@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"time"
+)
+
+// 连接保活的默认间隔、下限和单次请求的超时
+const (
+	DefaultKeepAliveInterval = 30 * time.Second
+	MinKeepAliveInterval     = 5 * time.Second
+	KeepAliveTimeout         = 15 * time.Second
+)
+
+// KeepAliveInterval 根据配置的秒数返回保活间隔：0 使用默认值，负数表示禁用（返回0）
+func KeepAliveInterval(seconds int) time.Duration {
+	if seconds < 0 {
+		return 0
+	}
+	if seconds == 0 {
+		return DefaultKeepAliveInterval
+	}
+	interval := time.Duration(seconds) * time.Second
+	if interval < MinKeepAliveInterval {
+		return MinKeepAliveInterval
+	}
+	return interval
+}
+
+// SendKeepAlive 发送一次 keepalive@openssh.com 请求，用于保活和检查连接是否存活
+// 这是轻量级检查，不会创建新 session；连接已半断开时请求可能一直阻塞，因此超时也视为失败
+func (s *SSHConnection) SendKeepAlive(timeout time.Duration) error {
+	client := s.Client
+	if client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return fmt.Errorf("保活请求失败: %v", err)
+		}
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("保活请求超时（%v）", timeout)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// 默认RSA密钥长度及允许的范围
+const (
+	defaultRSABits = 4096
+	minRSABits     = 2048
+	maxRSABits     = 8192
+)
+
+// GenerateKeyPair 生成SSH密钥对
+// keyType 支持 "ed25519"（为空时默认）、"rsa"（默认4096位）以及 "rsa-2048"/"rsa:3072" 等指定位数的形式。
+// 返回 OpenSSH 格式的 PEM 私钥和 authorized_keys 格式的公钥
+func GenerateKeyPair(keyType string, comment string) (privatePEM, publicAuthorizedKey string, err error) {
+	keyType = strings.ToLower(strings.TrimSpace(keyType))
+
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+
+	switch {
+	case keyType == "" || keyType == "ed25519":
+		pub, priv, genErr := ed25519.GenerateKey(rand.Reader)
+		if genErr != nil {
+			return "", "", fmt.Errorf("生成ed25519密钥失败: %v", genErr)
+		}
+		privateKey, publicKey = priv, pub
+	case strings.HasPrefix(keyType, "rsa"):
+		bits, parseErr := parseRSABits(keyType)
+		if parseErr != nil {
+			return "", "", parseErr
+		}
+		priv, genErr := rsa.GenerateKey(rand.Reader, bits)
+		if genErr != nil {
+			return "", "", fmt.Errorf("生成RSA密钥失败: %v", genErr)
+		}
+		privateKey, publicKey = priv, &priv.PublicKey
+	default:
+		return "", "", fmt.Errorf("不支持的密钥类型: %s", keyType)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return "", "", fmt.Errorf("序列化私钥失败: %v", err)
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", "", fmt.Errorf("生成公钥失败: %v", err)
+	}
+
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+	if comment != "" {
+		authorizedKey += " " + comment
+	}
+
+	return string(pem.EncodeToMemory(block)), authorizedKey, nil
+}
+
+// parseRSABits 从 "rsa"、"rsa-3072"、"rsa:4096" 形式的类型中解析密钥位数
+func parseRSABits(keyType string) (int, error) {
+	suffix := strings.TrimLeft(strings.TrimPrefix(keyType, "rsa"), "-:")
+	if suffix == "" {
+		return defaultRSABits, nil
+	}
+
+	bits, err := strconv.Atoi(suffix)
+	if err != nil {
+		return 0, fmt.Errorf("无效的RSA密钥位数: %s", suffix)
+	}
+	if bits < minRSABits || bits > maxRSABits {
+		return 0, fmt.Errorf("RSA密钥位数必须在 %d 到 %d 之间", minRSABits, maxRSABits)
+	}
+	return bits, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyMismatchError 服务器主机密钥与 known_hosts 中记录的不一致，可能存在中间人攻击
+type HostKeyMismatchError struct {
+	Host        string // 主机地址
+	Fingerprint string // 服务器提供的密钥指纹
+	KnownFile   string // 记录旧密钥的文件
+	KnownLine   int    // 记录旧密钥的行号
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("主机密钥不匹配，可能存在中间人攻击: %s 提供的密钥指纹为 %s，与 %s 第%d行记录的不一致",
+		e.Host, e.Fingerprint, e.KnownFile, e.KnownLine)
+}
+
+// ErrHostKeyRejected 用户拒绝信任未知主机密钥
+var ErrHostKeyRejected = errors.New("用户拒绝信任该主机密钥")
+
+// HostKeyConfirmFunc 首次连接未知主机时询问是否信任，返回 true 表示接受并记录
+type HostKeyConfirmFunc func(hostname string, remote net.Addr, key ssh.PublicKey) bool
+
+// KnownHostsManager known_hosts 文件管理器
+type KnownHostsManager struct {
+	path  string
+	mutex sync.Mutex
+}
+
+// NewKnownHostsManager 创建 known_hosts 管理器
+func NewKnownHostsManager(path string) *KnownHostsManager {
+	return &KnownHostsManager{path: path}
+}
+
+// ensureFile 确保 known_hosts 文件存在
+func (k *KnownHostsManager) ensureFile() error {
+	if _, err := os.Stat(k.path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(k.path), 0755); err != nil {
+		return fmt.Errorf("无法创建目录: %v", err)
+	}
+	f, err := os.OpenFile(k.path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("无法创建known_hosts文件: %v", err)
+	}
+	return f.Close()
+}
+
+// HostKeyCallback 返回基于 known_hosts 的主机密钥校验回调
+// 已知主机密钥不一致时返回 *HostKeyMismatchError；未知主机通过 confirm 询问，接受后写入 known_hosts
+func (k *KnownHostsManager) HostKeyCallback(confirm HostKeyConfirmFunc) ssh.HostKeyCallback {
+	return k.hostKeyCallback(confirm, true)
+}
+
+// VerifyHostKeyCallback 与 HostKeyCallback 相同，但只校验不记录：未知主机经 confirm 接受后本次连接继续，密钥不写入 known_hosts
+// 用于测试连接等不应改变信任状态的场景
+func (k *KnownHostsManager) VerifyHostKeyCallback(confirm HostKeyConfirmFunc) ssh.HostKeyCallback {
+	return k.hostKeyCallback(confirm, false)
+}
+
+// hostKeyCallback 校验主机密钥，persist 为 true 时把用户接受的未知主机密钥写入 known_hosts
+func (k *KnownHostsManager) hostKeyCallback(confirm HostKeyConfirmFunc, persist bool) ssh.HostKeyCallback {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		k.mutex.Lock()
+		if err := k.ensureFile(); err != nil {
+			k.mutex.Unlock()
+			return err
+		}
+		// 每次重新读取文件，保证其他连接新接受的密钥立即生效
+		callback, err := knownhosts.New(k.path)
+		k.mutex.Unlock()
+		if err != nil {
+			return fmt.Errorf("无法读取known_hosts文件: %v", err)
+		}
+
+		err = callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			want := keyErr.Want[0]
+			return &HostKeyMismatchError{
+				Host:        hostname,
+				Fingerprint: ssh.FingerprintSHA256(key),
+				KnownFile:   want.Filename,
+				KnownLine:   want.Line,
+			}
+		}
+
+		// 未知主机，询问用户
+		if confirm == nil || !confirm(hostname, remote, key) {
+			return ErrHostKeyRejected
+		}
+		if !persist {
+			return nil
+		}
+		return k.AddHostKey(hostname, remote, key)
+	}
+}
+
+// AddHostKey 将主机密钥追加到 known_hosts 文件
+func (k *KnownHostsManager) AddHostKey(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if err := k.ensureFile(); err != nil {
+		return err
+	}
+
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		if remoteAddr := knownhosts.Normalize(remote.String()); remoteAddr != addresses[0] {
+			addresses = append(addresses, remoteAddr)
+		}
+	}
+
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("无法写入known_hosts文件: %v", err)
+	}
+	defer f.Close()
+
+	line := knownhosts.Line(addresses, key)
+	if _, err := f.WriteString(strings.TrimSpace(line) + "\n"); err != nil {
+		return fmt.Errorf("无法写入known_hosts文件: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestHostKey 生成测试用的主机公钥
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("转换公钥失败: %v", err)
+	}
+	return key
+}
+
+func TestVerifyHostKeyCallbackDoesNotPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	k := NewKnownHostsManager(path)
+	remote := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 22}
+	key := newTestHostKey(t)
+	accept := func(string, net.Addr, ssh.PublicKey) bool { return true }
+	reject := func(string, net.Addr, ssh.PublicKey) bool { return false }
+
+	readKnownHosts := func() string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("读取known_hosts失败: %v", err)
+		}
+		return string(data)
+	}
+
+	// 只校验：接受未知主机，但不写入文件
+	if err := k.VerifyHostKeyCallback(accept)("10.0.0.1:22", remote, key); err != nil {
+		t.Fatalf("接受未知主机后校验失败: %v", err)
+	}
+	if content := readKnownHosts(); content != "" {
+		t.Fatalf("只校验的回调写入了known_hosts: %q", content)
+	}
+	if err := k.VerifyHostKeyCallback(reject)("10.0.0.1:22", remote, key); !errors.Is(err, ErrHostKeyRejected) {
+		t.Fatalf("拒绝未知主机时错误为 %v", err)
+	}
+
+	// 普通回调接受后写入文件
+	if err := k.HostKeyCallback(accept)("10.0.0.1:22", remote, key); err != nil {
+		t.Fatalf("接受未知主机失败: %v", err)
+	}
+	if content := readKnownHosts(); !strings.Contains(content, "10.0.0.1") {
+		t.Fatalf("接受的主机密钥没有写入known_hosts: %q", content)
+	}
+
+	// 已记录的主机：相同密钥通过，不同密钥报告不匹配
+	if err := k.VerifyHostKeyCallback(reject)("10.0.0.1:22", remote, key); err != nil {
+		t.Fatalf("已知主机校验失败: %v", err)
+	}
+	var mismatch *HostKeyMismatchError
+	if err := k.VerifyHostKeyCallback(accept)("10.0.0.1:22", remote, newTestHostKey(t)); !errors.As(err, &mismatch) {
+		t.Fatalf("密钥变化时错误为 %v, 期望 *HostKeyMismatchError", err)
+	}
+}
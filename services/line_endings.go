@@ -0,0 +1,99 @@
+package services
+
+import "bytes"
+
+// 传输时的换行符处理方式
+const (
+	LineEndingPreserve = "preserve" // 保持原样（默认）
+	LineEndingLF       = "lf"       // CRLF -> LF，适用于上传到Linux的脚本
+	LineEndingCRLF     = "crlf"     // LF -> CRLF，适用于下载到Windows的文件
+)
+
+// binarySniffLen 判断文本/二进制时检查的字节数
+const binarySniffLen = 8000
+
+// IsBinaryContent 判断数据是否为二进制内容（前 8000 字节中包含 NUL 字节）
+func IsBinaryContent(sample []byte) bool {
+	if len(sample) > binarySniffLen {
+		sample = sample[:binarySniffLen]
+	}
+	return bytes.IndexByte(sample, 0) != -1
+}
+
+// lineEndingConverter 流式换行符转换器，能正确处理跨数据块的 \r\n
+type lineEndingConverter struct {
+	mode        string
+	pendingCR   bool // LF模式：上一块以 \r 结尾，尚未确定是否为 \r\n
+	lastWasCR   bool // CRLF模式：上一个字节是否为 \r
+	decided     bool // 是否已判断过文本/二进制
+	passThrough bool // 二进制文件或保持原样时直接透传
+}
+
+// newLineEndingConverter 创建换行符转换器，未知模式按保持原样处理
+func newLineEndingConverter(mode string) *lineEndingConverter {
+	c := &lineEndingConverter{mode: mode}
+	if mode != LineEndingLF && mode != LineEndingCRLF {
+		c.passThrough = true
+		c.decided = true
+	}
+	return c
+}
+
+// Convert 转换一个数据块，第一个数据块用于判断是否为二进制文件
+func (c *lineEndingConverter) Convert(chunk []byte) []byte {
+	if !c.decided {
+		c.decided = true
+		c.passThrough = IsBinaryContent(chunk)
+	}
+	if c.passThrough || len(chunk) == 0 {
+		return chunk
+	}
+
+	out := make([]byte, 0, len(chunk)+len(chunk)/16)
+	switch c.mode {
+	case LineEndingLF:
+		if c.pendingCR {
+			c.pendingCR = false
+			if chunk[0] != '\n' {
+				out = append(out, '\r')
+			}
+		}
+		for i := 0; i < len(chunk); i++ {
+			if chunk[i] == '\r' {
+				if i+1 == len(chunk) {
+					c.pendingCR = true
+					continue
+				}
+				if chunk[i+1] == '\n' {
+					continue
+				}
+			}
+			out = append(out, chunk[i])
+		}
+	case LineEndingCRLF:
+		for _, b := range chunk {
+			if b == '\n' && !c.lastWasCR {
+				out = append(out, '\r')
+			}
+			out = append(out, b)
+			c.lastWasCR = b == '\r'
+		}
+	}
+	return out
+}
+
+// Flush 返回转换器中尚未输出的数据
+func (c *lineEndingConverter) Flush() []byte {
+	if c.pendingCR {
+		c.pendingCR = false
+		return []byte{'\r'}
+	}
+	return nil
+}
+
+// ConvertLineEndings 一次性转换整段内容的换行符，二进制内容原样返回
+func ConvertLineEndings(data []byte, mode string) []byte {
+	c := newLineEndingConverter(mode)
+	out := c.Convert(data)
+	return append(out, c.Flush()...)
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-term/internal/sshtest"
+)
+
+func TestConvertLineEndings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		mode string
+		want string
+	}{
+		{"preserve mixed", "a\r\nb\nc\rd", LineEndingPreserve, "a\r\nb\nc\rd"},
+		{"unknown mode preserves", "a\r\nb\n", "mac", "a\r\nb\n"},
+		{"lf mixed", "#!/bin/bash\r\necho a\necho b\r\n", LineEndingLF, "#!/bin/bash\necho a\necho b\n"},
+		{"lf keeps lone cr", "progress\r50%\r\ndone", LineEndingLF, "progress\r50%\ndone"},
+		{"lf trailing cr", "a\r\nb\r", LineEndingLF, "a\nb\r"},
+		{"crlf mixed", "a\nb\r\nc\n", LineEndingCRLF, "a\r\nb\r\nc\r\n"},
+		{"crlf keeps lone cr", "a\rb\n", LineEndingCRLF, "a\rb\r\n"},
+		{"empty", "", LineEndingLF, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ConvertLineEndings([]byte(tt.in), tt.mode)
+			if string(got) != tt.want {
+				t.Fatalf("ConvertLineEndings(%q, %q) = %q, 期望 %q", tt.in, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertLineEndingsLeavesBinaryUnchanged(t *testing.T) {
+	binary := []byte{0x7f, 'E', 'L', 'F', 0x00, '\r', '\n', 0x01, '\n', 0x00, '\r'}
+	for _, mode := range []string{LineEndingLF, LineEndingCRLF} {
+		got := ConvertLineEndings(binary, mode)
+		if !bytes.Equal(got, binary) {
+			t.Fatalf("模式 %s 修改了二进制内容: %v", mode, got)
+		}
+	}
+}
+
+func TestLineEndingConverterAcrossChunks(t *testing.T) {
+	inputs := []string{
+		"a\r\nb\r\nc\rd\n",
+		"\r\n\r\n\r",
+		"line1\r\nline2\nline3\r\n",
+	}
+	for _, mode := range []string{LineEndingLF, LineEndingCRLF} {
+		for _, in := range inputs {
+			want := ConvertLineEndings([]byte(in), mode)
+
+			// 逐字节转换，\r 和 \n 总是落在不同的数据块中
+			c := newLineEndingConverter(mode)
+			var got []byte
+			for i := 0; i < len(in); i++ {
+				got = append(got, c.Convert([]byte{in[i]})...)
+			}
+			got = append(got, c.Flush()...)
+
+			if !bytes.Equal(got, want) {
+				t.Fatalf("模式 %s 分块转换 %q 得到 %q, 期望 %q", mode, in, got, want)
+			}
+		}
+	}
+}
+
+func TestLineEndingConverterDecidesOnFirstChunk(t *testing.T) {
+	// 第一个数据块包含 NUL 时整个文件按二进制透传，后续看起来像文本的块也不转换
+	c := newLineEndingConverter(LineEndingLF)
+	first := []byte{0x00, 'a', '\r', '\n'}
+	second := []byte("b\r\n")
+	if got := c.Convert(first); !bytes.Equal(got, first) {
+		t.Fatalf("二进制数据块被修改: %q", got)
+	}
+	if got := c.Convert(second); !bytes.Equal(got, second) {
+		t.Fatalf("二进制文件的后续数据块被修改: %q", got)
+	}
+}
+
+func TestTransferOptionsLineEndingMode(t *testing.T) {
+	tests := []struct {
+		options TransferOptions
+		want    string
+	}{
+		{TransferOptions{}, LineEndingPreserve},
+		{TransferOptions{NormalizeForUnix: true}, LineEndingLF},
+		{TransferOptions{LineEnding: LineEndingCRLF}, LineEndingCRLF},
+		{TransferOptions{LineEnding: LineEndingCRLF, NormalizeForUnix: true}, LineEndingLF},
+	}
+	for _, tt := range tests {
+		if got := tt.options.lineEndingMode(); got != tt.want {
+			t.Fatalf("%+v 的换行符处理方式为 %q, 期望 %q", tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestTransferConvertsLineEndings(t *testing.T) {
+	conn, client := openTestSFTP(t, sshtest.NewServer(t, nil))
+	dir := t.TempDir()
+
+	script := "#!/bin/bash\r\necho a\necho b\r\n"
+	binary := append([]byte{0x00, 0x01}, []byte("keep\r\nthis\r\n")...)
+	writeLocal := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("写入本地文件失败: %v", err)
+		}
+		return path
+	}
+	readRemote := func(path string) []byte {
+		file, err := client.Open(path)
+		if err != nil {
+			t.Fatalf("打开远程文件失败: %v", err)
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			t.Fatalf("读取远程文件失败: %v", err)
+		}
+		return data
+	}
+
+	ctx := context.Background()
+	unix := TransferOptions{NormalizeForUnix: true}
+	if err := conn.UploadFileWithOptions(ctx, client, writeLocal("deploy.sh", []byte(script)), "/deploy.sh", unix, nil); err != nil {
+		t.Fatalf("上传脚本失败: %v", err)
+	}
+	if got := string(readRemote("/deploy.sh")); got != "#!/bin/bash\necho a\necho b\n" {
+		t.Fatalf("上传后的脚本为 %q", got)
+	}
+
+	if err := conn.UploadFileWithOptions(ctx, client, writeLocal("data.bin", binary), "/data.bin", unix, nil); err != nil {
+		t.Fatalf("上传二进制文件失败: %v", err)
+	}
+	if got := readRemote("/data.bin"); !bytes.Equal(got, binary) {
+		t.Fatalf("二进制文件被修改: %q", got)
+	}
+
+	// 默认保持原样
+	if err := conn.UploadFile(ctx, client, writeLocal("raw.sh", []byte(script)), "/raw.sh", nil); err != nil {
+		t.Fatalf("上传脚本失败: %v", err)
+	}
+	if got := string(readRemote("/raw.sh")); got != script {
+		t.Fatalf("默认选项修改了换行符: %q", got)
+	}
+
+	local := filepath.Join(dir, "deploy.txt")
+	if err := conn.DownloadFileWithOptions(ctx, client, "/deploy.sh", local, TransferOptions{LineEnding: LineEndingCRLF}, nil); err != nil {
+		t.Fatalf("下载文件失败: %v", err)
+	}
+	got, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatalf("读取下载的文件失败: %v", err)
+	}
+	if string(got) != "#!/bin/bash\r\necho a\r\necho b\r\n" {
+		t.Fatalf("下载后的文件为 %q", got)
+	}
+}
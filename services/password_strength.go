@@ -0,0 +1,73 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 主密码强度要求
+const (
+	MinPasswordLength         = 8  // 低于此长度视为弱密码
+	RecommendedPasswordLength = 12 // 达到此长度时只要求两类字符
+)
+
+// commonPasswords 常见弱密码（小写），命中时视为弱密码
+var commonPasswords = map[string]bool{
+	"123456": true, "12345678": true, "123456789": true, "1234567890": true,
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "1q2w3e4r": true,
+	"abc123": true, "abcd1234": true, "111111": true, "000000": true,
+	"admin": true, "admin123": true, "root": true, "root123": true,
+	"letmein": true, "welcome": true, "welcome1": true, "iloveyou": true,
+	"changeme": true, "secret": true, "p@ssw0rd": true, "a123456": true,
+}
+
+// ValidatePasswordStrength 检查主密码强度，弱密码返回说明原因的错误
+// 要求：不少于8位；少于12位时需包含大写、小写、数字、符号中的至少三类，否则至少两类；不能是常见密码
+func ValidatePasswordStrength(password string) error {
+	var problems []string
+
+	length := len([]rune(password))
+	if length < MinPasswordLength {
+		problems = append(problems, fmt.Sprintf("长度不足%d位", MinPasswordLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{upper, lower, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	required := 3
+	if length >= RecommendedPasswordLength {
+		required = 2
+	}
+	if classes < required {
+		problems = append(problems, fmt.Sprintf("需包含大写字母、小写字母、数字、符号中的至少%d类", required))
+	}
+
+	if commonPasswords[strings.ToLower(password)] {
+		problems = append(problems, "是常见密码")
+	} else if length > 1 && strings.Trim(password, password[:1]) == "" {
+		problems = append(problems, "由单个字符重复组成")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("密码强度较弱: %s", strings.Join(problems, "，"))
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 端口转发类型
+const (
+	ForwardTypeLocal   = "local"   // 本地端口转发（ssh -L）
+	ForwardTypeRemote  = "remote"  // 远程端口转发（ssh -R）
+	ForwardTypeDynamic = "dynamic" // 动态转发 / SOCKS 代理（ssh -D）
+)
+
+// ForwardInfo 端口转发的状态信息
+type ForwardInfo struct {
+	ID            string `json:"id"`
+	ServerID      string `json:"serverId"`
+	Type          string `json:"type"`          // local, remote, dynamic
+	LocalAddr     string `json:"localAddr"`     // 本地地址
+	RemoteAddr    string `json:"remoteAddr"`    // 远程地址（动态转发为空）
+	Connections   int64  `json:"connections"`   // 累计接受的连接数
+	Active        int64  `json:"active"`        // 当前活动的连接数
+	BytesSent     int64  `json:"bytesSent"`     // 从监听端发往目标端的字节数
+	BytesReceived int64  `json:"bytesReceived"` // 从目标端返回的字节数
+	StartedAt     string `json:"startedAt"`
+}
+
+// PortForward 一个正在运行的端口转发，负责接受连接并在两端之间转发数据
+type PortForward struct {
+	info     ForwardInfo
+	listener net.Listener
+
+	connections   int64
+	active        int64
+	bytesSent     int64
+	bytesReceived int64
+
+	mutex     sync.Mutex
+	conns     map[net.Conn]struct{}
+	closed    bool
+	closeOnce sync.Once
+}
+
+// NewPortForward 创建端口转发，listener 由调用方根据转发类型创建
+func NewPortForward(id, serverID, forwardType, localAddr, remoteAddr string, listener net.Listener) *PortForward {
+	return &PortForward{
+		info: ForwardInfo{
+			ID:         id,
+			ServerID:   serverID,
+			Type:       forwardType,
+			LocalAddr:  localAddr,
+			RemoteAddr: remoteAddr,
+			StartedAt:  time.Now().Format("2006-01-02 15:04:05"),
+		},
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// Info 返回转发的当前状态
+func (f *PortForward) Info() ForwardInfo {
+	info := f.info
+	info.Connections = atomic.LoadInt64(&f.connections)
+	info.Active = atomic.LoadInt64(&f.active)
+	info.BytesSent = atomic.LoadInt64(&f.bytesSent)
+	info.BytesReceived = atomic.LoadInt64(&f.bytesReceived)
+	return info
+}
+
+// Serve 循环接受连接，对每个连接调用 dial 建立目标连接并双向转发，直到转发被关闭
+// 单个连接的错误通过 onError 回调通知，不会中断转发；监听出错时通知后返回，转发随之失效
+func (f *PortForward) Serve(dial func(accepted net.Conn) (net.Conn, error), onError func(error)) {
+	for {
+		accepted, err := f.listener.Accept()
+		if err != nil {
+			f.mutex.Lock()
+			closed := f.closed
+			f.mutex.Unlock()
+			if !closed && onError != nil {
+				onError(fmt.Errorf("接受连接失败: %v", err))
+			}
+			return
+		}
+		atomic.AddInt64(&f.connections, 1)
+
+		go func() {
+			target, err := dial(accepted)
+			if err != nil {
+				accepted.Close()
+				if onError != nil {
+					onError(fmt.Errorf("连接目标失败: %v", err))
+				}
+				return
+			}
+			f.pipe(accepted, target)
+		}()
+	}
+}
+
+// pipe 在两个连接之间双向复制数据并统计流量，任一方向结束后关闭两端
+func (f *PortForward) pipe(accepted, target net.Conn) {
+	if !f.track(accepted, target) {
+		accepted.Close()
+		target.Close()
+		return
+	}
+	atomic.AddInt64(&f.active, 1)
+	defer atomic.AddInt64(&f.active, -1)
+	defer f.untrack(accepted, target)
+
+	// 流量在数据写出时即计入，长连接传输过程中也能看到实时统计
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(&countingWriter{w: target, n: &f.bytesSent}, accepted)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(&countingWriter{w: accepted, n: &f.bytesReceived}, target)
+		done <- struct{}{}
+	}()
+	<-done
+	accepted.Close()
+	target.Close()
+	<-done
+}
+
+// countingWriter 把写出的字节数累加到计数器
+type countingWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	atomic.AddInt64(c.n, int64(n))
+	return n, err
+}
+
+func (f *PortForward) track(conns ...net.Conn) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.closed {
+		return false
+	}
+	for _, c := range conns {
+		f.conns[c] = struct{}{}
+	}
+	return true
+}
+
+func (f *PortForward) untrack(conns ...net.Conn) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, c := range conns {
+		delete(f.conns, c)
+	}
+}
+
+// Close 停止监听并关闭所有活动连接
+func (f *PortForward) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		f.mutex.Lock()
+		f.closed = true
+		conns := f.conns
+		f.conns = make(map[net.Conn]struct{})
+		f.mutex.Unlock()
+
+		if f.listener != nil {
+			err = f.listener.Close()
+		}
+		for c := range conns {
+			c.Close()
+		}
+	})
+	return err
+}
+
+// ForwardManager 管理所有活动的端口转发
+type ForwardManager struct {
+	mutex    sync.Mutex
+	forwards map[string]*PortForward
+}
+
+// NewForwardManager 创建端口转发管理器
+func NewForwardManager() *ForwardManager {
+	return &ForwardManager{forwards: make(map[string]*PortForward)}
+}
+
+// Add 登记端口转发
+func (m *ForwardManager) Add(forward *PortForward) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.forwards[forward.info.ID] = forward
+}
+
+// List 返回所有活动转发的状态，按启动顺序排列
+func (m *ForwardManager) List() []ForwardInfo {
+	m.mutex.Lock()
+	forwards := make([]*PortForward, 0, len(m.forwards))
+	for _, forward := range m.forwards {
+		forwards = append(forwards, forward)
+	}
+	m.mutex.Unlock()
+
+	infos := make([]ForwardInfo, 0, len(forwards))
+	for _, forward := range forwards {
+		infos = append(infos, forward.Info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].StartedAt != infos[j].StartedAt {
+			return infos[i].StartedAt < infos[j].StartedAt
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Stop 停止并移除指定的转发
+func (m *ForwardManager) Stop(id string) error {
+	m.mutex.Lock()
+	forward, exists := m.forwards[id]
+	delete(m.forwards, id)
+	m.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("端口转发不存在: %s", id)
+	}
+	return forward.Close()
+}
+
+// Remove 移除并关闭已经停止服务的转发（例如监听出错后）；已被替换或移除时只关闭该转发
+func (m *ForwardManager) Remove(forward *PortForward) {
+	m.mutex.Lock()
+	if m.forwards[forward.info.ID] == forward {
+		delete(m.forwards, forward.info.ID)
+	}
+	m.mutex.Unlock()
+
+	forward.Close()
+}
+
+// StopServer 停止服务器上的所有转发（断开连接时调用）
+func (m *ForwardManager) StopServer(serverID string) {
+	m.mutex.Lock()
+	var stopped []*PortForward
+	for id, forward := range m.forwards {
+		if forward.info.ServerID == serverID {
+			stopped = append(stopped, forward)
+			delete(m.forwards, id)
+		}
+	}
+	m.mutex.Unlock()
+
+	for _, forward := range stopped {
+		forward.Close()
+	}
+}
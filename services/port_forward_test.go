@@ -0,0 +1,71 @@
+package services
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// startEchoServer 启动一个把收到的数据原样返回的 TCP 服务器
+func startEchoServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestPortForwardCountsBytesWhileConnectionOpen(t *testing.T) {
+	target := startEchoServer(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	forward := NewPortForward("f", "s", ForwardTypeLocal, listener.Addr().String(), target, listener)
+	t.Cleanup(func() { forward.Close() })
+	go forward.Serve(func(net.Conn) (net.Conn, error) {
+		return net.Dial("tcp", target)
+	}, nil)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("连接转发端口失败: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+
+	// 连接仍然打开时流量统计就应更新，而不是等到连接结束
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info := forward.Info()
+		if info.BytesSent == 5 && info.BytesReceived == 5 && info.Active == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("连接打开期间的流量统计 = 发送 %d / 接收 %d / 活动 %d, 期望 5 / 5 / 1", info.BytesSent, info.BytesReceived, info.Active)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// minRateLimitChunk 限速时单次读写的最小块大小
+const minRateLimitChunk = 4 * 1024
+
+// transferRateLimiter 传输限速器，按累计字节数与已用时间计算需要等待的时间
+type transferRateLimiter struct {
+	bytesPerSec int64
+	start       time.Time
+	consumed    int64
+}
+
+// newTransferRateLimiter 创建限速器，bytesPerSec <= 0 表示不限速并返回 nil
+func newTransferRateLimiter(bytesPerSec int64) *transferRateLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &transferRateLimiter{bytesPerSec: bytesPerSec, start: time.Now()}
+}
+
+// chunkSize 返回限速时合适的单次读取大小（约 1/10 秒的数据量），避免大缓冲区造成突发流量
+func (l *transferRateLimiter) chunkSize(bufSize int) int {
+	if l == nil {
+		return bufSize
+	}
+	size := int(l.bytesPerSec / 10)
+	if size < minRateLimitChunk {
+		size = minRateLimitChunk
+	}
+	if size > bufSize {
+		size = bufSize
+	}
+	return size
+}
+
+// Wait 记录已传输的 n 个字节，并等待到符合限速的时间点；ctx 取消时立即返回
+func (l *transferRateLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	l.consumed += int64(n)
+	due := l.start.Add(time.Duration(float64(l.consumed) / float64(l.bytesPerSec) * float64(time.Second)))
+	delay := time.Until(due)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// CopyRemoteFile 在同一服务器上复制文件或目录（目录递归复制），保留权限和修改时间
+// 符号链接按链接本身复制；目标已存在时返回错误
+func (s *SSHConnection) CopyRemoteFile(sftpClient *sftp.Client, srcPath, dstPath string) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+
+	srcPath = path.Clean(srcPath)
+	dstPath = path.Clean(dstPath)
+	if srcPath == dstPath {
+		return fmt.Errorf("源路径与目标路径相同")
+	}
+	if strings.HasPrefix(dstPath, srcPath+"/") {
+		return fmt.Errorf("不能将目录复制到其自身的子目录中")
+	}
+	if _, err := sftpClient.Lstat(dstPath); err == nil {
+		return fmt.Errorf("目标已存在: %s", dstPath)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("检查目标路径失败: %v", err)
+	}
+
+	return s.copyRemoteEntry(sftpClient, srcPath, dstPath)
+}
+
+func (s *SSHConnection) copyRemoteEntry(sftpClient *sftp.Client, srcPath, dstPath string) error {
+	info, err := sftpClient.Lstat(srcPath)
+	if err != nil {
+		return fmt.Errorf("获取文件信息失败 %s: %v", srcPath, err)
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := sftpClient.ReadLink(srcPath)
+		if err != nil {
+			return fmt.Errorf("读取符号链接失败 %s: %v", srcPath, err)
+		}
+		if err := sftpClient.Symlink(target, dstPath); err != nil {
+			return fmt.Errorf("创建符号链接失败 %s: %v", dstPath, err)
+		}
+		return nil
+
+	case info.IsDir():
+		if err := sftpClient.Mkdir(dstPath); err != nil {
+			return fmt.Errorf("创建目录失败 %s: %v", dstPath, err)
+		}
+		entries, err := sftpClient.ReadDir(srcPath)
+		if err != nil {
+			return fmt.Errorf("读取目录失败 %s: %v", srcPath, err)
+		}
+		for _, entry := range entries {
+			if err := s.copyRemoteEntry(sftpClient, path.Join(srcPath, entry.Name()), path.Join(dstPath, entry.Name())); err != nil {
+				return err
+			}
+		}
+
+	default:
+		if err := copyRemoteContent(sftpClient, srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+
+	// 目录的修改时间需在写入子项之后设置
+	if err := sftpClient.Chmod(dstPath, info.Mode()); err != nil {
+		return fmt.Errorf("设置权限失败 %s: %v", dstPath, err)
+	}
+	if err := sftpClient.Chtimes(dstPath, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("设置修改时间失败 %s: %v", dstPath, err)
+	}
+	return nil
+}
+
+// copyRemoteContent 复制单个普通文件的内容
+func copyRemoteContent(sftpClient *sftp.Client, srcPath, dstPath string) error {
+	src, err := sftpClient.Open(srcPath)
+	if err != nil {
+		return fmt.Errorf("无法打开源文件 %s: %v", srcPath, err)
+	}
+	defer src.Close()
+
+	dst, err := sftpClient.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return fmt.Errorf("无法创建目标文件 %s: %v", dstPath, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return newTransferError("复制", dstPath, true, 0, 0, err)
+	}
+	if err := dst.Close(); err != nil {
+		return newTransferError("复制", dstPath, true, 0, 0, err)
+	}
+	return nil
+}
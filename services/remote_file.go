@@ -0,0 +1,209 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/sftp"
+)
+
+// MaxDiffFileSize 进行内容比较的最大文件大小
+const MaxDiffFileSize = 1024 * 1024
+
+// StatRemote 获取远程路径的信息，路径不存在时返回 exists=false 且不返回错误
+// 符号链接返回链接本身的信息，Type 按其指向的目标区分文件和目录
+func (s *SSHConnection) StatRemote(sftpClient *sftp.Client, remotePath string) (FileInfo, bool, error) {
+	if s.Client == nil {
+		return FileInfo{}, false, fmt.Errorf("SSH连接未建立")
+	}
+
+	info, err := sftpClient.Lstat(remotePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return FileInfo{}, false, nil
+		}
+		return FileInfo{}, false, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	return describeRemoteFile(sftpClient, remotePath, info), true, nil
+}
+
+// GlobRemoteFiles 展开远程路径中的通配符（*、?、[...]），返回匹配的文件（不含目录），按名称排序
+func (s *SSHConnection) GlobRemoteFiles(sftpClient *sftp.Client, pattern string) ([]string, error) {
+	if s.Client == nil {
+		return nil, fmt.Errorf("SSH连接未建立")
+	}
+
+	matches, err := sftpClient.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("远程文件匹配模式无效: %v", err)
+	}
+
+	var files []string
+	for _, match := range matches {
+		info, err := sftpClient.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, match)
+	}
+	return files, nil
+}
+
+// ReadRemoteFileLimited 读取远程文件的全部内容，文件超过 maxBytes 时返回错误
+// 文件不存在时返回 exists=false 且不返回错误
+func (s *SSHConnection) ReadRemoteFileLimited(sftpClient *sftp.Client, path string, maxBytes int64) (data []byte, exists bool, err error) {
+	if s.Client == nil {
+		return nil, false, fmt.Errorf("SSH连接未建立")
+	}
+
+	info, err := sftpClient.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if info.IsDir() {
+		return nil, true, fmt.Errorf("%s 是目录", path)
+	}
+	if info.Size() > maxBytes {
+		return nil, true, fmt.Errorf("文件过大（%d 字节），超过限制 %d 字节", info.Size(), maxBytes)
+	}
+
+	file, err := sftpClient.Open(path)
+	if err != nil {
+		return nil, true, fmt.Errorf("无法打开远程文件: %v", err)
+	}
+	defer file.Close()
+
+	// 多读一个字节，防止文件在读取期间变大
+	data, err = io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return nil, true, fmt.Errorf("读取远程文件失败: %v", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, true, fmt.Errorf("文件过大，超过限制 %d 字节", maxBytes)
+	}
+	return data, true, nil
+}
+
+// DefaultPreviewBytes 预览远程文件时默认读取的最大字节数
+const DefaultPreviewBytes = 1024 * 1024
+
+// RemoteFileContent 直接读取的远程文件内容，用于文件预览
+type RemoteFileContent struct {
+	Content   string `json:"content"`   // 文件内容，二进制文件为空
+	Size      int64  `json:"size"`      // 文件的实际大小
+	Truncated bool   `json:"truncated"` // 文件超过读取上限，只返回了开头部分
+	Binary    bool   `json:"binary"`    // 二进制内容，不应作为文本显示
+}
+
+// ReadRemoteFile 读取远程文件内容而不保存到本地，maxBytes <= 0 时使用 DefaultPreviewBytes
+// 文件超过 maxBytes 时返回错误，truncate 为 true 时改为只返回前 maxBytes 字节
+func (s *SSHConnection) ReadRemoteFile(sftpClient *sftp.Client, remotePath string, maxBytes int64, truncate bool) (RemoteFileContent, error) {
+	if s.Client == nil {
+		return RemoteFileContent{}, fmt.Errorf("SSH连接未建立")
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultPreviewBytes
+	}
+
+	info, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return RemoteFileContent{}, fmt.Errorf("文件不存在: %s", remotePath)
+		}
+		return RemoteFileContent{}, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if info.IsDir() {
+		return RemoteFileContent{}, fmt.Errorf("%s 是目录", remotePath)
+	}
+	if info.Size() > maxBytes && !truncate {
+		return RemoteFileContent{}, fmt.Errorf("文件过大（%d 字节），超过限制 %d 字节", info.Size(), maxBytes)
+	}
+
+	file, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return RemoteFileContent{}, fmt.Errorf("无法打开远程文件: %v", err)
+	}
+	defer file.Close()
+
+	// 多读一个字节，判断文件在读取期间是否变大
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		return RemoteFileContent{}, fmt.Errorf("读取远程文件失败: %v", err)
+	}
+
+	result := RemoteFileContent{Size: info.Size()}
+	if int64(len(data)) > maxBytes {
+		if !truncate {
+			return RemoteFileContent{}, fmt.Errorf("文件过大，超过限制 %d 字节", maxBytes)
+		}
+		data = data[:maxBytes]
+		result.Truncated = true
+	}
+	if result.Size > int64(len(data)) {
+		result.Truncated = true
+	}
+
+	if IsBinaryContent(data) {
+		result.Binary = true
+		return result, nil
+	}
+	result.Content = string(data)
+	return result, nil
+}
+
+// WriteRemoteFile 用 content 创建或覆盖远程文件
+// 在原文件上截断后写入，已存在的文件保持原有的权限和所有者；路径为目录时返回错误
+func (s *SSHConnection) WriteRemoteFile(sftpClient *sftp.Client, remotePath, content string) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+
+	info, err := sftpClient.Stat(remotePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if err == nil && info.IsDir() {
+		return fmt.Errorf("%s 是目录，无法写入", remotePath)
+	}
+
+	file, err := sftpClient.OpenFile(remotePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("无法打开远程文件 %s：%s: %w", remotePath, classifyTransferError(err, true), err)
+	}
+
+	if _, err := file.Write([]byte(content)); err != nil {
+		file.Close()
+		return fmt.Errorf("写入远程文件 %s 失败：%s: %w", remotePath, classifyTransferError(err, true), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("写入远程文件 %s 失败：%s: %w", remotePath, classifyTransferError(err, true), err)
+	}
+	return nil
+}
+
+// DiffRemoteFile 比较远程文件当前内容与新内容，返回统一diff格式的差异
+// 远程文件不存在时视为空文件；任一方为二进制内容时不做比较
+func (s *SSHConnection) DiffRemoteFile(sftpClient *sftp.Client, remotePath, newContent string) (string, error) {
+	if int64(len(newContent)) > MaxDiffFileSize {
+		return "", fmt.Errorf("新内容过大，超过限制 %d 字节", MaxDiffFileSize)
+	}
+
+	current, exists, err := s.ReadRemoteFileLimited(sftpClient, remotePath, MaxDiffFileSize)
+	if err != nil {
+		return "", err
+	}
+
+	if IsBinaryContent(current) || IsBinaryContent([]byte(newContent)) {
+		return "二进制文件，不显示差异", nil
+	}
+
+	oldName := "a" + remotePath
+	if !exists {
+		oldName = "/dev/null"
+	}
+	return UnifiedDiff(oldName, "b"+remotePath, string(current), newContent), nil
+}
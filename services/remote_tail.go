@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+)
+
+// DefaultTailPollInterval 跟踪远程文件时检查文件大小的间隔
+const DefaultTailPollInterval = time.Second
+
+// 跟踪开始时先输出的末尾字节数（从其中第一个完整行开始），以及每次最多读取的字节数
+const (
+	tailInitialBytes = 8 * 1024
+	tailMaxReadBytes = 1024 * 1024
+)
+
+// StatTailFile 检查远程文件能否跟踪（存在且不是目录），返回文件信息供 TailRemoteFile 使用
+func (s *SSHConnection) StatTailFile(sftpClient *sftp.Client, remotePath string) (os.FileInfo, error) {
+	if s.Client == nil {
+		return nil, fmt.Errorf("SSH连接未建立")
+	}
+
+	info, err := sftpClient.Stat(remotePath)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件信息失败: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s 是目录", remotePath)
+	}
+	return info, nil
+}
+
+// TailRemoteFile 通过 SFTP 轮询跟踪远程文件（类似 tail -f，不需要 PTY），新增内容通过 onData 回调，直到 ctx 取消
+// info 为 StatTailFile 返回的文件信息，从其大小处开始跟踪；
+// 文件变小（被截断或日志轮转后重新创建）时从头读取并调用 onRotate；轮转过程中文件暂时不存在不视为错误
+func (s *SSHConnection) TailRemoteFile(ctx context.Context, sftpClient *sftp.Client, remotePath string, info os.FileInfo, interval time.Duration, onData func([]byte), onRotate func()) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+	if interval <= 0 {
+		interval = DefaultTailPollInterval
+	}
+
+	// 先输出文件末尾的一部分，从中间开始时丢弃不完整的第一行
+	offset := info.Size() - tailInitialBytes
+	if offset < 0 {
+		offset = 0
+	}
+	data, next, err := readRemoteRange(sftpClient, remotePath, offset, info.Size())
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	if len(data) > 0 {
+		onData(data)
+	}
+	offset = next
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := sftpClient.Stat(remotePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("获取文件信息失败: %v", err)
+		}
+
+		size := info.Size()
+		if size < offset {
+			offset = 0
+			if onRotate != nil {
+				onRotate()
+			}
+		}
+		for offset < size && ctx.Err() == nil {
+			data, next, err := readRemoteRange(sftpClient, remotePath, offset, size)
+			if err != nil {
+				return err
+			}
+			if next == offset {
+				break
+			}
+			onData(data)
+			offset = next
+		}
+	}
+}
+
+// readRemoteRange 读取远程文件 [offset, end) 范围内的数据，单次最多 tailMaxReadBytes，返回数据和下一次读取的偏移
+func readRemoteRange(sftpClient *sftp.Client, remotePath string, offset, end int64) ([]byte, int64, error) {
+	if end-offset > tailMaxReadBytes {
+		end = offset + tailMaxReadBytes
+	}
+	if end <= offset {
+		return nil, offset, nil
+	}
+
+	file, err := sftpClient.Open(remotePath)
+	if err != nil {
+		return nil, offset, fmt.Errorf("无法打开远程文件: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, offset, fmt.Errorf("定位远程文件失败: %v", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(file, end-offset))
+	if err != nil {
+		return nil, offset, fmt.Errorf("读取远程文件失败: %v", err)
+	}
+	return data, offset + int64(len(data)), nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultScrollbackSize 终端回滚缓冲区的默认大小
+const DefaultScrollbackSize = 1024 * 1024
+
+// RingBuffer 固定容量的环形字节缓冲区，写满后覆盖最早的数据，并发安全
+type RingBuffer struct {
+	mutex sync.Mutex
+	data  []byte
+	start int // 最早数据的位置
+	size  int // 当前数据量
+}
+
+// NewRingBuffer 创建指定容量的环形缓冲区，capacity <= 0 时使用 DefaultScrollbackSize
+func NewRingBuffer(capacity int) *RingBuffer {
+	if capacity <= 0 {
+		capacity = DefaultScrollbackSize
+	}
+	return &RingBuffer{data: make([]byte, capacity)}
+}
+
+// Write 追加数据，超出容量时丢弃最早的数据
+func (rb *RingBuffer) Write(p []byte) (int, error) {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	n := len(p)
+	capacity := len(rb.data)
+	if n >= capacity {
+		// 只保留最后 capacity 个字节
+		copy(rb.data, p[n-capacity:])
+		rb.start = 0
+		rb.size = capacity
+		return n, nil
+	}
+
+	end := (rb.start + rb.size) % capacity
+	copied := copy(rb.data[end:], p)
+	copy(rb.data, p[copied:])
+
+	rb.size += n
+	if rb.size > capacity {
+		rb.start = (rb.start + rb.size - capacity) % capacity
+		rb.size = capacity
+	}
+	return n, nil
+}
+
+// Tail 返回最近的最多 maxBytes 个字节（maxBytes <= 0 时返回全部），不会从 UTF-8 字符中间截断
+func (rb *RingBuffer) Tail(maxBytes int) []byte {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+
+	n := rb.size
+	if maxBytes > 0 && maxBytes < n {
+		n = maxBytes
+	}
+	out := make([]byte, n)
+	capacity := len(rb.data)
+	from := (rb.start + rb.size - n) % capacity
+	copied := copy(out, rb.data[from:min(from+n, capacity)])
+	copy(out[copied:], rb.data[:n-copied])
+
+	// 跳过开头不完整的 UTF-8 字符
+	skip := 0
+	for skip < len(out) && skip < utf8.UTFMax && !utf8.RuneStart(out[skip]) {
+		skip++
+	}
+	return out[skip:]
+}
+
+// Len 返回当前保存的字节数
+func (rb *RingBuffer) Len() int {
+	rb.mutex.Lock()
+	defer rb.mutex.Unlock()
+	return rb.size
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"go-term/models"
+)
+
+// 条件块的条件：上一条命令成功或失败
+const (
+	ConditionSuccess = "success"
+	ConditionFailure = "failure"
+)
+
+// maxConditionalDepth 条件块最多嵌套的层数（最外层的条件块中可以再有一层）
+const maxConditionalDepth = 2
+
+// commandModeState 命令模式按段执行时在各段之间传递的状态
+type commandModeState struct {
+	workDir     string // 之前的 $cd 指定的远程目录
+	lastSuccess bool   // 上一条已执行的命令是否成功，没有执行过命令时为 true
+}
+
+// parseConditionalDirective 解析条件块指令行，返回指令类型（if、else、endif）和 $if 的条件
+func parseConditionalDirective(line string) (directive, arg string, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", "", false
+	}
+	switch fields[0] {
+	case "$if":
+		return "if", strings.Join(fields[1:], " "), true
+	case "$else":
+		return "else", strings.Join(fields[1:], " "), true
+	case "$endif":
+		return "endif", strings.Join(fields[1:], " "), true
+	}
+	return "", "", false
+}
+
+// isConditionalDirective 检查解析后的命令是否是条件块指令
+func isConditionalDirective(cmd ParsedCommand) bool {
+	switch cmd.CommandType {
+	case "if", "else", "endif":
+		return true
+	}
+	return false
+}
+
+// ValidateConditionals 检查命令中的条件块是否完整，执行前调用，格式错误时返回错误
+//
+// 语法：
+//
+//	$if success | failure   根据上一条命令的执行结果（退出码是否为0）选择分支
+//	...                     条件成立时执行的命令
+//	$else                   可选
+//	...                     条件不成立时执行的命令
+//	$endif
+//
+// 条件块内最多再嵌套一层条件块；$if 之前的一条命令失败后不会中止执行，但没有带 $ne 时仍计入整体失败，
+// $if 位于开头或紧跟在另一个条件块之后时，以之前最后执行的命令为准，没有执行过命令时视为成功。
+// 条件块指令把脚本分成多段，每段的shell命令在各自的session中执行，环境变量和 cd 切换的目录不会带到下一段，
+// 只有 $cd 指定的目录在各段之间保持
+func ValidateConditionals(commands []ParsedCommand) error {
+	index, err := checkConditionals(commands)
+	if err == nil {
+		return nil
+	}
+	if line := commands[index].Line; line > 0 {
+		return fmt.Errorf("第%d行: %v", line, err)
+	}
+	return fmt.Errorf("第%d条命令: %v", index+1, err)
+}
+
+// checkConditionals 检查条件块，返回出错的命令序号（从0开始）；缺少 $endif 时为未结束的 $if 所在的序号
+func checkConditionals(commands []ParsedCommand) (int, error) {
+	var open []int     // 未结束的 $if 所在的序号
+	var hasElse []bool // 每层未结束的条件块是否已有 $else
+	for i, cmd := range commands {
+		switch cmd.CommandType {
+		case "if":
+			if cmd.Command != ConditionSuccess && cmd.Command != ConditionFailure {
+				return i, fmt.Errorf("不支持的条件 %q，只能是 %s 或 %s", cmd.Command, ConditionSuccess, ConditionFailure)
+			}
+			if len(hasElse) >= maxConditionalDepth {
+				return i, fmt.Errorf("条件块最多嵌套一层")
+			}
+			open = append(open, i)
+			hasElse = append(hasElse, false)
+		case "else":
+			if cmd.Command != "" {
+				return i, fmt.Errorf("$else 不需要参数")
+			}
+			if len(hasElse) == 0 {
+				return i, fmt.Errorf("$else 没有对应的 $if")
+			}
+			if hasElse[len(hasElse)-1] {
+				return i, fmt.Errorf("同一个条件块中有多个 $else")
+			}
+			hasElse[len(hasElse)-1] = true
+		case "endif":
+			if cmd.Command != "" {
+				return i, fmt.Errorf("$endif 不需要参数")
+			}
+			if len(hasElse) == 0 {
+				return i, fmt.Errorf("$endif 没有对应的 $if")
+			}
+			open = open[:len(open)-1]
+			hasElse = hasElse[:len(hasElse)-1]
+		}
+	}
+	if len(open) > 0 {
+		return open[len(open)-1], fmt.Errorf("条件块缺少 $endif")
+	}
+	return -1, nil
+}
+
+// executeCommandBlocks 按顺序执行命令：条件块之前的命令作为一段执行，再根据上一条命令的结果执行其中一个分支，
+// 另一个分支中的命令标记为 skipped；命令最终失败时停止执行。命令必须已通过 ValidateConditionals 检查
+func (ese *EnhancedScriptExecutor) executeCommandBlocks(
+	commands []ParsedCommand,
+	executor CommandExecutor,
+	serverID string,
+	options ExecutionOptions,
+	state *commandModeState,
+) ([]models.CommandOutput, error) {
+	var commandOutputs []models.CommandOutput
+	start := 0
+	for i := 0; i < len(commands); i++ {
+		if commands[i].CommandType != "if" {
+			continue
+		}
+
+		// 先执行条件块之前的命令，确定上一条命令的结果
+		outputs, err := ese.executeCommandSegment(commands[start:i], executor, serverID, options, state, true)
+		commandOutputs = append(commandOutputs, outputs...)
+		if err != nil {
+			return commandOutputs, err
+		}
+
+		elseIndex, endIndex := matchConditional(commands, i)
+		thenBranch, elseBranch := commands[i+1:endIndex], []ParsedCommand(nil)
+		if elseIndex >= 0 {
+			thenBranch, elseBranch = commands[i+1:elseIndex], commands[elseIndex+1:endIndex]
+		}
+
+		if (commands[i].Command == ConditionSuccess) == state.lastSuccess {
+			outputs, err = ese.executeCommandBlocks(thenBranch, executor, serverID, options, state)
+			commandOutputs = append(commandOutputs, outputs...)
+			commandOutputs = append(commandOutputs, skippedOutputs(elseBranch, options)...)
+		} else {
+			commandOutputs = append(commandOutputs, skippedOutputs(thenBranch, options)...)
+			outputs, err = ese.executeCommandBlocks(elseBranch, executor, serverID, options, state)
+			commandOutputs = append(commandOutputs, outputs...)
+		}
+		if err != nil {
+			return commandOutputs, err
+		}
+
+		i = endIndex
+		start = endIndex + 1
+	}
+
+	outputs, err := ese.executeCommandSegment(commands[start:], executor, serverID, options, state, false)
+	commandOutputs = append(commandOutputs, outputs...)
+	return commandOutputs, err
+}
+
+// matchConditional 找到从 ifIndex 开始的条件块对应的 $else（没有时为 -1）和 $endif 的位置
+func matchConditional(commands []ParsedCommand, ifIndex int) (elseIndex, endIndex int) {
+	elseIndex = -1
+	depth := 0
+	for i := ifIndex; i < len(commands); i++ {
+		switch commands[i].CommandType {
+		case "if":
+			depth++
+		case "else":
+			if depth == 1 {
+				elseIndex = i
+			}
+		case "endif":
+			depth--
+			if depth == 0 {
+				return elseIndex, i
+			}
+		}
+	}
+	return elseIndex, len(commands)
+}
+
+// skippedOutputs 为条件不成立而未执行的分支生成执行结果并逐条通知，分支中的条件块指令不显示
+func skippedOutputs(commands []ParsedCommand, options ExecutionOptions) []models.CommandOutput {
+	var outputs []models.CommandOutput
+	for _, cmd := range commands {
+		if isConditionalDirective(cmd) {
+			continue
+		}
+		outputs = append(outputs, models.CommandOutput{
+			Command:  displayCommand(cmd),
+			Status:   "skipped",
+			Error:    "条件不成立，未执行",
+			Output:   "条件不成立，未执行",
+			ExitCode: -1,
+			Line:     cmd.Line,
+		})
+		options.commandDone(outputs[len(outputs)-1])
+	}
+	return outputs
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"go-term/internal/sshtest"
+)
+
+func TestParseConditionalDirectives(t *testing.T) {
+	ese := NewEnhancedScriptExecutor()
+	commands := ese.ParseCommandsWithSpecialHandling("systemctl restart nginx\n$if success\necho ok\n$else\necho failed\n$endif")
+
+	want := []struct {
+		commandType string
+		command     string
+	}{
+		{"shell", "systemctl restart nginx"},
+		{"if", "success"},
+		{"shell", "echo ok"},
+		{"else", ""},
+		{"shell", "echo failed"},
+		{"endif", ""},
+	}
+	if len(commands) != len(want) {
+		t.Fatalf("解析出 %d 条命令, 期望 %d", len(commands), len(want))
+	}
+	for i, w := range want {
+		if commands[i].CommandType != w.commandType || commands[i].Command != w.command || commands[i].Line != i+1 {
+			t.Errorf("第 %d 条命令 = {%s %q 第%d行}, 期望 {%s %q 第%d行}", i+1,
+				commands[i].CommandType, commands[i].Command, commands[i].Line, w.commandType, w.command, i+1)
+		}
+	}
+
+	// $if 之前的命令保持用户写下的标记
+	if commands[0].ContinueOnError {
+		t.Fatal("$if 之前的命令不应被自动标记为 ContinueOnError")
+	}
+	if got := displayCommand(commands[0]); got != "systemctl restart nginx" {
+		t.Fatalf("显示的命令 = %q", got)
+	}
+}
+
+func TestValidateConditionals(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		wantErr string // 为空表示没有错误
+	}{
+		{"if without else", "true\n$if success\necho ok\n$endif", ""},
+		{"if with else", "true\n$if failure\necho a\n$else\necho b\n$endif", ""},
+		{"one nested level", "$if success\n$if failure\necho a\n$endif\n$endif", ""},
+		{"unknown condition", "$if maybe\n$endif", "第1行: 不支持的条件"},
+		{"missing endif", "true\n$if success\necho ok", "第2行: 条件块缺少 $endif"},
+		{"else without if", "$else\n$endif", "第1行: $else 没有对应的 $if"},
+		{"endif without if", "echo ok\n$endif", "第2行: $endif 没有对应的 $if"},
+		{"duplicate else", "$if success\n$else\n$else\n$endif", "第3行: 同一个条件块中有多个 $else"},
+		{"too deep", "$if success\n$if success\n$if success\n$endif\n$endif\n$endif", "第3行: 条件块最多嵌套一层"},
+		{"else with argument", "$if success\n$else now\n$endif", "第2行: $else 不需要参数"},
+		{"endif with argument", "$if success\n$endif x", "第2行: $endif 不需要参数"},
+	}
+
+	ese := NewEnhancedScriptExecutor()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConditionals(ese.ParseCommandsWithSpecialHandling(tt.script))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("不应报错: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("错误 = %v, 期望以 %q 开头", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConditionalFailureKeepsUserFlags(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+	executor := &sessionExecutor{conn: conn}
+	ese := NewEnhancedScriptExecutor()
+
+	commands := ese.ParseCommandsWithSpecialHandling("false\n$if failure\necho handled\n$else\necho unexpected\n$endif")
+	outputs, err := ese.ExecuteCommandMode(commands, executor, "s1")
+	if err != nil {
+		t.Fatalf("$if 之前的命令失败不应中止执行: %v", err)
+	}
+	if len(outputs) != 3 {
+		t.Fatalf("执行结果数量 = %d, 期望 3", len(outputs))
+	}
+
+	// 失败的命令按原样显示，并计入整体失败
+	if outputs[0].Command != "false" || outputs[0].Status != "failed" || outputs[0].ContinueOnError {
+		t.Fatalf("$if 之前的命令结果 = %q/%s/%v", outputs[0].Command, outputs[0].Status, outputs[0].ContinueOnError)
+	}
+	if outputs[1].Status != "success" || outputs[1].Output != "handled" {
+		t.Fatalf("条件成立的分支结果 = %s/%q", outputs[1].Status, outputs[1].Output)
+	}
+	if outputs[2].Status != "skipped" {
+		t.Fatalf("条件不成立的分支结果 = %s", outputs[2].Status)
+	}
+}
+
+func TestCommandBeforeIfWithNeIsExcluded(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+	executor := &sessionExecutor{conn: conn}
+	ese := NewEnhancedScriptExecutor()
+
+	commands := ese.ParseCommandsWithSpecialHandling("$ne false\n$if failure\necho handled\n$endif")
+	outputs, err := ese.ExecuteCommandMode(commands, executor, "s1")
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if outputs[0].Command != "$ne false" || !outputs[0].ContinueOnError {
+		t.Fatalf("带 $ne 的命令结果 = %q/%v", outputs[0].Command, outputs[0].ContinueOnError)
+	}
+	if outputs[1].Output != "handled" {
+		t.Fatalf("条件成立的分支输出 = %q", outputs[1].Output)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"sync"
 	"time"
 
@@ -54,18 +53,13 @@ func (sm *ScriptManager) LoadFromFile(filename string) error {
 
 // saveToFile 保存脚本配置到文件
 func (sm *ScriptManager) saveToFile() error {
-	// 确保目录存在
-	dir := filepath.Dir(sm.configFile)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("创建目录失败: %v", err)
-	}
-
 	data, err := json.MarshalIndent(sm.scripts, "", "  ")
 	if err != nil {
 		return fmt.Errorf("序列化脚本配置失败: %v", err)
 	}
 
-	if err := os.WriteFile(sm.configFile, data, 0644); err != nil {
+	// 先备份现有文件，再写临时文件并重命名，写入中途崩溃不会损坏原配置
+	if err := writeConfigFile(sm.configFile, data, 0644); err != nil {
 		return fmt.Errorf("写入脚本配置文件失败: %v", err)
 	}
 
@@ -79,25 +73,56 @@ func (sm *ScriptManager) GetScripts() []models.BatchScript {
 
 	// 返回副本避免外部修改
 	scripts := make([]models.BatchScript, len(sm.scripts))
-	copy(scripts, sm.scripts)
+	for i, script := range sm.scripts {
+		scripts[i] = cloneScript(script)
+	}
 	return scripts
 }
 
-// GetScriptByID 根据ID获取脚本
+// GetScriptByID 根据ID获取脚本（返回副本）
 func (sm *ScriptManager) GetScriptByID(id string) (*models.BatchScript, error) {
+	snapshot, err := sm.SnapshotScript(id)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// SnapshotScript 获取脚本的深拷贝快照
+// 执行期间使用快照，之后对脚本的更新或删除不会影响正在进行的执行
+func (sm *ScriptManager) SnapshotScript(id string) (models.BatchScript, error) {
 	sm.mutex.RLock()
 	defer sm.mutex.RUnlock()
 
 	for _, script := range sm.scripts {
 		if script.ID == id {
-			return &script, nil
+			return cloneScript(script), nil
 		}
 	}
-	return nil, fmt.Errorf("未找到脚本: %s", id)
+	return models.BatchScript{}, fmt.Errorf("未找到脚本: %s", id)
+}
+
+// cloneScript 深拷贝脚本，避免与管理器内部数据共享切片和map
+func cloneScript(script models.BatchScript) models.BatchScript {
+	if script.ServerIDs != nil {
+		script.ServerIDs = append([]string(nil), script.ServerIDs...)
+	}
+	if script.Variables != nil {
+		variables := make(map[string]string, len(script.Variables))
+		for name, value := range script.Variables {
+			variables[name] = value
+		}
+		script.Variables = variables
+	}
+	return script
 }
 
 // AddScript 添加脚本
 func (sm *ScriptManager) AddScript(script models.BatchScript) error {
+	if err := validateScript(&script); err != nil {
+		return err
+	}
+
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
@@ -119,6 +144,10 @@ func (sm *ScriptManager) AddScript(script models.BatchScript) error {
 
 // UpdateScript 更新脚本
 func (sm *ScriptManager) UpdateScript(script models.BatchScript) error {
+	if err := validateScript(&script); err != nil {
+		return err
+	}
+
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
@@ -146,4 +175,15 @@ func (sm *ScriptManager) DeleteScript(id string) error {
 		}
 	}
 	return fmt.Errorf("未找到脚本: %s", id)
-}
\ No newline at end of file
+}
+
+// validateScript 保存前补全旧版本脚本缺少的执行类型，再检查脚本的执行配置和内容，有问题时返回 *ScriptValidationError
+func validateScript(script *models.BatchScript) error {
+	if script.ExecutionType == "" {
+		script.ExecutionType = DefaultExecutionType
+	}
+	if issues := ValidateScript(*script); len(issues) > 0 {
+		return &ScriptValidationError{Issues: issues}
+	}
+	return nil
+}
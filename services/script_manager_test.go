@@ -0,0 +1,59 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-term/models"
+)
+
+func TestUpdateLegacyScriptWithoutExecutionType(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "scripts.json")
+	// 旧版本保存的脚本没有 executionType 字段
+	legacy := `[{"id":"s1","name":"deploy","content":"echo hello","serverIds":["a"]}]`
+	if err := os.WriteFile(filename, []byte(legacy), 0644); err != nil {
+		t.Fatalf("无法写入脚本配置: %v", err)
+	}
+
+	sm := NewScriptManager()
+	if err := sm.LoadFromFile(filename); err != nil {
+		t.Fatalf("加载脚本配置失败: %v", err)
+	}
+	stored, err := sm.GetScriptByID("s1")
+	if err != nil {
+		t.Fatalf("获取脚本失败: %v", err)
+	}
+	script := *stored
+	if issues := ValidateScript(script); len(issues) != 0 {
+		t.Fatalf("旧脚本校验出问题: %v", issues)
+	}
+
+	script.Content = "echo updated"
+	if err := sm.UpdateScript(script); err != nil {
+		t.Fatalf("更新旧脚本失败: %v", err)
+	}
+	updated, err := sm.GetScriptByID("s1")
+	if err != nil {
+		t.Fatalf("获取脚本失败: %v", err)
+	}
+	if updated.ExecutionType != DefaultExecutionType || updated.Content != "echo updated" {
+		t.Fatalf("更新后的脚本 = %q/%q", updated.ExecutionType, updated.Content)
+	}
+}
+
+func TestAddScriptRejectsInvalidExecutionType(t *testing.T) {
+	sm := NewScriptManager()
+	sm.configFile = filepath.Join(t.TempDir(), "scripts.json")
+
+	err := sm.AddScript(models.BatchScript{ID: "s1", Content: "echo hi", ExecutionType: "commnd"})
+	var validationErr *ScriptValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Issues) != 1 || validationErr.Issues[0].Line != 0 {
+		t.Fatalf("错误 = %v, 期望执行类型校验失败", err)
+	}
+
+	if err := sm.AddScript(models.BatchScript{ID: "s2", Content: "echo hi"}); err != nil {
+		t.Fatalf("没有指定执行类型的脚本应按命令模式保存: %v", err)
+	}
+}
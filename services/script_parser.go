@@ -2,7 +2,10 @@ package services
 
 import (
 	"bufio"
+	"fmt"
+	"regexp"
 	"strings"
+	"unicode"
 )
 
 // ScriptParser 脚本解析器
@@ -15,15 +18,48 @@ func NewScriptParser() *ScriptParser {
 
 // ParseCommands 解析脚本内容，提取有效命令
 func (sp *ScriptParser) ParseCommands(scriptContent string) []string {
+	commands := []string{}
+	for _, cmd := range sp.parseSourceCommands(scriptContent) {
+		commands = append(commands, cmd.Text)
+	}
+	return commands
+}
+
+// sourceCommand 脚本中的一条命令及其起始行号（从1开始）
+type sourceCommand struct {
+	Text string
+	Line int
+}
+
+// parseSourceCommands 解析脚本内容，提取有效命令并记录每条命令在脚本中的起始行号
+// 行末的 \ 把下一行合并到同一条命令；here-document 从开始标记所在的行到结束标记作为一条命令，内容按原样保留
+func (sp *ScriptParser) parseSourceCommands(scriptContent string) []sourceCommand {
 	if scriptContent == "" {
-		return []string{}
+		return nil
 	}
 
-	var commands []string
+	var commands []sourceCommand
 	scanner := bufio.NewScanner(strings.NewReader(scriptContent))
 
 	var currentCommand strings.Builder
+	var heredocs []heredocMarker // 当前命令中尚未结束的 here-document
+	lineNo, startLine := 0, 0
 	for scanner.Scan() {
+		lineNo++
+
+		// here-document 的内容原样保留（不跳过空行和 # 开头的行），直到结束标记
+		if len(heredocs) > 0 {
+			currentCommand.WriteString("\n" + scanner.Text())
+			if heredocs[0].isEnd(scanner.Text()) {
+				heredocs = heredocs[1:]
+				if len(heredocs) == 0 {
+					commands = append(commands, sourceCommand{Text: currentCommand.String(), Line: startLine})
+					currentCommand.Reset()
+				}
+			}
+			continue
+		}
+
 		line := strings.TrimSpace(scanner.Text())
 
 		// 跳过空行
@@ -36,6 +72,10 @@ func (sp *ScriptParser) ParseCommands(scriptContent string) []string {
 			continue
 		}
 
+		if currentCommand.Len() == 0 {
+			startLine = lineNo
+		}
+
 		// 检查是否是多行命令的延续 (行末有 \)
 		if strings.HasSuffix(line, "\\") {
 			// 移除末尾的 \ 并添加到当前命令
@@ -43,10 +83,16 @@ func (sp *ScriptParser) ParseCommands(scriptContent string) []string {
 		} else {
 			// 添加当前行到命令
 			currentCommand.WriteString(line)
+
+			// 命令中开始了 here-document，之后的行直到结束标记都属于这条命令
+			if heredocs = findHeredocs(line); len(heredocs) > 0 {
+				continue
+			}
+
 			command := strings.TrimSpace(currentCommand.String())
 
 			if command != "" {
-				commands = append(commands, command)
+				commands = append(commands, sourceCommand{Text: command, Line: startLine})
 				currentCommand.Reset()
 			}
 		}
@@ -56,13 +102,144 @@ func (sp *ScriptParser) ParseCommands(scriptContent string) []string {
 	if currentCommand.Len() > 0 {
 		command := strings.TrimSpace(currentCommand.String())
 		if command != "" {
-			commands = append(commands, command)
+			commands = append(commands, sourceCommand{Text: command, Line: startLine})
 		}
 	}
 
 	return commands
 }
 
+// heredocStartPattern 匹配 here-document 的开始标记：<<EOF、<<-EOF、<<'EOF'、<<"EOF"、<<\EOF
+var heredocStartPattern = regexp.MustCompile(`<<(-?)[ \t]*(?:'([^']+)'|"([^"]+)"|\\?([A-Za-z_][A-Za-z0-9_.-]*))`)
+
+// heredocMarker 一个 here-document 的结束标记
+type heredocMarker struct {
+	delimiter string
+	stripTabs bool // <<- 形式，结束标记前可以有制表符
+}
+
+// isEnd 检查一行是否是该 here-document 的结束标记
+func (m heredocMarker) isEnd(line string) bool {
+	line = strings.TrimSuffix(line, "\r")
+	if m.stripTabs {
+		line = strings.TrimLeft(line, "\t")
+	}
+	return line == m.delimiter
+}
+
+// findHeredocs 找出一行命令中开始的所有 here-document，按出现顺序返回
+// <<< (here-string)、(( )) 中的左移运算以及引号内或转义的 << 不算在内
+func findHeredocs(line string) []heredocMarker {
+	var markers []heredocMarker
+	quoted := quotedBytes(line)
+	for _, match := range heredocStartPattern.FindAllStringSubmatchIndex(line, -1) {
+		start := match[0]
+		if quoted[start] {
+			continue
+		}
+		if start > 0 && line[start-1] == '<' {
+			continue
+		}
+		if strings.Contains(line[:start], "((") {
+			continue
+		}
+		marker := heredocMarker{stripTabs: match[3] > match[2]}
+		for group := 2; group <= 4; group++ {
+			if match[2*group] >= 0 {
+				marker.delimiter = line[match[2*group]:match[2*group+1]]
+				break
+			}
+		}
+		markers = append(markers, marker)
+	}
+	return markers
+}
+
+// quotedBytes 标记一行中位于单引号、双引号内或被反斜杠转义的字节
+func quotedBytes(line string) []bool {
+	quoted := make([]bool, len(line))
+	var quote byte // 当前所在的引号，0 表示不在引号中
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				quoted[i] = true
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				quoted[i] = true
+				if c == '\\' && i+1 < len(line) {
+					i++
+					quoted[i] = true
+				}
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '\\' && i+1 < len(line):
+			i++
+			quoted[i] = true
+		}
+	}
+	return quoted
+}
+
+// RawSegment 按原样拆分脚本得到的片段
+type RawSegment struct {
+	Text      string // 指令行为去掉首尾空白的指令；shell 片段为原始内容，保留注释、空行和缩进
+	Line      int    // 片段在脚本中的起始行号（从1开始）
+	Directive bool   // 是否为单独执行的指令行
+}
+
+// ParseRaw 按原样拆分脚本，用于脚本模式：isDirective 判定为指令的行（here-document 中的行除外）单独成段，
+// 其余连续的行作为一个 shell 片段原样保留（不去除注释和空行，不合并续行），只包含空行和注释的片段会被丢弃
+func (sp *ScriptParser) ParseRaw(scriptContent string, isDirective func(line string) bool) []RawSegment {
+	var segments []RawSegment
+	var chunk []string
+	chunkLine := 0
+
+	flush := func() {
+		text := strings.Join(chunk, "\n")
+		for _, line := range chunk {
+			if sp.IsValidCommand(line) {
+				segments = append(segments, RawSegment{Text: text, Line: chunkLine})
+				break
+			}
+		}
+		chunk = nil
+	}
+
+	var heredocs []heredocMarker // 尚未结束的 here-document，其中的行不会被当作指令
+	lines := strings.Split(strings.ReplaceAll(scriptContent, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if len(heredocs) == 0 && trimmed != "" && isDirective(trimmed) {
+			flush()
+			segments = append(segments, RawSegment{Text: trimmed, Line: i + 1, Directive: true})
+			continue
+		}
+		if len(chunk) == 0 {
+			chunkLine = i + 1
+		}
+		chunk = append(chunk, line)
+
+		if len(heredocs) > 0 {
+			if heredocs[0].isEnd(line) {
+				heredocs = heredocs[1:]
+			}
+		} else if !strings.HasPrefix(trimmed, "#") {
+			heredocs = findHeredocs(line)
+		}
+	}
+	flush()
+
+	return segments
+}
+
 // IsValidCommand 检查是否是有效命令（不是注释或空行）
 func (sp *ScriptParser) IsValidCommand(line string) bool {
 	trimmed := strings.TrimSpace(line)
@@ -94,3 +271,65 @@ func (sp *ScriptParser) StripLocalCommandPrefix(line string) string {
 	}
 	return trimmed
 }
+
+// SplitCommandArgs 按类似 shell 的规则把指令参数拆分为多个参数，用于 $upload、$download 等指令
+// 单引号中的内容原样保留；双引号中可以用 \" 表示引号，但位于参数末尾（之后是空白或结尾）的 \" 视为反斜杠加结束引号，
+// 因此以反斜杠结尾的 Windows 目录（如 "C:\My Files\"）可以直接用双引号括起来；引号外的反斜杠只转义空白和引号，
+// 其他情况下按原样保留，因此 Windows 路径（如 C:\logs\a.txt）不需要转义。引号未闭合时返回错误
+func SplitCommandArgs(s string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune // 当前所在的引号，0 表示不在引号中
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' && i+1 < len(runes) && runes[i+1] == '"' && !closesArg(runes, i+1) {
+				current.WriteRune('"')
+				i++
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\' && i+1 < len(runes) && (unicode.IsSpace(runes[i+1]) || runes[i+1] == '\'' || runes[i+1] == '"'):
+			current.WriteRune(runes[i+1])
+			inArg = true
+			i++
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("引号未闭合: %s", s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// closesArg 检查位于 i 的引号之后是否是空白或结尾，即该引号是否结束当前参数
+func closesArg(runes []rune, i int) bool {
+	return i+1 == len(runes) || unicode.IsSpace(runes[i+1])
+}
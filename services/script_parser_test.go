@@ -0,0 +1,161 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandArgsQuotedPaths(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{`C:\logs\a.txt /tmp/`, []string{`C:\logs\a.txt`, `/tmp/`}, false},
+		{`"C:\My Files\a.txt" '/remote/my folder/'`, []string{`C:\My Files\a.txt`, `/remote/my folder/`}, false},
+		// 以反斜杠结尾的 Windows 目录
+		{`/var/log/app.log "C:\My Files\"`, []string{`/var/log/app.log`, `C:\My Files\`}, false},
+		{`"C:\My Files\" /tmp`, []string{`C:\My Files\`, `/tmp`}, false},
+		{`"C:\My Files\"	"D:\Backup\"`, []string{`C:\My Files\`, `D:\Backup\`}, false},
+		// 参数中间的 \" 仍表示引号
+		{`"say \"hi\"there" /tmp`, []string{`say "hi"there`, `/tmp`}, false},
+		{`'C:\dir\' /tmp`, []string{`C:\dir\`, `/tmp`}, false},
+		{`my\ file.txt /tmp`, []string{`my file.txt`, `/tmp`}, false},
+		{`"C:\My Files\a.txt /tmp`, nil, true},
+		{`'/remote/dir /tmp`, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := SplitCommandArgs(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SplitCommandArgs(%s) 错误 = %v, 期望出错: %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitCommandArgs(%s) = %q, 期望 %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseSourceCommandsHeredoc(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []sourceCommand
+	}{
+		{
+			name:   "plain delimiter",
+			script: "cat <<EOF > /tmp/a.conf\n# not a comment\n\nkey=value\nEOF\necho done",
+			want: []sourceCommand{
+				{Text: "cat <<EOF > /tmp/a.conf\n# not a comment\n\nkey=value\nEOF", Line: 1},
+				{Text: "echo done", Line: 6},
+			},
+		},
+		{
+			name:   "dash strips leading tabs from delimiter",
+			script: "cat <<-END\n\tindented\n\tEND\necho done",
+			want: []sourceCommand{
+				{Text: "cat <<-END\n\tindented\n\tEND", Line: 1},
+				{Text: "echo done", Line: 4},
+			},
+		},
+		{
+			name:   "plain delimiter does not end on indented marker",
+			script: "cat <<END\n\tEND\nEND\necho done",
+			want: []sourceCommand{
+				{Text: "cat <<END\n\tEND\nEND", Line: 1},
+				{Text: "echo done", Line: 4},
+			},
+		},
+		{
+			name:   "single quoted delimiter",
+			script: "cat <<'EOF'\n$HOME stays literal\nEOF",
+			want:   []sourceCommand{{Text: "cat <<'EOF'\n$HOME stays literal\nEOF", Line: 1}},
+		},
+		{
+			name:   "double quoted delimiter with dash",
+			script: "cat <<-\"MARK\"\n\t$upload not a directive\n\tMARK\nls",
+			want: []sourceCommand{
+				{Text: "cat <<-\"MARK\"\n\t$upload not a directive\n\tMARK", Line: 1},
+				{Text: "ls", Line: 4},
+			},
+		},
+		{
+			name:   "escaped delimiter",
+			script: "cat <<\\EOF\nbody\nEOF",
+			want:   []sourceCommand{{Text: "cat <<\\EOF\nbody\nEOF", Line: 1}},
+		},
+		{
+			name:   "two heredocs on one line",
+			script: "paste <<A <<B\n1\nA\n2\nB\necho done",
+			want: []sourceCommand{
+				{Text: "paste <<A <<B\n1\nA\n2\nB", Line: 1},
+				{Text: "echo done", Line: 6},
+			},
+		},
+		{
+			name:   "here-string is not a heredoc",
+			script: "cat <<< hello\necho done",
+			want: []sourceCommand{
+				{Text: "cat <<< hello", Line: 1},
+				{Text: "echo done", Line: 2},
+			},
+		},
+		{
+			name:   "double quoted << is not a heredoc",
+			script: "grep \"<<EOF\" app.log\necho done",
+			want: []sourceCommand{
+				{Text: "grep \"<<EOF\" app.log", Line: 1},
+				{Text: "echo done", Line: 2},
+			},
+		},
+		{
+			name:   "single quoted << is not a heredoc",
+			script: "echo 'a<<b'\necho done",
+			want: []sourceCommand{
+				{Text: "echo 'a<<b'", Line: 1},
+				{Text: "echo done", Line: 2},
+			},
+		},
+		{
+			name:   "heredoc after quoted <<",
+			script: "grep '<<X' <<EOF\n<<X\nEOF\necho done",
+			want: []sourceCommand{
+				{Text: "grep '<<X' <<EOF\n<<X\nEOF", Line: 1},
+				{Text: "echo done", Line: 4},
+			},
+		},
+		{
+			name:   "unterminated heredoc keeps rest of script",
+			script: "cat <<EOF\nline",
+			want:   []sourceCommand{{Text: "cat <<EOF\nline", Line: 1}},
+		},
+	}
+
+	sp := NewScriptParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sp.parseSourceCommands(tt.script)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseSourceCommands = %q, 期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRawKeepsDirectivesInsideHeredoc(t *testing.T) {
+	sp := NewScriptParser()
+	script := "cat <<'EOF' > run.sh\n!not-local\n$upload a b\nEOF\n!echo local"
+	segments := sp.ParseRaw(script, func(line string) bool {
+		return strings.HasPrefix(line, "!") || strings.HasPrefix(line, "$upload ")
+	})
+
+	want := []RawSegment{
+		{Text: "cat <<'EOF' > run.sh\n!not-local\n$upload a b\nEOF", Line: 1},
+		{Text: "!echo local", Line: 5, Directive: true},
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Fatalf("ParseRaw = %+v, 期望 %+v", segments, want)
+	}
+}
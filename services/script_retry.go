@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+
+	"go-term/models"
+)
+
+// maxRetryDelay 重试等待时间的上限
+const maxRetryDelay = 5 * time.Minute
+
+// ExecutionOptions 批量脚本的执行选项
+type ExecutionOptions struct {
+	CommandTimeout time.Duration // 单条命令的超时时间，0表示不限制
+	RetryCount     int           // 失败命令的最大重试次数，0表示不重试
+	RetryDelay     time.Duration // 首次重试前的等待时间，之后每次翻倍
+
+	// OnCommandDone 每条命令得到最终结果（成功、失败、超时或跳过）时调用，可为 nil
+	OnCommandDone func(output models.CommandOutput)
+
+	// Context 执行的上下文，取消后不再等待重试，可为 nil
+	Context context.Context
+}
+
+// NewExecutionOptions 根据批量脚本的配置创建执行选项
+func NewExecutionOptions(script models.BatchScript) ExecutionOptions {
+	options := ExecutionOptions{
+		CommandTimeout: CommandTimeout(script.CommandTimeoutSeconds),
+		RetryCount:     script.RetryCount,
+	}
+	if options.RetryCount < 0 {
+		options.RetryCount = 0
+	}
+	if script.RetryDelaySeconds > 0 {
+		options.RetryDelay = time.Duration(script.RetryDelaySeconds) * time.Second
+	}
+	return options
+}
+
+// 批量执行时同时执行的服务器数量：默认值，以及避免同时建立过多SSH连接耗尽文件描述符的上限
+const (
+	DefaultBatchConcurrency = 10
+	MaxBatchConcurrency     = 100
+)
+
+// BatchConcurrency 返回批量脚本并发执行的服务器数量，未设置时为默认值，超过上限时取上限
+func BatchConcurrency(script models.BatchScript) int {
+	if script.MaxConcurrency <= 0 {
+		return DefaultBatchConcurrency
+	}
+	if script.MaxConcurrency > MaxBatchConcurrency {
+		return MaxBatchConcurrency
+	}
+	return script.MaxConcurrency
+}
+
+// commandDone 通知一条命令的最终结果
+func (o ExecutionOptions) commandDone(output models.CommandOutput) {
+	if o.OnCommandDone != nil {
+		o.OnCommandDone(output)
+	}
+}
+
+// retryDelay 第 attempt 次尝试失败后的等待时间（attempt 从1开始），按指数退避
+func (o ExecutionOptions) retryDelay(attempt int) time.Duration {
+	delay := o.RetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// waitRetry 等待第 attempt 次尝试失败后的重试间隔，上下文取消时立即返回其错误
+func (o ExecutionOptions) waitRetry(attempt int) error {
+	ctx := o.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	timer := time.NewTimer(o.retryDelay(attempt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// commandNotFoundMarkers 命令不存在时 shell 输出的提示
+var commandNotFoundMarkers = []string{
+	"command not found",
+	"未找到命令",
+	"is not recognized as an internal or external command",
+	"不是内部或外部命令",
+}
+
+// connectionErrorMarkers 已被格式化为字符串的连接错误提示
+var connectionErrorMarkers = []string{
+	"连接已断开",
+	"connection reset",
+	"connection lost",
+	"broken pipe",
+	"use of closed network connection",
+	"无法创建会话",
+}
+
+// IsRetryableError 判断命令失败后是否值得重试
+// 只有非0退出码和连接类错误会重试；超时、中止和命令不存在（退出码127）不重试
+func IsRetryableError(err error, output string) bool {
+	if err == nil {
+		return false
+	}
+
+	var timeoutErr *CommandTimeoutError
+	if errors.As(err, &timeoutErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isCommandNotFound(err, output) {
+		return false
+	}
+
+	var sshExit *ssh.ExitError
+	if errors.As(err, &sshExit) {
+		return sshExit.ExitStatus() != 0
+	}
+	var commandExit *CommandExitError
+	if errors.As(err, &commandExit) {
+		return commandExit.ExitCode != 0
+	}
+	var execExit *exec.ExitError
+	if errors.As(err, &execExit) {
+		return true
+	}
+
+	return isConnectionError(err)
+}
+
+// isCommandNotFound 判断错误是否由命令不存在引起
+func isCommandNotFound(err error, output string) bool {
+	var sshExit *ssh.ExitError
+	if errors.As(err, &sshExit) && sshExit.ExitStatus() == 127 {
+		return true
+	}
+	var commandExit *CommandExitError
+	if errors.As(err, &commandExit) && commandExit.ExitCode == 127 {
+		return true
+	}
+	var execExit *exec.ExitError
+	if errors.As(err, &execExit) && execExit.ExitCode() == 127 {
+		return true
+	}
+
+	text := strings.ToLower(err.Error() + "\n" + output)
+	for _, marker := range commandNotFoundMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isConnectionError 判断错误是否为连接断开等网络错误
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	var exitMissing *ssh.ExitMissingError
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNRESET), errors.Is(err, net.ErrClosed),
+		errors.Is(err, sftp.ErrSSHFxConnectionLost), errors.Is(err, sftp.ErrSSHFxNoConnection),
+		errors.As(err, &netErr), errors.As(err, &exitMissing):
+		return true
+	}
+
+	text := strings.ToLower(err.Error())
+	for _, marker := range connectionErrorMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-term/models"
+)
+
+// failingExecutor 每次执行都以非零退出码失败的命令执行器，只实现重试测试用到的方法
+type failingExecutor struct {
+	CommandExecutor
+	calls int32
+}
+
+func (e *failingExecutor) ExecCommandDirectWithTimeout(serverID, command string, timeout time.Duration) (string, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return "", &CommandExitError{Command: command, ExitCode: 1}
+}
+
+func (e *failingExecutor) ExecCommandsInSharedSessionWithTimeout(serverID string, commands []string, stopOnError []bool, timeout time.Duration) ([]CommandResult, error) {
+	atomic.AddInt32(&e.calls, 1)
+	return nil, &CommandExitError{Command: commands[0], ExitCode: 1}
+}
+
+func TestRetryWaitStopsOnCancel(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ese *EnhancedScriptExecutor, executor CommandExecutor, options ExecutionOptions) ([]models.CommandOutput, error)
+	}{
+		{"script mode", func(ese *EnhancedScriptExecutor, executor CommandExecutor, options ExecutionOptions) ([]models.CommandOutput, error) {
+			return ese.ExecuteScriptModeWithOptions("false", executor, "s1", options)
+		}},
+		{"command mode", func(ese *EnhancedScriptExecutor, executor CommandExecutor, options ExecutionOptions) ([]models.CommandOutput, error) {
+			return ese.ExecuteCommandModeWithOptions([]ParsedCommand{{Command: "false", CommandType: "shell"}}, executor, "s1", options)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			executor := &failingExecutor{}
+			options := ExecutionOptions{RetryCount: 3, RetryDelay: time.Minute, Context: ctx}
+
+			done := make(chan []models.CommandOutput, 1)
+			go func() {
+				outputs, _ := tt.run(NewEnhancedScriptExecutor(), executor, options)
+				done <- outputs
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			select {
+			case outputs := <-done:
+				if len(outputs) != 1 || outputs[0].Status != "failed" {
+					t.Fatalf("取消后执行应以失败结束: %+v", outputs)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("取消后仍在等待重试")
+			}
+			if calls := atomic.LoadInt32(&executor.calls); calls != 1 {
+				t.Fatalf("执行了 %d 次, 取消后不应再重试", calls)
+			}
+		})
+	}
+}
+
+func TestRetryWaitRetriesAfterDelay(t *testing.T) {
+	executor := &failingExecutor{}
+	options := ExecutionOptions{RetryCount: 2, RetryDelay: time.Millisecond}
+	outputs, err := NewEnhancedScriptExecutor().ExecuteCommandModeWithOptions([]ParsedCommand{{Command: "false", CommandType: "shell"}}, executor, "s1", options)
+	if err == nil {
+		t.Fatal("命令应执行失败")
+	}
+	if calls := atomic.LoadInt32(&executor.calls); calls != 3 {
+		t.Fatalf("执行了 %d 次, 期望 3 次", calls)
+	}
+	if len(outputs) != 1 || outputs[0].Attempts != 3 || len(outputs[0].AttemptErrors) != 2 {
+		t.Fatalf("重试记录不正确: %+v", outputs)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"go-term/models"
+)
+
+// DefaultExecutionType 没有指定执行类型（旧版本保存的脚本）时使用的执行类型，与执行时的默认行为一致
+const DefaultExecutionType = "command"
+
+// ScriptValidationIssue 脚本校验发现的一个问题
+type ScriptValidationIssue struct {
+	Line    int    `json:"line"`    // 问题所在的行（从1开始），0 表示与具体行无关（如执行配置）
+	Message string `json:"message"` // 问题描述
+}
+
+// ScriptValidationError 保存脚本时校验失败，Issues 为发现的全部问题
+type ScriptValidationError struct {
+	Issues []ScriptValidationIssue
+}
+
+func (e *ScriptValidationError) Error() string {
+	var messages []string
+	for _, issue := range e.Issues {
+		if issue.Line > 0 {
+			messages = append(messages, fmt.Sprintf("第%d行: %s", issue.Line, issue.Message))
+		} else {
+			messages = append(messages, issue.Message)
+		}
+	}
+	return "脚本校验失败: " + strings.Join(messages, "; ")
+}
+
+// ValidateScript 检查脚本的执行配置，并预先解析脚本内容，找出格式错误的 $upload、$download、$cd 指令和条件块
+// （包括使用相对路径的第一个 $cd），返回发现的全部问题，没有问题时返回 nil
+func ValidateScript(script models.BatchScript) []ScriptValidationIssue {
+	var issues []ScriptValidationIssue
+	addIssue := func(line int, format string, args ...interface{}) {
+		issues = append(issues, ScriptValidationIssue{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if script.ExecutionType == "" {
+		script.ExecutionType = DefaultExecutionType
+	}
+	commandMode := script.ExecutionType == "command"
+	if !commandMode && script.ExecutionType != "script" {
+		addIssue(0, "执行类型无效: %q，只能是 script 或 command", script.ExecutionType)
+	}
+	switch script.ExecutionMode {
+	case "", "parallel", "sequential":
+	default:
+		addIssue(0, "服务器执行方式无效: %q，只能是 parallel 或 sequential", script.ExecutionMode)
+	}
+	if script.MaxConcurrency < 0 {
+		addIssue(0, "最大并发数不能为负数: %d", script.MaxConcurrency)
+	}
+
+	ese := NewEnhancedScriptExecutor()
+	sources := ese.scriptParser.parseSourceCommands(script.Content)
+	commands := make([]ParsedCommand, len(sources))
+	for i, source := range sources {
+		commands[i] = ese.parseCommand(source.Text)
+		commands[i].Line = source.Line
+		if message := validateDirective(commands[i], commandMode); message != "" {
+			addIssue(source.Line, "%s", message)
+		}
+	}
+
+	if commandMode {
+		if index, err := checkConditionals(commands); err != nil {
+			addIssue(commands[index].Line, "%v", err)
+		} else if index, err := checkRemoteWorkDirs(commands); err != nil {
+			addIssue(commands[index].Line, "%v", err)
+		}
+	}
+
+	return issues
+}
+
+// validateDirective 检查一条指令的参数，返回问题描述，没有问题时返回空字符串
+func validateDirective(cmd ParsedCommand, commandMode bool) string {
+	switch cmd.CommandType {
+	case "upload", "download":
+		usage := "$upload 本地文件路径 远程保存目录"
+		if cmd.CommandType == "download" {
+			usage = "$download 远程文件路径 本地保存路径"
+		}
+		args, err := SplitCommandArgs(cmd.Command)
+		if err != nil {
+			return fmt.Sprintf("%v，格式: %s", err, usage)
+		}
+		if len(args) != 2 {
+			return fmt.Sprintf("需要2个参数，实际为%d个（包含空格的路径需要用引号括起来），格式: %s", len(args), usage)
+		}
+	case "cd":
+		if !commandMode {
+			return "$cd 只能在命令模式中使用"
+		}
+	case "if", "else", "endif":
+		if !commandMode {
+			return "条件块只能在命令模式中使用"
+		}
+	case "shell":
+		// 指令缺少参数时会被当作普通命令，执行时才会失败
+		switch cmd.Command {
+		case "$upload", "$download", "$cd":
+			return fmt.Sprintf("%s 缺少参数", cmd.Command)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-term/models"
+)
+
+// scriptVariablePattern 匹配脚本变量 ${NAME}，以及转义写法 $${NAME}（保留为 ${NAME} 交给 shell）
+// ${NAME:-default} 等 shell 参数展开形式不会被匹配，原样交给 shell
+var scriptVariablePattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ScriptVariables 合并脚本变量和服务器内置变量，内置变量不能被脚本变量覆盖
+// 内置变量：SERVER_ID、SERVER_NAME、SERVER_HOST、SERVER_PORT、SERVER_USER
+func ScriptVariables(variables map[string]string, server models.Server) map[string]string {
+	result := make(map[string]string, len(variables)+5)
+	for name, value := range variables {
+		result[name] = value
+	}
+	result["SERVER_ID"] = server.ID
+	result["SERVER_NAME"] = server.Name
+	result["SERVER_HOST"] = server.Host
+	result["SERVER_PORT"] = strconv.Itoa(server.Port)
+	result["SERVER_USER"] = server.Username
+	return result
+}
+
+// SubstituteVariables 替换脚本中的 ${NAME} 变量
+// 未定义的变量返回错误（列出变量名和首次出现的行号），而不是把 ${NAME} 原样发送给 shell；
+// 需要使用 shell 变量（如 ${HOME}、循环变量 ${i}）时写成 $${NAME}
+func (sp *ScriptParser) SubstituteVariables(scriptContent string, variables map[string]string) (string, error) {
+	undefined := make(map[string]int) // 变量名 -> 首次出现的行号
+
+	lines := strings.Split(scriptContent, "\n")
+	for i, line := range lines {
+		lines[i] = scriptVariablePattern.ReplaceAllStringFunc(line, func(match string) string {
+			// 转义写法：去掉一个 $
+			if strings.HasPrefix(match, "$$") {
+				return match[1:]
+			}
+			name := match[2 : len(match)-1]
+			value, ok := variables[name]
+			if !ok {
+				if _, seen := undefined[name]; !seen {
+					undefined[name] = i + 1
+				}
+				return match
+			}
+			return value
+		})
+	}
+
+	if len(undefined) > 0 {
+		names := make([]string, 0, len(undefined))
+		for name := range undefined {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(a, b int) bool {
+			if undefined[names[a]] != undefined[names[b]] {
+				return undefined[names[a]] < undefined[names[b]]
+			}
+			return names[a] < names[b]
+		})
+
+		details := make([]string, 0, len(names))
+		for _, name := range names {
+			details = append(details, fmt.Sprintf("${%s}（第%d行）", name, undefined[name]))
+		}
+		return "", fmt.Errorf("脚本中存在未定义的变量: %s；如需使用 shell 变量请写成 $${NAME}", strings.Join(details, ", "))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"go-term/models"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	server := models.Server{ID: "s1", Name: "web-01", Host: "10.0.0.1", Port: 22, Username: "root"}
+	variables := ScriptVariables(map[string]string{"PORT": "8080", "SERVER_HOST": "ignored"}, server)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr string
+	}{
+		{"script variable", "curl localhost:${PORT}", "curl localhost:8080", ""},
+		{"builtin variables", "echo ${SERVER_NAME} ${SERVER_HOST}:${SERVER_PORT} ${SERVER_USER} ${SERVER_ID}", "echo web-01 10.0.0.1:22 root s1", ""},
+		{"escaped shell variable", "cd $${HOME}/app", "cd ${HOME}/app", ""},
+		{"escaped loop variable", "for i in 1 2; do echo $${i}; done", "for i in 1 2; do echo ${i}; done", ""},
+		{"shell parameter expansion kept", "echo ${PORT:-80} ${#PATH}", "echo ${PORT:-80} ${#PATH}", ""},
+		{"escaped variable", "echo $${PORT}", "echo ${PORT}", ""},
+		{"multiline", "a=${PORT}\nb=${PORT}", "a=8080\nb=8080", ""},
+		{"undefined variable", "echo ok\ncd ${DEPLY_DIR}\necho ${DEPLY_DIR} ${HOME}", "", "${DEPLY_DIR}（第2行）, ${HOME}（第3行）"},
+		{"unknown builtin", "echo ok\necho ${SERVER_PASSWORD}\necho ${SERVER_HOSTNAME}", "", "${SERVER_PASSWORD}（第2行）, ${SERVER_HOSTNAME}（第3行）"},
+	}
+	sp := NewScriptParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sp.SubstituteVariables(tt.in, variables)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误为 %v, 期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("替换变量失败: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("SubstituteVariables(%q) = %q, 期望 %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxScrollbackMatches 单次搜索返回的最大匹配数
+const maxScrollbackMatches = 1000
+
+// ScrollbackMatch 回滚缓冲区中的一个匹配
+type ScrollbackMatch struct {
+	Offset int    `json:"offset"` // 在去除ANSI序列后的文本中的字节偏移
+	Length int    `json:"length"` // 匹配长度（字节）
+	Line   int    `json:"line"`   // 行号（从1开始）
+	Column int    `json:"column"` // 列号（从1开始，字节）
+	Text   string `json:"text"`   // 匹配所在行的内容
+}
+
+// GetScrollback 获取回滚缓冲区中保留的全部输出（原始数据，包含ANSI序列）
+func (ts *TerminalSession) GetScrollback() string {
+	return ts.GetHistory(0)
+}
+
+// GetHistory 获取最近的最多 maxBytes 字节输出（原始数据），maxBytes <= 0 时返回全部
+func (ts *TerminalSession) GetHistory(maxBytes int) string {
+	return string(ts.scrollback.Tail(maxBytes))
+}
+
+// FindInScrollback 在去除ANSI序列的回滚内容中搜索，支持普通文本和正则、忽略大小写
+// 这是纯读操作，不会影响终端输出
+func (ts *TerminalSession) FindInScrollback(pattern string, useRegex, ignoreCase bool) ([]ScrollbackMatch, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("搜索内容不能为空")
+	}
+
+	expr := pattern
+	if !useRegex {
+		expr = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式: %v", err)
+	}
+
+	text := removeANSIEscapeSequences(ts.GetScrollback())
+
+	// 记录每行的起始偏移，用于换算行列号
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	var matches []ScrollbackMatch
+	line := 0
+	for _, loc := range re.FindAllStringIndex(text, maxScrollbackMatches) {
+		// 空匹配没有意义，跳过
+		if loc[0] == loc[1] {
+			continue
+		}
+		for line+1 < len(lineStarts) && lineStarts[line+1] <= loc[0] {
+			line++
+		}
+		lineEnd := strings.IndexByte(text[lineStarts[line]:], '\n')
+		lineText := text[lineStarts[line]:]
+		if lineEnd != -1 {
+			lineText = lineText[:lineEnd]
+		}
+		matches = append(matches, ScrollbackMatch{
+			Offset: loc[0],
+			Length: loc[1] - loc[0],
+			Line:   line + 1,
+			Column: loc[0] - lineStarts[line] + 1,
+			Text:   lineText,
+		})
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-term/models"
+)
+
+// ExportToFile 将服务器分组导出为明文JSON（与配置文件格式相同），includeSecrets 为 false 时去掉密码和密钥
+func (sm *ServerManager) ExportToFile(filename string, includeSecrets bool) error {
+	groups := sm.GetGroups()
+	if !includeSecrets {
+		for i := range groups {
+			for j := range groups[i].Servers {
+				stripServerSecrets(&groups[i].Servers[j])
+			}
+		}
+	}
+
+	exported := &ServerManager{Groups: groups}
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化配置: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("无法创建目录: %v", err)
+	}
+
+	// 包含密码时仅允许当前用户读取
+	perm := os.FileMode(0644)
+	if includeSecrets {
+		perm = 0600
+	}
+	if err := os.WriteFile(filename, data, perm); err != nil {
+		return fmt.Errorf("无法写入导出文件: %v", err)
+	}
+	return nil
+}
+
+// LoadServerGroupsFromFile 读取 ExportToFile 导出的（或明文配置文件中的）服务器分组
+func LoadServerGroupsFromFile(filename string) ([]models.ServerGroup, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取导入文件: %v", err)
+	}
+
+	var imported ServerManager
+	if err := json.Unmarshal(data, &imported); err != nil {
+		return nil, fmt.Errorf("无法解析导入文件（需为明文JSON格式）: %v", err)
+	}
+
+	for _, group := range imported.Groups {
+		if group.ID == "" {
+			return nil, fmt.Errorf("导入文件中存在缺少ID的分组: %s", group.Name)
+		}
+		for _, server := range group.Servers {
+			if server.ID == "" {
+				return nil, fmt.Errorf("分组 %s 中存在缺少ID的服务器: %s", group.Name, server.Name)
+			}
+		}
+	}
+	return imported.Groups, nil
+}
+
+// stripServerSecrets 去掉服务器中的认证信息
+func stripServerSecrets(server *models.Server) {
+	server.Password = ""
+	server.KeyFile = ""
+	server.KeyData = ""
+	if server.ProxySOCKS5 != nil {
+		server.ProxySOCKS5.Password = ""
+	}
+}
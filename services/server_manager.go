@@ -1,18 +1,25 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path/filepath"
+	"sync"
 
 	"go-term/models"
 )
 
 // ServerManager 服务器管理器
+// 所有方法都是并发安全的；获取类方法返回数据的副本，修改副本不会影响配置
 type ServerManager struct {
 	Groups []models.ServerGroup `json:"groups"`
+
+	// 明文配置文件中密码、私钥字段的加密器，为 nil 时这些字段以明文保存
+	secrets *secretCipher
+	mutex   sync.RWMutex
 }
 
 // NewServerManager 创建新的服务器管理器
@@ -24,10 +31,13 @@ func NewServerManager() *ServerManager {
 
 // LoadFromFile 从文件加载服务器配置
 func (sm *ServerManager) LoadFromFile(filename string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	// 如果文件不存在，创建默认配置
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		sm.createDefaultConfig()
-		return sm.SaveToFile(filename)
+		return sm.saveToFile(filename)
 	}
 
 	data, err := ioutil.ReadFile(filename)
@@ -35,29 +45,71 @@ func (sm *ServerManager) LoadFromFile(filename string) error {
 		return fmt.Errorf("无法读取配置文件: %v", err)
 	}
 
-	err = json.Unmarshal(data, sm)
+	var loaded ServerManager
+	err = json.Unmarshal(data, &loaded)
 	if err != nil {
 		return fmt.Errorf("无法解析配置文件: %v", err)
 	}
+	if err := decryptServerSecrets(loaded.Groups, sm.secrets); err != nil {
+		return err
+	}
+	sm.Groups = loaded.Groups
 
 	return nil
 }
 
 // SaveToFile 保存服务器配置到文件（明文格式，用于向后兼容）
 func (sm *ServerManager) SaveToFile(filename string) error {
-	data, err := json.MarshalIndent(sm, "", "  ")
-	if err != nil {
-		return fmt.Errorf("无法序列化配置: %v", err)
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.saveToFile(filename)
+}
+
+// SaveStatsToFile 保存只有连接统计变化的明文配置，不轮转备份
+func (sm *ServerManager) SaveStatsToFile(filename string) error {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.writeToFile(filename, false)
+}
+
+// SetSecretPassword 设置明文配置中密码、私钥字段的加密密码，为空时不加密这些字段
+func (sm *ServerManager) SetSecretPassword(password string) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.secrets = nil
+	if password != "" {
+		sm.secrets = newSecretCipher(password)
 	}
+}
+
+// saveToFile 保存明文配置，设置了加密密码时密码和私钥字段加密保存，调用方需持有锁
+func (sm *ServerManager) saveToFile(filename string) error {
+	return sm.writeToFile(filename, true)
+}
 
-	// 确保目录存在
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("无法创建目录: %v", err)
+// writeToFile 保存明文配置，backup 为 false 时不轮转备份，调用方需持有锁
+func (sm *ServerManager) writeToFile(filename string, backup bool) error {
+	toSave := &ServerManager{Groups: sm.Groups}
+	if sm.secrets != nil {
+		groups, err := encryptServerSecrets(sm.Groups, sm.secrets)
+		if err != nil {
+			return err
+		}
+		toSave.Groups = groups
 	}
 
-	err = ioutil.WriteFile(filename, data, 0644)
+	data, err := json.MarshalIndent(toSave, "", "  ")
 	if err != nil {
+		return fmt.Errorf("无法序列化配置: %v", err)
+	}
+
+	// 先备份现有文件，再写临时文件并重命名，写入中途崩溃不会损坏原配置
+	write := writeConfigFile
+	if !backup {
+		write = writeConfigFileNoBackup
+	}
+	if err := write(filename, data, 0644); err != nil {
 		return fmt.Errorf("无法写入配置文件: %v", err)
 	}
 
@@ -66,6 +118,24 @@ func (sm *ServerManager) SaveToFile(filename string) error {
 
 // SaveToEncryptedFile 保存服务器配置到加密文件
 func (sm *ServerManager) SaveToEncryptedFile(filename string, password string) error {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.saveToEncryptedFile(filename, password)
+}
+
+// SaveStatsToEncryptedFile 保存只有连接统计变化的加密配置，不轮转备份
+func (sm *ServerManager) SaveStatsToEncryptedFile(filename string, password string) error {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	if err := NewEncryptedConfigManager(password).saveEncryptedServerManager(sm, filename, false); err != nil {
+		return fmt.Errorf("无法保存加密配置文件: %v", err)
+	}
+	return nil
+}
+
+// saveToEncryptedFile 保存加密配置，调用方需持有锁
+func (sm *ServerManager) saveToEncryptedFile(filename string, password string) error {
 	// 创建加密配置管理器
 	ecm := NewEncryptedConfigManager(password)
 
@@ -80,10 +150,13 @@ func (sm *ServerManager) SaveToEncryptedFile(filename string, password string) e
 
 // LoadFromEncryptedFile 从加密文件加载服务器配置
 func (sm *ServerManager) LoadFromEncryptedFile(filename string, password string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	// 如果文件不存在，创建默认配置
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		sm.createDefaultConfig()
-		return sm.SaveToEncryptedFile(filename, password)
+		return sm.saveToEncryptedFile(filename, password)
 	}
 
 	// 创建加密配置管理器
@@ -92,7 +165,7 @@ func (sm *ServerManager) LoadFromEncryptedFile(filename string, password string)
 	// 加载加密配置
 	loadedSM, err := ecm.LoadEncryptedServerManager(filename)
 	if err != nil {
-		return fmt.Errorf("无法加载加密配置文件: %v", err)
+		return fmt.Errorf("无法加载加密配置文件: %w", err)
 	}
 
 	// 更新当前实例
@@ -103,6 +176,9 @@ func (sm *ServerManager) LoadFromEncryptedFile(filename string, password string)
 
 // LoadFromFileWithFallback 从文件加载配置，支持明文和加密格式的自动识别
 func (sm *ServerManager) LoadFromFileWithFallback(filename string, password string) (bool, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	// 检查文件是否存在
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		// 文件不存在，创建默认配置
@@ -118,17 +194,31 @@ func (sm *ServerManager) LoadFromFileWithFallback(filename string, password stri
 
 	// 尝试以JSON格式解析（明文格式）
 	var tempSM ServerManager
-	if json.Unmarshal(data, &tempSM) == nil {
-		// 成功解析为JSON，说明是明文格式
-		*sm = tempSM
+	jsonErr := json.Unmarshal(data, &tempSM)
+	if jsonErr == nil {
+		// 成功解析为JSON，说明是明文格式，其中可能有字段级加密的认证信息
+		if err := decryptServerSecrets(tempSM.Groups, newSecretCipher(password)); err != nil {
+			return false, err
+		}
+		sm.Groups = tempSM.Groups
 		return true, nil // 需要保存为加密格式
 	}
 
-	// 尝试以加密格式解析
+	// 尝试以加密格式解析，根据失败原因给出不同的错误，密码错误时不能覆盖原文件
 	ecm := NewEncryptedConfigManager(password)
 	loadedSM, err := ecm.LoadEncryptedServerManager(filename)
 	if err != nil {
-		return false, fmt.Errorf("无法解析配置文件（既不是有效的JSON也不是有效的加密格式）: %v", err)
+		switch {
+		case errors.Is(err, ErrNotEncrypted):
+			if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+				return false, fmt.Errorf("明文配置文件已损坏: %v", jsonErr)
+			}
+			return false, fmt.Errorf("无法解析配置文件（既不是有效的JSON也不是有效的加密格式）: %w", err)
+		case errors.Is(err, ErrWrongPassword):
+			return false, fmt.Errorf("无法解密配置文件: %w", err)
+		default:
+			return false, fmt.Errorf("加密配置文件已损坏: %w", err)
+		}
 	}
 
 	// 成功解析为加密格式
@@ -136,7 +226,7 @@ func (sm *ServerManager) LoadFromFileWithFallback(filename string, password stri
 	return false, nil // 不需要重新保存，已经是加密格式
 }
 
-// createDefaultConfig 创建默认配置
+// createDefaultConfig 创建默认配置，调用方需持有写锁
 func (sm *ServerManager) createDefaultConfig() {
 	defaultGroup := models.ServerGroup{
 		ID:   "group1",
@@ -157,21 +247,28 @@ func (sm *ServerManager) createDefaultConfig() {
 	sm.Groups = append(sm.Groups, defaultGroup)
 }
 
-// GetGroups 获取所有分组
+// GetGroups 获取所有分组（副本）
 func (sm *ServerManager) GetGroups() []models.ServerGroup {
-	return sm.Groups
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return cloneGroups(sm.Groups)
 }
 
 // AddGroup 添加分组
 func (sm *ServerManager) AddGroup(group models.ServerGroup) {
-	sm.Groups = append(sm.Groups, group)
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.Groups = append(sm.Groups, cloneGroup(group))
 }
 
 // UpdateGroup 更新分组
 func (sm *ServerManager) UpdateGroup(updatedGroup models.ServerGroup) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	for i, group := range sm.Groups {
 		if group.ID == updatedGroup.ID {
-			sm.Groups[i] = updatedGroup
+			sm.Groups[i] = cloneGroup(updatedGroup)
 			return nil
 		}
 	}
@@ -180,6 +277,9 @@ func (sm *ServerManager) UpdateGroup(updatedGroup models.ServerGroup) error {
 
 // DeleteGroup 删除分组
 func (sm *ServerManager) DeleteGroup(groupID string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	for i, group := range sm.Groups {
 		if group.ID == groupID {
 			sm.Groups = append(sm.Groups[:i], sm.Groups[i+1:]...)
@@ -191,9 +291,14 @@ func (sm *ServerManager) DeleteGroup(groupID string) error {
 
 // AddServer 添加服务器到指定分组
 func (sm *ServerManager) AddServer(groupID string, server models.Server) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	for i, group := range sm.Groups {
 		if group.ID == groupID {
+			server = cloneServer(server)
 			server.GroupID = groupID
+			server.Tags = normalizeTags(server.Tags)
 			sm.Groups[i].Servers = append(sm.Groups[i].Servers, server)
 			return nil
 		}
@@ -203,11 +308,19 @@ func (sm *ServerManager) AddServer(groupID string, server models.Server) error {
 
 // UpdateServer 更新服务器信息
 func (sm *ServerManager) UpdateServer(groupID string, updatedServer models.Server) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	for i, group := range sm.Groups {
 		if group.ID == groupID {
 			for j, server := range group.Servers {
 				if server.ID == updatedServer.ID {
+					updatedServer = cloneServer(updatedServer)
 					updatedServer.GroupID = groupID
+					updatedServer.Tags = normalizeTags(updatedServer.Tags)
+					// 连接统计只由 RecordConnection 维护，避免被前端的旧数据覆盖
+					updatedServer.LastConnected = server.LastConnected
+					updatedServer.ConnectCount = server.ConnectCount
 					sm.Groups[i].Servers[j] = updatedServer
 					return nil
 				}
@@ -220,6 +333,9 @@ func (sm *ServerManager) UpdateServer(groupID string, updatedServer models.Serve
 
 // DeleteServer 从指定分组删除服务器
 func (sm *ServerManager) DeleteServer(groupID, serverID string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
 	for i, group := range sm.Groups {
 		if group.ID == groupID {
 			for j, server := range group.Servers {
@@ -235,13 +351,61 @@ func (sm *ServerManager) DeleteServer(groupID, serverID string) error {
 }
 
 // GetServerByID 根据ID获取服务器信息
+// 返回的是副本，修改后需通过 UpdateServer 写回配置
 func (sm *ServerManager) GetServerByID(serverID string) (*models.Server, error) {
-	for _, group := range sm.Groups {
-		for _, server := range group.Servers {
-			if server.ID == serverID {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	for i := range sm.Groups {
+		for j := range sm.Groups[i].Servers {
+			if sm.Groups[i].Servers[j].ID == serverID {
+				server := cloneServer(sm.Groups[i].Servers[j])
 				return &server, nil
 			}
 		}
 	}
 	return nil, fmt.Errorf("未找到ID为 %s 的服务器", serverID)
 }
+
+// cloneServer 深拷贝服务器信息，避免与管理器内部数据共享map
+func cloneServer(server models.Server) models.Server {
+	if server.Env != nil {
+		env := make(map[string]string, len(server.Env))
+		for name, value := range server.Env {
+			env[name] = value
+		}
+		server.Env = env
+	}
+	if server.Tags != nil {
+		server.Tags = append([]string(nil), server.Tags...)
+	}
+	if server.ProxySOCKS5 != nil {
+		proxy := *server.ProxySOCKS5
+		server.ProxySOCKS5 = &proxy
+	}
+	return server
+}
+
+// cloneGroup 深拷贝分组及其服务器列表
+func cloneGroup(group models.ServerGroup) models.ServerGroup {
+	if group.Servers != nil {
+		servers := make([]models.Server, len(group.Servers))
+		for i, server := range group.Servers {
+			servers[i] = cloneServer(server)
+		}
+		group.Servers = servers
+	}
+	return group
+}
+
+// cloneGroups 深拷贝分组列表
+func cloneGroups(groups []models.ServerGroup) []models.ServerGroup {
+	if groups == nil {
+		return nil
+	}
+	result := make([]models.ServerGroup, len(groups))
+	for i, group := range groups {
+		result[i] = cloneGroup(group)
+	}
+	return result
+}
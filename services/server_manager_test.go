@@ -0,0 +1,139 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"go-term/models"
+)
+
+// newTestServerManager 创建包含一个分组和一台服务器的管理器
+func newTestServerManager(t *testing.T) *ServerManager {
+	t.Helper()
+	sm := NewServerManager()
+	sm.AddGroup(models.ServerGroup{ID: "group", Name: "group"})
+	server := models.Server{
+		ID:          "s1",
+		Name:        "web-01",
+		Host:        "10.0.0.1",
+		Port:        22,
+		Tags:        []string{"prod"},
+		Env:         map[string]string{"LANG": "C"},
+		ProxySOCKS5: &models.SOCKS5Proxy{Address: "127.0.0.1:1080"},
+	}
+	if err := sm.AddServer("group", server); err != nil {
+		t.Fatalf("添加服务器失败: %v", err)
+	}
+	return sm
+}
+
+func TestGetServerByIDReturnsCopy(t *testing.T) {
+	sm := newTestServerManager(t)
+
+	server, err := sm.GetServerByID("s1")
+	if err != nil {
+		t.Fatalf("获取服务器失败: %v", err)
+	}
+	original := *server
+
+	// 通过返回的指针修改不影响管理器中的数据
+	server.Name = "changed"
+	server.Tags[0] = "changed"
+	server.Env["LANG"] = "changed"
+	server.ProxySOCKS5.Address = "changed"
+
+	stored, _ := sm.GetServerByID("s1")
+	if stored.Name != "web-01" || stored.Tags[0] != "prod" || stored.Env["LANG"] != "C" || stored.ProxySOCKS5.Address != "127.0.0.1:1080" {
+		t.Fatalf("修改返回的副本影响了管理器中的服务器: %+v", stored)
+	}
+	if original.Name != "web-01" {
+		t.Fatalf("副本的初始内容不正确: %+v", original)
+	}
+
+	// 通过 UpdateServer 写回后生效
+	if err := sm.UpdateServer("group", *server); err != nil {
+		t.Fatalf("更新服务器失败: %v", err)
+	}
+	server.Env["LANG"] = "after update"
+	stored, _ = sm.GetServerByID("s1")
+	if stored.Name != "changed" || stored.Tags[0] != "changed" || stored.Env["LANG"] != "changed" || stored.ProxySOCKS5.Address != "changed" {
+		t.Fatalf("UpdateServer 写回后服务器为 %+v", stored)
+	}
+}
+
+func TestGetGroupsReturnsCopy(t *testing.T) {
+	sm := newTestServerManager(t)
+	want := sm.GetGroups()
+
+	groups := sm.GetGroups()
+	groups[0].Name = "changed"
+	groups[0].Servers[0].Env["LANG"] = "changed"
+	groups[0].Servers = append(groups[0].Servers[:0], models.Server{ID: "other"})
+
+	selected := sm.SelectServers(models.ServerSelector{GroupIDs: []string{"group"}})
+	selected[0].Tags[0] = "changed"
+
+	if got := sm.GetGroups(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("修改返回的分组影响了管理器中的数据\n得到 %+v\n期望 %+v", got, want)
+	}
+}
+
+func TestServerManagerConcurrentAccess(t *testing.T) {
+	// 在 -race 下运行：并发增删改查服务器，同时读取和修改返回的副本
+	sm := newTestServerManager(t)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				id := fmt.Sprintf("w%d-%d", w, i)
+				server := models.Server{ID: id, Name: id, Tags: []string{"tmp"}, Env: map[string]string{"N": id}}
+				if err := sm.AddServer("group", server); err != nil {
+					t.Errorf("添加服务器失败: %v", err)
+					return
+				}
+
+				got, err := sm.GetServerByID(id)
+				if err != nil {
+					t.Errorf("获取服务器失败: %v", err)
+					return
+				}
+				got.Env["N"] = "changed"
+				got.Name = "renamed"
+				if err := sm.UpdateServer("group", *got); err != nil {
+					t.Errorf("更新服务器失败: %v", err)
+					return
+				}
+
+				for _, group := range sm.GetGroups() {
+					for j := range group.Servers {
+						group.Servers[j].Env = nil
+					}
+				}
+				for _, selected := range sm.SelectServers(models.ServerSelector{Tags: []string{"tmp"}}) {
+					selected.Tags[0] = "changed"
+				}
+				sm.GetServersByTag("prod")
+				sm.SearchServers("renamed")
+
+				if err := sm.DeleteServer("group", id); err != nil {
+					t.Errorf("删除服务器失败: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	groups := sm.GetGroups()
+	if len(groups) != 1 || len(groups[0].Servers) != 1 || groups[0].Servers[0].ID != "s1" {
+		t.Fatalf("并发增删后分组为 %+v", groups)
+	}
+	if groups[0].Servers[0].Env["LANG"] != "C" || groups[0].Servers[0].Tags[0] != "prod" {
+		t.Fatalf("并发修改副本影响了原有服务器: %+v", groups[0].Servers[0])
+	}
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"fmt"
+
+	"go-term/models"
+)
+
+// ReorderGroups 按 orderedIDs 的顺序重新排列分组
+// 不存在的ID会被忽略，未列出的分组保持原有相对顺序追加到末尾；ID重复时返回错误
+func (sm *ServerManager) ReorderGroups(orderedIDs []string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	ids := make([]string, len(sm.Groups))
+	for i, group := range sm.Groups {
+		ids[i] = group.ID
+	}
+	indexes, err := reorderIndexes(ids, orderedIDs)
+	if err != nil {
+		return err
+	}
+
+	groups := make([]models.ServerGroup, 0, len(sm.Groups))
+	for _, i := range indexes {
+		groups = append(groups, sm.Groups[i])
+	}
+	sm.Groups = groups
+	return nil
+}
+
+// ReorderServers 按 orderedIDs 的顺序重新排列分组内的服务器，规则与 ReorderGroups 相同
+func (sm *ServerManager) ReorderServers(groupID string, orderedIDs []string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	for g := range sm.Groups {
+		if sm.Groups[g].ID != groupID {
+			continue
+		}
+
+		current := sm.Groups[g].Servers
+		ids := make([]string, len(current))
+		for i, server := range current {
+			ids[i] = server.ID
+		}
+		indexes, err := reorderIndexes(ids, orderedIDs)
+		if err != nil {
+			return err
+		}
+
+		servers := make([]models.Server, 0, len(current))
+		for _, i := range indexes {
+			servers = append(servers, current[i])
+		}
+		sm.Groups[g].Servers = servers
+		return nil
+	}
+	return fmt.Errorf("未找到ID为 %s 的分组", groupID)
+}
+
+// reorderIndexes 计算按 orderedIDs 排序后各元素在 ids 中的原位置：
+// 先是 orderedIDs 中存在的元素，再按原顺序追加未列出的元素
+func reorderIndexes(ids []string, orderedIDs []string) ([]int, error) {
+	listed := make(map[string]bool, len(orderedIDs))
+	for _, id := range orderedIDs {
+		if listed[id] {
+			return nil, fmt.Errorf("排序列表中存在重复的ID: %s", id)
+		}
+		listed[id] = true
+	}
+
+	position := make(map[string]int, len(ids))
+	for i, id := range ids {
+		if _, exists := position[id]; !exists {
+			position[id] = i
+		}
+	}
+
+	indexes := make([]int, 0, len(ids))
+	for _, id := range orderedIDs {
+		if i, ok := position[id]; ok {
+			indexes = append(indexes, i)
+		}
+	}
+	for i, id := range ids {
+		if !listed[id] || position[id] != i {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go-term/models"
+)
+
+// RecordConnection 记录一次成功连接：更新最近连接时间并增加连接次数
+func (sm *ServerManager) RecordConnection(serverID string) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	gi, si := sm.findServer(serverID)
+	if gi == -1 {
+		return fmt.Errorf("未找到ID为 %s 的服务器", serverID)
+	}
+	server := &sm.Groups[gi].Servers[si]
+	server.LastConnected = time.Now().Format("2006-01-02 15:04:05")
+	server.ConnectCount++
+	return nil
+}
+
+// GetRecentServers 获取最近连接过的服务器，按最近连接时间降序；limit 小于等于0时返回全部
+func (sm *ServerManager) GetRecentServers(limit int) []models.Server {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	result := []models.Server{}
+	for _, group := range sm.Groups {
+		for _, server := range group.Servers {
+			if server.LastConnected != "" {
+				result = append(result, cloneServer(server))
+			}
+		}
+	}
+
+	// 时间格式固定，按字符串比较即为按时间比较
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].LastConnected > result[j].LastConnected
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"strings"
+
+	"go-term/models"
+)
+
+// tagQueryPrefix 搜索查询中按标签筛选的前缀
+const tagQueryPrefix = "tag:"
+
+// SearchServers 搜索服务器，返回匹配的服务器及其所在分组（按配置顺序）
+// 查询按空白分词，所有词都需匹配：普通词在名称、主机、用户名、备注和标签中做不区分大小写的子串匹配，
+// tag:xxx 要求服务器带有标签 xxx（不区分大小写）；查询为空时返回所有服务器
+func (sm *ServerManager) SearchServers(query string) []models.ServerSearchResult {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	var terms, tags []string
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		if strings.HasPrefix(field, tagQueryPrefix) {
+			if tag := strings.TrimPrefix(field, tagQueryPrefix); tag != "" {
+				tags = append(tags, tag)
+			}
+			continue
+		}
+		terms = append(terms, field)
+	}
+
+	result := []models.ServerSearchResult{}
+	for _, group := range sm.Groups {
+		for _, server := range group.Servers {
+			if !serverMatches(server, terms, tags) {
+				continue
+			}
+			result = append(result, models.ServerSearchResult{
+				Server:    cloneServer(server),
+				GroupID:   group.ID,
+				GroupName: group.Name,
+			})
+		}
+	}
+	return result
+}
+
+// serverMatches 判断服务器是否匹配所有搜索词和标签，terms 和 tags 需已转为小写
+func serverMatches(server models.Server, terms, tags []string) bool {
+	for _, tag := range tags {
+		if !hasTag(server, tag) {
+			return false
+		}
+	}
+
+	if len(terms) == 0 {
+		return true
+	}
+	fields := []string{server.Name, server.Host, server.Username, server.Note}
+	fields = append(fields, server.Tags...)
+	text := strings.ToLower(strings.Join(fields, "\n"))
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasTag 判断服务器是否带有指定标签（不区分大小写）
+func hasTag(server models.Server, tag string) bool {
+	for _, t := range server.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"go-term/models"
+)
+
+// GetServersByTag 获取带有指定标签的服务器（不区分大小写，按配置顺序，跨所有分组）
+func (sm *ServerManager) GetServersByTag(tag string) []models.Server {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	tag = strings.TrimSpace(tag)
+	result := []models.Server{}
+	if tag == "" {
+		return result
+	}
+	for _, group := range sm.Groups {
+		for _, server := range group.Servers {
+			if hasTag(server, tag) {
+				result = append(result, cloneServer(server))
+			}
+		}
+	}
+	return result
+}
+
+// GetAllTags 获取所有服务器使用过的标签（不区分大小写去重，按字母排序），保留首次出现时的写法
+func (sm *ServerManager) GetAllTags() []string {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, group := range sm.Groups {
+		for _, server := range group.Servers {
+			for _, tag := range server.Tags {
+				key := strings.ToLower(tag)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i]) < strings.ToLower(tags[j])
+	})
+	return tags
+}
+
+// normalizeTags 去掉标签首尾空白、空标签和重复标签（不区分大小写），没有标签时返回 nil 以便 JSON 中省略
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-term/internal/sshtest"
+)
+
+// sessionExecutor 直接通过 SSHConnection 执行共享session命令的执行器
+type sessionExecutor struct {
+	CommandExecutor
+	conn *SSHConnection
+}
+
+func (e *sessionExecutor) ExecCommandsInSharedSessionWithTimeout(serverID string, commands []string, stopOnError []bool, timeout time.Duration) ([]CommandResult, error) {
+	return e.conn.ExecuteCommandsWithSharedSessionTimeout(context.Background(), commands, stopOnError, timeout)
+}
+
+func TestSharedSessionContinuesAfterFailure(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+
+	for _, timeout := range []time.Duration{0, 5 * time.Second} {
+		results, err := conn.ExecuteCommandsWithSharedSessionTimeout(context.Background(),
+			[]string{"X=shared", "echo failing; false", "echo $X", "(exit 3)"}, nil, timeout)
+
+		want := []CommandResult{
+			{Output: "", ExitCode: 0},
+			{Output: "failing", ExitCode: 1},
+			{Output: "shared", ExitCode: 0},
+			{Output: "", ExitCode: 3},
+		}
+		if !reflect.DeepEqual(results, want) {
+			t.Fatalf("超时 %v: 执行结果为 %+v, 期望 %+v", timeout, results, want)
+		}
+
+		// 错误对应第一个失败的命令
+		var sessionErr *SharedSessionError
+		var exitErr *CommandExitError
+		if !errors.As(err, &sessionErr) || sessionErr.Completed != 1 || !errors.As(err, &exitErr) || exitErr.ExitCode != 1 {
+			t.Fatalf("超时 %v: 错误为 %v", timeout, err)
+		}
+	}
+}
+
+func TestSharedSessionStopOnError(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+
+	for _, timeout := range []time.Duration{0, 5 * time.Second} {
+		results, err := conn.ExecuteCommandsWithSharedSessionTimeout(context.Background(),
+			[]string{"false", "echo allowed", "false", "echo never"}, []bool{false, true, true, true}, timeout)
+
+		codes := make([]int, len(results))
+		for i, result := range results {
+			codes[i] = result.ExitCode
+		}
+		if want := []int{1, 0, 1, -1}; !reflect.DeepEqual(codes, want) {
+			t.Fatalf("超时 %v: 退出码为 %v, 期望 %v", timeout, codes, want)
+		}
+		var sessionErr *SharedSessionError
+		if !errors.As(err, &sessionErr) || sessionErr.Completed != 0 {
+			t.Fatalf("超时 %v: 错误为 %v", timeout, err)
+		}
+	}
+}
+
+func TestExecuteSharedShellCommandsContinueOnError(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+	executor := &sessionExecutor{conn: conn}
+	ese := NewEnhancedScriptExecutor()
+
+	tests := []struct {
+		name     string
+		commands []ParsedCommand
+		statuses []string
+		outputs  []string
+		wantErr  bool
+	}{
+		{
+			name: "continue on error keeps session state",
+			commands: []ParsedCommand{
+				{Command: "X=kept", CommandType: "shell"},
+				{Command: "false", CommandType: "shell", ContinueOnError: true},
+				{Command: "echo $X", CommandType: "shell"},
+			},
+			statuses: []string{"success", "failed", "success"},
+			outputs:  []string{"", "命令退出码为 1: false", "kept"},
+		},
+		{
+			name: "failure stops remaining commands",
+			commands: []ParsedCommand{
+				{Command: "echo first", CommandType: "shell"},
+				{Command: "false", CommandType: "shell"},
+				{Command: "echo never", CommandType: "shell"},
+			},
+			statuses: []string{"success", "failed", "skipped"},
+			outputs:  []string{"first", "命令退出码为 1: false", "前面的命令执行失败，未执行"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scripts, _ := remoteCommandScripts("", tt.commands)
+			results, err := ese.executeSharedShellCommands(executor, "s1", tt.commands, scripts, ExecutionOptions{}, "", false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("执行错误为 %v", err)
+			}
+			for i, result := range results {
+				if result.Status != tt.statuses[i] || result.Output != tt.outputs[i] {
+					t.Fatalf("第 %d 条命令的结果为 %s/%q, 期望 %s/%q", i+1, result.Status, result.Output, tt.statuses[i], tt.outputs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCommandModeRelativeCdResolvedOnce(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, runShell))
+	executor := &sessionExecutor{conn: conn}
+	ese := NewEnhancedScriptExecutor()
+
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("无法解析临时目录: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(base, "app", "logs"), 0755); err != nil {
+		t.Fatalf("无法创建目录: %v", err)
+	}
+
+	commands := ese.ParseCommandsWithSpecialHandling("$cd " + base + "\n$cd app\npwd\n$cd logs\npwd")
+	outputs, err := ese.ExecuteCommandMode(commands, executor, "s1")
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if got, want := outputs[2].Output, filepath.Join(base, "app"); got != want {
+		t.Fatalf("第一次 pwd = %q, 期望 %q", got, want)
+	}
+	if got, want := outputs[4].Output, filepath.Join(base, "app", "logs"); got != want {
+		t.Fatalf("第二次 pwd = %q, 期望 %q", got, want)
+	}
+}
@@ -1,15 +1,29 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
+	"net"
 	"os"
+	"path"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
+	"go-term/models"
+
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/net/proxy"
 )
 
 // FileInfo 文件信息
@@ -19,76 +33,291 @@ type FileInfo struct {
 	Size  int64  `json:"size"`
 	Mtime int64  `json:"mtime"`
 	Type  string `json:"type"` // "file" 或 "dir"
+	Perm  string `json:"perm"` // 八进制权限位，如 "755"
+
+	Mode       string `json:"mode"`                 // ls 风格的权限字符串，如 "-rwxr-xr-x"
+	Uid        uint32 `json:"uid"`                  // 所有者用户ID
+	Gid        uint32 `json:"gid"`                  // 所有者组ID
+	IsSymlink  bool   `json:"isSymlink"`            // 是否为符号链接
+	LinkTarget string `json:"linkTarget,omitempty"` // 符号链接指向的路径
+	LinkBroken bool   `json:"linkBroken,omitempty"` // 符号链接的目标不存在
 }
 
 // SSHConnection SSH连接信息
 type SSHConnection struct {
 	Client *ssh.Client
+
+	// 私钥内容（PEM），未指定密钥文件时使用
+	KeyData string
+
+	// 是否优先尝试 ssh-agent 中的密钥
+	UseAgent bool
+
+	// 主机密钥校验回调，为空时不校验主机密钥（仅用于兼容）
+	HostKeyCallback ssh.HostKeyCallback
+
+	// keyboard-interactive 认证的问答回调（用于OTP等二次验证），为空时不启用
+	KeyboardInteractive ssh.KeyboardInteractiveChallenge
+
+	// 命令包装配置（来自服务器配置），为空时不包装
+	CommandWrapper string
+	WrapTerminal   bool
+
+	// 终端会话的环境变量（来自服务器配置）
+	Env map[string]string
+
+	// 建立TCP连接的超时时间，0使用默认的30秒
+	DialTimeout time.Duration
+
+	// SOCKS5 代理，为空时直接连接服务器
+	ProxySOCKS5 *models.SOCKS5Proxy
+
+	// ExecuteCommand 和 ExecuteCommandOnHost 的超时时间（含创建会话），0表示不限制
+	CommandTimeout time.Duration
+}
+
+// ConnectTimeout 根据配置的秒数返回连接超时时间，0或负数返回0（使用默认值）
+func ConnectTimeout(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
 }
 
+// defaultDialTimeout 建立TCP连接的默认超时时间
+const defaultDialTimeout = 30 * time.Second
+
 // Connect 建立SSH连接
+// 认证方式按 ssh-agent、私钥、密码、keyboard-interactive 的顺序依次尝试
 func (s *SSHConnection) Connect(host string, port int, username string, password string, keyFile string) error {
-	var auth []ssh.AuthMethod
-
-	if keyFile != "" {
-		// 使用私钥认证
-		key, err := ioutil.ReadFile(keyFile)
-		if err != nil {
-			return fmt.Errorf("无法读取密钥文件: %v", err)
-		}
+	auth, methods, cleanup, err := s.buildAuthMethods(password, keyFile)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 
-		signer, err := ssh.ParsePrivateKey(key)
-		if err != nil {
-			return fmt.Errorf("无法解析私钥: %v", err)
-		}
+	hostKeyCallback := s.HostKeyCallback
+	if hostKeyCallback == nil {
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	}
 
-		auth = append(auth, ssh.PublicKeys(signer))
-	} else {
-		// 使用密码认证
-		auth = append(auth, ssh.Password(password))
+	dialTimeout := s.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
 	}
 
 	config := &ssh.ClientConfig{
 		User:            username,
 		Auth:            auth,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 在生产环境中应该使用更安全的主机密钥验证
-		Timeout:         30 * time.Second,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         dialTimeout,
 	}
 
 	address := fmt.Sprintf("%s:%d", host, port)
-	client, err := ssh.Dial("tcp", address, config)
+	var client *ssh.Client
+	if s.ProxySOCKS5 != nil && s.ProxySOCKS5.Address != "" {
+		client, err = s.dialThroughSOCKS5(address, config)
+	} else {
+		client, err = ssh.Dial("tcp", address, config)
+	}
 	if err != nil {
-		return fmt.Errorf("无法连接到服务器: %v", err)
+		return fmt.Errorf("无法连接到服务器（已尝试认证方式: %s）: %w", strings.Join(methods, ", "), err)
 	}
 
 	s.Client = client
 	return nil
 }
 
-// ExecuteCommand 执行远程命令
+// dialThroughSOCKS5 通过 SOCKS5 代理连接服务器并完成SSH握手
+// 关闭返回的客户端时会一并关闭经代理建立的连接
+func (s *SSHConnection) dialThroughSOCKS5(address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	var auth *proxy.Auth
+	if s.ProxySOCKS5.Username != "" {
+		auth = &proxy.Auth{User: s.ProxySOCKS5.Username, Password: s.ProxySOCKS5.Password}
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", s.ProxySOCKS5.Address, auth, &net.Dialer{Timeout: config.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("无效的SOCKS5代理配置: %v", err)
+	}
+
+	// 超时时间同时覆盖连接代理和代理建立到服务器的连接
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("通过SOCKS5代理 %s 连接失败: %w", s.ProxySOCKS5.Address, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// buildAuthMethods 按顺序构造认证方式：ssh-agent、私钥、密码、keyboard-interactive
+// agent 和私钥都属于 publickey 认证，SSH 库同一种认证只尝试一次，因此合并为一个回调按顺序提供签名者。
+// 返回的 cleanup 用于关闭 agent 连接，需在连接建立后调用
+func (s *SSHConnection) buildAuthMethods(password, keyFile string) ([]ssh.AuthMethod, []string, func(), error) {
+	var auth []ssh.AuthMethod
+	var methods []string
+	cleanup := func() {}
+
+	var signers []ssh.Signer
+
+	if s.UseAgent {
+		agentConn, err := dialSSHAgent()
+		if err != nil {
+			log.Printf("无法连接ssh-agent，跳过agent认证: %v", err)
+		} else {
+			cleanup = func() { agentConn.Close() }
+			agentSigners, err := agent.NewClient(agentConn).Signers()
+			if err != nil {
+				log.Printf("无法从ssh-agent获取密钥: %v", err)
+			} else if len(agentSigners) > 0 {
+				signers = append(signers, agentSigners...)
+				methods = append(methods, "agent")
+			}
+		}
+	}
+
+	if keyFile != "" || s.KeyData != "" {
+		// 使用私钥认证，密钥文件优先于保存的私钥内容
+		key := []byte(s.KeyData)
+		if keyFile != "" {
+			var err error
+			key, err = ioutil.ReadFile(keyFile)
+			if err != nil {
+				cleanup()
+				return nil, nil, nil, fmt.Errorf("无法读取密钥文件: %v", err)
+			}
+		}
+
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			cleanup()
+			return nil, nil, nil, fmt.Errorf("无法解析私钥: %v", err)
+		}
+
+		signers = append(signers, signer)
+		methods = append(methods, "publickey")
+	}
+
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+
+	// 配置了密码，或没有任何其他认证方式时使用密码认证
+	if password != "" || len(auth) == 0 {
+		auth = append(auth, ssh.Password(password))
+		methods = append(methods, "password")
+	}
+
+	// 二次验证（OTP等）通过 keyboard-interactive 完成
+	if s.KeyboardInteractive != nil {
+		auth = append(auth, ssh.KeyboardInteractive(s.KeyboardInteractive))
+		methods = append(methods, "keyboard-interactive")
+	}
+
+	return auth, methods, cleanup, nil
+}
+
+// dialSSHAgent 连接本机的 ssh-agent：优先使用 SSH_AUTH_SOCK，Windows 下使用 OpenSSH 的命名管道
+func dialSSHAgent() (io.ReadWriteCloser, error) {
+	if socket := os.Getenv("SSH_AUTH_SOCK"); socket != "" {
+		return net.Dial("unix", socket)
+	}
+	if runtime.GOOS == "windows" {
+		return os.OpenFile(`\\.\pipe\openssh-ssh-agent`, os.O_RDWR, 0)
+	}
+	return nil, fmt.Errorf("未设置 SSH_AUTH_SOCK")
+}
+
+// ExecuteCommand 执行远程命令（应用服务器的命令包装），超过 CommandTimeout 时返回 *CommandTimeoutError
 func (s *SSHConnection) ExecuteCommand(command string) (string, error) {
+	return s.ExecuteCommandTimeout(context.Background(), command, s.CommandTimeout)
+}
+
+// ExecuteCommandContext 执行远程命令（应用服务器的命令包装），执行时间只受 ctx 限制，ctx 取消时终止远程命令
+// 不应用 CommandTimeout；需要超时限制时使用 ExecuteCommandTimeout
+func (s *SSHConnection) ExecuteCommandContext(ctx context.Context, command string) (string, error) {
+	return s.executeOnHost(ctx, WrapCommand(s.CommandWrapper, command))
+}
+
+// ExecuteCommandOnHost 直接在主机上执行远程命令，跳过命令包装（用于诊断等场景），同样受 CommandTimeout 限制
+func (s *SSHConnection) ExecuteCommandOnHost(command string) (string, error) {
+	if s.CommandTimeout <= 0 {
+		return s.executeOnHost(context.Background(), command)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.CommandTimeout)
+	defer cancel()
+
+	start := time.Now()
+	output, err := s.executeOnHost(ctx, command)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return output, &CommandTimeoutError{Command: command, Timeout: s.CommandTimeout, Elapsed: time.Since(start)}
+	}
+	return output, err
+}
+
+func (s *SSHConnection) executeOnHost(ctx context.Context, command string) (string, error) {
 	if s.Client == nil {
 		return "", fmt.Errorf("SSH连接未建立")
 	}
 
-	session, err := s.Client.NewSession()
+	session, err := s.newSessionContext(ctx)
 	if err != nil {
-		return "", fmt.Errorf("无法创建会话: %v", err)
+		return "", err
 	}
 	defer session.Close()
 
-	output, err := session.CombinedOutput(command)
+	// 输出写入并发安全的缓冲区，超时或中止时可以返回已捕获的部分输出
+	output := &notifyingBuffer{notify: make(chan struct{}, 1)}
+	session.Stdout = output
+	session.Stderr = output
+	if err := session.Start(command); err != nil {
+		return "", fmt.Errorf("执行命令失败: %w", err)
+	}
+
+	// 在协程中等待命令结束，调用方不会因为命令不返回（如等待交互输入）而一直阻塞
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = session.Signal(ssh.SIGKILL)
+		_ = session.Close()
+		select {
+		case <-done:
+		case <-time.After(sessionKillWait):
+		}
+		return output.String(), fmt.Errorf("执行已中止: %w", ctx.Err())
+	}
+
 	if err != nil {
 		// 返回错误信息时同时返回输出内容，以便前端能看到错误详情
-		return string(output), fmt.Errorf("执行命令失败: %v", err)
+		return output.String(), fmt.Errorf("执行命令失败: %w", err)
 	}
 
-	return string(output), nil
+	return output.String(), nil
 }
 
 // ExecuteCommandsWithSharedSession 在同一个 shell session 中执行多个命令
-// 这样可以共享工作目录、环境变量等
-func (s *SSHConnection) ExecuteCommandsWithSharedSession(commands []string) ([]string, error) {
+// 这样可以共享工作目录、环境变量等；某个命令以非0退出码结束时记录其退出码并继续执行之后的命令，
+// 存在失败的命令时返回 *SharedSessionError（对应第一个失败的命令）
+func (s *SSHConnection) ExecuteCommandsWithSharedSession(commands []string) ([]CommandResult, error) {
+	return s.ExecuteCommandsWithSharedSessionContext(context.Background(), commands, nil)
+}
+
+// ExecuteCommandsWithSharedSessionContext 与 ExecuteCommandsWithSharedSession 相同，ctx 取消时终止整个会话
+// stopOnError 与 commands 一一对应，为 true 的命令以非0退出码结束时不再执行之后的命令；为 nil 时全部继续执行
+func (s *SSHConnection) ExecuteCommandsWithSharedSessionContext(ctx context.Context, commands []string, stopOnError []bool) ([]CommandResult, error) {
 	if s.Client == nil {
 		return nil, fmt.Errorf("SSH连接未建立")
 	}
@@ -98,52 +327,209 @@ func (s *SSHConnection) ExecuteCommandsWithSharedSession(commands []string) ([]s
 		return nil, fmt.Errorf("无法创建会话: %v", err)
 	}
 	defer session.Close()
+	defer closeSessionOnDone(ctx, session)()
 
-	// 为每个命令添加一个唯一的分隔符，用于分割输出
-	// 使用一个不太可能出现在正常输出中的标记
-	separator := fmt.Sprintf("===COMMAND_SEPARATOR_%d===", time.Now().UnixNano())
+	script, separator := s.sharedSessionScript(commands, stopOnError)
 
-	// 将每个命令用分隔符包装
-	var wrappedCommands []string
-	for _, cmd := range commands {
-		wrappedCommands = append(wrappedCommands, fmt.Sprintf("%s; echo '%s'", cmd, separator))
+	output, err := session.CombinedOutput(script)
+	if err != nil {
+		// 即使失败，也尝试分割输出，这样可以看到每个命令的部分输出
 	}
 
-	// 将多个命令组合成一个 shell 脚本
-	script := strings.Join(wrappedCommands, "; ")
+	results := splitSharedSessionOutput(string(output), separator, len(commands))
+	completed := strings.Count(string(output), separator)
 
-	output, err := session.CombinedOutput(script)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return results, &SharedSessionError{Completed: completed, Err: fmt.Errorf("执行已中止: %w", ctxErr)}
+	}
+	if failure := sharedSessionFailure(commands, results); failure != nil {
+		return results, failure
+	}
 	if err != nil {
-		// 即使失败，也尝试分割输出，这样可以看到每个命令的部分输出
+		return results, &SharedSessionError{Completed: completed, Err: fmt.Errorf("执行命令失败: %w", err)}
 	}
 
-	// 按分隔符分割输出
-	outputStr := string(output)
-	parts := strings.Split(outputStr, separator)
+	return results, nil
+}
 
-	var outputs []string
-	for i := 0; i < len(commands); i++ {
-		if i < len(parts) {
-			// 移除每个部分前后的空格和换行
-			outputText := strings.TrimSpace(parts[i])
-			outputs = append(outputs, outputText)
-		} else {
-			outputs = append(outputs, "")
+// sharedSessionScript 把多个命令组合成一个 shell 脚本
+// 每个命令之后用 printf 输出一行"分隔符:退出码"，用于分割输出和判断每个命令是否成功；
+// 命令以非0退出码结束时继续执行下一条命令，stopOnError 中对应为 true 的命令失败时脚本随即退出
+func (s *SSHConnection) sharedSessionScript(commands []string, stopOnError []bool) (script, separator string) {
+	// 随机生成分隔符，命令的正常输出几乎不可能包含它；
+	// 只包含字母、数字、- 和 _，放在单引号中不需要转义
+	random := make([]byte, 18)
+	if _, err := rand.Read(random); err != nil {
+		// 随机数不可用时退回时间戳
+		random = []byte(fmt.Sprintf("%d", time.Now().UnixNano()))
+	}
+	separator = "GOTERM_" + base64.RawURLEncoding.EncodeToString(random)
+
+	// 命令之间用换行分隔，避免命令末尾的注释或 & 影响后面的分隔符输出
+	var lines []string
+	for i, cmd := range commands {
+		lines = append(lines,
+			cmd,
+			"__goterm_rc=$?",
+			fmt.Sprintf("printf '\\n%%s:%%d\\n' '%s' \"$__goterm_rc\"", separator),
+		)
+		if i < len(stopOnError) && stopOnError[i] {
+			lines = append(lines, `[ "$__goterm_rc" -eq 0 ] || exit "$__goterm_rc"`)
 		}
 	}
 
+	// 将多个命令组合成一个 shell 脚本，整体应用命令包装以保持共享的工作目录和环境变量
+	return WrapCommand(s.CommandWrapper, strings.Join(lines, "\n")), separator
+}
+
+// CommandResult 共享会话中单个命令的执行结果
+type CommandResult struct {
+	Output   string `json:"output"`   // 命令输出（含标准错误）
+	ExitCode int    `json:"exitCode"` // 退出码，没有执行到的命令为 -1
+}
+
+// CommandExitError 共享会话中的命令以非0退出码结束
+type CommandExitError struct {
+	Command  string
+	ExitCode int
+}
+
+func (e *CommandExitError) Error() string {
+	return fmt.Sprintf("命令退出码为 %d: %s", e.ExitCode, e.Command)
+}
+
+// SharedSessionError 共享会话执行失败，Completed 为失败前已执行完成的命令数量
+// 某条命令以非0退出码结束时 Err 为 *CommandExitError，Completed 为该命令的序号
+type SharedSessionError struct {
+	Completed int
+	Err       error
+}
+
+func (e *SharedSessionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SharedSessionError) Unwrap() error {
+	return e.Err
+}
+
+// splitSharedSessionOutput 按分隔符把共享会话的输出分割为每个命令的输出和退出码
+func splitSharedSessionOutput(output, separator string, count int) []CommandResult {
+	parts := strings.Split(output, separator)
+
+	results := make([]CommandResult, count)
+	for i := 0; i < count; i++ {
+		results[i].ExitCode = -1
+		if i >= len(parts) {
+			continue
+		}
+
+		// 第i个命令的输出在第i段，其退出码在下一段开头的":退出码"中
+		part := parts[i]
+		if i > 0 {
+			_, part = parseSeparatorStatus(part)
+		}
+		// 移除每个部分前后的空格和换行
+		results[i].Output = strings.TrimSpace(part)
+
+		if i+1 < len(parts) {
+			if code, _ := parseSeparatorStatus(parts[i+1]); code >= 0 {
+				results[i].ExitCode = code
+			}
+		}
+	}
+	return results
+}
+
+// parseSeparatorStatus 解析分隔符之后的":退出码"，返回退出码（无法解析时为-1）和剩余内容
+func parseSeparatorStatus(part string) (int, string) {
+	if !strings.HasPrefix(part, ":") {
+		return -1, part
+	}
+	end := 1
+	for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+		end++
+	}
+	code, err := strconv.Atoi(part[1:end])
 	if err != nil {
-		return outputs, fmt.Errorf("执行命令失败: %v", err)
+		return -1, part
+	}
+	return code, part[end:]
+}
+
+// sharedSessionFailure 找到第一个以非0退出码结束的命令，没有时返回 nil
+func sharedSessionFailure(commands []string, results []CommandResult) *SharedSessionError {
+	for i, result := range results {
+		if result.ExitCode > 0 {
+			return &SharedSessionError{
+				Completed: i,
+				Err:       &CommandExitError{Command: commands[i], ExitCode: result.ExitCode},
+			}
+		}
+	}
+	return nil
+}
+
+// newSessionContext 创建会话，ctx 结束时不再等待（连接异常时 NewSession 可能长时间阻塞），稍后创建成功的会话会被关闭
+func (s *SSHConnection) newSessionContext(ctx context.Context) (*ssh.Session, error) {
+	client := s.Client
+	if ctx.Done() == nil {
+		session, err := client.NewSession()
+		if err != nil {
+			return nil, fmt.Errorf("无法创建会话: %v", err)
+		}
+		return session, nil
 	}
 
-	return outputs, nil
+	type result struct {
+		session *ssh.Session
+		err     error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		session, err := client.NewSession()
+		ch <- result{session, err}
+	}()
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			return nil, fmt.Errorf("无法创建会话: %v", r.err)
+		}
+		return r.session, nil
+	case <-ctx.Done():
+		go func() {
+			if r := <-ch; r.session != nil {
+				r.session.Close()
+			}
+		}()
+		return nil, fmt.Errorf("创建会话未完成，执行已中止: %w", ctx.Err())
+	}
 }
 
-// Close 关闭SSH连接
+// closeSessionOnDone 在 ctx 取消时终止并关闭会话，使阻塞中的执行立即返回
+// 返回的函数用于在执行结束后停止监听
+func closeSessionOnDone(ctx context.Context, session *ssh.Session) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Signal(ssh.SIGKILL)
+			_ = session.Close()
+		case <-stop:
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// Close 关闭SSH连接，可重复调用
+// 不清空 Client 字段：连接可能同时被其他协程使用，关闭后的 Client 上的操作会返回错误
 func (s *SSHConnection) Close() {
 	if s.Client != nil {
 		s.Client.Close()
-		s.Client = nil
 	}
 }
 
@@ -166,12 +552,82 @@ func (s *SSHConnection) CreateSFTPClient() (*sftp.Client, error) {
 	return client, nil
 }
 
-// UploadFile 上传文件
-func (s *SSHConnection) UploadFile(sftpClient *sftp.Client, localPath, remotePath string, progressCallback func(transferred int64, total int64)) error {
+// TransferOptions 单次文件传输的选项
+type TransferOptions struct {
+	LineEnding           string `json:"lineEnding"`           // 换行符处理: preserve(默认), lf, crlf；仅对文本文件生效
+	NormalizeForUnix     bool   `json:"normalizeForUnix"`     // 便捷选项，等同于 LineEnding = "lf"
+	TransferID           string `json:"transferId"`           // 传输ID，用于取消传输；为空时自动生成
+	Preflight            bool   `json:"preflight"`            // 上传前先检查远程目录是否可写
+	VerifyChecksum       bool   `json:"verifyChecksum"`       // 传输完成后比较两端的 SHA-256，需要远程额外读取一遍文件
+	RateLimitBytesPerSec int64  `json:"rateLimitBytesPerSec"` // 传输限速（字节/秒），0 表示不限速
+}
+
+// validate 检查选项组合是否有效
+func (o TransferOptions) validate() error {
+	if o.VerifyChecksum && o.lineEndingMode() != LineEndingPreserve {
+		return fmt.Errorf("转换换行符时无法进行校验和验证")
+	}
+	return nil
+}
+
+// lineEndingMode 返回实际使用的换行符处理方式
+func (o TransferOptions) lineEndingMode() string {
+	if o.NormalizeForUnix {
+		return LineEndingLF
+	}
+	if o.LineEnding == "" {
+		return LineEndingPreserve
+	}
+	return o.LineEnding
+}
+
+// CheckRemoteWritable 通过创建并立即删除一个临时文件检查远程目录是否可写
+// 不可写时返回具体的失败原因（如权限不足、磁盘已满）
+func (s *SSHConnection) CheckRemoteWritable(sftpClient *sftp.Client, dirPath string) error {
 	if s.Client == nil {
 		return fmt.Errorf("SSH连接未建立")
 	}
 
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Errorf("生成临时文件名失败: %v", err)
+	}
+	testPath := path.Join(dirPath, ".writetest."+hex.EncodeToString(suffix))
+
+	file, err := sftpClient.OpenFile(testPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return fmt.Errorf("目录 %s 不可写：%s: %w", dirPath, classifyTransferError(err, true), err)
+	}
+	_, writeErr := file.Write([]byte("ok"))
+	closeErr := file.Close()
+	removeErr := sftpClient.Remove(testPath)
+
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return fmt.Errorf("目录 %s 不可写：%s: %w", dirPath, classifyTransferError(writeErr, true), writeErr)
+	}
+	if removeErr != nil {
+		return fmt.Errorf("测试文件已写入但无法删除 %s: %v", testPath, removeErr)
+	}
+	return nil
+}
+
+// UploadFile 上传文件，ctx 取消时中止传输并删除未完成的远程文件
+func (s *SSHConnection) UploadFile(ctx context.Context, sftpClient *sftp.Client, localPath, remotePath string, progressCallback func(transferred int64, total int64)) error {
+	return s.UploadFileWithOptions(ctx, sftpClient, localPath, remotePath, TransferOptions{}, progressCallback)
+}
+
+// UploadFileWithOptions 按传输选项上传文件
+func (s *SSHConnection) UploadFileWithOptions(ctx context.Context, sftpClient *sftp.Client, localPath, remotePath string, options TransferOptions, progressCallback func(transferred int64, total int64)) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+	if err := options.validate(); err != nil {
+		return err
+	}
+
 	// 获取文件大小
 	localFile, err := os.Open(localPath)
 	if err != nil {
@@ -194,7 +650,7 @@ func (s *SSHConnection) UploadFile(sftpClient *sftp.Client, localPath, remotePat
 
 	dstFile, err := sftpClient.Create(remotePath)
 	if err != nil {
-		return fmt.Errorf("无法创建远程文件: %v", err)
+		return newTransferError("上传", remotePath, true, 0, totalSize, fmt.Errorf("无法创建远程文件: %w", err))
 	}
 	defer dstFile.Close()
 
@@ -203,15 +659,28 @@ func (s *SSHConnection) UploadFile(sftpClient *sftp.Client, localPath, remotePat
 	var transferred int64
 	var lastProgressUpdate int64
 	const progressUpdateInterval = 100 * 1024 // 每 100KB 更新一次进度
+	converter := newLineEndingConverter(options.lineEndingMode())
+	limiter := newTransferRateLimiter(options.RateLimitBytesPerSec)
+	buf = buf[:limiter.chunkSize(len(buf))]
 
 	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			dstFile.Close()
+			if err := sftpClient.Remove(remotePath); err != nil {
+				return fmt.Errorf("上传已取消，但删除未完成的远程文件失败: %v", err)
+			}
+			return fmt.Errorf("上传已取消: %w", ctxErr)
+		}
+
 		n, err := srcFile.Read(buf)
 		if n > 0 {
-			_, writeErr := dstFile.Write(buf[:n])
+			_, writeErr := dstFile.Write(converter.Convert(buf[:n]))
 			if writeErr != nil {
-				return fmt.Errorf("文件传输失败: %v", writeErr)
+				return newTransferError("上传", remotePath, true, transferred, totalSize, writeErr)
 			}
 			transferred += int64(n)
+			// 限速等待期间被取消时，由循环开头的取消检查清理未完成的文件
+			_ = limiter.Wait(ctx, n)
 
 			// 节流进度回调，减少事件发送频率
 			if progressCallback != nil && (transferred-lastProgressUpdate >= progressUpdateInterval || transferred == totalSize) {
@@ -223,25 +692,46 @@ func (s *SSHConnection) UploadFile(sftpClient *sftp.Client, localPath, remotePat
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("读取文件失败: %v", err)
+			return newTransferError("上传", remotePath, false, transferred, totalSize, fmt.Errorf("读取文件失败: %w", err))
+		}
+	}
+	if tail := converter.Flush(); len(tail) > 0 {
+		if _, err := dstFile.Write(tail); err != nil {
+			return newTransferError("上传", remotePath, true, transferred, totalSize, err)
 		}
 	}
 
-	// 确保数据刷新到磁盘
+	// 确保数据刷新到磁盘；服务端不支持 fsync 扩展时忽略
 	_ = dstFile.Sync()
 
+	// 磁盘已满等错误可能在关闭文件时才被服务端报告
+	if err := dstFile.Close(); err != nil {
+		return newTransferError("上传", remotePath, true, transferred, totalSize, err)
+	}
+
+	if options.VerifyChecksum {
+		return s.VerifyTransferChecksum(localPath, remotePath)
+	}
 	return nil
 }
 
-// DownloadFile 下载文件
-func (s *SSHConnection) DownloadFile(sftpClient *sftp.Client, remotePath, localPath string, progressCallback func(transferred int64, total int64)) error {
+// DownloadFile 下载文件，ctx 取消时中止传输并删除未完成的本地文件
+func (s *SSHConnection) DownloadFile(ctx context.Context, sftpClient *sftp.Client, remotePath, localPath string, progressCallback func(transferred int64, total int64)) error {
+	return s.DownloadFileWithOptions(ctx, sftpClient, remotePath, localPath, TransferOptions{}, progressCallback)
+}
+
+// DownloadFileWithOptions 按传输选项下载文件
+func (s *SSHConnection) DownloadFileWithOptions(ctx context.Context, sftpClient *sftp.Client, remotePath, localPath string, options TransferOptions, progressCallback func(transferred int64, total int64)) error {
 	if s.Client == nil {
 		return fmt.Errorf("SSH连接未建立")
 	}
+	if err := options.validate(); err != nil {
+		return err
+	}
 
 	remoteFile, err := sftpClient.Open(remotePath)
 	if err != nil {
-		return fmt.Errorf("无法打开远程文件: %v", err)
+		return newTransferError("下载", remotePath, true, 0, 0, fmt.Errorf("无法打开远程文件: %w", err))
 	}
 	defer remoteFile.Close()
 
@@ -254,7 +744,7 @@ func (s *SSHConnection) DownloadFile(sftpClient *sftp.Client, remotePath, localP
 
 	localFile, err := os.Create(localPath)
 	if err != nil {
-		return fmt.Errorf("无法创建本地文件: %v", err)
+		return newTransferError("下载", remotePath, false, 0, totalSize, fmt.Errorf("无法创建本地文件: %w", err))
 	}
 	defer localFile.Close()
 
@@ -265,15 +755,28 @@ func (s *SSHConnection) DownloadFile(sftpClient *sftp.Client, remotePath, localP
 	// 进度更新频率控制，避免频繁调用回调
 	const progressUpdateInterval = 100 * 1024 // 每传输 100KB 更新一次进度
 	var lastProgressUpdate int64
+	converter := newLineEndingConverter(options.lineEndingMode())
+	limiter := newTransferRateLimiter(options.RateLimitBytesPerSec)
+	buf = buf[:limiter.chunkSize(len(buf))]
 
 	for {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			localFile.Close()
+			if err := os.Remove(localPath); err != nil {
+				return fmt.Errorf("下载已取消，但删除未完成的本地文件失败: %v", err)
+			}
+			return fmt.Errorf("下载已取消: %w", ctxErr)
+		}
+
 		n, err := remoteFile.Read(buf)
 		if n > 0 {
-			_, writeErr := localFile.Write(buf[:n])
+			_, writeErr := localFile.Write(converter.Convert(buf[:n]))
 			if writeErr != nil {
-				return fmt.Errorf("文件传输失败: %v", writeErr)
+				return newTransferError("下载", remotePath, false, transferred, totalSize, writeErr)
 			}
 			transferred += int64(n)
+			// 限速等待期间被取消时，由循环开头的取消检查清理未完成的文件
+			_ = limiter.Wait(ctx, n)
 
 			// 节流进度回调，减少事件发送频率
 			if progressCallback != nil && (transferred-lastProgressUpdate >= progressUpdateInterval || transferred == totalSize) {
@@ -285,15 +788,24 @@ func (s *SSHConnection) DownloadFile(sftpClient *sftp.Client, remotePath, localP
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("读取远程文件失败: %v", err)
+			return newTransferError("下载", remotePath, true, transferred, totalSize, fmt.Errorf("读取远程文件失败: %w", err))
+		}
+	}
+	if tail := converter.Flush(); len(tail) > 0 {
+		if _, err := localFile.Write(tail); err != nil {
+			return newTransferError("下载", remotePath, false, transferred, totalSize, err)
 		}
 	}
 
 	// 确保数据刷新到磁盘
 	if err := localFile.Sync(); err != nil {
-		return fmt.Errorf("刷新本地文件失败: %v", err)
+		return newTransferError("下载", remotePath, false, transferred, totalSize, fmt.Errorf("刷新本地文件失败: %w", err))
 	}
 
+	if options.VerifyChecksum {
+		localFile.Close()
+		return s.VerifyTransferChecksum(localPath, remotePath)
+	}
 	return nil
 }
 
@@ -311,23 +823,132 @@ func (s *SSHConnection) ListDirectory(sftpClient *sftp.Client, path string) ([]F
 
 	var result []FileInfo
 	for _, file := range files {
-		fileInfo := FileInfo{
-			Name:  file.Name(),
-			Path:  fmt.Sprintf("%s/%s", path, file.Name()),
-			Size:  file.Size(),
-			Mtime: file.ModTime().Unix(),
-		}
+		result = append(result, describeRemoteFile(sftpClient, fmt.Sprintf("%s/%s", path, file.Name()), file))
+	}
 
-		if file.IsDir() {
-			fileInfo.Type = "dir"
+	return result, nil
+}
+
+// describeRemoteFile 根据 Lstat 得到的信息构造 FileInfo，符号链接按其最终指向的目标分类
+func describeRemoteFile(sftpClient *sftp.Client, filePath string, file os.FileInfo) FileInfo {
+	fileInfo := FileInfo{
+		Name:  file.Name(),
+		Path:  filePath,
+		Size:  file.Size(),
+		Mtime: file.ModTime().Unix(),
+		Perm:  fmt.Sprintf("%o", file.Mode().Perm()),
+		Mode:  FormatFileMode(file.Mode()),
+	}
+	if stat, ok := file.Sys().(*sftp.FileStat); ok {
+		fileInfo.Uid = stat.UID
+		fileInfo.Gid = stat.GID
+	}
+	isDir := file.IsDir()
+	if file.Mode()&os.ModeSymlink != 0 {
+		fileInfo.IsSymlink = true
+		if target, err := sftpClient.ReadLink(fileInfo.Path); err == nil {
+			fileInfo.LinkTarget = target
+		}
+		if targetInfo, err := sftpClient.Stat(fileInfo.Path); err == nil {
+			isDir = targetInfo.IsDir()
 		} else {
-			fileInfo.Type = "file"
+			fileInfo.LinkBroken = true
 		}
+	}
 
-		result = append(result, fileInfo)
+	if isDir {
+		fileInfo.Type = "dir"
+	} else {
+		fileInfo.Type = "file"
 	}
+	return fileInfo
+}
 
-	return result, nil
+// RenameFile 重命名或移动文件/目录（同一文件系统内）
+// 目标已存在时返回错误，不会覆盖
+func (s *SSHConnection) RenameFile(sftpClient *sftp.Client, oldPath, newPath string) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+	if oldPath == newPath {
+		return nil
+	}
+
+	if _, err := sftpClient.Lstat(oldPath); err != nil {
+		return fmt.Errorf("源文件不存在: %v", err)
+	}
+	// posix-rename 会直接覆盖目标，标准 rename 在不同服务器上行为不一，统一先检查目标
+	if _, err := sftpClient.Lstat(newPath); err == nil {
+		return fmt.Errorf("目标已存在: %s", newPath)
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("检查目标路径失败: %v", err)
+	}
+
+	var err error
+	if _, ok := sftpClient.HasExtension("posix-rename@openssh.com"); ok {
+		err = sftpClient.PosixRename(oldPath, newPath)
+	} else {
+		err = sftpClient.Rename(oldPath, newPath)
+	}
+	if err != nil {
+		return fmt.Errorf("重命名失败: %v", err)
+	}
+	return nil
+}
+
+// Chmod 修改远程文件或目录的权限
+func (s *SSHConnection) Chmod(sftpClient *sftp.Client, path string, mode os.FileMode) error {
+	if s.Client == nil {
+		return fmt.Errorf("SSH连接未建立")
+	}
+
+	if err := sftpClient.Chmod(path, mode); err != nil {
+		return fmt.Errorf("修改权限失败: %v", err)
+	}
+	return nil
+}
+
+// FormatFileMode 将文件模式格式化为 ls -l 风格的字符串，如 "drwxr-xr-x"、"lrwxrwxrwx"
+func FormatFileMode(mode os.FileMode) string {
+	buf := []byte("----------")
+	switch {
+	case mode&os.ModeDir != 0:
+		buf[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		buf[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		buf[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		buf[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		buf[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		buf[0] = 'b'
+	}
+
+	const rwx = "rwxrwxrwx"
+	perm := mode.Perm()
+	for i := 0; i < 9; i++ {
+		if perm&(1<<uint(8-i)) != 0 {
+			buf[i+1] = rwx[i]
+		}
+	}
+
+	// setuid/setgid/sticky 位显示在对应的执行位上
+	special := func(pos int, set bool, lower, upper byte) {
+		if !set {
+			return
+		}
+		if buf[pos] == 'x' {
+			buf[pos] = lower
+		} else {
+			buf[pos] = upper
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 's', 'S')
+	special(6, mode&os.ModeSetgid != 0, 's', 'S')
+	special(9, mode&os.ModeSticky != 0, 't', 'T')
+	return string(buf)
 }
 
 // CreateDirectory 创建目录
@@ -351,8 +972,8 @@ func (s *SSHConnection) DeleteFile(sftpClient *sftp.Client, path string) error {
 		return fmt.Errorf("SSH连接未建立")
 	}
 
-	// 获取文件信息以确定是文件还是目录
-	fileInfo, err := sftpClient.Stat(path)
+	// 获取文件信息以确定是文件还是目录；使用 Lstat，符号链接只删除链接本身而不会递归删除其目标
+	fileInfo, err := sftpClient.Lstat(path)
 	if err != nil {
 		return fmt.Errorf("获取文件信息失败: %v", err)
 	}
@@ -429,7 +1050,6 @@ func (s *SSHConnection) newSessionWithTimeout(timeout time.Duration) (*ssh.Sessi
 		// 超时：认为当前 underlying client 可能处于不健康状态，强制关闭 client。
 		// 上层会收到错误并可以选择重连（Connect）。
 		_ = s.Client.Close()
-		s.Client = nil
 		return nil, fmt.Errorf("NewSession timeout after %v; closed underlying client for recovery", timeout)
 	}
 }
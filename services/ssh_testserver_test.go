@@ -0,0 +1,48 @@
+package services
+
+import (
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+
+	"go-term/internal/sshtest"
+
+	"github.com/pkg/sftp"
+)
+
+// connectTestServer 建立到测试服务器的连接，测试结束时自动断开
+func connectTestServer(t *testing.T, srv *sshtest.Server) *SSHConnection {
+	t.Helper()
+	conn := &SSHConnection{Client: srv.Dial(t)}
+	t.Cleanup(conn.Close)
+	return conn
+}
+
+// openTestSFTP 建立到测试服务器的连接并打开 SFTP 客户端，测试结束时自动关闭
+func openTestSFTP(t *testing.T, srv *sshtest.Server) (*SSHConnection, *sftp.Client) {
+	t.Helper()
+
+	conn := connectTestServer(t, srv)
+	client, err := conn.CreateSFTPClient()
+	if err != nil {
+		t.Fatalf("创建SFTP客户端失败: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return conn, client
+}
+
+// runShell 在本机用 sh 执行测试服务器收到的命令，模拟远程 shell
+func runShell(command string, stdin io.Reader, stdout io.Writer) int {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stdout
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	} else if err != nil {
+		return 127
+	}
+	return 0
+}
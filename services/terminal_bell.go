@@ -0,0 +1,68 @@
+package services
+
+import "time"
+
+// bellMinInterval 两次 terminal:bell 事件之间的最小间隔，避免连续响铃刷屏
+const bellMinInterval = 500 * time.Millisecond
+
+const (
+	bellStateNormal = iota
+	bellStateEsc    // 刚读到 ESC
+	bellStateString // 位于 OSC/DCS 等控制字符串中
+	bellStateStrEsc // 控制字符串中读到 ESC，可能是 ST（ESC \）
+)
+
+// bellFilter 从终端输出中移除独立的响铃字符（BEL, 0x07）
+// OSC 序列可以用 BEL 作为结束符（如设置窗口标题），这类 BEL 会被保留且不计为响铃；状态跨数据块保持
+type bellFilter struct {
+	state int
+}
+
+// Filter 移除数据中的独立响铃字符，返回过滤后的数据和移除的响铃数
+func (f *bellFilter) Filter(data []byte) ([]byte, int) {
+	bells := 0
+	out := data[:0:0]
+	for i, b := range data {
+		switch f.state {
+		case bellStateNormal:
+			if b == 0x07 {
+				if bells == 0 {
+					// 第一次遇到响铃时才复制，没有响铃的数据块原样返回
+					out = append(make([]byte, 0, len(data)), data[:i]...)
+				}
+				bells++
+				continue
+			}
+			if b == 0x1b {
+				f.state = bellStateEsc
+			}
+		case bellStateEsc:
+			switch b {
+			case ']', 'P', '_', '^', 'X': // OSC、DCS、APC、PM、SOS
+				f.state = bellStateString
+			case 0x1b:
+			default:
+				f.state = bellStateNormal
+			}
+		case bellStateString:
+			if b == 0x07 {
+				f.state = bellStateNormal
+			} else if b == 0x1b {
+				f.state = bellStateStrEsc
+			}
+		case bellStateStrEsc:
+			if b == '\\' {
+				f.state = bellStateNormal
+			} else if b != 0x1b {
+				f.state = bellStateString
+			}
+		}
+		if bells > 0 {
+			out = append(out, b)
+		}
+	}
+	if bells == 0 {
+		return data, 0
+	}
+	return out, bells
+}
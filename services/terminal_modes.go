@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// terminalModeNames 终端模式名称到 RFC 4254 操作码的映射，用于按名称覆盖模式
+var terminalModeNames = map[string]uint8{
+	"VINTR": ssh.VINTR, "VQUIT": ssh.VQUIT, "VERASE": ssh.VERASE, "VKILL": ssh.VKILL,
+	"VEOF": ssh.VEOF, "VEOL": ssh.VEOL, "VEOL2": ssh.VEOL2, "VSTART": ssh.VSTART,
+	"VSTOP": ssh.VSTOP, "VSUSP": ssh.VSUSP, "VDSUSP": ssh.VDSUSP, "VREPRINT": ssh.VREPRINT,
+	"VWERASE": ssh.VWERASE, "VLNEXT": ssh.VLNEXT, "VFLUSH": ssh.VFLUSH, "VSWTCH": ssh.VSWTCH,
+	"VSTATUS": ssh.VSTATUS, "VDISCARD": ssh.VDISCARD,
+	"IGNPAR": ssh.IGNPAR, "PARMRK": ssh.PARMRK, "INPCK": ssh.INPCK, "ISTRIP": ssh.ISTRIP,
+	"INLCR": ssh.INLCR, "IGNCR": ssh.IGNCR, "ICRNL": ssh.ICRNL, "IUCLC": ssh.IUCLC,
+	"IXON": ssh.IXON, "IXANY": ssh.IXANY, "IXOFF": ssh.IXOFF, "IMAXBEL": ssh.IMAXBEL,
+	"IUTF8": ssh.IUTF8,
+	"ISIG":  ssh.ISIG, "ICANON": ssh.ICANON, "XCASE": ssh.XCASE, "ECHO": ssh.ECHO,
+	"ECHOE": ssh.ECHOE, "ECHOK": ssh.ECHOK, "ECHONL": ssh.ECHONL, "NOFLSH": ssh.NOFLSH,
+	"TOSTOP": ssh.TOSTOP, "IEXTEN": ssh.IEXTEN, "ECHOCTL": ssh.ECHOCTL, "ECHOKE": ssh.ECHOKE,
+	"PENDIN": ssh.PENDIN,
+	"OPOST":  ssh.OPOST, "OLCUC": ssh.OLCUC, "ONLCR": ssh.ONLCR, "OCRNL": ssh.OCRNL,
+	"ONOCR": ssh.ONOCR, "ONLRET": ssh.ONLRET,
+	"CS7": ssh.CS7, "CS8": ssh.CS8, "PARENB": ssh.PARENB, "PARODD": ssh.PARODD,
+	"TTY_OP_ISPEED": ssh.TTY_OP_ISPEED, "TTY_OP_OSPEED": ssh.TTY_OP_OSPEED,
+}
+
+// DefaultTerminalModes 交互式终端的默认模式
+// 开启回显和规范输入，退格键发送 DEL（与 xterm.js 一致），关闭 IXON 使 Ctrl-S/Ctrl-Q 传递给程序而不是冻结输出
+func DefaultTerminalModes() ssh.TerminalModes {
+	return ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.ECHOE:         1,
+		ssh.ECHOK:         1,
+		ssh.ECHOCTL:       1,
+		ssh.ECHOKE:        1,
+		ssh.ICANON:        1,
+		ssh.ISIG:          1,
+		ssh.IEXTEN:        1,
+		ssh.ICRNL:         1,
+		ssh.IUTF8:         1,
+		ssh.IXON:          0,
+		ssh.IXANY:         0,
+		ssh.OPOST:         1,
+		ssh.ONLCR:         1,
+		ssh.CS8:           1,
+		ssh.VERASE:        0x7f,
+		ssh.VINTR:         0x03,
+		ssh.VEOF:          0x04,
+		ssh.VSUSP:         0x1a,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+}
+
+// BuildTerminalModes 在默认模式的基础上应用按名称指定的覆盖值（名称不区分大小写，如 {"ECHO": 0}）
+func BuildTerminalModes(overrides map[string]uint32) (ssh.TerminalModes, error) {
+	modes := DefaultTerminalModes()
+	for name, value := range overrides {
+		opcode, ok := terminalModeNames[strings.ToUpper(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("未知的终端模式: %s", name)
+		}
+		modes[opcode] = value
+	}
+	return modes, nil
+}
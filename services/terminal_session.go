@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -19,27 +23,116 @@ type TerminalSession struct {
 
 	OutputChan chan []byte
 	ErrorChan  chan []byte
-	closeChan  chan struct{}
+	closeChan  <-chan struct{} // 即 ctx.Done()，会话关闭时关闭
 	closeOnce  sync.Once
+	// 会话已结束（调用了 Close，或读取到 EOF/错误）时为1，用于无副作用地判断会话是否存活
+	closed int32
+	// 为 true 时读取到的输出暂存在 suppressedOutput 中，不推送到界面也不进入回滚缓冲区（用于Tab补全查询），
+	// 恢复推送时按顺序补发；由 suppressMutex 保护
+	suppressMutex    sync.Mutex
+	outputSuppressed bool
+	suppressedOutput []suppressedChunk
+	suppressedBytes  int
+	// 远程程序是否开启了括号粘贴模式（1 开启），由输出中的 ESC[?2004h / ESC[?2004l 更新
+	bracketedPaste int32
+
+	// 会话级生命周期：Close() 时取消，所有会话相关的协程都应监听 ctx
+	ctx           context.Context
+	cancel        context.CancelFunc
+	workers       sync.WaitGroup
+	activeWorkers int32
 
 	// 添加一个缓冲区来存储最近的输出，用于处理自动补全等场景
 	outputBuffer []byte
 	bufferMutex  sync.Mutex
+	// 收到新输出时发出通知（容量为1，不阻塞读取协程），以及累计收到的输出字节数
+	outputNotify   chan struct{}
+	outputReceived int64
+
+	// 回滚缓冲区，保存最近的原始输出（含ANSI序列），用于界面重连后重绘和搜索
+	scrollback *RingBuffer
+
+	// 输出通道满时是否等待而不是立即丢弃
+	backpressure bool
+
+	// 最近一次输入的时间（UnixNano），以及空闲超时时间（0 表示不启用）
+	lastInput   int64
+	idleTimeout time.Duration
 
-	width  int
-	height int
+	// 会话录制（asciinema），为空表示未录制
+	termType      string
+	recorder      *CastRecorder
+	recorderMutex sync.Mutex
+
+	// 终端尺寸，由 sizeMutex 保护
+	sizeMutex sync.Mutex
+	width     int
+	height    int
+	// 创建会话时的选项，用于重新连接后按相同选项重建会话
+	options TerminalOptions
 
 	// 事件推送相关字段
 	serverID       string
 	eventEmitFunc  func(event string, data ...interface{})
 	outputPushDone chan struct{}
+
+	// 输出空闲刷新间隔（纳秒），没有新输出超过该时间时推送所有暂存的数据
+	idleFlushInterval int64
+
+	// 是否推送 terminal:bell 事件（1 启用，0 禁用）；响铃字符总是从输出中移除
+	bellEnabled int32
+}
+
+// 输出合并推送的参数
+const (
+	outputCoalesceDelay      = 8 * time.Millisecond  // 收到第一块数据后最多等待多久合并推送
+	outputCoalesceChunks     = 5                     // 累计多少块数据时立即推送
+	DefaultIdleFlushInterval = 50 * time.Millisecond // 默认的空闲刷新间隔
+)
+
+// DefaultTermType 默认的终端类型
+const DefaultTermType = "xterm-256color"
+
+// TerminalOptions 创建终端会话的选项
+type TerminalOptions struct {
+	TermType string            `json:"termType"` // PTY 的终端类型（$TERM），为空时使用 DefaultTermType
+	Modes    map[string]uint32 `json:"modes"`    // 覆盖默认终端模式，键为模式名称，如 {"ECHO": 0}
+	Env      map[string]string `json:"env"`      // 本次会话的环境变量，与服务器配置的默认环境变量合并，同名时优先
+
+	ScrollbackBytes int `json:"scrollbackBytes"` // 回滚缓冲区大小（字节），为0时使用 DefaultScrollbackSize
+
+	// 输出通道的缓冲数量，为0时使用 DefaultOutputBufferSize
+	OutputBufferSize int `json:"outputBufferSize"`
+	// 输出通道满时的处理方式：false（默认）丢弃最旧的数据，保证读取不阻塞，但高输出时（tail -f、yes）界面会丢失部分输出；
+	// true 时读取协程最多等待 backpressureMaxWait，期间 SSH 流量控制会让远程暂停输出，仍满时才丢弃
+	Backpressure bool `json:"backpressure"`
+
+	// 空闲超时（秒），超过该时间没有任何输入时自动关闭会话；0表示不启用
+	IdleTimeoutSeconds int `json:"idleTimeoutSeconds"`
 }
 
+const (
+	// DefaultOutputBufferSize 输出通道默认的缓冲数量
+	DefaultOutputBufferSize = 200
+	// backpressureMaxWait 背压模式下输出通道满时的最长等待时间
+	backpressureMaxWait = time.Second
+)
+
 func (s *SSHConnection) CreateTerminalSession(width, height int) (*TerminalSession, error) {
+	return s.CreateTerminalSessionWithOptions(width, height, TerminalOptions{})
+}
+
+// CreateTerminalSessionWithOptions 按选项创建终端会话
+func (s *SSHConnection) CreateTerminalSessionWithOptions(width, height int, options TerminalOptions) (*TerminalSession, error) {
 	if s.Client == nil {
 		return nil, fmt.Errorf("SSH连接未建立")
 	}
 
+	modes, err := BuildTerminalModes(options.Modes)
+	if err != nil {
+		return nil, err
+	}
+
 	session, err := s.Client.NewSession()
 	if err != nil {
 		return nil, err
@@ -51,7 +144,22 @@ func (s *SSHConnection) CreateTerminalSession(width, height int) (*TerminalSessi
 		height = 24
 	}
 
-	if err := session.RequestPty("xterm", height, width, ssh.TerminalModes{}); err != nil {
+	// 发送环境变量；sshd 未在 AcceptEnv 中允许的变量会被拒绝，这些变量改为在启动shell时设置
+	env := make(map[string]string, len(s.Env)+len(options.Env))
+	for name, value := range s.Env {
+		env[name] = value
+	}
+	for name, value := range options.Env {
+		env[name] = value
+	}
+	rejectedEnv := applySessionEnv(session, env)
+
+	termType := strings.TrimSpace(options.TermType)
+	if termType == "" {
+		termType = DefaultTermType
+	}
+
+	if err := session.RequestPty(termType, height, width, modes); err != nil {
 		session.Close()
 		return nil, err
 	}
@@ -60,33 +168,194 @@ func (s *SSHConnection) CreateTerminalSession(width, height int) (*TerminalSessi
 	stdout, _ := session.StdoutPipe()
 	stderr, _ := session.StderrPipe()
 
-	if err := session.Shell(); err != nil {
+	// 配置了终端包装或有被拒绝的环境变量时，通过命令启动登录shell，否则直接请求shell
+	wrap := s.WrapTerminal && strings.TrimSpace(s.CommandWrapper) != ""
+	if wrap || len(rejectedEnv) > 0 {
+		shellCmd := `exec ` + envPrefix(rejectedEnv) + `"${SHELL:-/bin/sh}" -l`
+		if wrap {
+			shellCmd = WrapCommand(s.CommandWrapper, shellCmd)
+		}
+		err = session.Start(shellCmd)
+	} else {
+		err = session.Shell()
+	}
+	if err != nil {
 		session.Close()
 		return nil, err
 	}
 
+	outputBufferSize := options.OutputBufferSize
+	if outputBufferSize <= 0 {
+		outputBufferSize = DefaultOutputBufferSize
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
 	ts := &TerminalSession{
 		Session:       session,
 		Stdin:         stdin,
 		stdout:        stdout,
 		stderr:        stderr,
-		OutputChan:    make(chan []byte, 200), // 适中的缓冲区大小，平衡内存和性能
+		OutputChan:    make(chan []byte, outputBufferSize), // 适中的缓冲区大小，平衡内存和性能
 		ErrorChan:     make(chan []byte, 100),
-		closeChan:     make(chan struct{}),
+		closeChan:     ctx.Done(),
+		ctx:           ctx,
+		cancel:        cancel,
 		width:         width,
 		height:        height,
 		outputPushDone: make(chan struct{}),
+		outputNotify:   make(chan struct{}, 1),
+		idleFlushInterval: int64(DefaultIdleFlushInterval),
+		bellEnabled:       1,
+		scrollback:        NewRingBuffer(options.ScrollbackBytes),
+		termType:          termType,
+		backpressure:      options.Backpressure,
+		lastInput:         time.Now().UnixNano(),
+		options:           options,
+	}
+	if options.IdleTimeoutSeconds > 0 {
+		ts.idleTimeout = time.Duration(options.IdleTimeoutSeconds) * time.Second
 	}
 
 	// 启动后台读协程
-	go ts.readLoop(ts.stdout, ts.OutputChan)
-	go ts.readLoop(ts.stderr, ts.ErrorChan)
+	ts.Go(func(ctx context.Context) { ts.readLoop(ts.stdout, ts.OutputChan) })
+	ts.Go(func(ctx context.Context) { ts.readLoop(ts.stderr, ts.ErrorChan) })
 
 	return ts, nil
 }
 
+// applySessionEnv 逐个发送环境变量，返回被服务端拒绝的变量
+func applySessionEnv(session *ssh.Session, env map[string]string) map[string]string {
+	rejected := make(map[string]string)
+	for name, value := range env {
+		if !validEnvName(name) {
+			fmt.Printf("忽略无效的环境变量名: %q\n", name)
+			continue
+		}
+		if err := session.Setenv(name, value); err != nil {
+			fmt.Printf("服务器拒绝设置环境变量 %s（可能未在 AcceptEnv 中允许），改为在启动shell时设置: %v\n", name, err)
+			rejected[name] = value
+		}
+	}
+	return rejected
+}
+
+// envPrefix 生成 "env K=V ... " 前缀，变量按名称排序以保证命令稳定
+func envPrefix(env map[string]string) string {
+	if len(env) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("env ")
+	for _, name := range names {
+		b.WriteString(ShellQuote(name + "=" + env[name]))
+		b.WriteString(" ")
+	}
+	return b.String()
+}
+
+// validEnvName 判断是否为合法的环境变量名
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z') || (i > 0 && r >= '0' && r <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// Go 启动一个会话级协程并登记到会话的协程注册表中
+// fn 必须在 ctx 取消后尽快返回，会话关闭时会等待这些协程退出
+func (ts *TerminalSession) Go(fn func(ctx context.Context)) {
+	ts.workers.Add(1)
+	atomic.AddInt32(&ts.activeWorkers, 1)
+	go func() {
+		defer ts.workers.Done()
+		defer atomic.AddInt32(&ts.activeWorkers, -1)
+		fn(ts.ctx)
+	}()
+}
+
+// Context 返回会话的生命周期上下文，会话关闭时被取消
+func (ts *TerminalSession) Context() context.Context {
+	return ts.ctx
+}
+
+// ActiveGoroutines 返回当前仍在运行的会话级协程数量
+func (ts *TerminalSession) ActiveGoroutines() int {
+	return int(atomic.LoadInt32(&ts.activeWorkers))
+}
+
+// WaitGoroutines 等待所有会话级协程退出，超时返回 false
+func (ts *TerminalSession) WaitGoroutines(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		ts.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// sendOutput 把读到的数据发送到输出通道，会话关闭时返回 false
+func (ts *TerminalSession) sendOutput(out chan []byte, data []byte) bool {
+	// 检查通道是否已关闭，使用非阻塞发送避免在高输出时阻塞
+	select {
+	case out <- data:
+		return true
+	case <-ts.closeChan:
+		return false
+	default:
+	}
+
+	// 背压模式：先等待消费者腾出空间，超时后再按丢弃策略处理
+	if ts.backpressure {
+		timer := time.NewTimer(backpressureMaxWait)
+		select {
+		case out <- data:
+			timer.Stop()
+			return true
+		case <-ts.closeChan:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+
+	// 如果通道满了，丢弃最旧的数据为新数据腾出空间
+	// 这样可以确保 tail -f 等高输出命令不会阻塞整个终端
+	select {
+	case <-out: // 丢弃一个旧数据
+		select {
+		case out <- data: // 发送新数据
+		default:
+		}
+	case <-ts.closeChan:
+		return false
+	default:
+		// 如果还是发不出去，直接丢弃这个数据包
+		// 这比阻塞整个读取循环要好
+	}
+	return true
+}
+
 func (ts *TerminalSession) readLoop(r io.Reader, out chan []byte) {
 	buf := make([]byte, 4096)
+	var pasteMode pasteModeTracker
 	for {
 		select {
 		case <-ts.closeChan:
@@ -97,23 +366,15 @@ func (ts *TerminalSession) readLoop(r io.Reader, out chan []byte) {
 				// 必须复制，否则 buf 复用导致数据错乱
 				data := make([]byte, n)
 				copy(data, buf[:n])
-				// 检查通道是否已关闭，使用非阻塞发送避免在高输出时阻塞
-				select {
-				case out <- data:
-				case <-ts.closeChan:
-					return
-				default:
-					// 如果通道满了，丢弃最旧的数据为新数据腾出空间
-					// 这样可以确保 tail -f 等高输出命令不会阻塞整个终端
-					select {
-					case <-out: // 丢弃一个旧数据
-						out <- data // 发送新数据
-					case <-ts.closeChan:
-						return
-					default:
-						// 如果还是发不出去，直接丢弃这个数据包
-						// 这比阻塞整个读取循环要好
+				if enabled, changed := pasteMode.Update(data); changed {
+					var v int32
+					if enabled {
+						v = 1
 					}
+					atomic.StoreInt32(&ts.bracketedPaste, v)
+				}
+				if !ts.deliverOutput(out, data) {
+					return
 				}
 
 				// 同时更新输出缓冲区，用于处理自动补全等场景
@@ -124,21 +385,114 @@ func (ts *TerminalSession) readLoop(r io.Reader, out chan []byte) {
 					ts.outputBuffer = ts.outputBuffer[len(ts.outputBuffer)-8192:]
 				}
 				ts.bufferMutex.Unlock()
+
+				atomic.AddInt64(&ts.outputReceived, int64(n))
+				select {
+				case ts.outputNotify <- struct{}{}:
+				default:
+				}
 			}
 			// EOF错误表示连接已正常关闭，可以直接返回
 			if err == io.EOF {
+				ts.markDisconnected()
 				return
 			}
 			if err != nil {
 				// 其他错误记录日志但继续运行
 				// 使用fmt.Println代替log.Printf避免导入问题
 				fmt.Printf("终端读取错误: %v\n", err)
+				ts.markDisconnected()
 				return
 			}
 		}
 	}
 }
 
+// BracketedPasteEnabled 远程程序当前是否开启了括号粘贴模式
+func (ts *TerminalSession) BracketedPasteEnabled() bool {
+	return atomic.LoadInt32(&ts.bracketedPaste) == 1
+}
+
+// maxSuppressedOutput 暂停推送期间最多暂存的输出字节数，超出时丢弃最旧的部分
+const maxSuppressedOutput = 64 * 1024
+
+// suppressedChunk 暂停推送期间读取到的一块输出及其目标通道
+type suppressedChunk struct {
+	out  chan []byte
+	data []byte
+}
+
+// deliverOutput 推送一块输出并写入回滚缓冲区；暂停推送期间先暂存，会话关闭时返回 false
+func (ts *TerminalSession) deliverOutput(out chan []byte, data []byte) bool {
+	ts.suppressMutex.Lock()
+	defer ts.suppressMutex.Unlock()
+
+	if ts.outputSuppressed {
+		ts.suppressedOutput = append(ts.suppressedOutput, suppressedChunk{out: out, data: data})
+		ts.suppressedBytes += len(data)
+		for ts.suppressedBytes > maxSuppressedOutput && len(ts.suppressedOutput) > 1 {
+			ts.suppressedBytes -= len(ts.suppressedOutput[0].data)
+			ts.suppressedOutput = ts.suppressedOutput[1:]
+		}
+		return true
+	}
+
+	if !ts.sendOutput(out, data) {
+		return false
+	}
+	ts.scrollback.Write(data)
+	return true
+}
+
+// SetOutputSuppressed 暂停或恢复向界面推送输出；暂停期间的输出仍可通过 GetBufferedOutput 读取，
+// 恢复时按原顺序补发（同一通道的连续输出合并为一块），界面一次性更新到与远程终端一致的状态，不会丢失输出
+func (ts *TerminalSession) SetOutputSuppressed(suppressed bool) {
+	ts.suppressMutex.Lock()
+	defer ts.suppressMutex.Unlock()
+
+	ts.outputSuppressed = suppressed
+	if suppressed {
+		return
+	}
+
+	pending := ts.suppressedOutput
+	ts.suppressedOutput = nil
+	ts.suppressedBytes = 0
+	for i := 0; i < len(pending); {
+		out := pending[i].out
+		var data []byte
+		for ; i < len(pending) && pending[i].out == out; i++ {
+			data = append(data, pending[i].data...)
+		}
+		if !ts.sendOutput(out, data) {
+			return
+		}
+		ts.scrollback.Write(data)
+	}
+}
+
+// IsClosed 判断会话是否已结束（已关闭，或远程 shell 退出、连接断开），不会读取输出
+func (ts *TerminalSession) IsClosed() bool {
+	return atomic.LoadInt32(&ts.closed) == 1
+}
+
+// MarkClosing 标记会话即将主动关闭，之后读取到的 EOF 或错误不再推送 session:disconnected 事件
+// 用于与SSH连接并行关闭的场景（连接可能先于会话关闭），会话本身仍需调用 Close 关闭
+func (ts *TerminalSession) MarkClosing() {
+	atomic.StoreInt32(&ts.closed, 1)
+}
+
+// markDisconnected 读取到 EOF 或错误时标记会话已结束，并推送 session:disconnected 事件
+// 主动调用 Close 或 MarkClosing 时已先标记，不会推送事件
+func (ts *TerminalSession) markDisconnected() {
+	if !atomic.CompareAndSwapInt32(&ts.closed, 0, 1) {
+		return
+	}
+	if ts.eventEmitFunc != nil {
+		ts.eventEmitFunc("session:disconnected", ts.serverID)
+	}
+}
+
 // GetLastOutput 获取最近的输出内容
 func (ts *TerminalSession) GetLastOutput() string {
 	ts.bufferMutex.Lock()
@@ -153,6 +507,60 @@ func (ts *TerminalSession) GetLastOutput() string {
 	return string(ts.outputBuffer[start:])
 }
 
+// GetBufferedOutput 获取输出缓冲区中的全部内容（最多8192字节）
+func (ts *TerminalSession) GetBufferedOutput() string {
+	ts.bufferMutex.Lock()
+	defer ts.bufferMutex.Unlock()
+	return string(ts.outputBuffer)
+}
+
+// WaitForOutputQuiet 等待终端输出平静下来，返回等待期间收到的输出字节数
+// 收到输出后 quiet 时间内没有新输出即返回；一直没有输出时最多等待 maxWait，
+// 这样慢速链路上也能等到完整的输出，而不依赖固定的等待时间
+func (ts *TerminalSession) WaitForOutputQuiet(quiet, maxWait time.Duration) int {
+	start := atomic.LoadInt64(&ts.outputReceived)
+	received := func() int {
+		return int(atomic.LoadInt64(&ts.outputReceived) - start)
+	}
+
+	// 丢弃调用之前残留的通知
+	select {
+	case <-ts.outputNotify:
+	default:
+	}
+
+	deadline := time.NewTimer(maxWait)
+	defer deadline.Stop()
+
+	// 收到第一块输出之前不启动平静计时
+	var quietChan <-chan time.Time
+	var quietTimer *time.Timer
+	defer func() {
+		if quietTimer != nil {
+			quietTimer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ts.outputNotify:
+			if quietTimer == nil {
+				quietTimer = time.NewTimer(quiet)
+				quietChan = quietTimer.C
+			} else {
+				stopTimer(quietTimer)
+				quietTimer.Reset(quiet)
+			}
+		case <-quietChan:
+			return received()
+		case <-deadline.C:
+			return received()
+		case <-ts.closeChan:
+			return received()
+		}
+	}
+}
+
 // ClearOutputBuffer 清空输出缓冲区
 func (ts *TerminalSession) ClearOutputBuffer() {
 	ts.bufferMutex.Lock()
@@ -166,72 +574,138 @@ func (ts *TerminalSession) SetEventEmitter(serverID string, emitFunc func(event
 	ts.eventEmitFunc = emitFunc
 }
 
+// SetIdleFlushInterval 设置输出空闲刷新间隔，小于等于0时恢复默认值
+func (ts *TerminalSession) SetIdleFlushInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultIdleFlushInterval
+	}
+	atomic.StoreInt64(&ts.idleFlushInterval, int64(interval))
+}
+
+// SetBellEnabled 启用或禁用 terminal:bell 事件
+func (ts *TerminalSession) SetBellEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&ts.bellEnabled, v)
+}
+
 // StartOutputPusher 启动输出推送协程
+// 输出按合并窗口批量推送；批次末尾不完整的 UTF-8 字符会暂存到下一批，避免被拆开显示为乱码。
+// 暂存的数据在没有新输出超过空闲刷新间隔后无条件推送，保证不带换行的提示符（如 "Password:"）及时显示
 func (ts *TerminalSession) StartOutputPusher() {
 	if ts.outputPushDone == nil {
 		ts.outputPushDone = make(chan struct{})
 	}
 
-	go func() {
+	ts.Go(func(ctx context.Context) {
 		defer close(ts.outputPushDone)
 
-		writeBuffer := make([][]byte, 0, 10)
-		var flushTimer *time.Timer
-		flushTimer = time.NewTimer(8 * time.Millisecond)
-		defer flushTimer.Stop()
-
-		flushBuffer := func() {
-			if len(writeBuffer) > 0 && ts.eventEmitFunc != nil {
-				// 合并所有数据块
-				totalLen := 0
-				for _, chunk := range writeBuffer {
-					totalLen += len(chunk)
-				}
-				combined := make([]byte, 0, totalLen)
-				for _, chunk := range writeBuffer {
-					combined = append(combined, chunk...)
-				}
+		var pending []byte
+		chunks := 0
+
+		// 响铃检测，事件按 bellMinInterval 限流
+		var bells bellFilter
+		var lastBell time.Time
 
-				// 使用事件推送数据
-				ts.eventEmitFunc("terminal-output:"+ts.serverID, string(combined))
-				writeBuffer = writeBuffer[:0] // 清空缓冲区
+		coalesceTimer := time.NewTimer(outputCoalesceDelay)
+		stopTimer(coalesceTimer)
+		coalesceArmed := false
+		idleTimer := time.NewTimer(DefaultIdleFlushInterval)
+		stopTimer(idleTimer)
+		defer coalesceTimer.Stop()
+		defer idleTimer.Stop()
+
+		// flush 推送暂存的数据，all 为 false 时保留末尾不完整的 UTF-8 字符
+		flush := func(all bool) {
+			n := len(pending)
+			if !all {
+				n = completeUTF8Prefix(pending)
 			}
-			// 重置定时器
-			if !flushTimer.Stop() {
-				select {
-				case <-flushTimer.C:
-				default:
-				}
+			if n > 0 && ts.eventEmitFunc != nil {
+				ts.eventEmitFunc("terminal-output:"+ts.serverID, string(pending[:n]))
+			}
+			pending = append(pending[:0], pending[n:]...)
+			chunks = 0
+			if coalesceArmed {
+				stopTimer(coalesceTimer)
+				coalesceArmed = false
 			}
-			flushTimer.Reset(8 * time.Millisecond)
 		}
 
 		for {
 			select {
 			case <-ts.closeChan:
 				// 退出前刷新缓冲区
-				flushBuffer()
+				flush(true)
 				return
 			case data, ok := <-ts.OutputChan:
 				if !ok {
 					// 通道已关闭,退出前刷新缓冲区
-					flushBuffer()
+					flush(true)
 					return
 				}
 
-				// 将数据写入缓冲区
-				writeBuffer = append(writeBuffer, data)
+				// 录制原始输出（异步写入，不阻塞推送）
+				if rec := ts.activeRecorder(); rec != nil {
+					rec.Output(data)
+				}
 
-				// 如果缓冲区达到一定大小,立即刷新
-				if len(writeBuffer) >= 5 {
-					flushBuffer()
+				filtered, bellCount := bells.Filter(data)
+				if bellCount > 0 && atomic.LoadInt32(&ts.bellEnabled) == 1 && ts.eventEmitFunc != nil &&
+					time.Since(lastBell) >= bellMinInterval {
+					lastBell = time.Now()
+					ts.eventEmitFunc("terminal:bell", ts.serverID)
 				}
-			case <-flushTimer.C:
-				// 定时器到期,刷新缓冲区
-				flushBuffer()
+
+				pending = append(pending, filtered...)
+				chunks++
+
+				// 每次有新输出都重新计算空闲时间
+				stopTimer(idleTimer)
+				idleTimer.Reset(time.Duration(atomic.LoadInt64(&ts.idleFlushInterval)))
+
+				// 累计足够多的数据块时立即推送，否则在合并窗口结束时推送
+				if chunks >= outputCoalesceChunks {
+					flush(false)
+				} else if !coalesceArmed {
+					coalesceTimer.Reset(outputCoalesceDelay)
+					coalesceArmed = true
+				}
+			case <-coalesceTimer.C:
+				coalesceArmed = false
+				flush(false)
+			case <-idleTimer.C:
+				// 输出已空闲，推送包括不完整字符在内的所有数据
+				flush(true)
 			}
 		}
-	}()
+	})
+}
+
+// stopTimer 停止定时器并清空其通道，使其可以安全地 Reset
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// completeUTF8Prefix 返回 data 中以完整 UTF-8 字符结尾的最长前缀长度
+func completeUTF8Prefix(data []byte) int {
+	// 从末尾向前查找最后一个字符的起始字节（最多回看 utf8.UTFMax-1 个字节）
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if utf8.FullRune(data[i:]) {
+				return len(data)
+			}
+			return i
+		}
+	}
+	return len(data)
 }
 
 // ParseAutoCompleteSuggestions 解析自动补全建议列表
@@ -353,52 +827,22 @@ func removeDuplicates(slice []string) []string {
 	return result
 }
 
-// removeANSIEscapeSequences 移除ANSI转义序列
-func removeANSIEscapeSequences(text string) string {
-	// 移除ANSI颜色码和控制字符
-	result := text
-
-	// 移除 \x1b[...m 格式的ANSI转义序列
-	re := strings.NewReplacer(
-		"\x1b[0m", "",
-		"\x1b[1m", "",
-		"\x1b[31m", "",
-		"\x1b[32m", "",
-		"\x1b[33m", "",
-		"\x1b[34m", "",
-		"\x1b[35m", "",
-		"\x1b[36m", "",
-		"\x1b[37m", "",
-		"\x1b[1;31m", "",
-		"\x1b[1;32m", "",
-		"\x1b[1;33m", "",
-		"\x1b[1;34m", "",
-		"\x1b[1;35m", "",
-		"\x1b[1;36m", "",
-		"\x1b[1;37m", "",
-		"\x07", "", // Bell character
-		"\r", "", // Carriage return
-	)
-
-	result = re.Replace(result)
-
-	// 移除其他ANSI转义序列（更通用的方法）
-	for strings.Contains(result, "\x1b[") {
-		start := strings.Index(result, "\x1b[")
-		if start == -1 {
-			break
-		}
-		end := strings.Index(result[start:], "m")
-		if end == -1 {
-			break
-		}
-		result = result[:start] + result[start+end+1:]
-	}
+// ansiEscapePattern 匹配终端转义序列：
+// OSC（ESC ] ... BEL 或 ESC \）、CSI（ESC [ 参数 中间字节 结束字节，包括光标移动、清除行等）、
+// 字符集选择（ESC ( B 等）以及其它两字节的 ESC 序列
+var ansiEscapePattern = regexp.MustCompile(`\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b\[[0-?]*[ -/]*[@-~]|\x1b[()*+][0-9A-Za-z]|\x1b[@-Z\\-_=>78]`)
 
-	return result
+// controlCharPattern 匹配除换行和制表符之外的控制字符（包括回车、响铃和残留的 ESC）
+var controlCharPattern = regexp.MustCompile(`[\x00-\x08\x0b-\x1f\x7f]`)
+
+// removeANSIEscapeSequences 移除ANSI转义序列和控制字符
+func removeANSIEscapeSequences(text string) string {
+	result := ansiEscapePattern.ReplaceAllString(text, "")
+	return controlCharPattern.ReplaceAllString(result, "")
 }
 
 func (ts *TerminalSession) SendCommand(c string) error {
+	ts.touchInput()
 	// Tab字符特殊处理 - 不添加换行符
 	if c == "\t" {
 		_, err := ts.Stdin.Write([]byte(c))
@@ -416,10 +860,53 @@ func (ts *TerminalSession) SendCommand(c string) error {
 
 // SendCommandWithoutNewline 发送命令但不添加换行符
 func (ts *TerminalSession) SendCommandWithoutNewline(c string) error {
+	ts.touchInput()
 	_, err := ts.Stdin.Write([]byte(c))
 	return err
 }
 
+// touchInput 记录最近一次输入的时间
+func (ts *TerminalSession) touchInput() {
+	atomic.StoreInt64(&ts.lastInput, time.Now().UnixNano())
+}
+
+// LastInputTime 获取最近一次输入的时间（未输入过时为会话创建时间）
+func (ts *TerminalSession) LastInputTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&ts.lastInput))
+}
+
+// IdleTimeout 获取会话的空闲超时时间，0表示不启用
+func (ts *TerminalSession) IdleTimeout() time.Duration {
+	return ts.idleTimeout
+}
+
+// StartIdleWatcher 启动空闲超时监视，超时后调用 onTimeout（在独立协程中）
+// onTimeout 负责通知界面并关闭会话；未启用空闲超时时不启动
+func (ts *TerminalSession) StartIdleWatcher(onTimeout func()) {
+	if ts.idleTimeout <= 0 || onTimeout == nil {
+		return
+	}
+
+	ts.Go(func(ctx context.Context) {
+		for {
+			remaining := ts.idleTimeout - time.Since(ts.LastInputTime())
+			if remaining <= 0 {
+				// Close() 会等待会话协程退出，不能在本协程中同步调用
+				go onTimeout()
+				return
+			}
+
+			timer := time.NewTimer(remaining)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
 func (ts *TerminalSession) ReadOutput() (string, error) {
 	select {
 	case d := <-ts.OutputChan:
@@ -429,6 +916,18 @@ func (ts *TerminalSession) ReadOutput() (string, error) {
 	}
 }
 
+// Size 返回终端当前的宽度和高度
+func (ts *TerminalSession) Size() (int, int) {
+	ts.sizeMutex.Lock()
+	defer ts.sizeMutex.Unlock()
+	return ts.width, ts.height
+}
+
+// Options 返回创建会话时使用的选项
+func (ts *TerminalSession) Options() TerminalOptions {
+	return ts.options
+}
+
 // ResizeTerminal 调整终端大小
 func (ts *TerminalSession) ResizeTerminal(width, height int) error {
 	if ts.Session == nil {
@@ -436,18 +935,69 @@ func (ts *TerminalSession) ResizeTerminal(width, height int) error {
 	}
 
 	// 更新本地记录的尺寸
+	ts.sizeMutex.Lock()
 	ts.width = width
 	ts.height = height
+	ts.sizeMutex.Unlock()
+
+	if rec := ts.activeRecorder(); rec != nil {
+		rec.Resize(width, height)
+	}
 
 	// 发送窗口大小调整请求到远程
 	return ts.Session.WindowChange(height, width)
 }
 
+// StartRecording 开始将终端输出录制为 asciinema v2 文件
+func (ts *TerminalSession) StartRecording(path string) error {
+	ts.recorderMutex.Lock()
+	defer ts.recorderMutex.Unlock()
+
+	if ts.recorder != nil {
+		return fmt.Errorf("会话正在录制: %s", ts.recorder.Path())
+	}
+	width, height := ts.Size()
+	rec, err := NewCastRecorder(path, width, height, ts.termType)
+	if err != nil {
+		return err
+	}
+	ts.recorder = rec
+	return nil
+}
+
+// StopRecording 停止录制并关闭录制文件，返回录制文件路径
+func (ts *TerminalSession) StopRecording() (string, error) {
+	ts.recorderMutex.Lock()
+	rec := ts.recorder
+	ts.recorder = nil
+	ts.recorderMutex.Unlock()
+
+	if rec == nil {
+		return "", fmt.Errorf("会话未在录制")
+	}
+	if err := rec.Close(); err != nil {
+		return rec.Path(), fmt.Errorf("关闭录制文件失败: %v", err)
+	}
+	if dropped := rec.Dropped(); dropped > 0 {
+		fmt.Printf("录制 %s 期间因写入过慢丢弃了 %d 个事件\n", rec.Path(), dropped)
+	}
+	return rec.Path(), nil
+}
+
+func (ts *TerminalSession) activeRecorder() *CastRecorder {
+	ts.recorderMutex.Lock()
+	defer ts.recorderMutex.Unlock()
+	return ts.recorder
+}
+
 func (ts *TerminalSession) Close() error {
 	var err error
 	ts.closeOnce.Do(func() {
-		// 先关闭channel，通知readLoop退出
-		close(ts.closeChan)
+		// 先标记为已结束，随后读取到的 EOF 不再视为意外断开
+		atomic.StoreInt32(&ts.closed, 1)
+
+		// 先取消会话上下文（同时关闭closeChan），通知所有会话协程退出
+		ts.cancel()
 
 		// 等待输出推送协程退出
 		if ts.outputPushDone != nil {
@@ -459,6 +1009,11 @@ func (ts *TerminalSession) Close() error {
 			}
 		}
 
+		// 结束录制，保证录制文件完整
+		if ts.activeRecorder() != nil {
+			ts.StopRecording()
+		}
+
 		// 设置一个超时上下文确保不会无限等待
 		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
@@ -498,6 +1053,11 @@ func (ts *TerminalSession) Close() error {
 				return
 			}
 		}
+
+		// stdin/session 关闭后读协程会收到EOF退出，确认没有遗留的会话协程
+		if !ts.WaitGoroutines(500 * time.Millisecond) {
+			fmt.Printf("终端会话关闭后仍有 %d 个协程未退出\n", ts.ActiveGoroutines())
+		}
 	})
 	return err
 }
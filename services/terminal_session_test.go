@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"go-term/internal/sshtest"
+)
+
+// waitGoroutines 等待协程数量回落到 limit 以内，返回最后一次观察到的数量
+func waitGoroutines(limit int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		n := runtime.NumGoroutine()
+		if n <= limit || time.Now().After(deadline) {
+			return n
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTerminalSessionCloseStopsGoroutines(t *testing.T) {
+	conn := connectTestServer(t, sshtest.NewServer(t, nil))
+
+	cycle := func() {
+		ts, err := conn.CreateTerminalSessionWithOptions(80, 24, TerminalOptions{IdleTimeoutSeconds: 3600})
+		if err != nil {
+			t.Fatalf("创建终端会话失败: %v", err)
+		}
+		ts.SetEventEmitter("test", func(string, ...interface{}) {})
+		ts.StartOutputPusher()
+		ts.StartIdleWatcher(func() {})
+
+		if err := ts.SendCommand("echo hello"); err != nil {
+			t.Fatalf("发送命令失败: %v", err)
+		}
+		ts.WaitForOutputQuiet(20*time.Millisecond, time.Second)
+		if !strings.Contains(ts.GetBufferedOutput(), "echo hello") {
+			t.Fatalf("没有收到回显输出: %q", ts.GetBufferedOutput())
+		}
+
+		if err := ts.Close(); err != nil {
+			t.Fatalf("关闭终端会话失败: %v", err)
+		}
+		if n := ts.ActiveGoroutines(); n != 0 {
+			t.Fatalf("关闭后仍有 %d 个会话协程在运行", n)
+		}
+		select {
+		case <-ts.Context().Done():
+		default:
+			t.Fatal("关闭后会话上下文没有被取消")
+		}
+	}
+
+	// 先完成一次创建/关闭，排除 ssh 库和测试服务器按需启动的常驻协程
+	cycle()
+	before := waitGoroutines(0, 200*time.Millisecond)
+
+	for i := 0; i < 20; i++ {
+		cycle()
+	}
+
+	// 测试服务器端的会话协程在通道关闭后异步退出，给出少量余量
+	if n := waitGoroutines(before, 2*time.Second); n > before {
+		t.Fatalf("创建/关闭会话后协程数量从 %d 增加到 %d", before, n)
+	}
+}
+
+func TestRemoveANSIEscapeSequences(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello world\n", "hello world\n"},
+		{"sgr color", "\x1b[01;34mbin\x1b[0m  \x1b[01;32mrun.sh\x1b[0m", "bin  run.sh"},
+		{"sgr 256 color", "\x1b[38;5;208morange\x1b[m", "orange"},
+		{"bash prompt with title", "\x1b]0;root@web-01: ~\x07root@web-01:~# ", "root@web-01:~# "},
+		{"osc terminated by st", "\x1b]2;title\x1b\\text", "text"},
+		{"bracketed paste mode", "\x1b[?2004hroot@host:~# ls\r\n\x1b[?2004l", "root@host:~# ls\n"},
+		{"erase line and cursor", "\x1b[K\x1b[2Kline\x1b[1A\x1b[10D\x1b[3G", "line"},
+		{"cursor save restore", "\x1b7saved\x1b8", "saved"},
+		{"charset selection", "\x1b(B\x1b)0text", "text"},
+		{"application keypad", "\x1b=\x1b>keys", "keys"},
+		{"bell and backspace", "abc\x07\x08d", "abcd"},
+		{"tab completion listing", "\x07\r\nfile1.txt  file2.txt\r\n\x1b[?2004hroot@host:~# cat file", "\nfile1.txt  file2.txt\nroot@host:~# cat file"},
+		{"tabs and newlines kept", "a\tb\nc", "a\tb\nc"},
+		{"lone escape", "x\x1b", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := removeANSIEscapeSequences(tt.in); got != tt.want {
+				t.Fatalf("removeANSIEscapeSequences(%q) = %q, 期望 %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAutoCompleteSuggestionsStripsEscapes(t *testing.T) {
+	ts := &TerminalSession{}
+	// bash 双击 Tab 后的真实输出：响铃、补全列表（带颜色）和重新绘制的提示符
+	output := "\x07\r\n\x1b[01;34mconfig\x1b[0m/  \x1b[01;32mconfigure\x1b[0m  config.yaml\r\n" +
+		"\x1b]0;root@web-01: ~\x07\x1b[?2004hroot@web-01:~# cat conf"
+	got := ts.ParseAutoCompleteSuggestions("cat conf", output)
+	want := []string{"cat config/", "cat configure", "cat config.yaml"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("补全建议为 %q, 期望 %q", got, want)
+	}
+}
+
+// newPipeTerminalSession 创建从 r 读取输出的终端会话（不需要 SSH 连接），测试结束时停止读取协程
+func newPipeTerminalSession(t *testing.T, r io.Reader, bufferSize int, backpressure bool) *TerminalSession {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := &TerminalSession{
+		OutputChan:   make(chan []byte, bufferSize),
+		closeChan:    ctx.Done(),
+		ctx:          ctx,
+		cancel:       cancel,
+		outputNotify: make(chan struct{}, 1),
+		scrollback:   NewRingBuffer(0),
+		backpressure: backpressure,
+	}
+	ts.Go(func(ctx context.Context) { ts.readLoop(r, ts.OutputChan) })
+	t.Cleanup(func() {
+		cancel()
+		ts.WaitGoroutines(time.Second)
+	})
+	return ts
+}
+
+// produceChunks 快速写入 count 个带序号的数据块，写完后关闭管道
+func produceChunks(w *io.PipeWriter, count int) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < count; i++ {
+			if _, err := fmt.Fprintf(w, "%06d\n", i); err != nil {
+				return
+			}
+		}
+		w.Close()
+	}()
+	return done
+}
+
+func TestTerminalOutputBackpressureKeepsFastOutput(t *testing.T) {
+	const chunks = 2000
+	r, w := io.Pipe()
+	ts := newPipeTerminalSession(t, r, 4, true)
+	produced := produceChunks(w, chunks)
+
+	// 消费者比生产者慢，背压模式下读取协程等待而不是丢弃数据
+	for i := 0; i < chunks; i++ {
+		select {
+		case data := <-ts.OutputChan:
+			if want := fmt.Sprintf("%06d\n", i); string(data) != want {
+				t.Fatalf("第 %d 块数据为 %q, 期望 %q", i, data, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("等待第 %d 块数据超时", i)
+		}
+		if i%100 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	<-produced
+}
+
+func TestTerminalOutputDropsOldestWithoutBlocking(t *testing.T) {
+	const chunks = 2000
+	r, w := io.Pipe()
+	ts := newPipeTerminalSession(t, r, 4, false)
+
+	// 没有消费者时读取协程也不能阻塞，生产者必须能写完
+	select {
+	case <-produceChunks(w, chunks):
+	case <-time.After(5 * time.Second):
+		t.Fatal("输出通道满时读取协程被阻塞")
+	}
+	if !ts.WaitGoroutines(time.Second) {
+		t.Fatal("读取到 EOF 后读取协程没有退出")
+	}
+
+	// 通道中保留的是最新的数据
+	var last string
+	for len(ts.OutputChan) > 0 {
+		last = string(<-ts.OutputChan)
+	}
+	if want := fmt.Sprintf("%06d\n", chunks-1); last != want {
+		t.Fatalf("最后保留的数据为 %q, 期望 %q", last, want)
+	}
+}
+
+// scriptedReader 按预设的间隔依次返回数据块，模拟远端的输出节奏
+type scriptedReader struct {
+	chunks []scriptedChunk
+	done   chan struct{}
+}
+
+type scriptedChunk struct {
+	delay time.Duration
+	data  string
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		// 输出结束后保持连接，不返回 EOF
+		<-r.done
+		return 0, io.EOF
+	}
+	chunk := r.chunks[0]
+	r.chunks = r.chunks[1:]
+	select {
+	case <-time.After(chunk.delay):
+	case <-r.done:
+		return 0, io.EOF
+	}
+	return copy(p, chunk.data), nil
+}
+
+func TestWaitForOutputQuiet(t *testing.T) {
+	const quiet = 50 * time.Millisecond
+	tests := []struct {
+		name    string
+		chunks  []scriptedChunk
+		maxWait time.Duration
+		want    int
+		minWait time.Duration
+		maxTook time.Duration
+	}{
+		{
+			name:    "no output waits until max",
+			maxWait: 150 * time.Millisecond,
+			want:    0,
+			minWait: 150 * time.Millisecond,
+			maxTook: time.Second,
+		},
+		{
+			name:    "slow first output is not cut off by quiet period",
+			chunks:  []scriptedChunk{{200 * time.Millisecond, "hello\n"}},
+			maxWait: 2 * time.Second,
+			want:    6,
+			minWait: 200 * time.Millisecond,
+			maxTook: time.Second,
+		},
+		{
+			name: "bursts within quiet period are collected",
+			chunks: []scriptedChunk{
+				{10 * time.Millisecond, "a"},
+				{20 * time.Millisecond, "bb"},
+				{20 * time.Millisecond, "ccc"},
+				{20 * time.Millisecond, "dddd"},
+			},
+			maxWait: 2 * time.Second,
+			want:    10,
+			minWait: 70 * time.Millisecond,
+			maxTook: time.Second,
+		},
+		{
+			name: "output after quiet period is not waited for",
+			chunks: []scriptedChunk{
+				{10 * time.Millisecond, "first"},
+				{500 * time.Millisecond, "late"},
+			},
+			maxWait: 2 * time.Second,
+			want:    5,
+			minWait: 10 * time.Millisecond,
+			maxTook: 400 * time.Millisecond,
+		},
+		{
+			name: "continuous output stops at max wait",
+			chunks: func() []scriptedChunk {
+				chunks := make([]scriptedChunk, 100)
+				for i := range chunks {
+					chunks[i] = scriptedChunk{10 * time.Millisecond, "x"}
+				}
+				return chunks
+			}(),
+			maxWait: 200 * time.Millisecond,
+			want:    -1,
+			minWait: 200 * time.Millisecond,
+			maxTook: 600 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &scriptedReader{chunks: tt.chunks, done: make(chan struct{})}
+			defer close(reader.done)
+			ts := newPipeTerminalSession(t, reader, 256, false)
+
+			start := time.Now()
+			got := ts.WaitForOutputQuiet(quiet, tt.maxWait)
+			took := time.Since(start)
+
+			if tt.want >= 0 && got != tt.want {
+				t.Fatalf("收到 %d 字节输出, 期望 %d", got, tt.want)
+			}
+			if tt.want < 0 && (got == 0 || got >= len(tt.chunks)) {
+				t.Fatalf("持续输出时收到 %d 字节, 应在最长等待时间内返回部分输出", got)
+			}
+			if took < tt.minWait || took > tt.maxTook {
+				t.Fatalf("等待了 %v, 期望在 %v 到 %v 之间", took, tt.minWait, tt.maxTook)
+			}
+		})
+	}
+}
+
+func TestTerminalSessionResizeConcurrent(t *testing.T) {
+	// 在 -race 下运行：调整尺寸与读取尺寸并发进行
+	conn := connectTestServer(t, sshtest.NewServer(t, nil))
+	ts, err := conn.CreateTerminalSessionWithOptions(80, 24, TerminalOptions{})
+	if err != nil {
+		t.Fatalf("创建终端会话失败: %v", err)
+	}
+	defer ts.Close()
+
+	if width, height := ts.Size(); width != 80 || height != 24 {
+		t.Fatalf("初始尺寸为 %dx%d", width, height)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			if err := ts.ResizeTerminal(100+i, 30+i); err != nil {
+				t.Errorf("调整终端大小失败: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		// 宽高总是同一次调整设置的
+		if width, height := ts.Size(); width-height != 56 && width != 80 {
+			t.Fatalf("读取到不一致的尺寸 %dx%d", width, height)
+		}
+	}
+	<-done
+
+	if width, height := ts.Size(); width != 199 || height != 129 {
+		t.Fatalf("调整后尺寸为 %dx%d, 期望 199x129", width, height)
+	}
+}
+
+func TestSuppressedOutputReplayedOnResume(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+	ts := newPipeTerminalSession(t, r, 16, false)
+
+	ts.SetOutputSuppressed(true)
+	for _, chunk := range []string{"first ", "second ", "third"} {
+		if _, err := io.WriteString(w, chunk); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+	ts.WaitForOutputQuiet(20*time.Millisecond, time.Second)
+
+	select {
+	case data := <-ts.OutputChan:
+		t.Fatalf("暂停期间不应推送输出: %q", data)
+	default:
+	}
+	if got := ts.GetBufferedOutput(); got != "first second third" {
+		t.Fatalf("暂停期间的输出应可读取: %q", got)
+	}
+
+	ts.SetOutputSuppressed(false)
+	select {
+	case data := <-ts.OutputChan:
+		if string(data) != "first second third" {
+			t.Fatalf("恢复后补发的输出 = %q", data)
+		}
+	default:
+		t.Fatal("恢复推送后应补发暂停期间的输出")
+	}
+	if got := string(ts.scrollback.Tail(1024)); got != "first second third" {
+		t.Fatalf("补发的输出应写入回滚缓冲区: %q", got)
+	}
+
+	// 恢复后新的输出照常推送
+	if _, err := io.WriteString(w, "after"); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	select {
+	case data := <-ts.OutputChan:
+		if string(data) != "after" {
+			t.Fatalf("恢复后的输出 = %q", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("恢复后没有推送新的输出")
+	}
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/pkg/sftp"
+)
+
+// SFTP 协议状态码（draft-ietf-secsh-filexfer），pkg/sftp 未导出其中部分常量
+const (
+	sftpStatusNoSuchFile          = 2
+	sftpStatusPermissionDenied    = 3
+	sftpStatusFailure             = 4
+	sftpStatusNoSpaceOnFilesystem = 14
+	sftpStatusQuotaExceeded       = 15
+)
+
+// TransferError 文件传输失败的详细信息，Reason 为可读的失败原因
+type TransferError struct {
+	Op          string // 上传 / 下载
+	Path        string // 远程路径
+	Transferred int64  // 失败前已传输的字节数
+	Total       int64  // 文件总字节数
+	Reason      string
+	Err         error
+}
+
+func (e *TransferError) Error() string {
+	return fmt.Sprintf("%s %s 失败：%s（已传输 %d/%d 字节）: %v", e.Op, e.Path, e.Reason, e.Transferred, e.Total, e.Err)
+}
+
+func (e *TransferError) Unwrap() error {
+	return e.Err
+}
+
+// newTransferError 对传输错误进行分类，remote 表示出错的一端是否为远程服务器
+func newTransferError(op, path string, remote bool, transferred, total int64, err error) error {
+	return &TransferError{
+		Op:          op,
+		Path:        path,
+		Transferred: transferred,
+		Total:       total,
+		Reason:      classifyTransferError(err, remote),
+		Err:         err,
+	}
+}
+
+// classifyTransferError 将常见的 SFTP 状态码和本地系统错误转换为可操作的提示
+func classifyTransferError(err error, remote bool) string {
+	side := "本地"
+	if remote {
+		side = "远程"
+	}
+
+	var status *sftp.StatusError
+	if errors.As(err, &status) {
+		switch status.Code {
+		case sftpStatusNoSpaceOnFilesystem:
+			return "远程磁盘空间不足，请清理目标磁盘后重试"
+		case sftpStatusQuotaExceeded:
+			return "超出远程用户的磁盘配额"
+		case sftpStatusPermissionDenied:
+			return "远程权限不足，请检查目标路径的写入权限"
+		case sftpStatusNoSuchFile:
+			return "远程文件或目录不存在"
+		case sftpStatusFailure:
+			// OpenSSH 的 sftp-server 会把磁盘已满等错误统一报告为 FAILURE
+			return "远程服务器拒绝了操作，常见原因为磁盘已满、配额超限或只读文件系统"
+		}
+	}
+
+	switch {
+	case errors.Is(err, sftp.ErrSSHFxConnectionLost), errors.Is(err, sftp.ErrSSHFxNoConnection),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.EPIPE):
+		return "连接已断开，请重新连接后重试"
+	case errors.Is(err, syscall.ENOSPC):
+		return side + "磁盘空间不足"
+	case errors.Is(err, os.ErrPermission):
+		return side + "权限不足，请检查路径的访问权限"
+	case errors.Is(err, os.ErrNotExist):
+		return side + "文件或目录不存在"
+	}
+	return "未知错误"
+}